@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Maximum number of background tasks running at the same time, and the time they are given to
+// finish once the server has shut down
+const (
+	maxBackgroundTasks     = 100
+	backgroundTasksTimeout = 5 * time.Second
+)
+
+// Tasks started by the handlers and the jobs which outlive the request or the run starting them, e.g.
+// counting the events of the movies. The tasks are given a context which is cancelled when they are
+// stopped along with the server, and the server waits for them before closing the database
+type backgroundTasks struct {
+	ctxt   context.Context
+	cancel context.CancelFunc
+	slots  chan struct{}
+	wg     sync.WaitGroup
+
+	mutx    sync.Mutex
+	stopped bool
+}
+
+// Create the tasks, cancelled along with the parent context
+func newBackgroundTasks(parent context.Context, limit int) *backgroundTasks {
+
+	ctxt, cancel := context.WithCancel(parent)
+
+	return &backgroundTasks{ctxt: ctxt, cancel: cancel, slots: make(chan struct{}, limit)}
+}
+
+// Run the task in the background. Once the maximum number of tasks are running, the task is run by
+// the caller instead, which holds the caller up rather than dropping the task
+func (tasks *backgroundTasks) run(task func(ctxt context.Context)) {
+
+	tasks.mutx.Lock()
+
+	if tasks.stopped {
+		tasks.mutx.Unlock()
+		task(tasks.ctxt)
+		return
+	}
+
+	tasks.wg.Add(1)
+	tasks.mutx.Unlock()
+
+	select {
+	case tasks.slots <- struct{}{}:
+		go func() {
+			defer func() {
+				<-tasks.slots
+				tasks.wg.Done()
+			}()
+
+			task(tasks.ctxt)
+		}()
+	default:
+		defer tasks.wg.Done()
+		task(tasks.ctxt)
+	}
+}
+
+// Wait for the running tasks to finish, cancelling them if they have not finished by the timeout.
+// The tasks started later are run by their callers with the cancelled context
+func (tasks *backgroundTasks) stop(timeout time.Duration) {
+
+	tasks.mutx.Lock()
+	tasks.stopped = true
+	tasks.mutx.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		tasks.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		tasks.cancel()
+		<-done
+	}
+
+	tasks.cancel()
+}
+
+// Run the task in the background, logging its panics
+func (app *application) background(task func(ctxt context.Context)) {
+
+	app.tasks.run(func(ctxt context.Context) {
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.PrintError(fmt.Errorf("%s", err), nil)
+			}
+		}()
+
+		task(ctxt)
+	})
+}
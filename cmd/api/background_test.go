@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+func TestBackgroundTasksLimit(t *testing.T) {
+
+	tasks := newBackgroundTasks(context.Background(), 1)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	tasks.run(func(ctxt context.Context) {
+		close(started)
+		<-release
+	})
+
+	<-started
+
+	// The only slot is taken, so the next task is run by the caller
+	inline := false
+	tasks.run(func(ctxt context.Context) { inline = true })
+
+	if !inline {
+		t.Error("got the task started in the background past the limit; want it run by the caller")
+	}
+
+	close(release)
+	tasks.stop(time.Second)
+
+	if tasks.ctxt.Err() == nil {
+		t.Error("got the context of the tasks alive after stopping; want it cancelled")
+	}
+}
+
+func TestBackgroundTasksStop(t *testing.T) {
+
+	tasks := newBackgroundTasks(context.Background(), 10)
+
+	finished := make(chan struct{})
+	cancelled := make(chan error, 1)
+
+	tasks.run(func(ctxt context.Context) {
+		<-finished
+	})
+
+	tasks.run(func(ctxt context.Context) {
+		<-ctxt.Done()
+		cancelled <- ctxt.Err()
+	})
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(finished)
+	}()
+
+	// The task waiting for the cancellation is only cancelled once the timeout has passed
+	start := time.Now()
+	tasks.stop(50 * time.Millisecond)
+
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("got stopped after %s; want the tasks waited for until the timeout", elapsed)
+	}
+
+	select {
+	case err := <-cancelled:
+		if err != context.Canceled {
+			t.Errorf("got error %v; want %v", err, context.Canceled)
+		}
+	default:
+		t.Error("got the stop returning before the tasks; want the tasks waited for")
+	}
+}
+
+func TestRecordMovieEvent(t *testing.T) {
+
+	app := newTestApplication(t)
+
+	addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"science-fiction"}})
+
+	for i := 0; i < 3; i++ {
+		res, _ := app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/movies/1"})
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("got status %d; want %d", res.StatusCode, http.StatusOK)
+		}
+	}
+
+	// The views are counted in the background, which the server waits for when it shuts down
+	app.tasks.stop(time.Second)
+
+	if count := app.models.Popularity.(data.MemoryPopularityModel).Count(1, data.EventView); count != 3 {
+		t.Errorf("got %d views counted; want 3", count)
+	}
+}
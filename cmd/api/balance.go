@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// Show the balance of the authenticated user along with the ledger entries
+func (app *application) showUserBalanceHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		data.Filters
+	}
+
+	val := validator.NewValidator()
+
+	queryString := r.URL.Query()
+
+	input.Filters.Page = app.readInt(queryString, "page", 1, val)
+	input.Filters.PageSize = app.readInt(queryString, "page_size", 20, val)
+	input.Filters.Sort = app.readString(queryString, "sort", "-created_at")
+	input.Filters.SortList = []string{"created_at", "amount", "-created_at", "-amount"}
+
+	if data.ValidateFilters(val, &input.Filters); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	balance, err := app.models.Ledger.GetBalance(r.Context(), user.ID, app.pricing.Currency())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	entries, metadata, err := app.models.Ledger.GetAllForUser(r.Context(), user.ID, input.Filters)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"balance": balance, "metadata": metadata, "entries": entries}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
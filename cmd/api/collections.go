@@ -0,0 +1,321 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+func (app *application) listCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	collections, err := app.modelsFor(r).Collections.GetAll(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"collections": collections}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) createCollectionHandler(w http.ResponseWriter, r *http.Request) {
+
+	var request struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+
+	err := app.readJsonRequest(w, r, &request)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	collection := &data.Collection{
+		Name:        request.Name,
+		Description: request.Description,
+	}
+
+	val := validator.NewValidator()
+
+	if data.ValidateCollection(val, collection); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	err = app.modelsFor(r).Collections.Insert(r.Context(), collection)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	header := make(http.Header)
+	header.Set("Location", fmt.Sprintf("/v1/collections/%d", collection.ID))
+
+	err = app.writeJsonResponse(w, r, envelope{"collection": collection}, header, http.StatusCreated)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Show a collection along with a page of its movies, in their order within the collection unless sorted otherwise
+func (app *application) showCollectionHandler(w http.ResponseWriter, r *http.Request) {
+
+	collection, ok := app.readCollection(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	val := validator.NewValidator()
+
+	queryString := r.URL.Query()
+
+	input.Filters.Page = app.readInt(queryString, "page", 1, val)
+	input.Filters.PageSize = app.readInt(queryString, "page_size", 20, val)
+	input.Filters.Sort = app.readString(queryString, "sort", "position")
+	input.Filters.SortList = []string{"position", "title", "year", "-position", "-title", "-year"}
+
+	proj := app.readProjection(queryString, data.MovieFields, movieIncludes, val, "movies")
+
+	input.Filters.Fields = proj.fields
+
+	if data.ValidateFilters(val, &input.Filters); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	movies, metadata, err := app.modelsFor(r).Movies.GetAll(r.Context(), data.MovieSearch{CollectionID: collection.ID}, input.Filters)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Load the related resources to be embedded
+	err = app.embedMovieRelations(r.Context(), proj, movies...)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	r = app.contextSetProjection(r, proj)
+
+	err = app.writeJsonResponse(w, r, envelope{"collection": collection, "metadata": metadata, "movies": movies}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) editCollectionHandler(w http.ResponseWriter, r *http.Request) {
+
+	collection, ok := app.readCollection(w, r)
+	if !ok {
+		return
+	}
+
+	// All members are pointers to check whether the values have been provided by the user or not
+	var request struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+	}
+
+	err := app.readJsonRequest(w, r, &request)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if request.Name != nil {
+		collection.Name = *request.Name
+	}
+
+	if request.Description != nil {
+		collection.Description = *request.Description
+	}
+
+	val := validator.NewValidator()
+
+	if data.ValidateCollection(val, collection); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	err = app.modelsFor(r).Collections.Update(r.Context(), collection)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictError(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"collection": collection}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) deleteCollectionHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	err = app.modelsFor(r).Collections.Delete(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"message": "collection successfully deleted"}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Put a movie at a position of the collection. A movie of another collection is moved to this one
+func (app *application) putCollectionMovieHandler(w http.ResponseWriter, r *http.Request) {
+
+	collection, ok := app.readCollection(w, r)
+	if !ok {
+		return
+	}
+
+	movieID, err := app.readNamedIDParam(r, "movie_id")
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	var request struct {
+		Position int32 `json:"position"`
+	}
+
+	err = app.readJsonRequest(w, r, &request)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	val := validator.NewValidator()
+
+	val.Check(request.Position != 0, "position", "must be provided")
+	val.Check(request.Position >= 0, "position", "must be a positive integer")
+
+	if !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	// The movie must be one of the tenant
+	movie, err := app.modelsFor(r).Movies.Get(r.Context(), movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrParentalRestricted):
+			app.parentalRestrictedResponse(w, r)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.models.Collections.SetMovie(r.Context(), collection.ID, movie.ID, request.Position)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateCollectionPosition):
+			val.AddError("position", "another movie is already at this position of the collection")
+			app.failedValidations(w, r, val.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	membership := &data.CollectionMembership{ID: collection.ID, Name: collection.Name, Position: request.Position}
+
+	err = app.writeJsonResponse(w, r, envelope{"collection": membership}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Remove a movie from the collection
+func (app *application) removeCollectionMovieHandler(w http.ResponseWriter, r *http.Request) {
+
+	collection, ok := app.readCollection(w, r)
+	if !ok {
+		return
+	}
+
+	movieID, err := app.readNamedIDParam(r, "movie_id")
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	err = app.models.Collections.RemoveMovie(r.Context(), collection.ID, movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"message": "movie successfully removed from the collection"}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Read the collection identified by the ID parameter. An error response is sent if it could not be read
+func (app *application) readCollection(w http.ResponseWriter, r *http.Request) (*data.Collection, bool) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	collection, err := app.modelsFor(r).Collections.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return nil, false
+	}
+
+	return collection, true
+}
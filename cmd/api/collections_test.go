@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+func TestCollectionHandlers(t *testing.T) {
+
+	app := newTestApplication(t)
+
+	addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"science-fiction"}})
+	addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Aliens", Year: 1986, Runtime: 137, Genres: []string{"science-fiction"}})
+
+	other := addTestTenant(t, app, "other")
+	addTestMovie(t, app, other, &data.Movies{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"thriller"}})
+
+	_, editor := addTestUser(t, app, data.DefaultTenantID, "editor@example.com", data.PermissionWriteMovies)
+
+	franchise := map[string]interface{}{"name": "Alien", "description": "The Alien franchise"}
+
+	res, body := app.testRequest(t, testRequest{method: http.MethodPost, path: "/v1/collections", body: map[string]interface{}{"description": "No name"}, token: editor})
+	if res.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("create without a name: got status %d; want %d (%v)", res.StatusCode, http.StatusUnprocessableEntity, body)
+	}
+
+	res, body = app.testRequest(t, testRequest{method: http.MethodPost, path: "/v1/collections", body: franchise, token: editor})
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("create: got status %d; want %d (%v)", res.StatusCode, http.StatusCreated, body)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		position int
+		status   int
+	}{
+		{"first movie", "/v1/collections/1/movies/1", 1, http.StatusOK},
+		{"second movie", "/v1/collections/1/movies/2", 2, http.StatusOK},
+		{"taken position", "/v1/collections/1/movies/2", 1, http.StatusUnprocessableEntity},
+		{"missing position", "/v1/collections/1/movies/2", 0, http.StatusUnprocessableEntity},
+		{"movie of another tenant", "/v1/collections/1/movies/3", 3, http.StatusNotFound},
+		{"unknown collection", "/v1/collections/9/movies/1", 1, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			res, body := app.testRequest(t, testRequest{method: http.MethodPut, path: tt.path, body: map[string]interface{}{"position": tt.position}, token: editor})
+			if res.StatusCode != tt.status {
+				t.Errorf("got status %d; want %d (%v)", res.StatusCode, tt.status, body)
+			}
+		})
+	}
+
+	// The collection is embedded in the movie along with the position of the movie
+	res, body = app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/movies/2?include=collection"})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("show movie: got status %d; want %d", res.StatusCode, http.StatusOK)
+	}
+
+	membership, _ := body["movie"].(map[string]interface{})["collection"].(map[string]interface{})
+	if membership["name"] != "Alien" || membership["position"] != float64(2) {
+		t.Errorf("show movie: got collection %v; want Alien at position 2", membership)
+	}
+
+	res, body = app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/collections/1"})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("show: got status %d; want %d", res.StatusCode, http.StatusOK)
+	}
+
+	if count := body["collection"].(map[string]interface{})["movie_count"]; count != float64(2) {
+		t.Errorf("show: got %v movies; want 2", count)
+	}
+
+	// The collections of the other tenants are not visible
+	res, _ = app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/collections/1", header: http.Header{"X-Tenant": []string{"other"}}})
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("show from another tenant: got status %d; want %d", res.StatusCode, http.StatusNotFound)
+	}
+
+	res, _ = app.testRequest(t, testRequest{method: http.MethodDelete, path: "/v1/collections/1/movies/1", token: editor})
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("remove movie: got status %d; want %d", res.StatusCode, http.StatusOK)
+	}
+
+	res, _ = app.testRequest(t, testRequest{method: http.MethodDelete, path: "/v1/collections/1/movies/1", token: editor})
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("remove movie again: got status %d; want %d", res.StatusCode, http.StatusNotFound)
+	}
+
+	res, _ = app.testRequest(t, testRequest{method: http.MethodDelete, path: "/v1/collections/1", token: editor})
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("delete: got status %d; want %d", res.StatusCode, http.StatusOK)
+	}
+}
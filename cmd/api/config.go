@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/jsonlog"
+	"github.com/narinderv/blockbuster/internal/validator"
+	"gopkg.in/yaml.v3"
+)
+
+// Prefix of the environment variables of the settings e.g. BLOCKBUSTER_DB_TIMEOUT for -db-timeout
+const envPrefix = "BLOCKBUSTER_"
+
+// Settings which are redacted when printed. Each of them can also be read from a file, given by the
+// setting of the same name suffixed with -file e.g. -dsn-file=/run/secrets/dsn
+var secretSettings = []string{"dsn", "s3-access-key", "s3-secret-key", "enrich-api-key"}
+
+var errConfigUsage = errors.New("usage: api [flags] config print")
+
+// Setting whose value is read from a file
+type secretFile struct {
+	flags   *flag.FlagSet
+	setting string
+	path    string
+}
+
+func (file *secretFile) String() string {
+	return file.path
+}
+
+func (file *secretFile) Set(path string) error {
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	file.path = path
+
+	// Editors add a newline at the end of the file, which is not part of the secret
+	return file.flags.Set(file.setting, strings.TrimRight(string(content), "\r\n"))
+}
+
+// Name of the environment variable of the setting
+func envName(setting string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(setting, "-", "_"))
+}
+
+// Get the setting a flag stands for. The flags reading a secret from a file stand for the secret
+func settingOf(name string) string {
+
+	for _, secret := range secretSettings {
+		if name == secret+"-file" {
+			return secret
+		}
+	}
+
+	return name
+}
+
+func isSecret(name string) bool {
+	return validator.Permittedvalues(settingOf(name), secretSettings...)
+}
+
+// Load the settings defined as flags. The settings are read, from the lowest to the highest precedence,
+// from the defaults of the flags, the configuration file, the BLOCKBUSTER_ environment variables
+// and the flags on the command line. The configuration file is given by -config or BLOCKBUSTER_CONFIG
+func loadConfig(flags *flag.FlagSet, args []string) error {
+
+	configFile := flags.String("config", "", "JSON, TOML (*.toml) or YAML (*.yaml, *.yml) file with the settings, keyed by the names of the flags")
+
+	for _, secret := range secretSettings {
+		flags.Var(&secretFile{flags: flags, setting: secret}, secret+"-file", "File to read the "+secret+" setting from")
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// Settings given on the command line, which are not overridden
+	explicit := make(map[string]bool)
+
+	flags.Visit(func(f *flag.Flag) {
+		explicit[settingOf(f.Name)] = true
+	})
+
+	if *configFile == "" {
+		*configFile = os.Getenv(envName("config"))
+	}
+
+	if *configFile != "" {
+		if err := loadConfigFile(flags, *configFile, explicit); err != nil {
+			return err
+		}
+	}
+
+	var err error
+
+	flags.VisitAll(func(f *flag.Flag) {
+
+		value, found := os.LookupEnv(envName(f.Name))
+		if !found || err != nil || explicit[settingOf(f.Name)] || f.Name == "config" {
+			return
+		}
+
+		if setErr := flags.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value of the environment variable %s: %w", envName(f.Name), setErr)
+		}
+	})
+
+	return err
+}
+
+// Apply the settings of the configuration file, except the ones given on the command line. The file is
+// read as TOML or YAML by its extension, and as JSON otherwise
+func loadConfigFile(flags *flag.FlagSet, path string, explicit map[string]bool) error {
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var settings map[string]interface{}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(content, &settings)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &settings)
+	default:
+		err = json.Unmarshal(content, &settings)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+
+	// Applied in the order of the names so that any errors are reported consistently
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if flags.Lookup(name) == nil || name == "config" {
+			return fmt.Errorf("unknown setting %q in the configuration file %s", name, path)
+		}
+
+		if explicit[settingOf(name)] {
+			continue
+		}
+
+		var value string
+
+		switch v := settings[name].(type) {
+		case string:
+			value = v
+		case float64:
+			value = strconv.FormatFloat(v, 'f', -1, 64)
+		case int:
+			value = strconv.Itoa(v)
+		case int64:
+			value = strconv.FormatInt(v, 10)
+		case bool:
+			value = strconv.FormatBool(v)
+		default:
+			return fmt.Errorf("setting %q in the configuration file %s must be a string, number or boolean", name, path)
+		}
+
+		if err = flags.Set(name, value); err != nil {
+			return fmt.Errorf("invalid value of the setting %q in the configuration file %s: %w", name, path, err)
+		}
+	}
+
+	return nil
+}
+
+// Validate the configuration, reporting all the invalid settings at once
+func validateConfig(conf configuration) error {
+
+	val := validator.NewValidator()
+
+	val.Check(conf.port >= 1 && conf.port <= 65535, "port", "must be between 1 and 65535")
+	val.Check(validator.Permittedvalues(conf.env, "development", "staging", "production"), "env",
+		"must be development, staging or production")
+
+	// Database
+	val.Check(conf.dbDetails.dsn != "", "dsn", "must be provided")
+	val.Check(conf.dbDetails.maxOpenConns >= 1, "db-max-open-conns", "must be atleast 1")
+	val.Check(conf.dbDetails.maxIdleConns >= 0, "db-max-idle-conns", "must not be negative")
+	val.Check(conf.dbDetails.maxIdleConns <= conf.dbDetails.maxOpenConns, "db-max-idle-conns",
+		"must not be more than db-max-open-conns")
+
+	idleTime, err := time.ParseDuration(conf.dbDetails.maxIdleTime)
+	val.Check(err == nil && idleTime > 0, "db-max-idle-time", "must be a positive duration e.g. 15m")
+
+	val.Check(conf.dbDetails.queryTimeout > 0, "db-timeout", "must be a positive duration")
+
+	if _, err = data.ParseTimeouts(conf.dbDetails.queryTimeouts); err != nil {
+		val.AddError("db-timeouts", err.Error())
+	}
+
+	_, err = jsonlog.ParseLevel(conf.logLevel)
+	val.Check(err == nil, "log-level", "must be info, error, fatal or off")
+
+	// Rate limiting
+	if conf.rateLimiter.enabled {
+		val.Check(conf.rateLimiter.tps > 0, "tps", "must be greater than zero")
+		val.Check(conf.rateLimiter.burstLimit >= 1, "burst", "must be atleast 1")
+	}
+
+	val.Check(conf.tenant.header != "", "tenant-header", "must be provided")
+
+	for _, origin := range strings.Fields(conf.corsTrustedOrigins) {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+			val.AddError("cors-trusted-origins", "must be http or https origins e.g. https://www.example.com")
+			break
+		}
+	}
+
+	// Background jobs
+	val.Check(conf.similarityRefresh >= 0, "similarity-refresh", "must not be negative")
+	val.Check(conf.recommendationRefresh >= 0, "recommendation-refresh", "must not be negative")
+	val.Check(conf.popularityRefresh >= 0, "popularity-refresh", "must not be negative")
+	val.Check(conf.enrich.interval >= 0, "enrich-interval", "must not be negative")
+
+	// Image storage
+	val.Check(validator.Permittedvalues(conf.storage.backend, "local", "s3"), "storage", "must be local or s3")
+
+	switch conf.storage.backend {
+	case "local":
+		val.Check(conf.storage.dir != "", "storage-dir", "must be provided for the local storage")
+	case "s3":
+		val.Check(conf.storage.s3.Bucket != "", "s3-bucket", "must be provided for the s3 storage")
+	}
+
+	// Metadata enrichment
+	if conf.enrich.config.BaseURL != "" {
+		val.Check(conf.enrich.config.RequestsPerSecond > 0, "enrich-rps", "must be greater than zero")
+		val.Check(conf.enrich.config.Burst >= 1, "enrich-burst", "must be atleast 1")
+	}
+
+	if val.IsValid() {
+		return nil
+	}
+
+	problems := make([]string, 0, len(val.Errors))
+	for setting, message := range val.Errors {
+		problems = append(problems, setting+" "+message)
+	}
+
+	sort.Strings(problems)
+
+	return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+}
+
+// Run the config subcommand. config print writes the effective settings as JSON, in the format of the
+// configuration file, with the secrets redacted, to the output
+func runConfigCommand(flags *flag.FlagSet, args []string, out io.Writer) error {
+
+	if len(args) != 1 || args[0] != "print" {
+		return errConfigUsage
+	}
+
+	settings := make(map[string]interface{})
+
+	flags.VisitAll(func(f *flag.Flag) {
+
+		// The files of the secrets have already been read into the secrets
+		if f.Name == "config" || settingOf(f.Name) != f.Name {
+			return
+		}
+
+		value := f.Value.String()
+
+		if isSecret(f.Name) && value != "" {
+			settings[f.Name] = "REDACTED"
+			return
+		}
+
+		if getter, ok := f.Value.(flag.Getter); ok {
+			switch v := getter.Get().(type) {
+			case bool, int, float64:
+				settings[f.Name] = v
+				return
+			}
+		}
+
+		settings[f.Name] = value
+	})
+
+	content, err := json.MarshalIndent(settings, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, string(content))
+	return err
+}
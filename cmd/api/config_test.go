@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Define the settings on a fresh set of flags, as the command line flags can only be parsed once
+func newTestFlags() (*flag.FlagSet, *configuration) {
+
+	var conf configuration
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(&bytes.Buffer{})
+
+	defineFlags(flags, &conf)
+
+	return flags, &conf
+}
+
+// Write the content to a file of the test, returning its path
+func writeTestFile(t *testing.T, name, content string) string {
+
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+
+	configFile := writeTestFile(t, "config.json", `{"port": 9000, "env": "staging", "enable-rate-limit": false}`)
+
+	tests := []struct {
+		name  string
+		file  bool              // Give the configuration file with -config
+		env   map[string]string // Environment variables
+		args  []string
+		port  int
+		stage string
+	}{
+		{"defaults", false, nil, nil, 8080, "development"},
+		{"file", true, nil, nil, 9000, "staging"},
+		{"file named in the environment", false, map[string]string{"BLOCKBUSTER_CONFIG": configFile}, nil, 9000, "staging"},
+		{"environment over file", true, map[string]string{"BLOCKBUSTER_PORT": "9100"}, nil, 9100, "staging"},
+		{"environment over defaults", false, map[string]string{"BLOCKBUSTER_ENV": "production"}, nil, 8080, "production"},
+		{"flags over environment", true, map[string]string{"BLOCKBUSTER_PORT": "9100"}, []string{"-port=9200"}, 9200, "staging"},
+		{"flags over file", true, nil, []string{"-env=production"}, 9000, "production"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			args := tt.args
+			if tt.file {
+				args = append([]string{"-config=" + configFile}, args...)
+			}
+
+			flags, conf := newTestFlags()
+
+			if err := loadConfig(flags, args); err != nil {
+				t.Fatal(err)
+			}
+
+			if conf.port != tt.port || conf.env != tt.stage {
+				t.Errorf("got port %d and env %s; want %d and %s", conf.port, conf.env, tt.port, tt.stage)
+			}
+
+			if tt.file && conf.rateLimiter.enabled {
+				t.Error("got rate limiting enabled; want it disabled by the file")
+			}
+		})
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		content string            // Content of the configuration file, if any
+		env     map[string]string // Environment variables
+		args    []string
+	}{
+		{"unknown setting", `{"colour": "red"}`, nil, nil},
+		{"config in the file", `{"config": "other.json"}`, nil, nil},
+		{"invalid json", `{"port": `, nil, nil},
+		{"invalid value in the file", `{"port": "http"}`, nil, nil},
+		{"nested value in the file", `{"port": [8080]}`, nil, nil},
+		{"invalid environment variable", "", map[string]string{"BLOCKBUSTER_PORT": "http"}, nil},
+		{"invalid flag", "", nil, []string{"-port=http"}},
+		{"missing secret file", "", nil, []string{"-dsn-file=/nonexistent/dsn"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			args := tt.args
+			if tt.content != "" {
+				args = append([]string{"-config=" + writeTestFile(t, "config.json", tt.content)}, args...)
+			}
+
+			flags, _ := newTestFlags()
+
+			if err := loadConfig(flags, args); err == nil {
+				t.Error("got no error; want an error")
+			}
+		})
+	}
+}
+
+func TestLoadConfigTOML(t *testing.T) {
+
+	configFile := writeTestFile(t, "config.toml", `
+# Settings of the staging server
+port = 9_000
+env = "staging" # Overridden by BLOCKBUSTER_ENV
+tps = 2.5
+enable-rate-limit = false
+"log-level" = 'error'
+db-timeouts = "movies.get_all=5s,similarities.refresh=10m"
+`)
+
+	t.Setenv("BLOCKBUSTER_ENV", "production")
+
+	flags, conf := newTestFlags()
+
+	if err := loadConfig(flags, []string{"-config=" + configFile}); err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.port != 9000 || conf.env != "production" || conf.rateLimiter.tps != 2.5 || conf.rateLimiter.enabled {
+		t.Errorf("got port %d, env %s, tps %v and rate limiting %t; want 9000, production, 2.5 and false",
+			conf.port, conf.env, conf.rateLimiter.tps, conf.rateLimiter.enabled)
+	}
+
+	if conf.logLevel != "error" || conf.dbDetails.queryTimeouts != "movies.get_all=5s,similarities.refresh=10m" {
+		t.Errorf("got log level %s and timeouts %s; want the ones of the file", conf.logLevel, conf.dbDetails.queryTimeouts)
+	}
+}
+
+func TestLoadConfigYAML(t *testing.T) {
+
+	for _, name := range []string{"config.yaml", "config.yml"} {
+		t.Run(name, func(t *testing.T) {
+
+			configFile := writeTestFile(t, name, `
+# Settings of the staging server
+port: 9000
+env: staging
+tps: 2.5
+enable-rate-limit: false
+"log-level": 'error'
+db-timeouts: "movies.get_all=5s,similarities.refresh=10m"
+`)
+
+			flags, conf := newTestFlags()
+
+			if err := loadConfig(flags, []string{"-config=" + configFile, "-port=9100"}); err != nil {
+				t.Fatal(err)
+			}
+
+			if conf.port != 9100 || conf.env != "staging" || conf.rateLimiter.tps != 2.5 || conf.rateLimiter.enabled {
+				t.Errorf("got port %d, env %s, tps %v and rate limiting %t; want 9100, staging, 2.5 and false",
+					conf.port, conf.env, conf.rateLimiter.tps, conf.rateLimiter.enabled)
+			}
+
+			if conf.logLevel != "error" || conf.dbDetails.queryTimeouts != "movies.get_all=5s,similarities.refresh=10m" {
+				t.Errorf("got log level %s and timeouts %s; want the ones of the file", conf.logLevel, conf.dbDetails.queryTimeouts)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"toml table", "config.toml", "[server]\nport = 8080"},
+		{"toml array", "config.toml", "port = [8080]"},
+		{"toml date", "config.toml", "env = 2023-01-01"},
+		{"toml duplicate key", "config.toml", "port = 8080\nport = 9000"},
+		{"toml unterminated string", "config.toml", `env = "staging`},
+		{"toml bare word", "config.toml", "env = staging"},
+		{"yaml mapping", "config.yaml", "server:\n  port: 8080"},
+		{"yaml list", "config.yml", "port: [8080]"},
+		{"yaml unknown setting", "config.yaml", "color: red"},
+		{"yaml not a mapping", "config.yaml", "- port"},
+		{"json invalid", "config.json", `{"port": }`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			configFile := writeTestFile(t, tt.file, tt.content)
+
+			flags, _ := newTestFlags()
+
+			if err := loadConfig(flags, []string{"-config=" + configFile}); err == nil {
+				t.Error("got no error; want an error")
+			}
+		})
+	}
+}
+
+func TestLoadConfigSecretFiles(t *testing.T) {
+
+	secretFile := writeTestFile(t, "dsn", "postgres://secret@localhost/blockbuster\n")
+	want := "postgres://secret@localhost/blockbuster"
+
+	tests := []struct {
+		name    string
+		content string            // Content of the configuration file, if any
+		env     map[string]string // Environment variables
+		args    []string
+	}{
+		{"flag", "", nil, []string{"-dsn-file=" + secretFile}},
+		{"environment", "", map[string]string{"BLOCKBUSTER_DSN_FILE": secretFile}, nil},
+		{"file", `{"dsn-file": "` + secretFile + `"}`, nil, nil},
+		{"flag over environment", "", map[string]string{"BLOCKBUSTER_DSN": "postgres://other@localhost/other"}, []string{"-dsn-file=" + secretFile}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			args := tt.args
+			if tt.content != "" {
+				args = append([]string{"-config=" + writeTestFile(t, "config.json", tt.content)}, args...)
+			}
+
+			flags, conf := newTestFlags()
+
+			if err := loadConfig(flags, args); err != nil {
+				t.Fatal(err)
+			}
+
+			if conf.dbDetails.dsn != want {
+				t.Errorf("got dsn %q; want %q", conf.dbDetails.dsn, want)
+			}
+		})
+	}
+}
+
+func TestConfigPrint(t *testing.T) {
+
+	secretFile := writeTestFile(t, "key", "enrich-key\n")
+
+	flags, _ := newTestFlags()
+
+	err := loadConfig(flags, []string{"-dsn=postgres://secret@localhost/blockbuster", "-enrich-api-key-file=" + secretFile, "-port=9000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+
+	if err = runConfigCommand(flags, []string{"print"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	var settings map[string]interface{}
+
+	if err = json.Unmarshal(out.Bytes(), &settings); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"dsn":               "REDACTED",
+		"enrich-api-key":    "REDACTED",
+		"s3-secret-key":     "",
+		"port":              float64(9000),
+		"enable-rate-limit": true,
+		"env":               "development",
+	}
+
+	got := make(map[string]interface{})
+	for name := range want {
+		got[name] = settings[name]
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+
+	// The settings which are not part of the configuration are left out
+	for _, name := range []string{"config", "dsn-file", "enrich-api-key-file"} {
+		if _, found := settings[name]; found {
+			t.Errorf("got the %s setting printed; want it left out", name)
+		}
+	}
+
+	if err = runConfigCommand(flags, []string{"show"}, &out); err != errConfigUsage {
+		t.Errorf("got error %v; want %v", err, errConfigUsage)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+// Custom type for the request context keys to avoid collisions with other packages
+type contextKey string
+
+const projectionContextKey = contextKey("projection")
+const userContextKey = contextKey("user")
+const tenantContextKey = contextKey("tenant")
+const parentalLimitContextKey = contextKey("parental_limit")
+const parentalOverrideContextKey = contextKey("parental_override")
+const profileContextKey = contextKey("profile")
+const shutdownContextKey = contextKey("shutdown")
+
+// Store the authenticated user in the request context
+func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
+	ctxt := context.WithValue(r.Context(), userContextKey, user)
+	return r.WithContext(ctxt)
+}
+
+// Get the user from the request context. The authenticate middleware always sets the user,
+// so a missing user is an unexpected condition
+func (app *application) contextGetUser(r *http.Request) *data.User {
+
+	user, ok := r.Context().Value(userContextKey).(*data.User)
+	if !ok {
+		panic("missing user value in request context")
+	}
+
+	return user
+}
+
+// Store the projection in the request context
+func (app *application) contextSetProjection(r *http.Request, proj *projection) *http.Request {
+	ctxt := context.WithValue(r.Context(), projectionContextKey, proj)
+	return r.WithContext(ctxt)
+}
+
+// Get the projection from the request context, if any
+func (app *application) contextGetProjection(r *http.Request) (*projection, bool) {
+	proj, ok := r.Context().Value(projectionContextKey).(*projection)
+	return proj, ok
+}
+
+// Store the tenant of the request in the request context
+func (app *application) contextSetTenant(r *http.Request, tenant *data.Tenant) *http.Request {
+	ctxt := context.WithValue(r.Context(), tenantContextKey, tenant)
+	return r.WithContext(ctxt)
+}
+
+// Get the tenant from the request context. The resolveTenant middleware always sets the tenant,
+// so a missing tenant is an unexpected condition
+func (app *application) contextGetTenant(r *http.Request) *data.Tenant {
+
+	tenant, ok := r.Context().Value(tenantContextKey).(*data.Tenant)
+	if !ok {
+		panic("missing tenant value in request context")
+	}
+
+	return tenant
+}
+
+// Store the parental limit in effect for the request in the request context
+func (app *application) contextSetParentalLimit(r *http.Request, limit *data.ParentalLimit) *http.Request {
+	ctxt := context.WithValue(r.Context(), parentalLimitContextKey, limit)
+	return r.WithContext(ctxt)
+}
+
+// Get the parental limit in effect for the request. There is no limit if none has been stored
+func (app *application) contextGetParentalLimit(r *http.Request) *data.ParentalLimit {
+	limit, _ := r.Context().Value(parentalLimitContextKey).(*data.ParentalLimit)
+	return limit
+}
+
+// Record in the request context that the parental controls have been overridden with the parental PIN
+func (app *application) contextSetParentalOverride(r *http.Request) *http.Request {
+	ctxt := context.WithValue(r.Context(), parentalOverrideContextKey, true)
+	return r.WithContext(ctxt)
+}
+
+// Check if the parental controls have been overridden for the request
+func (app *application) contextGetParentalOverride(r *http.Request) bool {
+	override, _ := r.Context().Value(parentalOverrideContextKey).(bool)
+	return override
+}
+
+// Store the profile selected for the request in the request context
+func (app *application) contextSetProfile(r *http.Request, profile *data.Profile) *http.Request {
+	ctxt := context.WithValue(r.Context(), profileContextKey, profile)
+	return r.WithContext(ctxt)
+}
+
+// Get the profile selected for the request. No profile is selected if none has been stored
+func (app *application) contextGetProfile(r *http.Request) *data.Profile {
+	profile, _ := r.Context().Value(profileContextKey).(*data.Profile)
+	return profile
+}
+
+// Get the ID of the profile selected for the request, zero if no profile is selected
+func (app *application) contextGetProfileID(r *http.Request) int64 {
+
+	if profile := app.contextGetProfile(r); profile != nil {
+		return profile.ID
+	}
+
+	return 0
+}
+
+// Mark the base context of the requests, so that the requests cancelled by the shutdown of the server
+// can be told apart from the requests the clients gave up on
+func contextWithShutdown(ctxt context.Context) context.Context {
+	return context.WithValue(ctxt, shutdownContextKey, ctxt.Done())
+}
+
+// Check if the request has been cancelled by the shutdown of the server
+func (app *application) contextShuttingDown(r *http.Request) bool {
+
+	done, ok := r.Context().Value(shutdownContextKey).(<-chan struct{})
+	if !ok {
+		return false
+	}
+
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
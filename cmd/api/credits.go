@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+func (app *application) createCreditHandler(w http.ResponseWriter, r *http.Request) {
+
+	movieID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	// Structure to hold the request parameters
+	var request struct {
+		PersonID     int64  `json:"person_id"`
+		Role         string `json:"role"`
+		Character    string `json:"character"`
+		BillingOrder int32  `json:"billing_order"`
+	}
+
+	err = app.readJsonRequest(w, r, &request)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	// The movie must exist
+	movie, err := app.modelsFor(r).Movies.Get(r.Context(), movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrParentalRestricted):
+			app.parentalRestrictedResponse(w, r)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	credit := &data.Credit{
+		MovieID:      movie.ID,
+		MovieTitle:   movie.Title,
+		PersonID:     request.PersonID,
+		Role:         request.Role,
+		Character:    request.Character,
+		BillingOrder: request.BillingOrder,
+	}
+
+	// Validate the input fields
+	val := validator.NewValidator()
+
+	if data.ValidateCredit(val, credit); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	// The person being credited must exist
+	person, err := app.models.People.Get(r.Context(), credit.PersonID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			val.AddError("person_id", "does not exist")
+			app.failedValidations(w, r, val.Errors)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	credit.PersonName = person.Name
+
+	err = app.models.Credits.Insert(r.Context(), credit)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateCredit):
+			val.AddError("person_id", "is already credited for this role")
+			app.failedValidations(w, r, val.Errors)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"credit": credit}, nil, http.StatusCreated)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) listCreditsHandler(w http.ResponseWriter, r *http.Request) {
+
+	movieID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	// The movie must exist
+	_, err = app.modelsFor(r).Movies.Get(r.Context(), movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrParentalRestricted):
+			app.parentalRestrictedResponse(w, r)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	credits, err := app.models.Credits.GetForMovies(r.Context(), []int64{movieID})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if credits[movieID] == nil {
+		credits[movieID] = []*data.Credit{}
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"credits": credits[movieID]}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) deleteCreditHandler(w http.ResponseWriter, r *http.Request) {
+
+	// The credits of the movies of the other tenants are not visible
+	movie, ok := app.readMovie(w, r)
+	if !ok {
+		return
+	}
+
+	creditID, err := app.readNamedIDParam(r, "credit_id")
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	err = app.models.Credits.Delete(r.Context(), movie.ID, creditID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"message": "credit successfully deleted"}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
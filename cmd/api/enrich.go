@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/enrich"
+)
+
+// Number of movies enriched by each run of the batch job. The provider rate limit spreads them out
+const enrichBatchSize = 20
+
+// Time allowed for enriching a movie on request, including the wait for the provider rate limit.
+// It is kept below the write timeout of the server so that the response can still be sent
+const enrichRequestTimeout = 8 * time.Second
+
+// Fill the missing metadata of a movie from the catalogue provider
+func (app *application) enrichMovieHandler(w http.ResponseWriter, r *http.Request) {
+
+	if app.enricher == nil || app.contextGetTenant(r).Config.EnrichDisabled {
+		app.errorResponse(w, r, http.StatusServiceUnavailable, "metadata enrichment is not enabled")
+		return
+	}
+
+	movie, ok := app.readMovie(w, r)
+	if !ok {
+		return
+	}
+
+	ctxt, cancel := context.WithTimeout(r.Context(), enrichRequestTimeout)
+	defer cancel()
+
+	filled, err := app.enrichMovie(ctxt, app.modelsFor(r), movie)
+	if err != nil {
+		switch {
+		case errors.Is(err, enrich.ErrNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "the movie could not be found at the catalogue provider")
+		case errors.Is(err, enrich.ErrRateLimited):
+			w.Header().Set("Retry-After", "60")
+			app.errorResponse(w, r, http.StatusServiceUnavailable, "the catalogue provider is rate limiting the requests, please try again later")
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"movie": movie, "filled": filled}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Fill in the plot, the cast and crew and the poster of a movie from the catalogue provider.
+// Only the missing details are filled so that manual edits are never overwritten. The names of
+// the filled details are returned. The models are those of the tenant of the movie
+func (app *application) enrichMovie(ctxt context.Context, models data.Models, movie *data.Movies) ([]string, error) {
+
+	details, err := app.enricher.Lookup(ctxt, movie.Title, movie.Year)
+	if err != nil {
+		return nil, err
+	}
+
+	filled := []string{}
+
+	if details.Plot != "" {
+		ok, err := models.Movies.FillPlot(ctxt, movie, details.Plot)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			filled = append(filled, "plot")
+		}
+	}
+
+	if len(details.Credits) > 0 {
+		ok, err := app.fillCredits(ctxt, movie, details.Credits)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			filled = append(filled, "credits")
+		}
+	}
+
+	if details.PosterURL != "" {
+		ok, err := app.fillPoster(ctxt, movie, details.PosterURL)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			filled = append(filled, "poster")
+		}
+	}
+
+	err = models.Movies.MarkEnriched(ctxt, movie.ID, details.ExternalID)
+	if err != nil {
+		return nil, err
+	}
+
+	return filled, nil
+}
+
+// Add the cast and crew of the provider if the movie has no credits yet. The people are matched by
+// name and added if unknown
+func (app *application) fillCredits(ctxt context.Context, movie *data.Movies, credits []enrich.Credit) (bool, error) {
+
+	existing, err := app.models.Credits.GetForMovies(ctxt, []int64{movie.ID})
+	if err != nil {
+		return false, err
+	}
+
+	if len(existing[movie.ID]) > 0 {
+		return false, nil
+	}
+
+	for i, credit := range credits {
+		person, err := app.models.People.GetByName(ctxt, credit.Name)
+		if err != nil {
+			if !errors.Is(err, data.ErrRecordNotFound) {
+				return false, err
+			}
+
+			person = &data.Person{Name: credit.Name}
+
+			if err = app.models.People.Insert(ctxt, person); err != nil {
+				return false, err
+			}
+		}
+
+		movieCredit := &data.Credit{
+			MovieID:      movie.ID,
+			PersonID:     person.ID,
+			Role:         credit.Role,
+			Character:    credit.Character,
+			BillingOrder: int32(i + 1),
+		}
+
+		err = app.models.Credits.Insert(ctxt, movieCredit)
+		if err != nil && !errors.Is(err, data.ErrDuplicateCredit) {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// Download and store the poster of the provider if the movie has no poster yet
+func (app *application) fillPoster(ctxt context.Context, movie *data.Movies, posterURL string) (bool, error) {
+
+	images, err := app.models.Images.GetAllForMovie(ctxt, movie.ID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, image := range images {
+		if image.Kind == "poster" {
+			return false, nil
+		}
+	}
+
+	content, err := app.enricher.FetchImage(ctxt, posterURL, maxImageBytes)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = app.saveMovieImage(ctxt, movie.ID, "poster", content)
+	if err != nil {
+		// A poster the provider serves in an unsupported format is skipped rather than failing the enrichment
+		if errors.Is(err, errUnsupportedImage) || errors.Is(err, errUndecodableImage) || errors.Is(err, errTooManyPixels) ||
+			errors.Is(err, enrich.ErrImageTooLarge) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Enrich a batch of the movies which have never been enriched for each tenant which has not
+// disabled enrichment
+func (app *application) enrichMovies(ctxt context.Context) error {
+
+	tenants, err := app.models.Tenants.GetAll(ctxt)
+	if err != nil {
+		return err
+	}
+
+	for _, tenant := range tenants {
+		if tenant.Config.EnrichDisabled {
+			continue
+		}
+
+		limited, err := app.enrichTenantMovies(ctxt, app.models.ForTenant(tenant.ID), tenant)
+		if err != nil {
+			return err
+		}
+
+		// The provider limits are shared by all the tenants
+		if limited {
+			app.logger.PrintInfo("catalogue provider is rate limiting, stopping the batch", nil)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Enrich a batch of the movies of a tenant. The batch is cut short if the provider starts
+// rate limiting, which is reported so that the batch is picked up again by the next run
+func (app *application) enrichTenantMovies(ctxt context.Context, models data.Models, tenant *data.Tenant) (bool, error) {
+
+	movies, err := models.Movies.GetUnenriched(ctxt, enrichBatchSize)
+	if err != nil {
+		return false, err
+	}
+
+	enriched := 0
+
+	for _, movie := range movies {
+		movieCtxt, cancel := context.WithTimeout(ctxt, time.Minute)
+		_, err := app.enrichMovie(movieCtxt, models, movie)
+		cancel()
+
+		switch {
+		case err == nil:
+			enriched++
+		case errors.Is(err, enrich.ErrNotFound):
+			// Mark the movie so that it is not looked up again on every run
+			if err = models.Movies.MarkEnriched(ctxt, movie.ID, ""); err != nil {
+				return false, err
+			}
+		case errors.Is(err, enrich.ErrRateLimited):
+			return true, nil
+		default:
+			app.logger.PrintError(err, map[string]string{"movie_id": strconv.FormatInt(movie.ID, 10)})
+
+			// Back off from the movie so that it does not hold up the movies after it
+			if err = models.Movies.MarkEnrichFailed(ctxt, movie.ID); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if len(movies) > 0 {
+		app.logger.PrintInfo("enriched movies", map[string]string{
+			"tenant":   tenant.Slug,
+			"movies":   strconv.Itoa(len(movies)),
+			"enriched": strconv.Itoa(enriched),
+		})
+	}
+
+	return false, nil
+}
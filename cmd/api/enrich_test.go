@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/enrich"
+)
+
+// Provider returning the same plot for every movie
+type fakeProvider struct {
+	plot string
+}
+
+func (p fakeProvider) Lookup(ctxt context.Context, title string, year int32) (*enrich.Details, error) {
+	return &enrich.Details{ExternalID: "tt0078748", Title: title, Year: year, Plot: p.plot}, nil
+}
+
+func (p fakeProvider) FetchImage(ctxt context.Context, url string, maxBytes int64) ([]byte, error) {
+	return nil, enrich.ErrNotFound
+}
+
+// Provider failing to look up the movies with the given titles, and returning the same plot for the others
+type failingProvider struct {
+	fakeProvider
+	failing map[string]bool
+}
+
+func (p failingProvider) Lookup(ctxt context.Context, title string, year int32) (*enrich.Details, error) {
+
+	if p.failing[title] {
+		return nil, errors.New("provider error")
+	}
+
+	return p.fakeProvider.Lookup(ctxt, title, year)
+}
+
+func TestEnrichFailingMovies(t *testing.T) {
+
+	app := newTestApplication(t)
+
+	// A whole batch of movies keeps failing ahead of the last movie
+	failing := make(map[string]bool)
+
+	for i := 1; i <= enrichBatchSize; i++ {
+		title := fmt.Sprintf("Broken %d", i)
+		failing[title] = true
+
+		addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: title, Year: 1980, Runtime: 90, Genres: []string{"drama"}})
+	}
+
+	last := addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"science-fiction"}})
+
+	app.enricher = failingProvider{fakeProvider: fakeProvider{plot: "From the provider"}, failing: failing}
+
+	models := app.models.ForTenant(data.DefaultTenantID)
+	tenant := &data.Tenant{ID: data.DefaultTenantID, Slug: "default"}
+
+	for run := 1; run <= 2; run++ {
+		if _, err := app.enrichTenantMovies(context.Background(), models, tenant); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+
+	movie, err := models.Movies.Get(context.Background(), last.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if movie.Plot != "From the provider" {
+		t.Errorf("got plot %q; want the movie after the failing ones enriched", movie.Plot)
+	}
+
+	// The failing movies are backing off, so nothing is left to enrich until their backoff has passed
+	movies, err := models.Movies.GetUnenriched(context.Background(), enrichBatchSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(movies) != 0 {
+		t.Errorf("got %d movies to enrich; want none", len(movies))
+	}
+}
+
+func TestEnrichMovieHandler(t *testing.T) {
+
+	app := newTestApplication(t)
+
+	addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"science-fiction"}})
+	addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"crime"}, Plot: "Edited by hand"})
+
+	_, editor := addTestUser(t, app, data.DefaultTenantID, "editor@example.com", data.PermissionWriteMovies)
+	_, viewer := addTestUser(t, app, data.DefaultTenantID, "viewer@example.com")
+
+	res, _ := app.testRequest(t, testRequest{method: http.MethodPost, path: "/v1/movies/1/enrich", token: editor})
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("without a provider: got status %d; want %d", res.StatusCode, http.StatusServiceUnavailable)
+	}
+
+	app.enricher = fakeProvider{plot: "From the provider"}
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		status int
+		plot   string
+	}{
+		{"without permission", "/v1/movies/1/enrich", viewer, http.StatusForbidden, ""},
+		{"missing plot is filled", "/v1/movies/1/enrich", editor, http.StatusOK, "From the provider"},
+		{"edited plot is kept", "/v1/movies/2/enrich", editor, http.StatusOK, "Edited by hand"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			res, body := app.testRequest(t, testRequest{method: http.MethodPost, path: tt.path, token: tt.token})
+			if res.StatusCode != tt.status {
+				t.Fatalf("got status %d; want %d (%v)", res.StatusCode, tt.status, body)
+			}
+
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			if plot := body["movie"].(map[string]interface{})["plot"]; plot != tt.plot {
+				t.Errorf("got plot %q; want %q", plot, tt.plot)
+			}
+		})
+	}
+}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/data"
 )
 
+// Non standard status for a request the client gave up on before the response was written,
+// as logged by nginx
+const statusClientClosedRequest = 499
+
 func (app *application) logError(r *http.Request, err error) {
 
 	app.logger.PrintError(err, map[string]string{
@@ -20,7 +27,7 @@ func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, st
 		"error": message,
 	}
 
-	err := app.writeJsonResponse(w, resp, nil, status)
+	err := app.writeJsonResponse(w, r, resp, nil, status)
 	if err != nil {
 		app.logError(r, err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -29,6 +36,22 @@ func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, st
 
 func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
 
+	// Queries stopped by the request going away or running out of time are not internal errors
+	err = data.ContextError(r.Context(), err)
+
+	switch {
+	case errors.Is(err, data.ErrCanceled) && app.contextShuttingDown(r):
+		app.shuttingDownResponse(w, r)
+		return
+	case errors.Is(err, data.ErrCanceled):
+		app.clientClosedResponse(w, r)
+		return
+	case errors.Is(err, data.ErrTimeout):
+		app.logError(r, err)
+		app.timeoutResponse(w, r)
+		return
+	}
+
 	app.logError(r, err)
 
 	msg := "the server encountered an internal error and could not process your request."
@@ -72,3 +95,67 @@ func (app *application) tpsExceedResponse(w http.ResponseWriter, r *http.Request
 	message := "rate limit exceeded"
 	app.errorResponse(w, r, http.StatusTooManyRequests, message)
 }
+
+func (app *application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
+	message := "invalid authentication credentials"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+
+	message := "invalid or missing authentication token"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "you must be authenticated to access this resource"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account doesn't have the necessary permissions to access this resource"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (app *application) tenantRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := fmt.Sprintf("the tenant must be given in the %s header or the subdomain", app.config.tenant.header)
+	app.errorResponse(w, r, http.StatusBadRequest, message)
+}
+
+func (app *application) unknownTenantResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the requested tenant could not be found"
+	app.errorResponse(w, r, http.StatusNotFound, message)
+}
+
+func (app *application) parentalRestrictedResponse(w http.ResponseWriter, r *http.Request) {
+	message := fmt.Sprintf("this movie is restricted by parental controls, send the parental PIN in the %s header to override", parentalPinHeader)
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (app *application) invalidParentalPinResponse(w http.ResponseWriter, r *http.Request) {
+	message := "invalid parental PIN"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (app *application) parentalPinLockedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "too many invalid parental PINs, please try again later"
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
+func (app *application) clientClosedResponse(w http.ResponseWriter, r *http.Request) {
+	// Nobody is left to read the response, it only shows up in the logs
+	w.WriteHeader(statusClientClosedRequest)
+}
+
+func (app *application) shuttingDownResponse(w http.ResponseWriter, r *http.Request) {
+	// The client is still waiting, and may retry with another instance
+	w.Header().Set("Connection", "close")
+	message := "the server is shutting down, please try again"
+	app.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
+
+func (app *application) timeoutResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the server took too long to process your request, please try again later"
+	app.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
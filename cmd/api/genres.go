@@ -0,0 +1,288 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+func (app *application) listGenresHandler(w http.ResponseWriter, r *http.Request) {
+
+	genres, err := app.models.Genres.GetAll(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"genres": genres}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) createGenreHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Structure to hold the request parameters
+	var request struct {
+		Slug    string   `json:"slug"`
+		Name    string   `json:"name"`
+		Aliases []string `json:"aliases"`
+	}
+
+	err := app.readJsonRequest(w, r, &request)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	genre := &data.Genre{
+		Slug:    request.Slug,
+		Name:    request.Name,
+		Aliases: request.Aliases,
+	}
+
+	// Default the slug from the name
+	if genre.Slug == "" {
+		genre.Slug = data.Slugify(genre.Name)
+	}
+
+	if genre.Aliases == nil {
+		genre.Aliases = []string{}
+	}
+
+	if !app.validateGenre(w, r, genre) {
+		return
+	}
+
+	err = app.models.Genres.Insert(r.Context(), genre)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateSlug):
+			app.failedValidations(w, r, map[string]string{"slug": "a genre with this slug already exists"})
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	header := make(http.Header)
+	header.Set("Location", fmt.Sprintf("/v1/genres/%d", genre.ID))
+
+	err = app.writeJsonResponse(w, r, envelope{"genre": genre}, header, http.StatusCreated)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) showGenreHandler(w http.ResponseWriter, r *http.Request) {
+
+	genre, ok := app.readGenre(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJsonResponse(w, r, envelope{"genre": genre}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) editGenreHandler(w http.ResponseWriter, r *http.Request) {
+
+	genre, ok := app.readGenre(w, r)
+	if !ok {
+		return
+	}
+
+	// All members are pointers to check whether the values have been provided by the user or not
+	var request struct {
+		Slug    *string  `json:"slug"`
+		Name    *string  `json:"name"`
+		Aliases []string `json:"aliases"`
+	}
+
+	err := app.readJsonRequest(w, r, &request)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	oldSlug := genre.Slug
+
+	if request.Slug != nil {
+		genre.Slug = *request.Slug
+	}
+
+	if request.Name != nil {
+		genre.Name = *request.Name
+	}
+
+	if request.Aliases != nil {
+		genre.Aliases = request.Aliases
+	}
+
+	if !app.validateGenre(w, r, genre) {
+		return
+	}
+
+	// Update the genre. A changed slug is renamed in the movies as well
+	err = app.models.Genres.Update(r.Context(), genre, oldSlug)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateSlug):
+			app.failedValidations(w, r, map[string]string{"slug": "a genre with this slug already exists"})
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictError(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"genre": genre}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) deleteGenreHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	err = app.models.Genres.Delete(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		case errors.Is(err, data.ErrGenreInUse):
+			app.errorResponse(w, r, http.StatusConflict, "the genre is used by movies. merge it into another genre instead")
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"message": "genre successfully deleted"}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Merge a genre into another genre. The movies of the merged genre are moved to the target genre
+// and the merged genre becomes an alias of the target genre
+func (app *application) mergeGenreHandler(w http.ResponseWriter, r *http.Request) {
+
+	source, ok := app.readGenre(w, r)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Into int64 `json:"into"`
+	}
+
+	err := app.readJsonRequest(w, r, &request)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	val := validator.NewValidator()
+
+	val.Check(request.Into > 0, "into", "must be provided")
+	val.Check(request.Into != source.ID, "into", "must not be the same genre")
+
+	if !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	target, err := app.models.Genres.Get(r.Context(), request.Into)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			val.AddError("into", "does not exist")
+			app.failedValidations(w, r, val.Errors)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	rewritten, err := app.models.Genres.Merge(r.Context(), source, target)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictError(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"genre": target, "movies_updated": rewritten}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Read the genre identified by the ID parameter. An error response is sent if it could not be read
+func (app *application) readGenre(w http.ResponseWriter, r *http.Request) (*data.Genre, bool) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	genre, err := app.models.Genres.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return nil, false
+	}
+
+	return genre, true
+}
+
+// Validate the genre fields and check that it does not clash with the other genres of the taxonomy.
+// An error response is sent if the genre is not valid
+func (app *application) validateGenre(w http.ResponseWriter, r *http.Request, genre *data.Genre) bool {
+
+	val := validator.NewValidator()
+
+	if data.ValidateGenre(val, genre); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return false
+	}
+
+	taxonomy, err := app.models.Genres.GetTaxonomy(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return false
+	}
+
+	if data.ValidateGenreUnique(val, genre, taxonomy); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+func TestGenreHandlers(t *testing.T) {
+
+	app := newTestApplication(t)
+
+	for _, genre := range []map[string]interface{}{
+		{"slug": "sci-fi", "name": "Sci-Fi"},
+		{"slug": "science-fiction", "name": "Science Fiction"},
+	} {
+		res, body := app.testRequest(t, testRequest{method: http.MethodPost, path: "/v1/genres", body: genre})
+		if res.StatusCode != http.StatusCreated {
+			t.Fatalf("create %v: got status %d; want %d (%v)", genre["slug"], res.StatusCode, http.StatusCreated, body)
+		}
+	}
+
+	res, body := app.testRequest(t, testRequest{method: http.MethodPost, path: "/v1/genres", body: map[string]interface{}{"slug": "space", "name": "SCI FI"}})
+	if res.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("create clashing genre: got status %d; want %d (%v)", res.StatusCode, http.StatusUnprocessableEntity, body)
+	}
+
+	addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"sci-fi"}})
+
+	res, _ = app.testRequest(t, testRequest{method: http.MethodDelete, path: "/v1/genres/1"})
+	if res.StatusCode != http.StatusConflict {
+		t.Errorf("delete used genre: got status %d; want %d", res.StatusCode, http.StatusConflict)
+	}
+
+	res, body = app.testRequest(t, testRequest{method: http.MethodPost, path: "/v1/genres/1/merge", body: map[string]interface{}{"into": 2}})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("merge: got status %d; want %d (%v)", res.StatusCode, http.StatusOK, body)
+	}
+
+	if body["movies_updated"] != float64(1) {
+		t.Errorf("merge: got %v movies updated; want 1", body["movies_updated"])
+	}
+
+	// The merged genre lives on as an alias of the target
+	res, body = app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/movies?genres=sci-fi"})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("list: got status %d; want %d", res.StatusCode, http.StatusOK)
+	}
+
+	movies := body["movies"].([]interface{})
+	if len(movies) != 1 {
+		t.Fatalf("list: got %d movies; want 1", len(movies))
+	}
+
+	genres := movies[0].(map[string]interface{})["genre"].([]interface{})
+	if len(genres) != 1 || genres[0] != "science-fiction" {
+		t.Errorf("list: got genres %v; want [science-fiction]", genres)
+	}
+}
@@ -1,14 +1,22 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/iso"
 	"github.com/narinderv/blockbuster/internal/validator"
 )
 
+// Related resources which can be embedded in a movie response using include=
+var movieIncludes = []string{"credits", "collection"}
+
 func (app *application) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Create a map of the response
@@ -21,7 +29,7 @@ func (app *application) healthCheckHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Now write out the response
-	err := app.writeJsonResponse(w, data, nil, http.StatusOK)
+	err := app.writeJsonResponse(w, r, data, nil, http.StatusOK)
 	if err != nil {
 		app.serverError(w, r, err)
 	}
@@ -35,6 +43,13 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 		Year    int32        `json:"year"`
 		Runtime data.Runtime `json:"runtime"`
 		Genres  []string     `json:"genres"`
+		Plot    string       `json:"plot"`
+
+		Certifications      map[string]string `json:"certifications"`
+		OriginalLanguage    string            `json:"original_language"`
+		SpokenLanguages     []string          `json:"spoken_languages"`
+		ProductionCountries []string          `json:"production_countries"`
+		ReleaseDates        map[string]string `json:"release_dates"`
 	}
 
 	// Decode the input json
@@ -50,12 +65,26 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 		Year:    request.Year,
 		Runtime: request.Runtime,
 		Genres:  request.Genres,
+		Plot:    request.Plot,
+
+		Certifications:      request.Certifications,
+		OriginalLanguage:    request.OriginalLanguage,
+		SpokenLanguages:     request.SpokenLanguages,
+		ProductionCountries: request.ProductionCountries,
+		ReleaseDates:        request.ReleaseDates,
+	}
+
+	// Get the genre taxonomy for validating the genres
+	taxonomy, err := app.models.Genres.GetTaxonomy(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
 	}
 
 	// Validate the input fields
 	val := validator.NewValidator()
 
-	data.ValidateMovie(val, movie)
+	data.ValidateMovie(val, movie, taxonomy)
 
 	// Check if there was any failure
 	if !val.IsValid() {
@@ -64,7 +93,7 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Insert the record into the database
-	err = app.models.Movies.Insert(movie)
+	err = app.modelsFor(r).Movies.Insert(r.Context(), movie)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
@@ -75,7 +104,7 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 	header.Set("Location", fmt.Sprintf("/v1/movies/%d", movie.ID))
 
 	// Send the response
-	app.writeJsonResponse(w, envelope{"movie": movie}, header, http.StatusCreated)
+	app.writeJsonResponse(w, r, envelope{"movie": movie}, header, http.StatusCreated)
 
 }
 
@@ -87,9 +116,22 @@ func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	movie, err := app.models.Movies.Get(id)
+	// Read the sparse fieldset and the related resources to be embedded
+	val := validator.NewValidator()
+
+	proj := app.readProjection(r.URL.Query(), data.MovieFields, movieIncludes, val, "movie")
+
+	if !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	// Get only the requested fields from the database
+	movie, err := app.modelsFor(r).Movies.GetFields(r.Context(), id, proj.fields)
 	if err != nil {
 		switch {
+		case errors.Is(err, data.ErrParentalRestricted):
+			app.parentalRestrictedResponse(w, r)
 		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFound(w, r)
 		default:
@@ -99,7 +141,25 @@ func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.writeJsonResponse(w, envelope{"movie": movie}, nil, http.StatusOK)
+	// Load the related resources to be embedded
+	err = app.embedMovieRelations(r.Context(), proj, movie)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Number of copies available in the stores
+	availability, err := app.models.Inventory.GetAvailability(r.Context(), movie.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.recordMovieEvent(movie.ID, data.EventView)
+
+	r = app.contextSetProjection(r, proj)
+
+	err = app.writeJsonResponse(w, r, envelope{"movie": movie, "availability": availability}, nil, http.StatusOK)
 	if err != nil {
 		app.serverError(w, r, err)
 	}
@@ -115,9 +175,11 @@ func (app *application) editMovieHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Get the existing record from the database
-	movie, err := app.models.Movies.Get(id)
+	movie, err := app.modelsFor(r).Movies.Get(r.Context(), id)
 	if err != nil {
 		switch {
+		case errors.Is(err, data.ErrParentalRestricted):
+			app.parentalRestrictedResponse(w, r)
 		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFound(w, r)
 		default:
@@ -135,6 +197,13 @@ func (app *application) editMovieHandler(w http.ResponseWriter, r *http.Request)
 		Year    *int32        `json:"year"`
 		Runtime *data.Runtime `json:"runtime"`
 		Genres  []string      `json:"genres"`
+		Plot    *string       `json:"plot"`
+
+		Certifications      map[string]string `json:"certifications"`
+		OriginalLanguage    *string           `json:"original_language"`
+		SpokenLanguages     []string          `json:"spoken_languages"`
+		ProductionCountries []string          `json:"production_countries"`
+		ReleaseDates        map[string]string `json:"release_dates"`
 	}
 
 	// Decode the input json
@@ -161,10 +230,42 @@ func (app *application) editMovieHandler(w http.ResponseWriter, r *http.Request)
 		movie.Genres = request.Genres
 	}
 
+	if request.Plot != nil {
+		movie.Plot = *request.Plot
+	}
+
+	// The maps and lists replace the existing values as a whole
+	if request.Certifications != nil {
+		movie.Certifications = request.Certifications
+	}
+
+	if request.OriginalLanguage != nil {
+		movie.OriginalLanguage = *request.OriginalLanguage
+	}
+
+	if request.SpokenLanguages != nil {
+		movie.SpokenLanguages = request.SpokenLanguages
+	}
+
+	if request.ProductionCountries != nil {
+		movie.ProductionCountries = request.ProductionCountries
+	}
+
+	if request.ReleaseDates != nil {
+		movie.ReleaseDates = request.ReleaseDates
+	}
+
+	// Get the genre taxonomy for validating the genres
+	taxonomy, err := app.models.Genres.GetTaxonomy(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
 	// Validate the input fields
 	val := validator.NewValidator()
 
-	data.ValidateMovie(val, movie)
+	data.ValidateMovie(val, movie, taxonomy)
 
 	// Check if there was any failure
 	if !val.IsValid() {
@@ -173,7 +274,7 @@ func (app *application) editMovieHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Update the data into the database
-	err = app.models.Movies.Update(movie)
+	err = app.modelsFor(r).Movies.Update(r.Context(), movie)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -185,7 +286,7 @@ func (app *application) editMovieHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Send the updated record as the response
-	err = app.writeJsonResponse(w, envelope{"movie": movie}, nil, http.StatusOK)
+	err = app.writeJsonResponse(w, r, envelope{"movie": movie}, nil, http.StatusOK)
 	if err != nil {
 		app.serverError(w, r, err)
 	}
@@ -201,11 +302,13 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Delete the record
-	err = app.models.Movies.Delete(id)
+	err = app.modelsFor(r).Movies.Delete(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFound(w, r)
+		case errors.Is(err, data.ErrMovieInUse):
+			app.errorResponse(w, r, http.StatusConflict, "the movie has been rented out and can not be deleted")
 		default:
 			app.serverError(w, r, err)
 		}
@@ -213,7 +316,7 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.writeJsonResponse(w, envelope{"message": "movie successfully deleted"}, nil, http.StatusOK)
+	err = app.writeJsonResponse(w, r, envelope{"message": "movie successfully deleted"}, nil, http.StatusOK)
 	if err != nil {
 		app.serverError(w, r, err)
 	}
@@ -223,8 +326,7 @@ func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request
 
 	// Define a structure to hold the query string values
 	var input struct {
-		Title  string
-		Genres []string
+		data.MovieSearch
 		data.Filters
 	}
 
@@ -237,14 +339,38 @@ func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request
 	// Get the query string values
 	input.Title = app.readString(queryString, "title", "")
 	input.Genres = app.readCSV(queryString, "genres", []string{})
+	input.PersonID = int64(app.readInt(queryString, "person", 0, val))
+
+	input.AvailableAtStore = int64(app.readInt(queryString, "available_at_store", 0, val))
+
+	val.Check(input.PersonID >= 0, "person", "must be a positive integer")
+	val.Check(input.AvailableAtStore >= 0, "available_at_store", "must be a positive integer")
+
+	input.CollectionID = int64(app.readInt(queryString, "collection", 0, val))
+
+	val.Check(input.CollectionID >= 0, "collection", "must be a positive integer")
+
+	// Release metadata
+	app.readReleaseFilters(queryString, &input.MovieSearch, val)
 
 	input.Filters.Page = app.readInt(queryString, "page", 1, val)
 	input.Filters.PageSize = app.readInt(queryString, "page_size", 10, val)
 
-	input.Filters.Sort = app.readString(queryString, "sort", "id")
+	// Supported sort values. The movies of a collection are in their order within the collection by default
+	input.Filters.SortList = []string{"id", "title", "year", "runtime", "rating", "popularity",
+		"-id", "-title", "-year", "-runtime", "-rating", "-popularity"}
+
+	if input.CollectionID > 0 {
+		input.Filters.Sort = app.readString(queryString, "sort", "position")
+		input.Filters.SortList = append(input.Filters.SortList, "position", "-position")
+	} else {
+		input.Filters.Sort = app.readString(queryString, "sort", "id")
+	}
 
-	// Supported sort values
-	input.Filters.SortList = []string{"id", "title", "year", "runtime", "-id", "-title", "-year", "-runtime"}
+	// Sparse fieldset and the related resources to be embedded
+	proj := app.readProjection(queryString, data.MovieFields, movieIncludes, val, "movies")
+
+	input.Filters.Fields = proj.fields
 
 	// Validate the provided filter values
 	data.ValidateFilters(val, &input.Filters)
@@ -255,16 +381,115 @@ func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Search using the canonical genre slugs
+	taxonomy, err := app.models.Genres.GetTaxonomy(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	input.Genres = taxonomy.Canonicalize(input.Genres)
+
 	// Get all the data from the database
-	movies, metadata, err := app.models.Movies.GetAll(input.Title, input.Genres, input.Filters)
+	movies, metadata, err := app.modelsFor(r).Movies.GetAll(r.Context(), input.MovieSearch, input.Filters)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
+	// Load the related resources to be embedded
+	err = app.embedMovieRelations(r.Context(), proj, movies...)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	r = app.contextSetProjection(r, proj)
+
 	// Send response
-	app.writeJsonResponse(w, envelope{"metadata": metadata, "movies": movies}, nil, http.StatusOK)
+	err = app.writeJsonResponse(w, r, envelope{"metadata": metadata, "movies": movies}, nil, http.StatusOK)
 	if err != nil {
 		app.serverError(w, r, err)
 	}
 }
+
+// Load the related resources requested using include= and add them to the projection
+// for embedding in the movies
+func (app *application) embedMovieRelations(ctxt context.Context, proj *projection, movies ...*data.Movies) error {
+
+	if len(proj.include) == 0 || len(movies) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(movies))
+	for _, movie := range movies {
+		ids = append(ids, movie.ID)
+	}
+
+	// Cast and crew
+	if proj.includes("credits") {
+		credits, err := app.models.Credits.GetForMovies(ctxt, ids)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if credits[id] == nil {
+				credits[id] = []*data.Credit{}
+			}
+			proj.embed("credits", id, credits[id])
+		}
+	}
+
+	// Collection the movie belongs to, if any
+	if proj.includes("collection") {
+		collections, err := app.models.Collections.GetForMovies(ctxt, ids)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			proj.embed("collection", id, collections[id])
+		}
+	}
+
+	return nil
+}
+
+// Read the release metadata filters of the movie search:
+// language=en, country=US, certification=US:PG-13, released_in=GB with released_after and released_before dates
+func (app *application) readReleaseFilters(queryString url.Values, search *data.MovieSearch, val *validator.Validator) {
+
+	search.Language = strings.ToLower(app.readString(queryString, "language", ""))
+	val.Check(search.Language == "" || iso.IsLanguage(search.Language), "language", "must be an ISO 639-1 language code")
+
+	search.Country = strings.ToUpper(app.readString(queryString, "country", ""))
+	val.Check(search.Country == "" || iso.IsCountry(search.Country), "country", "must be an ISO 3166-1 country code")
+
+	if certification := app.readString(queryString, "certification", ""); certification != "" {
+		parts := strings.SplitN(certification, ":", 2)
+
+		if len(parts) != 2 || !iso.IsCountry(strings.ToUpper(parts[0])) {
+			val.AddError("certification", "must be a country code and a certification e.g. US:PG-13")
+		} else {
+			search.CertificationCountry = strings.ToUpper(parts[0])
+			search.Certification, _ = data.CanonicalCertification(search.CertificationCountry, parts[1])
+		}
+	}
+
+	search.ReleaseCountry = strings.ToUpper(app.readString(queryString, "released_in", ""))
+	val.Check(search.ReleaseCountry == "" || iso.IsCountry(search.ReleaseCountry), "released_in", "must be an ISO 3166-1 country code")
+
+	for key, date := range map[string]*string{"released_after": &search.ReleasedAfter, "released_before": &search.ReleasedBefore} {
+		*date = app.readString(queryString, key, "")
+		if *date == "" {
+			continue
+		}
+
+		if _, err := time.Parse(data.ReleaseDateLayout, *date); err != nil {
+			val.AddError(key, fmt.Sprintf("must be a date in the format %s", data.ReleaseDateLayout))
+		}
+
+		val.Check(search.ReleaseCountry != "", "released_in", "must be provided to filter on the release dates")
+	}
+}
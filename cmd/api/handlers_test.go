@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+// Create an application holding the genres used by the movie tests
+func newMovieTestApplication(t *testing.T) *application {
+
+	t.Helper()
+
+	app := newTestApplication(t)
+
+	genres := []*data.Genre{
+		{Slug: "drama", Name: "Drama"},
+		{Slug: "science-fiction", Name: "Science Fiction", Aliases: []string{"sci-fi"}},
+		{Slug: "thriller", Name: "Thriller"},
+	}
+
+	for _, genre := range genres {
+		if err := app.models.Genres.Insert(context.Background(), genre); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return app
+}
+
+func TestCreateMovieHandler(t *testing.T) {
+
+	app := newMovieTestApplication(t)
+
+	tests := []struct {
+		name   string
+		body   map[string]interface{}
+		status int
+		errors map[string]interface{}
+	}{
+		{
+			name:   "valid movie",
+			body:   map[string]interface{}{"title": "Alien", "year": 1979, "runtime": "117 mins", "genres": []string{"sci-fi", "thriller"}},
+			status: http.StatusCreated,
+		},
+		{
+			name:   "unknown genre",
+			body:   map[string]interface{}{"title": "Alien", "year": 1979, "runtime": "117 mins", "genres": []string{"space-horror"}},
+			status: http.StatusUnprocessableEntity,
+			errors: map[string]interface{}{"genres": "unknown genre: space-horror"},
+		},
+		{
+			name:   "missing title",
+			body:   map[string]interface{}{"year": 1979, "runtime": "117 mins", "genres": []string{"drama"}},
+			status: http.StatusUnprocessableEntity,
+			errors: map[string]interface{}{"title": "must be provided"},
+		},
+		{
+			name:   "invalid runtime",
+			body:   map[string]interface{}{"title": "Alien", "year": 1979, "runtime": 117, "genres": []string{"drama"}},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			res, body := app.testRequest(t, testRequest{method: http.MethodPost, path: "/v1/movies", body: tt.body})
+
+			if res.StatusCode != tt.status {
+				t.Fatalf("got status %d; want %d (%v)", res.StatusCode, tt.status, body)
+			}
+
+			for field, message := range tt.errors {
+				errors, _ := body["error"].(map[string]interface{})
+				if errors[field] != message {
+					t.Errorf("got %s error %v; want %q", field, errors[field], message)
+				}
+			}
+
+			if tt.status != http.StatusCreated {
+				return
+			}
+
+			movie := body["movie"].(map[string]interface{})
+
+			if location := res.Header.Get("Location"); location != "/v1/movies/1" {
+				t.Errorf("got Location %q; want %q", location, "/v1/movies/1")
+			}
+
+			// The aliases are stored as the canonical genre slugs
+			genres := movie["genre"].([]interface{})
+			if len(genres) != 2 || genres[0] != "science-fiction" || genres[1] != "thriller" {
+				t.Errorf("got genres %v; want [science-fiction thriller]", genres)
+			}
+		})
+	}
+}
+
+func TestShowMovieHandler(t *testing.T) {
+
+	app := newMovieTestApplication(t)
+	ctxt := context.Background()
+
+	other := addTestTenant(t, app, "other")
+
+	movie := addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"science-fiction"}})
+	otherMovie := addTestMovie(t, app, other, &data.Movies{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"thriller"}})
+
+	store := &data.Store{Name: "Main Street"}
+	if err := app.models.Stores.Insert(ctxt, store); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, barcode := range []string{"A-1", "A-2"} {
+		item := &data.InventoryItem{MovieID: movie.ID, StoreID: store.ID, Format: "dvd", Condition: "good", Barcode: barcode, Status: data.InventoryAvailable}
+		if err := app.models.Inventory.Insert(ctxt, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	credit := &data.Credit{MovieID: movie.ID, PersonID: 1, Role: "director"}
+	if err := app.models.Credits.Insert(ctxt, credit); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("availability", func(t *testing.T) {
+
+		res, body := app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/movies/1"})
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("got status %d; want %d", res.StatusCode, http.StatusOK)
+		}
+
+		availability := body["availability"].([]interface{})
+		if len(availability) != 1 {
+			t.Fatalf("got %d availability entries; want 1", len(availability))
+		}
+
+		entry := availability[0].(map[string]interface{})
+		if entry["store_name"] != "Main Street" || entry["available"] != float64(2) || entry["total"] != float64(2) {
+			t.Errorf("got availability %v; want 2 of 2 copies at Main Street", entry)
+		}
+	})
+
+	t.Run("sparse fieldset", func(t *testing.T) {
+
+		res, body := app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/movies/1?fields=id,title"})
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("got status %d; want %d", res.StatusCode, http.StatusOK)
+		}
+
+		fields := body["movie"].(map[string]interface{})
+		if len(fields) != 2 || fields["title"] != "Alien" {
+			t.Errorf("got movie %v; want only the id and title", fields)
+		}
+	})
+
+	t.Run("embedded credits", func(t *testing.T) {
+
+		res, body := app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/movies/1?include=credits"})
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("got status %d; want %d", res.StatusCode, http.StatusOK)
+		}
+
+		credits, _ := body["movie"].(map[string]interface{})["credits"].([]interface{})
+		if len(credits) != 1 {
+			t.Errorf("got credits %v; want the director", credits)
+		}
+	})
+
+	t.Run("movie of another tenant", func(t *testing.T) {
+
+		res, _ := app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/movies/2"})
+		if res.StatusCode != http.StatusNotFound {
+			t.Errorf("got status %d; want %d", res.StatusCode, http.StatusNotFound)
+		}
+
+		header := http.Header{"X-Tenant": []string{"other"}}
+
+		res, body := app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/movies/2", header: header})
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("got status %d; want %d", res.StatusCode, http.StatusOK)
+		}
+
+		if title := body["movie"].(map[string]interface{})["title"]; title != otherMovie.Title {
+			t.Errorf("got title %v; want %q", title, otherMovie.Title)
+		}
+	})
+
+	t.Run("unknown tenant", func(t *testing.T) {
+
+		header := http.Header{"X-Tenant": []string{"nowhere"}}
+
+		res, _ := app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/movies/1", header: header})
+		if res.StatusCode != http.StatusNotFound {
+			t.Errorf("got status %d; want %d", res.StatusCode, http.StatusNotFound)
+		}
+	})
+}
+
+func TestListMoviesHandler(t *testing.T) {
+
+	app := newMovieTestApplication(t)
+
+	addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"science-fiction"}})
+	addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"thriller"}})
+	addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Moon", Year: 2009, Runtime: 97, Genres: []string{"drama", "science-fiction"}})
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		titles []string
+	}{
+		{"all movies", "", http.StatusOK, []string{"Alien", "Heat", "Moon"}},
+		{"genre alias", "?genres=sci-fi", http.StatusOK, []string{"Alien", "Moon"}},
+		{"sorted by year descending", "?sort=-year", http.StatusOK, []string{"Moon", "Heat", "Alien"}},
+		{"second page", "?page=2&page_size=2", http.StatusOK, []string{"Moon"}},
+		{"unknown sort", "?sort=budget", http.StatusUnprocessableEntity, nil},
+		{"unknown language", "?language=xx", http.StatusUnprocessableEntity, nil},
+		{"certification without country", "?certification=PG-13", http.StatusUnprocessableEntity, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			res, body := app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/movies" + tt.query})
+			if res.StatusCode != tt.status {
+				t.Fatalf("got status %d; want %d (%v)", res.StatusCode, tt.status, body)
+			}
+
+			if tt.titles == nil {
+				return
+			}
+
+			movies := body["movies"].([]interface{})
+
+			var titles []string
+			for _, movie := range movies {
+				titles = append(titles, movie.(map[string]interface{})["title"].(string))
+			}
+
+			if len(titles) != len(tt.titles) {
+				t.Fatalf("got titles %v; want %v", titles, tt.titles)
+			}
+
+			for i := range titles {
+				if titles[i] != tt.titles[i] {
+					t.Fatalf("got titles %v; want %v", titles, tt.titles)
+				}
+			}
+		})
+	}
+}
+
+func TestEditAndDeleteMovieHandlers(t *testing.T) {
+
+	app := newMovieTestApplication(t)
+
+	addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"science-fiction"}})
+
+	res, body := app.testRequest(t, testRequest{method: http.MethodPatch, path: "/v1/movies/1", body: map[string]interface{}{"title": "Aliens", "year": 1986}})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("edit: got status %d; want %d (%v)", res.StatusCode, http.StatusOK, body)
+	}
+
+	movie := body["movie"].(map[string]interface{})
+	if movie["title"] != "Aliens" || movie["year"] != float64(1986) || movie["info_version"] != float64(2) {
+		t.Errorf("edit: got movie %v; want Aliens (1986) at version 2", movie)
+	}
+
+	res, _ = app.testRequest(t, testRequest{method: http.MethodDelete, path: "/v1/movies/1"})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("delete: got status %d; want %d", res.StatusCode, http.StatusOK)
+	}
+
+	res, _ = app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/movies/1"})
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("show deleted: got status %d; want %d", res.StatusCode, http.StatusNotFound)
+	}
+}
@@ -21,7 +21,17 @@ const unknownField = "json: unknown field "
 
 type envelope map[string]interface{}
 
-func (app *application) writeJsonResponse(w http.ResponseWriter, data envelope, headers http.Header, status int) error {
+func (app *application) writeJsonResponse(w http.ResponseWriter, r *http.Request, data envelope, headers http.Header, status int) error {
+
+	// Apply the sparse fieldset and embed the related resources requested by the client, if any
+	if proj, ok := app.contextGetProjection(r); ok {
+		projected, err := proj.apply(data)
+		if err != nil {
+			return err
+		}
+
+		data = projected
+	}
 
 	// Marshal the input data into a JSON byte array
 	// respJson, err := json.Marshal(data)
@@ -50,7 +60,27 @@ func (app *application) writeJsonResponse(w http.ResponseWriter, data envelope,
 	return nil
 }
 
+// Serve the requests having the given value for the parameter using the static handler. httprouter does
+// not allow a static route segment where there is a parameter e.g. /v1/movies/trending and /v1/movies/:id
+func (app *application) staticParam(name, value string, static, nxtHandler http.HandlerFunc) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if httprouter.ParamsFromContext(r.Context()).ByName(name) == value {
+			static(w, r)
+			return
+		}
+
+		nxtHandler(w, r)
+	}
+}
+
 func (app *application) readIDParam(r *http.Request) (int64, error) {
+	return app.readNamedIDParam(r, "id")
+}
+
+// Read an ID parameter other than "id" e.g. /v1/movies/:id/credits/:credit_id
+func (app *application) readNamedIDParam(r *http.Request, name string) (int64, error) {
 
 	// Get the parameters from the request URL.
 	// Fur httprouter, the parameters are obtained from the request context
@@ -61,7 +91,7 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 
 	// Parameter values can be retrieved by using the names of the parameters.
 	// This will always return string values and need to be converted to appropriate types before use.
-	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	id, err := strconv.ParseInt(params.ByName(name), 10, 64)
 	if err != nil || id < 1 {
 		return 0, errors.New("invalid ID parameter")
 	}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// Place a hold on a movie at a store for the authenticated user. Holds are queued first in first out
+// and can only be placed when all the copies at the store are out
+func (app *application) createHoldHandler(w http.ResponseWriter, r *http.Request) {
+
+	movie, ok := app.readMovie(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		StoreID int64  `json:"store_id"`
+		Format  string `json:"format"`
+	}
+
+	err := app.readJsonRequest(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	hold := &data.Hold{
+		UserID:  app.contextGetUser(r).ID,
+		MovieID: movie.ID,
+		StoreID: input.StoreID,
+		Format:  input.Format,
+	}
+
+	val := validator.NewValidator()
+
+	if data.ValidateHold(val, hold); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	_, err = app.modelsFor(r).Stores.Get(r.Context(), hold.StoreID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			val.AddError("store_id", "does not exist")
+			app.failedValidations(w, r, val.Errors)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.models.Holds.Insert(r.Context(), hold)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCopyAvailable):
+			app.errorResponse(w, r, http.StatusConflict, "a copy of the movie is available in this store and can be rented now")
+		case errors.Is(err, data.ErrDuplicateHold):
+			val.AddError("movie_id", "you already have an open hold on this movie in this store")
+			app.failedValidations(w, r, val.Errors)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	// Read back the hold for its position in the queue
+	hold, err = app.models.Holds.Get(r.Context(), hold.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	header := make(http.Header)
+	header.Set("Location", fmt.Sprintf("/v1/holds/%d", hold.ID))
+
+	err = app.writeJsonResponse(w, r, envelope{"hold": hold}, header, http.StatusCreated)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) showHoldHandler(w http.ResponseWriter, r *http.Request) {
+
+	hold, ok := app.readHold(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJsonResponse(w, r, envelope{"hold": hold}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Cancel a hold. A copy set aside for the hold goes to the next hold in the queue
+func (app *application) cancelHoldHandler(w http.ResponseWriter, r *http.Request) {
+
+	hold, ok := app.readHold(w, r)
+	if !ok {
+		return
+	}
+
+	next, err := app.models.Holds.Cancel(r.Context(), hold)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrHoldClosed):
+			app.errorResponse(w, r, http.StatusConflict, fmt.Sprintf("the hold is already %s", hold.Status))
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictError(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	if next != nil {
+		app.notifyHoldsReady(next)
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"hold": hold}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// List the open holds of the authenticated user
+func (app *application) listUserHoldsHandler(w http.ResponseWriter, r *http.Request) {
+
+	holds, err := app.models.Holds.GetAllForUser(r.Context(), app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"holds": holds}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Read the hold identified by the ID parameter. Holds are only visible to the user who placed them
+// and to the store staff. An error response is sent if the hold could not be read
+func (app *application) readHold(w http.ResponseWriter, r *http.Request) (*data.Hold, bool) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	hold, err := app.models.Holds.Get(r.Context(), id)
+	if err == nil {
+		// The holds of the stores of the other tenants are not visible
+		_, err = app.modelsFor(r).Stores.Get(r.Context(), hold.StoreID)
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return nil, false
+	}
+
+	user := app.contextGetUser(r)
+
+	if hold.UserID != user.ID {
+		permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return nil, false
+		}
+
+		// Do not reveal the holds of other users
+		if !permissions.Include(data.PermissionManageInventory) {
+			app.notFound(w, r)
+			return nil, false
+		}
+	}
+
+	return hold, true
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// Show the household of the user along with its profiles and the balance of all its members
+func (app *application) showUserHouseholdHandler(w http.ResponseWriter, r *http.Request) {
+
+	household, ok := app.readHousehold(w, r, app.contextGetUser(r).HouseholdID)
+	if !ok {
+		return
+	}
+
+	profiles, err := app.models.Profiles.GetAllForHousehold(r.Context(), household.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	balance, err := app.models.Ledger.GetHouseholdBalance(r.Context(), household.ID, app.pricing.Currency())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	resp := envelope{"household": household, "profiles": profiles, "balance": balance}
+
+	err = app.writeJsonResponse(w, r, resp, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Rename the household of the user. The rental limit can only be changed by the staff
+func (app *application) editUserHouseholdHandler(w http.ResponseWriter, r *http.Request) {
+
+	household, ok := app.readHousehold(w, r, app.contextGetUser(r).HouseholdID)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name *string `json:"name"`
+	}
+
+	err := app.readJsonRequest(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		household.Name = *input.Name
+	}
+
+	app.updateHousehold(w, r, household)
+}
+
+// Show a household of the tenant
+func (app *application) showHouseholdHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	household, ok := app.readHousehold(w, r, id)
+	if !ok {
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"household": household}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Change a household of the tenant, including its rental limit
+func (app *application) editHouseholdHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	household, ok := app.readHousehold(w, r, id)
+	if !ok {
+		return
+	}
+
+	// All members are pointers to check whether the values have been provided by the user or not
+	var input struct {
+		Name             *string `json:"name"`
+		MaxActiveRentals *int32  `json:"max_active_rentals"`
+	}
+
+	err = app.readJsonRequest(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		household.Name = *input.Name
+	}
+
+	if input.MaxActiveRentals != nil {
+		household.MaxActiveRentals = *input.MaxActiveRentals
+	}
+
+	app.updateHousehold(w, r, household)
+}
+
+// Validate and save the household, and send it in the response
+func (app *application) updateHousehold(w http.ResponseWriter, r *http.Request, household *data.Household) {
+
+	val := validator.NewValidator()
+
+	if data.ValidateHousehold(val, household); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	err := app.modelsFor(r).Households.Update(r.Context(), household)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictError(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"household": household}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Read the household of the tenant with the given ID. An error response is sent if it could not be read
+func (app *application) readHousehold(w http.ResponseWriter, r *http.Request, id int64) (*data.Household, bool) {
+
+	household, err := app.modelsFor(r).Households.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return nil, false
+	}
+
+	return household, true
+}
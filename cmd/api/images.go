@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/storage"
+	"github.com/narinderv/blockbuster/internal/thumbnail"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// Limits of the image uploads. These are separate from the maxBytes limit of the JSON requests
+const (
+	maxImageBytes   int64 = 10 * 1_048_576
+	maxImagePixels        = 50_000_000 // Guards against images which are small on disk but huge when decoded
+	thumbnailWidth        = 320
+	thumbnailHeight       = 480
+)
+
+// Reasons an image is refused
+var (
+	errUnsupportedImage = errors.New("unsupported image type")
+	errUndecodableImage = errors.New("image could not be decoded")
+	errTooManyPixels    = errors.New("image has too many pixels")
+)
+
+// Images are stored under immutable keys, so they can be cached by the clients for long. They are looked up
+// within the tenant and the parental limit of the request, so the shared caches must not hand them to others
+const imageCacheControl = "private, max-age=31536000, immutable"
+
+// Upload a poster or a still of a movie as a multipart form with the "image" file and an optional "kind"
+func (app *application) uploadMovieImageHandler(w http.ResponseWriter, r *http.Request) {
+
+	movie, ok := app.readMovie(w, r)
+	if !ok {
+		return
+	}
+
+	// Allow some room for the multipart headers and the other fields
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageBytes+64*1024)
+
+	err := r.ParseMultipartForm(1_048_576)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.errorResponse(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("image must not be larger than %d bytes", maxImageBytes))
+		default:
+			app.badRequest(w, r, err)
+		}
+
+		return
+	}
+
+	defer r.MultipartForm.RemoveAll()
+
+	val := validator.NewValidator()
+
+	kind := r.FormValue("kind")
+	if kind == "" {
+		kind = "poster"
+	}
+
+	val.Check(validator.Permittedvalues(kind, data.ImageKinds...), "kind", "invalid kind value")
+
+	file, fileHeader, err := r.FormFile("image")
+	if err != nil {
+		val.AddError("image", "must be provided")
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	defer file.Close()
+
+	val.Check(fileHeader.Size <= maxImageBytes, "image", fmt.Sprintf("must not be larger than %d bytes", maxImageBytes))
+
+	if !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	movieImage, err := app.saveMovieImage(r.Context(), movie.ID, kind, content)
+	if err != nil {
+		switch {
+		case errors.Is(err, errUnsupportedImage):
+			val.AddError("image", "must be a JPEG, PNG or GIF image")
+			app.failedValidations(w, r, val.Errors)
+		case errors.Is(err, errUndecodableImage):
+			val.AddError("image", "could not be decoded")
+			app.failedValidations(w, r, val.Errors)
+		case errors.Is(err, errTooManyPixels):
+			val.AddError("image", "has too many pixels")
+			app.failedValidations(w, r, val.Errors)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	header := make(http.Header)
+	header.Set("Location", movieImage.URL)
+
+	err = app.writeJsonResponse(w, r, envelope{"image": movieImage}, header, http.StatusCreated)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Check the image, generate its thumbnail and store both, then record the image of the movie.
+// The content is sniffed rather than trusting any declared type
+func (app *application) saveMovieImage(ctxt context.Context, movieID int64, kind string, content []byte) (*data.MovieImage, error) {
+
+	// Sniff the content type and check the dimensions before decoding the whole image
+	contentType := http.DetectContentType(content)
+
+	if !validator.Permittedvalues(contentType, thumbnail.ContentTypes...) {
+		return nil, errUnsupportedImage
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, errUndecodableImage
+	}
+
+	if config.Width*config.Height > maxImagePixels {
+		return nil, errTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, errUndecodableImage
+	}
+
+	thumb, err := thumbnail.Generate(img, thumbnailWidth, thumbnailHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+
+	movieImage := &data.MovieImage{
+		MovieID:      movieID,
+		Kind:         kind,
+		ContentType:  contentType,
+		Width:        int32(config.Width),
+		Height:       int32(config.Height),
+		Size:         int64(len(content)),
+		StorageKey:   fmt.Sprintf("movies/%d/%ss/%s%s", movieID, kind, name, imageExtension(contentType)),
+		ThumbnailKey: fmt.Sprintf("movies/%d/thumbnails/%s.jpg", movieID, name),
+	}
+
+	err = app.storage.Put(ctxt, movieImage.StorageKey, bytes.NewReader(content), movieImage.Size, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.storage.Put(ctxt, movieImage.ThumbnailKey, bytes.NewReader(thumb), int64(len(thumb)), "image/jpeg")
+	if err != nil {
+		app.deleteImageObjects(movieImage)
+		return nil, err
+	}
+
+	err = app.models.Images.Insert(ctxt, movieImage)
+	if err != nil {
+		app.deleteImageObjects(movieImage)
+		return nil, err
+	}
+
+	return movieImage, nil
+}
+
+func (app *application) listMovieImagesHandler(w http.ResponseWriter, r *http.Request) {
+
+	movie, ok := app.readMovie(w, r)
+	if !ok {
+		return
+	}
+
+	images, err := app.models.Images.GetAllForMovie(r.Context(), movie.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"images": images}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Serve an uploaded image
+func (app *application) serveImageHandler(w http.ResponseWriter, r *http.Request) {
+	app.serveImage(w, r, false)
+}
+
+// Serve the thumbnail of an uploaded image
+func (app *application) serveThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	app.serveImage(w, r, true)
+}
+
+func (app *application) deleteImageHandler(w http.ResponseWriter, r *http.Request) {
+
+	movieImage, ok := app.readImage(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.Images.Delete(r.Context(), movieImage.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	app.deleteImageObjects(movieImage)
+
+	err = app.writeJsonResponse(w, r, envelope{"message": "image successfully deleted"}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Stream the image or its thumbnail from the storage with the cache headers
+func (app *application) serveImage(w http.ResponseWriter, r *http.Request, thumb bool) {
+
+	movieImage, ok := app.readImage(w, r)
+	if !ok {
+		return
+	}
+
+	key, contentType, etag := movieImage.StorageKey, movieImage.ContentType, fmt.Sprintf(`"image-%d"`, movieImage.ID)
+	if thumb {
+		key, contentType, etag = movieImage.ThumbnailKey, "image/jpeg", fmt.Sprintf(`"thumbnail-%d"`, movieImage.ID)
+	}
+
+	w.Header().Set("Cache-Control", imageCacheControl)
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body, info, err := app.storage.Get(r.Context(), key)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	defer body.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if info.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	// The status has already been sent, so a failure can only be logged
+	if _, err = io.Copy(w, body); err != nil {
+		app.logError(r, err)
+	}
+}
+
+// Read the image identified by the ID parameter. An error response is sent if it could not be read
+func (app *application) readImage(w http.ResponseWriter, r *http.Request) (*data.MovieImage, bool) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	movieImage, err := app.models.Images.Get(r.Context(), id)
+	if err == nil {
+		// The images of the movies of the other tenants, or of restricted movies, are not visible
+		_, err = app.modelsFor(r).Movies.Get(r.Context(), movieImage.MovieID)
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrParentalRestricted):
+			app.parentalRestrictedResponse(w, r)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return nil, false
+	}
+
+	return movieImage, true
+}
+
+// Delete the stored objects of an image. Failures are only logged, leaving orphaned objects behind
+func (app *application) deleteImageObjects(movieImage *data.MovieImage) {
+
+	ctxt, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, key := range []string{movieImage.StorageKey, movieImage.ThumbnailKey} {
+		if err := app.storage.Delete(ctxt, key); err != nil {
+			app.logger.PrintError(err, map[string]string{"key": key})
+		}
+	}
+}
+
+// Random name of the stored objects, so that the keys are never reused and can be cached forever
+func randomName() (string, error) {
+
+	buf := make([]byte, 16)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+func imageExtension(contentType string) string {
+
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	default:
+		return ".jpg"
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+// Send a multipart upload of the content as the image of the movie
+func (app *application) uploadTestImage(t *testing.T, movieID, token string, content []byte) *http.Response {
+
+	t.Helper()
+
+	var body bytes.Buffer
+
+	form := multipart.NewWriter(&body)
+	form.WriteField("kind", "poster")
+
+	file, err := form.CreateFormFile("image", "poster.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file.Write(content)
+	form.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/movies/"+movieID+"/images", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	app.routes().ServeHTTP(w, r)
+
+	return w.Result()
+}
+
+func TestImageHandlers(t *testing.T) {
+
+	app := newTestApplication(t)
+
+	addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"science-fiction"}})
+
+	_, editor := addTestUser(t, app, data.DefaultTenantID, "editor@example.com", data.PermissionWriteMovies)
+	_, viewer := addTestUser(t, app, data.DefaultTenantID, "viewer@example.com")
+
+	var poster bytes.Buffer
+	if err := png.Encode(&poster, image.NewRGBA(image.Rect(0, 0, 640, 960))); err != nil {
+		t.Fatal(err)
+	}
+
+	if res := app.uploadTestImage(t, "1", viewer, poster.Bytes()); res.StatusCode != http.StatusForbidden {
+		t.Errorf("upload without permission: got status %d; want %d", res.StatusCode, http.StatusForbidden)
+	}
+
+	if res := app.uploadTestImage(t, "1", editor, []byte("not an image")); res.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("upload of text: got status %d; want %d", res.StatusCode, http.StatusUnprocessableEntity)
+	}
+
+	if res := app.uploadTestImage(t, "1", editor, make([]byte, maxImageBytes+128*1024)); res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("upload of a large file: got status %d; want %d", res.StatusCode, http.StatusRequestEntityTooLarge)
+	}
+
+	res := app.uploadTestImage(t, "1", editor, poster.Bytes())
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("upload: got status %d; want %d", res.StatusCode, http.StatusCreated)
+	}
+
+	if location := res.Header.Get("Location"); location != "/v1/images/1" {
+		t.Errorf("upload: got Location %q; want /v1/images/1", location)
+	}
+
+	tests := []struct {
+		path        string
+		contentType string
+	}{
+		{"/v1/images/1", "image/png"},
+		{"/v1/images/1/thumbnail", "image/jpeg"},
+	}
+
+	for _, tt := range tests {
+
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		w := httptest.NewRecorder()
+		app.routes().ServeHTTP(w, r)
+
+		res := w.Result()
+		content, _ := io.ReadAll(res.Body)
+
+		if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != tt.contentType || len(content) == 0 {
+			t.Errorf("GET %s: got status %d with %d bytes of %q; want %s", tt.path, res.StatusCode, len(content), res.Header.Get("Content-Type"), tt.contentType)
+		}
+
+		if cacheControl := res.Header.Get("Cache-Control"); !strings.HasPrefix(cacheControl, "private,") {
+			t.Errorf("GET %s: got Cache-Control %q; want it private", tt.path, cacheControl)
+		}
+
+		// The images never change, so the clients can revalidate with the ETag
+		r = httptest.NewRequest(http.MethodGet, tt.path, nil)
+		r.Header.Set("If-None-Match", res.Header.Get("ETag"))
+		w = httptest.NewRecorder()
+		app.routes().ServeHTTP(w, r)
+
+		if w.Code != http.StatusNotModified {
+			t.Errorf("GET %s with the ETag: got status %d; want %d", tt.path, w.Code, http.StatusNotModified)
+		}
+	}
+
+	res, _ = app.testRequest(t, testRequest{method: http.MethodDelete, path: "/v1/images/1", token: viewer})
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("delete without permission: got status %d; want %d", res.StatusCode, http.StatusForbidden)
+	}
+
+	res, _ = app.testRequest(t, testRequest{method: http.MethodDelete, path: "/v1/images/1", token: editor})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("delete: got status %d; want %d", res.StatusCode, http.StatusOK)
+	}
+
+	res, _ = app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/images/1"})
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("GET deleted image: got status %d; want %d", res.StatusCode, http.StatusNotFound)
+	}
+}
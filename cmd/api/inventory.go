@@ -0,0 +1,282 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// List the copies of a movie, optionally restricted to a store using store=
+func (app *application) listMovieInventoryHandler(w http.ResponseWriter, r *http.Request) {
+
+	movie, ok := app.readMovie(w, r)
+	if !ok {
+		return
+	}
+
+	val := validator.NewValidator()
+
+	storeID := int64(app.readInt(r.URL.Query(), "store", 0, val))
+	val.Check(storeID >= 0, "store", "must be a positive integer")
+
+	if !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	items, err := app.models.Inventory.GetAllForMovie(r.Context(), movie.ID, storeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"inventory": items}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) createInventoryItemHandler(w http.ResponseWriter, r *http.Request) {
+
+	var request struct {
+		MovieID   int64  `json:"movie_id"`
+		StoreID   int64  `json:"store_id"`
+		Format    string `json:"format"`
+		Condition string `json:"condition"`
+		Barcode   string `json:"barcode"`
+	}
+
+	err := app.readJsonRequest(w, r, &request)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	item := &data.InventoryItem{
+		MovieID:   request.MovieID,
+		StoreID:   request.StoreID,
+		Format:    request.Format,
+		Condition: request.Condition,
+		Barcode:   request.Barcode,
+		Status:    data.InventoryAvailable,
+	}
+
+	// New copies are in a good condition unless specified otherwise
+	if item.Condition == "" {
+		item.Condition = "good"
+	}
+
+	val := validator.NewValidator()
+
+	if data.ValidateInventoryItem(val, item); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	// The movie and the store must exist
+	if !app.checkInventoryReferences(w, r, val, item) {
+		return
+	}
+
+	err = app.models.Inventory.Insert(r.Context(), item)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateBarcode):
+			val.AddError("barcode", "a copy with this barcode already exists")
+			app.failedValidations(w, r, val.Errors)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	header := make(http.Header)
+	header.Set("Location", fmt.Sprintf("/v1/inventory/%d", item.ID))
+
+	err = app.writeJsonResponse(w, r, envelope{"inventory_item": item}, header, http.StatusCreated)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) showInventoryItemHandler(w http.ResponseWriter, r *http.Request) {
+
+	item, ok := app.readInventoryItem(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJsonResponse(w, r, envelope{"inventory_item": item}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) editInventoryItemHandler(w http.ResponseWriter, r *http.Request) {
+
+	item, ok := app.readInventoryItem(w, r)
+	if !ok {
+		return
+	}
+
+	// All members are pointers to check whether the values have been provided by the user or not
+	var request struct {
+		StoreID   *int64  `json:"store_id"`
+		Format    *string `json:"format"`
+		Condition *string `json:"condition"`
+		Barcode   *string `json:"barcode"`
+		Status    *string `json:"status"`
+	}
+
+	err := app.readJsonRequest(w, r, &request)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	val := validator.NewValidator()
+
+	if request.StoreID != nil {
+		item.StoreID = *request.StoreID
+	}
+
+	if request.Format != nil {
+		item.Format = *request.Format
+	}
+
+	if request.Condition != nil {
+		item.Condition = *request.Condition
+	}
+
+	if request.Barcode != nil {
+		item.Barcode = *request.Barcode
+	}
+
+	// Copies are marked as rented and returned by the rentals only, and set aside by the holds only
+	if request.Status != nil {
+		val.Check(*request.Status != data.InventoryRented, "status", "copies can only be rented using a rental")
+		val.Check(item.Status != data.InventoryRented, "status", "copy is currently rented")
+		val.Check(*request.Status != data.InventoryOnHold, "status", "copies can only be set aside using a hold")
+		val.Check(item.Status != data.InventoryOnHold, "status", "copy is set aside for a hold")
+		item.Status = *request.Status
+	}
+
+	if data.ValidateInventoryItem(val, item); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	if !app.checkInventoryReferences(w, r, val, item) {
+		return
+	}
+
+	err = app.models.Inventory.Update(r.Context(), item)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateBarcode):
+			val.AddError("barcode", "a copy with this barcode already exists")
+			app.failedValidations(w, r, val.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictError(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"inventory_item": item}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) deleteInventoryItemHandler(w http.ResponseWriter, r *http.Request) {
+
+	item, ok := app.readInventoryItem(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.Inventory.Delete(r.Context(), item.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"message": "inventory item successfully deleted"}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Read the inventory item identified by the ID parameter. An error response is sent if it could not be read
+func (app *application) readInventoryItem(w http.ResponseWriter, r *http.Request) (*data.InventoryItem, bool) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	item, err := app.models.Inventory.Get(r.Context(), id)
+	if err == nil {
+		// Copies held by the stores of the other tenants are not visible
+		_, err = app.modelsFor(r).Stores.Get(r.Context(), item.StoreID)
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return nil, false
+	}
+
+	return item, true
+}
+
+// Check that the movie and the store of the inventory item exist. A validation error response
+// is sent if they do not
+func (app *application) checkInventoryReferences(w http.ResponseWriter, r *http.Request, val *validator.Validator, item *data.InventoryItem) bool {
+
+	_, err := app.modelsFor(r).Movies.Get(r.Context(), item.MovieID)
+	switch {
+	case errors.Is(err, data.ErrParentalRestricted):
+		val.AddError("movie_id", "is restricted by parental controls")
+	case errors.Is(err, data.ErrRecordNotFound):
+		val.AddError("movie_id", "does not exist")
+	case err != nil:
+		app.serverError(w, r, err)
+		return false
+	}
+
+	_, err = app.modelsFor(r).Stores.Get(r.Context(), item.StoreID)
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		val.AddError("store_id", "does not exist")
+	case err != nil:
+		app.serverError(w, r, err)
+		return false
+	}
+
+	if !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+func TestInventoryHandlers(t *testing.T) {
+
+	app := newTestApplication(t)
+
+	addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"science-fiction"}})
+
+	_, staff := addTestUser(t, app, data.DefaultTenantID, "staff@example.com", data.PermissionManageInventory)
+	_, customer := addTestUser(t, app, data.DefaultTenantID, "customer@example.com")
+
+	store := map[string]interface{}{"name": "Main Street"}
+
+	res, _ := app.testRequest(t, testRequest{method: http.MethodPost, path: "/v1/stores", body: store, token: customer})
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("create store without permission: got status %d; want %d", res.StatusCode, http.StatusForbidden)
+	}
+
+	res, body := app.testRequest(t, testRequest{method: http.MethodPost, path: "/v1/stores", body: store, token: staff})
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("create store: got status %d; want %d (%v)", res.StatusCode, http.StatusCreated, body)
+	}
+
+	tests := []struct {
+		name   string
+		item   map[string]interface{}
+		status int
+		errors map[string]interface{}
+	}{
+		{"new copy", map[string]interface{}{"movie_id": 1, "store_id": 1, "format": "dvd", "barcode": "A-1"}, http.StatusCreated, nil},
+		{"duplicate barcode", map[string]interface{}{"movie_id": 1, "store_id": 1, "format": "dvd", "barcode": "A-1"}, http.StatusUnprocessableEntity, map[string]interface{}{"barcode": "a copy with this barcode already exists"}},
+		{"unknown movie", map[string]interface{}{"movie_id": 9, "store_id": 1, "format": "dvd", "barcode": "A-2"}, http.StatusUnprocessableEntity, map[string]interface{}{"movie_id": "does not exist"}},
+		{"unknown store", map[string]interface{}{"movie_id": 1, "store_id": 9, "format": "dvd", "barcode": "A-2"}, http.StatusUnprocessableEntity, map[string]interface{}{"store_id": "does not exist"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			res, body := app.testRequest(t, testRequest{method: http.MethodPost, path: "/v1/inventory", body: tt.item, token: staff})
+			if res.StatusCode != tt.status {
+				t.Fatalf("got status %d; want %d (%v)", res.StatusCode, tt.status, body)
+			}
+
+			for field, message := range tt.errors {
+				errors, _ := body["error"].(map[string]interface{})
+				if errors[field] != message {
+					t.Errorf("got %s error %v; want %q", field, errors[field], message)
+				}
+			}
+		})
+	}
+
+	res, body = app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/movies/1/inventory"})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("list copies: got status %d; want %d", res.StatusCode, http.StatusOK)
+	}
+
+	if items := body["inventory"].([]interface{}); len(items) != 1 {
+		t.Errorf("list copies: got %d copies; want 1", len(items))
+	}
+}
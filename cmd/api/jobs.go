@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Interval between the runs of the hold sweeper
+const holdSweepInterval = time.Minute
+
+// Start the background jobs. The jobs stop when the context is cancelled
+func (app *application) startJobs(ctxt context.Context) {
+
+	go app.runPeriodically(ctxt, "hold sweeper", func() time.Duration { return holdSweepInterval }, app.sweepHolds)
+
+	// The intervals of the other jobs can be changed by reloading the configuration. The similarities and the
+	// recommendation neighbours can be refreshed by an external scheduler instead, disabling their jobs
+	go app.runPeriodically(ctxt, "similarity refresh", func() time.Duration { return app.jobIntervals().similarityRefresh }, app.refreshSimilarities)
+	go app.runPeriodically(ctxt, "recommendation refresh", func() time.Duration { return app.jobIntervals().recommendationRefresh }, app.refreshRecommendations)
+	go app.runPeriodically(ctxt, "popularity refresh", func() time.Duration { return app.jobIntervals().popularityRefresh }, app.refreshPopularity)
+
+	if app.enricher != nil {
+		go app.runPeriodically(ctxt, "movie enrichment", func() time.Duration { return app.jobIntervals().enrich }, app.enrichMovies)
+	}
+}
+
+// Run the job right away and then every interval until the context is cancelled. The interval is read
+// again when the configuration is reloaded. A changed interval reschedules the job, the next run then
+// coming an interval after the reload, while an unchanged one keeps the job on its schedule, and a zero
+// interval leaves the job idle. The job is given the context so that a run in progress is stopped
+// along with the job. Errors and panics are logged so that a failed run does not stop the later runs
+func (app *application) runPeriodically(ctxt context.Context, name string, interval func() time.Duration, job func(context.Context) error) {
+
+	run := func() {
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.PrintError(fmt.Errorf("%s", err), map[string]string{"job": name})
+			}
+		}()
+
+		if err := job(ctxt); err != nil {
+			app.logger.PrintError(err, map[string]string{"job": name})
+		}
+	}
+
+	every := interval()
+
+	if every > 0 {
+		run()
+	}
+
+	var timer *time.Timer
+	var next <-chan time.Time
+
+	// Schedule the next run an interval from now
+	schedule := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+
+		timer, next = nil, nil
+
+		if every > 0 {
+			timer = time.NewTimer(every)
+			next = timer.C
+		}
+	}
+
+	schedule()
+
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		// Taken before reading the interval, so that a reload in between is not missed
+		reload := app.nextReload()
+
+		if current := interval(); current != every {
+			every = current
+			schedule()
+		}
+
+		select {
+		case <-ctxt.Done():
+			return
+		case <-reload:
+		case <-next:
+			run()
+			schedule()
+		}
+	}
+}
+
+// Expire the unclaimed ready holds and pass their copies on to the next holds in the queues
+func (app *application) sweepHolds(ctxt context.Context) error {
+
+	ready, err := app.models.Holds.ExpireReady(ctxt)
+	if err != nil {
+		return err
+	}
+
+	if len(ready) > 0 {
+		app.logger.PrintInfo("reassigned copies of expired holds", map[string]string{
+			"ready": strconv.Itoa(len(ready)),
+		})
+
+		app.notifyHoldsReady(ready...)
+	}
+
+	return nil
+}
+
+// Recompute the similarities of the movies served by the similar movies endpoint
+func (app *application) refreshSimilarities(ctxt context.Context) error {
+
+	start := time.Now()
+
+	count, err := app.models.Similarities.Refresh(ctxt)
+	if err != nil {
+		return err
+	}
+
+	app.logger.PrintInfo("refreshed movie similarities", map[string]string{
+		"pairs":    strconv.FormatInt(count, 10),
+		"duration": time.Since(start).String(),
+	})
+
+	return nil
+}
+
+// Recompute the collaborative filtering neighbours used for the recommendations
+func (app *application) refreshRecommendations(ctxt context.Context) error {
+
+	start := time.Now()
+
+	count, err := app.models.Recommendations.Refresh(ctxt)
+	if err != nil {
+		return err
+	}
+
+	app.logger.PrintInfo("refreshed recommendation neighbours", map[string]string{
+		"pairs":    strconv.FormatInt(count, 10),
+		"duration": time.Since(start).String(),
+	})
+
+	return nil
+}
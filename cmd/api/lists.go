@@ -0,0 +1,406 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// List the lists of the authenticated user for the selected profile
+func (app *application) listUserListsHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		data.Filters
+	}
+
+	val := validator.NewValidator()
+
+	queryString := r.URL.Query()
+
+	input.Filters.Page = app.readInt(queryString, "page", 1, val)
+	input.Filters.PageSize = app.readInt(queryString, "page_size", 20, val)
+	input.Filters.Sort = app.readString(queryString, "sort", "name")
+	input.Filters.SortList = []string{"id", "name", "created_at", "-id", "-name", "-created_at"}
+
+	if data.ValidateFilters(val, &input.Filters); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	lists, metadata, err := app.models.Lists.GetAllForUser(r.Context(), app.contextGetUser(r).ID, app.contextGetProfileID(r), input.Filters)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"metadata": metadata, "lists": lists}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) createListHandler(w http.ResponseWriter, r *http.Request) {
+
+	var request struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Public      bool   `json:"public"`
+	}
+
+	err := app.readJsonRequest(w, r, &request)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	list := &data.List{
+		UserID:      app.contextGetUser(r).ID,
+		ProfileID:   app.contextGetProfileID(r),
+		Name:        request.Name,
+		Description: request.Description,
+		Public:      request.Public,
+	}
+
+	val := validator.NewValidator()
+
+	if data.ValidateList(val, list); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	err = app.models.Lists.Insert(r.Context(), list)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	header := make(http.Header)
+	header.Set("Location", fmt.Sprintf("/v1/lists/%d", list.ID))
+
+	err = app.writeJsonResponse(w, r, envelope{"list": list}, header, http.StatusCreated)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Show a list along with a page of its movies, in the order of the list unless sorted otherwise
+func (app *application) showListHandler(w http.ResponseWriter, r *http.Request) {
+
+	list, ok := app.readList(w, r, false)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	val := validator.NewValidator()
+
+	queryString := r.URL.Query()
+
+	input.Filters.Page = app.readInt(queryString, "page", 1, val)
+	input.Filters.PageSize = app.readInt(queryString, "page_size", 20, val)
+	input.Filters.Sort = app.readString(queryString, "sort", "position")
+	input.Filters.SortList = []string{"position", "added_at", "title", "year", "-position", "-added_at", "-title", "-year"}
+
+	proj := app.readProjection(queryString, data.MovieFields, movieIncludes, val, "movies")
+
+	input.Filters.Fields = proj.fields
+
+	if data.ValidateFilters(val, &input.Filters); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	movies, metadata, err := app.modelsFor(r).Lists.GetItems(r.Context(), list.ID, input.Filters)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Load the related resources to be embedded
+	err = app.embedMovieRelations(r.Context(), proj, movies...)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"list": list, "metadata": metadata, "movies": movies}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) editListHandler(w http.ResponseWriter, r *http.Request) {
+
+	list, ok := app.readList(w, r, true)
+	if !ok {
+		return
+	}
+
+	// All members are pointers to check whether the values have been provided by the user or not
+	var request struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		Public      *bool   `json:"public"`
+	}
+
+	err := app.readJsonRequest(w, r, &request)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if request.Name != nil {
+		list.Name = *request.Name
+	}
+
+	if request.Description != nil {
+		list.Description = *request.Description
+	}
+
+	if request.Public != nil {
+		list.Public = *request.Public
+	}
+
+	val := validator.NewValidator()
+
+	if data.ValidateList(val, list); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	err = app.models.Lists.Update(r.Context(), list)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictError(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"list": list}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) deleteListHandler(w http.ResponseWriter, r *http.Request) {
+
+	list, ok := app.readList(w, r, true)
+	if !ok {
+		return
+	}
+
+	err := app.models.Lists.Delete(r.Context(), list.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"message": "list successfully deleted"}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Add a movie to a list, at the end unless a position is given
+func (app *application) addListItemHandler(w http.ResponseWriter, r *http.Request) {
+
+	list, ok := app.readList(w, r, true)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		MovieID  int64 `json:"movie_id"`
+		Position int32 `json:"position"`
+	}
+
+	err := app.readJsonRequest(w, r, &request)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	val := validator.NewValidator()
+
+	val.Check(request.MovieID > 0, "movie_id", "must be provided")
+	val.Check(request.Position >= 0, "position", "must not be negative")
+
+	if !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	// Only the movies of the tenant can be added
+	_, err = app.modelsFor(r).Movies.Get(r.Context(), request.MovieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrParentalRestricted):
+			app.parentalRestrictedResponse(w, r)
+		case errors.Is(err, data.ErrRecordNotFound):
+			val.AddError("movie_id", "does not exist")
+			app.failedValidations(w, r, val.Errors)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.models.Lists.AddItem(r.Context(), list.ID, request.MovieID, request.Position)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateListItem):
+			val.AddError("movie_id", "is already in the list")
+			app.failedValidations(w, r, val.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			val.AddError("movie_id", "does not exist")
+			app.failedValidations(w, r, val.Errors)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"message": "movie successfully added to the list"}, nil, http.StatusCreated)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Move a movie of a list to another position
+func (app *application) moveListItemHandler(w http.ResponseWriter, r *http.Request) {
+
+	list, ok := app.readList(w, r, true)
+	if !ok {
+		return
+	}
+
+	movieID, err := app.readNamedIDParam(r, "movie_id")
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	var request struct {
+		Position int32 `json:"position"`
+	}
+
+	err = app.readJsonRequest(w, r, &request)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	val := validator.NewValidator()
+
+	if val.Check(request.Position > 0, "position", "must be greater than zero"); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	err = app.models.Lists.MoveItem(r.Context(), list.ID, movieID, request.Position)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"message": "movie successfully moved"}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) removeListItemHandler(w http.ResponseWriter, r *http.Request) {
+
+	list, ok := app.readList(w, r, true)
+	if !ok {
+		return
+	}
+
+	movieID, err := app.readNamedIDParam(r, "movie_id")
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	err = app.models.Lists.RemoveItem(r.Context(), list.ID, movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"message": "movie successfully removed from the list"}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Read the list identified by the ID parameter. Private lists are only visible to their owner
+// and lists can only be changed by their owner. An error response is sent if the list could not be read
+func (app *application) readList(w http.ResponseWriter, r *http.Request, change bool) (*data.List, bool) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	// The lists of the users of the other tenants are not visible
+	list, err := app.modelsFor(r).Lists.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return nil, false
+	}
+
+	user := app.contextGetUser(r)
+
+	// The lists of the other profiles of the household are treated like those of other users
+	if list.UserID != user.ID || list.ProfileID != app.contextGetProfileID(r) {
+		switch {
+		// Do not reveal the private lists of other users
+		case !list.Public:
+			app.notFound(w, r)
+			return nil, false
+		case change:
+			app.notPermittedResponse(w, r)
+			return nil, false
+		}
+	}
+
+	return list, true
+}
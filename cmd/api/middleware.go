@@ -1,12 +1,17 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
 	"golang.org/x/time/rate"
 )
 
@@ -25,6 +30,35 @@ func (app *application) recoverFromPanic(nxtHandler http.Handler) http.Handler {
 	})
 }
 
+// Allow the cross-origin requests of the trusted origins, answering their preflight requests. The other
+// origins get no CORS headers, so that the browsers keep their responses from them
+func (app *application) enableCORS(nxtHandler http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		// The responses depend on the origin, and on whether the request is a preflight request
+		w.Header().Add("Vary", "Origin")
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+
+		origin := r.Header.Get("Origin")
+
+		if origin != "" && validator.Permittedvalues(origin, app.corsTrustedOrigins()...) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, PUT, PATCH, DELETE")
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join([]string{"Authorization", "Content-Type",
+					app.config.tenant.header, parentalPinHeader}, ", "))
+
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+		}
+
+		nxtHandler.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) rateLimit(nxtHandler http.Handler) http.Handler {
 
 	// Structure to hold the rate limiters created for different clients
@@ -48,11 +82,11 @@ func (app *application) rateLimit(nxtHandler http.Handler) http.Handler {
 			// Lock the mutex before accessing the clients map
 			mutx.Lock()
 
-			for ip, client := range clients {
+			for key, client := range clients {
 				// Check if there has been no request since last three minutes
 				// If no, remove the entry from the map
 				if time.Since(client.lastSeen) > time.Minute*3 {
-					delete(clients, ip)
+					delete(clients, key)
 				}
 			}
 
@@ -63,10 +97,25 @@ func (app *application) rateLimit(nxtHandler http.Handler) http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
+		// The tenant can override the rate limits of the deployment
+		tenant := app.contextGetTenant(r)
+
+		// The limits may be changed by reloading the configuration
+		limits := app.rateLimits()
+
+		tps, burstLimit := limits.tps, limits.burstLimit
+		if tenant.Config.RateLimitTPS > 0 {
+			tps = tenant.Config.RateLimitTPS
+		}
+
+		if tenant.Config.RateLimitBurst > 0 {
+			burstLimit = tenant.Config.RateLimitBurst
+		}
+
 		// Get the ip of the client and check if this client is in the map or not.
 		// If no, add it to the map and check if this request is within the define rate limit
 		// Check if rate limiting is enabled
-		if app.config.rateLimiter.enabled {
+		if limits.enabled && !tenant.Config.RateLimitDisabled {
 
 			ip, _, err := net.SplitHostPort(r.RemoteAddr)
 			if err != nil {
@@ -74,20 +123,29 @@ func (app *application) rateLimit(nxtHandler http.Handler) http.Handler {
 				return
 			}
 
+			// The clients are limited separately for each tenant
+			key := fmt.Sprintf("%d/%s", tenant.ID, ip)
+
 			// Lock the mutext before accessing the clients map
 			mutx.Lock()
 
-			if _, found := clients[ip]; !found {
+			if _, found := clients[key]; !found {
 				// No earlier request, add to the map
-				clients[ip] = &client{
-					limiter: rate.NewLimiter(rate.Limit(app.config.rateLimiter.tps), app.config.rateLimiter.burstLimit),
+				clients[key] = &client{
+					limiter: rate.NewLimiter(rate.Limit(tps), burstLimit),
 				}
 
-				clients[ip].lastSeen = time.Now()
+				clients[key].lastSeen = time.Now()
+			}
+
+			// Clients seen before the limits were changed move on to the new limits
+			if limiter := clients[key].limiter; limiter.Limit() != rate.Limit(tps) || limiter.Burst() != burstLimit {
+				limiter.SetLimit(rate.Limit(tps))
+				limiter.SetBurst(burstLimit)
 			}
 
 			// Check if request is within the configured rate
-			if !clients[ip].limiter.Allow() {
+			if !clients[key].limiter.Allow() {
 				mutx.Unlock()
 				app.tpsExceedResponse(w, r)
 				return
@@ -99,3 +157,176 @@ func (app *application) rateLimit(nxtHandler http.Handler) http.Handler {
 		nxtHandler.ServeHTTP(w, r)
 	})
 }
+
+func (app *application) authenticate(nxtHandler http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		// The response varies depending upon the Authorization header
+		w.Header().Add("Vary", "Authorization")
+
+		// Requests without the Authorization header are treated as anonymous
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			r = app.contextSetUser(r, data.AnonymousUser)
+			nxtHandler.ServeHTTP(w, r)
+			return
+		}
+
+		// The header is expected in the format "Bearer <token>"
+		headerParts := strings.Split(authHeader, " ")
+		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		token := headerParts[1]
+
+		val := validator.NewValidator()
+
+		if data.ValidateTokenPlaintext(val, token); !val.IsValid() {
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		// Get the user owning the token. Tokens are only valid for the tenant of their user
+		users := app.modelsFor(r).Users
+
+		user, err := users.GetForToken(r.Context(), data.ScopeAuthentication, token)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.invalidAuthenticationTokenResponse(w, r)
+			default:
+				app.serverError(w, r, err)
+			}
+			return
+		}
+
+		r = app.contextSetUser(r, user)
+
+		nxtHandler.ServeHTTP(w, r)
+	})
+}
+
+// Select the profile the authentication token is bound to for the request. The profile is part of
+// the token, so that a client cannot leave it out to fall back to the account
+func (app *application) selectProfile(nxtHandler http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		user := app.contextGetUser(r)
+
+		if user.IsAnonymous() || user.ProfileID == 0 {
+			nxtHandler.ServeHTTP(w, r)
+			return
+		}
+
+		profile, err := app.models.Profiles.Get(r.Context(), user.HouseholdID, user.ProfileID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.invalidAuthenticationTokenResponse(w, r)
+			default:
+				app.serverError(w, r, err)
+			}
+			return
+		}
+
+		r = app.contextSetProfile(r, profile)
+
+		nxtHandler.ServeHTTP(w, r)
+	})
+}
+
+// Put the parental limit of the selected profile, or else of the user, in effect for the request,
+// unless it is overridden by sending the parental PIN of the user in the parental PIN header
+func (app *application) applyParentalControls(nxtHandler http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		// The response varies depending upon the parental PIN header
+		w.Header().Add("Vary", parentalPinHeader)
+
+		user := app.contextGetUser(r)
+
+		limit := user.ParentalLimit
+		if profile := app.contextGetProfile(r); profile != nil && profile.ParentalLimit != nil {
+			limit = profile.ParentalLimit
+		}
+
+		if pin := r.Header.Get(parentalPinHeader); pin != "" && user.ParentalPin.IsSet() {
+			// The PIN is not even compared while it is locked, which also spares the hashing
+			if wait, locked := app.pinAttempts.locked(user.ID, time.Now()); locked {
+				w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+				app.parentalPinLockedResponse(w, r)
+				return
+			}
+
+			match, err := user.ParentalPin.MatchPassword(pin)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+
+			if !match {
+				app.pinAttempts.fail(user.ID, time.Now())
+				app.invalidParentalPinResponse(w, r)
+				return
+			}
+
+			app.pinAttempts.reset(user.ID)
+
+			r = app.contextSetParentalOverride(r)
+
+			nxtHandler.ServeHTTP(w, r)
+			return
+		}
+
+		if limit != nil {
+			r = app.contextSetParentalLimit(r, limit)
+		}
+
+		nxtHandler.ServeHTTP(w, r)
+	})
+}
+
+// Allow the request only if the user has been authenticated
+func (app *application) requireAuthenticatedUser(nxtHandler http.HandlerFunc) http.HandlerFunc {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		user := app.contextGetUser(r)
+
+		if user.IsAnonymous() {
+			app.authenticationRequiredResponse(w, r)
+			return
+		}
+
+		nxtHandler.ServeHTTP(w, r)
+	})
+}
+
+// Allow the request only if the authenticated user has the given permission
+func (app *application) requirePermission(code string, nxtHandler http.HandlerFunc) http.HandlerFunc {
+
+	fn := func(w http.ResponseWriter, r *http.Request) {
+
+		user := app.contextGetUser(r)
+
+		permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if !permissions.Include(code) {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		nxtHandler.ServeHTTP(w, r)
+	}
+
+	return app.requireAuthenticatedUser(fn)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/jsonlog"
+	"github.com/narinderv/blockbuster/internal/migrate"
+	"github.com/narinderv/blockbuster/migrations"
+)
+
+var errMigrateUsage = errors.New("usage: api [flags] migrate up|down [N]|status|goto N")
+
+// Run the migrate subcommand:
+//   - up applies all the pending migrations
+//   - down reverts the last N applied migrations, 1 by default
+//   - status lists the migrations along with when they were applied
+//   - goto migrates to version N, applying or reverting the migrations as needed. 0 reverts all the migrations
+func runMigrateCommand(conf configuration, logger *jsonlog.Logger, args []string) error {
+
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	dbConn, err := connectToDatabase(conf)
+	if err != nil {
+		return err
+	}
+
+	defer dbConn.Close()
+
+	migrator, err := migrate.New(dbConn, migrations.Files)
+	if err != nil {
+		return err
+	}
+
+	ctxt := context.Background()
+
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		applied, err := migrator.Up(ctxt)
+		logMigrations(logger, "applied migration", applied)
+		return err
+
+	case args[0] == "down" && len(args) <= 2:
+		steps := 1
+
+		if len(args) == 2 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return errMigrateUsage
+			}
+		}
+
+		reverted, err := migrator.Down(ctxt, steps)
+		logMigrations(logger, "reverted migration", reverted)
+		return err
+
+	case args[0] == "goto" && len(args) == 2:
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return errMigrateUsage
+		}
+
+		reverted, applied, err := migrator.Goto(ctxt, version)
+		logMigrations(logger, "reverted migration", reverted)
+		logMigrations(logger, "applied migration", applied)
+		return err
+
+	case args[0] == "status" && len(args) == 1:
+		statuses, err := migrator.Status(ctxt)
+		if err != nil {
+			return err
+		}
+
+		printMigrationStatus(statuses)
+		return nil
+
+	default:
+		return errMigrateUsage
+	}
+}
+
+// Apply the pending migrations at startup
+func autoMigrate(dbConn *sql.DB, logger *jsonlog.Logger) error {
+
+	migrator, err := migrate.New(dbConn, migrations.Files)
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(context.Background())
+	logMigrations(logger, "applied migration", applied)
+
+	return err
+}
+
+func logMigrations(logger *jsonlog.Logger, message string, done []*migrate.Migration) {
+
+	for _, migration := range done {
+		logger.PrintInfo(message, map[string]string{
+			"version": strconv.FormatInt(migration.Version, 10),
+			"name":    migration.Name,
+		})
+	}
+}
+
+// Print the status of the migrations as a table
+func printMigrationStatus(statuses []*migrate.Status) {
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer out.Flush()
+
+	fmt.Fprintln(out, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+
+	for _, status := range statuses {
+		state, appliedAt := "pending", ""
+
+		if !status.AppliedAt.IsZero() {
+			state, appliedAt = "applied", status.AppliedAt.Format(time.RFC3339)
+		}
+
+		switch {
+		case status.Legacy:
+			state = "applied, legacy, not converted"
+		case status.Missing:
+			state = "applied, unknown to this binary"
+		case status.Modified:
+			state = "applied, changed since"
+		}
+
+		fmt.Fprintf(out, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+}
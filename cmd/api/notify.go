@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/jsonlog"
+)
+
+// Hook called when a hold becomes ready for pickup
+type holdNotifier interface {
+	HoldReady(ctxt context.Context, hold *data.Hold) error
+}
+
+// Notifier which only logs the ready holds. Used when no webhook is configured
+type logHoldNotifier struct {
+	logger *jsonlog.Logger
+}
+
+func (n logHoldNotifier) HoldReady(ctxt context.Context, hold *data.Hold) error {
+
+	n.logger.PrintInfo("hold ready for pickup", map[string]string{
+		"hold_id":  strconv.FormatInt(hold.ID, 10),
+		"user_id":  strconv.FormatInt(hold.UserID, 10),
+		"movie_id": strconv.FormatInt(hold.MovieID, 10),
+		"store_id": strconv.FormatInt(hold.StoreID, 10),
+	})
+
+	return nil
+}
+
+// Notifier which posts the ready holds as JSON to a webhook e.g. of a mailing or SMS service
+type webhookHoldNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n webhookHoldNotifier) HoldReady(ctxt context.Context, hold *data.Hold) error {
+
+	body, err := json.Marshal(envelope{"event": "hold.ready", "hold": hold})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctxt, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("hold webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Create the notifier as per the configuration
+func newHoldNotifier(conf configuration, logger *jsonlog.Logger) holdNotifier {
+
+	if conf.holdWebhookURL == "" {
+		return logHoldNotifier{logger: logger}
+	}
+
+	return webhookHoldNotifier{url: conf.holdWebhookURL, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Notify the users of the ready holds in the background, so that the request or the job
+// making the holds ready is not held up by a slow webhook
+func (app *application) notifyHoldsReady(holds ...*data.Hold) {
+
+	app.background(func(ctxt context.Context) {
+		for _, hold := range holds {
+			holdCtxt, cancel := context.WithTimeout(ctxt, 15*time.Second)
+
+			err := app.notifier.HoldReady(holdCtxt, hold)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{"hold_id": strconv.FormatInt(hold.ID, 10)})
+			}
+
+			cancel()
+		}
+	})
+}
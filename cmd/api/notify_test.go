@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+func TestWebhookHoldNotifier(t *testing.T) {
+
+	var received struct {
+		Event string    `json:"event"`
+		Hold  data.Hold `json:"hold"`
+	}
+
+	status := http.StatusNoContent
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s request with content type %q; want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Error(err)
+		}
+
+		w.WriteHeader(status)
+	}))
+
+	defer server.Close()
+
+	notifier := webhookHoldNotifier{url: server.URL, client: &http.Client{Timeout: time.Second}}
+
+	hold := &data.Hold{ID: 7, UserID: 3, MovieID: 5, StoreID: 2, Status: data.HoldReady}
+
+	if err := notifier.HoldReady(context.Background(), hold); err != nil {
+		t.Fatal(err)
+	}
+
+	if received.Event != "hold.ready" || received.Hold.ID != hold.ID || received.Hold.MovieID != hold.MovieID {
+		t.Errorf("got event %q for hold %+v; want hold.ready for hold 7", received.Event, received.Hold)
+	}
+
+	status = http.StatusBadGateway
+
+	if err := notifier.HoldReady(context.Background(), hold); err == nil {
+		t.Error("got no error for a failed webhook; want one")
+	}
+}
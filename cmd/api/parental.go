@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// Header carrying the parental PIN, which overrides the parental limit for the request
+const parentalPinHeader = "X-Parental-Pin"
+
+// Number of invalid parental PINs after which the PIN of a user is locked, and for how long
+const (
+	maxParentalPinFailures = 5
+	parentalPinLockout     = 15 * time.Minute
+)
+
+// Invalid parental PINs sent by the users. A PIN is only a few digits long, so it is locked for a while
+// after a few failures, without comparing the PINs sent in the meantime. The failures are kept in memory
+// by each instance, like the rate limits of the clients
+type pinAttempts struct {
+	mutx     sync.Mutex
+	failures map[int64]*pinFailures
+}
+
+type pinFailures struct {
+	count       int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+func newPinAttempts() *pinAttempts {
+	return &pinAttempts{failures: make(map[int64]*pinFailures)}
+}
+
+// Check if the PIN of the user is locked, returning the time until it is unlocked
+func (attempts *pinAttempts) locked(userID int64, now time.Time) (time.Duration, bool) {
+
+	attempts.mutx.Lock()
+	defer attempts.mutx.Unlock()
+
+	failures, found := attempts.failures[userID]
+	if !found || !now.Before(failures.lockedUntil) {
+		return 0, false
+	}
+
+	return failures.lockedUntil.Sub(now), true
+}
+
+// Record an invalid PIN of the user, locking the PIN once the maximum number of failures is reached.
+// The failures older than the lockout period are forgotten
+func (attempts *pinAttempts) fail(userID int64, now time.Time) {
+
+	attempts.mutx.Lock()
+	defer attempts.mutx.Unlock()
+
+	for id, failures := range attempts.failures {
+		if now.Sub(failures.lastFailure) > parentalPinLockout && !now.Before(failures.lockedUntil) {
+			delete(attempts.failures, id)
+		}
+	}
+
+	failures, found := attempts.failures[userID]
+	if !found {
+		failures = &pinFailures{}
+		attempts.failures[userID] = failures
+	}
+
+	failures.count++
+	failures.lastFailure = now
+
+	if failures.count >= maxParentalPinFailures {
+		failures.count = 0
+		failures.lockedUntil = now.Add(parentalPinLockout)
+	}
+}
+
+// Forget the failures of the user once the right PIN has been sent
+func (attempts *pinAttempts) reset(userID int64) {
+
+	attempts.mutx.Lock()
+	defer attempts.mutx.Unlock()
+
+	delete(attempts.failures, userID)
+}
+
+// Show the parental limit of the user. The PIN is never shown
+func (app *application) showParentalControlsHandler(w http.ResponseWriter, r *http.Request) {
+
+	user := app.contextGetUser(r)
+
+	err := app.writeJsonResponse(w, r, envelope{"parental_controls": user.ParentalLimit}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Set the parental limit and the PIN overriding it. The limit may be left out to only set the PIN
+// guarding the limits of the profiles. Existing controls can only be changed with the PIN
+func (app *application) putParentalControlsHandler(w http.ResponseWriter, r *http.Request) {
+
+	user := app.contextGetUser(r)
+
+	if !app.parentalControlsUnlocked(w, r, user) {
+		return
+	}
+
+	var input struct {
+		Country          string `json:"country"`
+		MaxCertification string `json:"max_certification"`
+		Pin              string `json:"pin"`
+	}
+
+	err := app.readJsonRequest(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	var limit *data.ParentalLimit
+
+	val := validator.NewValidator()
+
+	if input.Country != "" || input.MaxCertification != "" {
+		limit = &data.ParentalLimit{
+			Country:          input.Country,
+			MaxCertification: input.MaxCertification,
+		}
+
+		data.ValidateParentalLimit(val, limit)
+	}
+
+	data.ValidateParentalPin(val, input.Pin)
+
+	if !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	user.ParentalLimit = limit
+
+	err = user.ParentalPin.SetPasswordHash(input.Pin)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !app.updateParentalControls(w, r, user) {
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"parental_controls": user.ParentalLimit}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Remove the parental controls, which requires the PIN
+func (app *application) deleteParentalControlsHandler(w http.ResponseWriter, r *http.Request) {
+
+	user := app.contextGetUser(r)
+
+	if !user.ParentalPin.IsSet() {
+		app.notFound(w, r)
+		return
+	}
+
+	if !app.parentalControlsUnlocked(w, r, user) {
+		return
+	}
+
+	user.ClearParentalControls()
+
+	if !app.updateParentalControls(w, r, user) {
+		return
+	}
+
+	err := app.writeJsonResponse(w, r, envelope{"message": "parental controls successfully removed"}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Check that the parental controls of the user and their profiles may be changed, which is the case
+// if no PIN has been set or the PIN has been sent. The PIN itself is checked by the applyParentalControls
+// middleware. An error response is sent if they may not be changed
+func (app *application) parentalControlsUnlocked(w http.ResponseWriter, r *http.Request, user *data.User) bool {
+
+	if user.ParentalPin.IsSet() && !app.contextGetParentalOverride(r) {
+		app.errorResponse(w, r, http.StatusForbidden, "the parental PIN must be sent in the "+parentalPinHeader+" header to change the parental controls")
+		return false
+	}
+
+	return true
+}
+
+// Save the parental controls of the user. An error response is sent if they could not be saved
+func (app *application) updateParentalControls(w http.ResponseWriter, r *http.Request, user *data.User) bool {
+
+	users := app.modelsFor(r).Users
+
+	err := users.Update(r.Context(), user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictError(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return false
+	}
+
+	return true
+}
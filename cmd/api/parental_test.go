@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+func TestPinAttempts(t *testing.T) {
+
+	attempts := newPinAttempts()
+	now := time.Now()
+
+	for i := 1; i < maxParentalPinFailures; i++ {
+		attempts.fail(1, now)
+	}
+
+	if _, locked := attempts.locked(1, now); locked {
+		t.Fatalf("got locked after %d failures; want unlocked", maxParentalPinFailures-1)
+	}
+
+	attempts.fail(1, now)
+
+	wait, locked := attempts.locked(1, now)
+	if !locked || wait != parentalPinLockout {
+		t.Fatalf("got locked %t for %s; want locked for %s", locked, wait, parentalPinLockout)
+	}
+
+	// The other users are not affected
+	if _, locked := attempts.locked(2, now); locked {
+		t.Error("got another user locked; want unlocked")
+	}
+
+	if _, locked := attempts.locked(1, now.Add(parentalPinLockout)); locked {
+		t.Error("got locked after the lockout period; want unlocked")
+	}
+
+	// A successful attempt forgets the failures
+	attempts.fail(2, now)
+	attempts.reset(2)
+
+	for i := 1; i < maxParentalPinFailures; i++ {
+		attempts.fail(2, now)
+	}
+
+	if _, locked := attempts.locked(2, now); locked {
+		t.Error("got locked after a reset; want unlocked")
+	}
+}
+
+func TestParentalPinLockout(t *testing.T) {
+
+	app := newTestApplication(t)
+
+	_, token := addTestUser(t, app, data.DefaultTenantID, "parent@example.com")
+
+	res, body := app.testRequest(t, testRequest{method: http.MethodPut, path: "/v1/users/me/parental-controls", body: map[string]interface{}{"pin": "2749"}, token: token})
+	if res.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("set a short PIN: got status %d; want %d (%v)", res.StatusCode, http.StatusUnprocessableEntity, body)
+	}
+
+	res, body = app.testRequest(t, testRequest{method: http.MethodPut, path: "/v1/users/me/parental-controls", body: map[string]interface{}{"pin": "274916"}, token: token})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("set the PIN: got status %d; want %d (%v)", res.StatusCode, http.StatusOK, body)
+	}
+
+	request := func(pin string) *http.Response {
+		header := http.Header{parentalPinHeader: []string{pin}}
+		res, _ := app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/users/me/parental-controls", token: token, header: header})
+		return res
+	}
+
+	if res := request("274916"); res.StatusCode != http.StatusOK {
+		t.Fatalf("right PIN: got status %d; want %d", res.StatusCode, http.StatusOK)
+	}
+
+	for i := 0; i < maxParentalPinFailures; i++ {
+		if res := request("000000"); res.StatusCode != http.StatusForbidden {
+			t.Fatalf("wrong PIN: got status %d; want %d", res.StatusCode, http.StatusForbidden)
+		}
+	}
+
+	// Even the right PIN is refused while the PIN is locked
+	res = request("274916")
+	if res.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("locked PIN: got status %d; want %d", res.StatusCode, http.StatusTooManyRequests)
+	}
+
+	if res.Header.Get("Retry-After") == "" {
+		t.Error("locked PIN: got no Retry-After header")
+	}
+}
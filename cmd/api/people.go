@@ -0,0 +1,258 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+func (app *application) createPersonHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Structure to hold the request parameters
+	var request struct {
+		Name      string `json:"name"`
+		BirthYear int32  `json:"birth_year"`
+		Biography string `json:"biography"`
+	}
+
+	// Decode the input json
+	err := app.readJsonRequest(w, r, &request)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	person := &data.Person{
+		Name:      request.Name,
+		BirthYear: request.BirthYear,
+		Biography: request.Biography,
+	}
+
+	// Validate the input fields
+	val := validator.NewValidator()
+
+	if data.ValidatePerson(val, person); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	// Insert the record into the database
+	err = app.models.People.Insert(r.Context(), person)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Send response containing the header with the location of the person added
+	header := make(http.Header)
+	header.Set("Location", fmt.Sprintf("/v1/people/%d", person.ID))
+
+	err = app.writeJsonResponse(w, r, envelope{"person": person}, header, http.StatusCreated)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) showPersonHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	person, err := app.models.People.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"person": person}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) editPersonHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Get the ID to be updated
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	// Get the existing record from the database
+	person, err := app.models.People.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	// All members are pointers to check whether the values have been provided by the user or not
+	var request struct {
+		Name      *string `json:"name"`
+		BirthYear *int32  `json:"birth_year"`
+		Biography *string `json:"biography"`
+	}
+
+	// Decode the input json
+	err = app.readJsonRequest(w, r, &request)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	// Copy only values that are provided in the request
+	if request.Name != nil {
+		person.Name = *request.Name
+	}
+
+	if request.BirthYear != nil {
+		person.BirthYear = *request.BirthYear
+	}
+
+	if request.Biography != nil {
+		person.Biography = *request.Biography
+	}
+
+	// Validate the input fields
+	val := validator.NewValidator()
+
+	if data.ValidatePerson(val, person); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	// Update the data into the database
+	err = app.models.People.Update(r.Context(), person)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictError(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"person": person}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) deletePersonHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Get the ID to be deleted
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	// Delete the record. The credits of the person are deleted along with it
+	err = app.models.People.Delete(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"message": "person successfully deleted"}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) listPeopleHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Define a structure to hold the query string values
+	var input struct {
+		Name string
+		data.Filters
+	}
+
+	// initalize a Validator for tracking any errors
+	val := validator.NewValidator()
+
+	queryString := r.URL.Query()
+
+	input.Name = app.readString(queryString, "name", "")
+
+	input.Filters.Page = app.readInt(queryString, "page", 1, val)
+	input.Filters.PageSize = app.readInt(queryString, "page_size", 10, val)
+
+	input.Filters.Sort = app.readString(queryString, "sort", "id")
+
+	// Supported sort values
+	input.Filters.SortList = []string{"id", "name", "birth_year", "-id", "-name", "-birth_year"}
+
+	if data.ValidateFilters(val, &input.Filters); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	people, metadata, err := app.models.People.GetAll(r.Context(), input.Name, input.Filters)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"metadata": metadata, "people": people}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) showFilmographyHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	person, err := app.models.People.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	credits, err := app.modelsFor(r).Credits.GetFilmography(r.Context(), person.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"person": person, "filmography": credits}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
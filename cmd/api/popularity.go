@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// List the movies trending in the window up to now e.g. window=7d or window=24h
+func (app *application) listTrendingMoviesHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		window time.Duration
+		data.Filters
+	}
+
+	val := validator.NewValidator()
+
+	queryString := r.URL.Query()
+
+	window, ok := parseWindow(app.readString(queryString, "window", "7d"))
+	val.Check(ok, "window", "must be a number of days or hours e.g. 7d or 24h")
+
+	if ok {
+		val.Check(window >= time.Hour, "window", "must be atleast 1h")
+		val.Check(window <= data.EventRetention, "window", fmt.Sprintf("must not be more than %dd", data.EventRetention/(24*time.Hour)))
+	}
+
+	input.window = window
+
+	input.Filters.Page = app.readInt(queryString, "page", 1, val)
+	input.Filters.PageSize = app.readInt(queryString, "page_size", 10, val)
+
+	// The movies are always in their trending order
+	input.Filters.Sort = "trending"
+	input.Filters.SortList = []string{"trending"}
+
+	proj := app.readProjection(queryString, data.MovieFields, movieIncludes, val, "movies")
+
+	input.Filters.Fields = proj.fields
+
+	if data.ValidateFilters(val, &input.Filters); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	movies, metadata, err := app.modelsFor(r).Movies.GetTrending(r.Context(), input.window, input.Filters)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Load the related resources to be embedded
+	err = app.embedMovieRelations(r.Context(), proj, movies...)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	r = app.contextSetProjection(r, proj)
+
+	err = app.writeJsonResponse(w, r, envelope{"metadata": metadata, "movies": movies}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Parse a window given as a whole number of days or hours e.g. 7d or 24h
+func parseWindow(value string) (time.Duration, bool) {
+
+	var unit time.Duration
+
+	switch {
+	case strings.HasSuffix(value, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(value, "h"):
+		unit = time.Hour
+	default:
+		return 0, false
+	}
+
+	count, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || count < 0 {
+		return 0, false
+	}
+
+	return time.Duration(count) * unit, true
+}
+
+// Count an event of the movie for its popularity in the background, so that the request is not
+// held up by it. A failure to count the event is only logged
+func (app *application) recordMovieEvent(movieID int64, kind string) {
+
+	// The request may well be over by the time the event is counted, so the event is counted
+	// within the lifetime of the server rather than of the request
+	app.background(func(ctxt context.Context) {
+		err := app.models.Popularity.Record(ctxt, movieID, kind)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"movie_id": strconv.FormatInt(movieID, 10), "event": kind})
+		}
+	})
+}
+
+// Recompute the decayed popularity of the movies used for sorting by popularity
+func (app *application) refreshPopularity(ctxt context.Context) error {
+
+	start := time.Now()
+
+	count, err := app.models.Popularity.Refresh(ctxt)
+	if err != nil {
+		return err
+	}
+
+	app.logger.PrintInfo("refreshed movie popularity", map[string]string{
+		"movies":   strconv.FormatInt(count, 10),
+		"duration": time.Since(start).String(),
+	})
+
+	return nil
+}
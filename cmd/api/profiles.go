@@ -0,0 +1,305 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// List the profiles of the household of the user
+func (app *application) listProfilesHandler(w http.ResponseWriter, r *http.Request) {
+
+	profiles, err := app.models.Profiles.GetAllForHousehold(r.Context(), app.contextGetUser(r).HouseholdID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"profiles": profiles}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Add a profile to the household of the user, optionally with a parental limit. Profiles can only
+// be added with the parental PIN if one has been set
+func (app *application) createProfileHandler(w http.ResponseWriter, r *http.Request) {
+
+	user := app.contextGetUser(r)
+
+	if !app.parentalControlsUnlocked(w, r, user) {
+		return
+	}
+
+	var input struct {
+		Name          string              `json:"name"`
+		ParentalLimit *data.ParentalLimit `json:"parental_limit"`
+	}
+
+	err := app.readJsonRequest(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	profile := &data.Profile{
+		HouseholdID:   user.HouseholdID,
+		Name:          input.Name,
+		ParentalLimit: input.ParentalLimit,
+	}
+
+	val := validator.NewValidator()
+
+	data.ValidateProfile(val, profile)
+
+	// A limit could be lifted by anyone without the PIN to guard it
+	if profile.ParentalLimit != nil {
+		val.Check(user.ParentalPin.IsSet(), "parental_limit", "a parental PIN must be set before limiting a profile")
+	}
+
+	if !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	err = app.models.Profiles.Insert(r.Context(), profile)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrTooManyProfiles):
+			app.errorResponse(w, r, http.StatusConflict, fmt.Sprintf("a household can have at most %d profiles", data.MaxProfiles))
+		case errors.Is(err, data.ErrDuplicateProfileName):
+			val.AddError("name", "a profile with this name already exists")
+			app.failedValidations(w, r, val.Errors)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	header := make(http.Header)
+	header.Set("Location", fmt.Sprintf("/v1/users/me/profiles/%d", profile.ID))
+
+	err = app.writeJsonResponse(w, r, envelope{"profile": profile}, header, http.StatusCreated)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) showProfileHandler(w http.ResponseWriter, r *http.Request) {
+
+	profile, ok := app.readProfile(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJsonResponse(w, r, envelope{"profile": profile}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Rename a profile. The parental limit of a profile is changed with its parental controls
+func (app *application) editProfileHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !app.parentalControlsUnlocked(w, r, app.contextGetUser(r)) {
+		return
+	}
+
+	profile, ok := app.readProfile(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name *string `json:"name"`
+	}
+
+	err := app.readJsonRequest(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		profile.Name = *input.Name
+	}
+
+	val := validator.NewValidator()
+
+	if data.ValidateProfile(val, profile); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	if !app.updateProfile(w, r, profile, val) {
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"profile": profile}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Delete a profile along with its ratings and lists
+func (app *application) deleteProfileHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !app.parentalControlsUnlocked(w, r, app.contextGetUser(r)) {
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	err = app.models.Profiles.Delete(r.Context(), app.contextGetUser(r).HouseholdID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"message": "profile successfully deleted"}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Set the parental limit of a profile. The limit is guarded by the parental PIN of the user,
+// which must have been set and must be sent
+func (app *application) putProfileParentalControlsHandler(w http.ResponseWriter, r *http.Request) {
+
+	user := app.contextGetUser(r)
+
+	if !user.ParentalPin.IsSet() {
+		app.errorResponse(w, r, http.StatusConflict, "a parental PIN must be set before limiting a profile")
+		return
+	}
+
+	if !app.parentalControlsUnlocked(w, r, user) {
+		return
+	}
+
+	profile, ok := app.readProfile(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Country          string `json:"country"`
+		MaxCertification string `json:"max_certification"`
+	}
+
+	err := app.readJsonRequest(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	profile.ParentalLimit = &data.ParentalLimit{
+		Country:          input.Country,
+		MaxCertification: input.MaxCertification,
+	}
+
+	val := validator.NewValidator()
+
+	if data.ValidateProfile(val, profile); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	if !app.updateProfile(w, r, profile, val) {
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"parental_controls": profile.ParentalLimit}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Remove the parental limit of a profile, which requires the parental PIN
+func (app *application) deleteProfileParentalControlsHandler(w http.ResponseWriter, r *http.Request) {
+
+	if !app.parentalControlsUnlocked(w, r, app.contextGetUser(r)) {
+		return
+	}
+
+	profile, ok := app.readProfile(w, r)
+	if !ok {
+		return
+	}
+
+	if profile.ParentalLimit == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	profile.ParentalLimit = nil
+
+	if !app.updateProfile(w, r, profile, validator.NewValidator()) {
+		return
+	}
+
+	err := app.writeJsonResponse(w, r, envelope{"message": "parental controls successfully removed"}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Save the profile. An error response is sent if it could not be saved
+func (app *application) updateProfile(w http.ResponseWriter, r *http.Request, profile *data.Profile, val *validator.Validator) bool {
+
+	err := app.models.Profiles.Update(r.Context(), profile)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateProfileName):
+			val.AddError("name", "a profile with this name already exists")
+			app.failedValidations(w, r, val.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictError(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// Read the profile of the household of the user identified by the ID parameter. An error response
+// is sent if it could not be read
+func (app *application) readProfile(w http.ResponseWriter, r *http.Request) (*data.Profile, bool) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	profile, err := app.models.Profiles.Get(r.Context(), app.contextGetUser(r).HouseholdID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return nil, false
+	}
+
+	return profile, true
+}
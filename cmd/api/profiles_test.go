@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+func TestProfileTokens(t *testing.T) {
+
+	app := newTestApplication(t)
+
+	addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"science-fiction"}, Certifications: map[string]string{"US": "R"}})
+	addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Toy Story", Year: 1995, Runtime: 81, Genres: []string{"animation"}, Certifications: map[string]string{"US": "G"}})
+
+	_, token := addTestUser(t, app, data.DefaultTenantID, "parent@example.com")
+
+	pin := http.Header{parentalPinHeader: []string{"274916"}}
+
+	res, body := app.testRequest(t, testRequest{method: http.MethodPut, path: "/v1/users/me/parental-controls", body: map[string]interface{}{"pin": "274916"}, token: token})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("set the PIN: got status %d; want %d (%v)", res.StatusCode, http.StatusOK, body)
+	}
+
+	kids := map[string]interface{}{"name": "Kids", "parental_limit": map[string]interface{}{"country": "US", "max_certification": "PG"}}
+
+	res, body = app.testRequest(t, testRequest{method: http.MethodPost, path: "/v1/users/me/profiles", body: kids, token: token, header: pin})
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("create the profile: got status %d; want %d (%v)", res.StatusCode, http.StatusCreated, body)
+	}
+
+	// Switch to a profile, returning the new token
+	switchProfile := func(token string, profileID int64, header http.Header) (int, string) {
+
+		res, body := app.testRequest(t, testRequest{method: http.MethodPost, path: "/v1/tokens/profile", body: map[string]interface{}{"profile_id": profileID}, token: token, header: header})
+		if res.StatusCode != http.StatusCreated {
+			return res.StatusCode, ""
+		}
+
+		return res.StatusCode, body["authentication_token"].(map[string]interface{})["token"].(string)
+	}
+
+	// List the titles of the movies seen with the token
+	titles := func(token string) []string {
+
+		res, body := app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/movies", token: token})
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("list movies: got status %d; want %d", res.StatusCode, http.StatusOK)
+		}
+
+		var titles []string
+		for _, movie := range body["movies"].([]interface{}) {
+			titles = append(titles, movie.(map[string]interface{})["title"].(string))
+		}
+
+		return titles
+	}
+
+	if status, _ := switchProfile(token, 9, nil); status != http.StatusUnprocessableEntity {
+		t.Errorf("switch to an unknown profile: got status %d; want %d", status, http.StatusUnprocessableEntity)
+	}
+
+	status, kidsToken := switchProfile(token, 1, nil)
+	if status != http.StatusCreated {
+		t.Fatalf("switch to the profile: got status %d; want %d", status, http.StatusCreated)
+	}
+
+	// The limit of the profile is bound to the token, there is no header to leave out
+	if got := titles(kidsToken); len(got) != 1 || got[0] != "Toy Story" {
+		t.Errorf("movies of the profile: got %v; want [Toy Story]", got)
+	}
+
+	if got := titles(token); len(got) != 2 {
+		t.Errorf("movies of the account: got %v; want both movies", got)
+	}
+
+	if status, _ := switchProfile(kidsToken, 0, nil); status != http.StatusForbidden {
+		t.Errorf("leave the profile without the PIN: got status %d; want %d", status, http.StatusForbidden)
+	}
+
+	status, accountToken := switchProfile(kidsToken, 0, pin)
+	if status != http.StatusCreated {
+		t.Fatalf("leave the profile with the PIN: got status %d; want %d", status, http.StatusCreated)
+	}
+
+	if got := titles(accountToken); len(got) != 2 {
+		t.Errorf("movies after leaving the profile: got %v; want both movies", got)
+	}
+
+	// The token of a deleted profile is refused rather than falling back to the account
+	res, body = app.testRequest(t, testRequest{method: http.MethodDelete, path: "/v1/users/me/profiles/1", token: token, header: pin})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("delete the profile: got status %d; want %d (%v)", res.StatusCode, http.StatusOK, body)
+	}
+
+	res, _ = app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/movies", token: kidsToken})
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("token of a deleted profile: got status %d; want %d", res.StatusCode, http.StatusUnauthorized)
+	}
+}
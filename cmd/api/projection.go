@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// Sparse fieldset and related resources requested by the client.
+// This is applied by writeJsonResponse to the listed envelope keys of the response
+type projection struct {
+	resources []string                         // Envelope keys the projection applies to e.g. "movie", "movies"
+	fields    []string                         // Fields to be returned. Empty means all the fields
+	include   []string                         // Names of the related resources to be embedded
+	related   map[string]map[int64]interface{} // Related resources by include name and resource ID
+}
+
+// Read the fields and include query string values and validate them against the allowed values
+func (app *application) readProjection(queryString url.Values, fieldList, includeList []string, val *validator.Validator, resources ...string) *projection {
+
+	proj := &projection{
+		resources: resources,
+		fields:    app.readCSV(queryString, "fields", []string{}),
+		include:   app.readCSV(queryString, "include", []string{}),
+		related:   make(map[string]map[int64]interface{}),
+	}
+
+	// Fields
+	data.ValidateFields(val, proj.fields, fieldList)
+
+	// Include
+	for _, name := range proj.include {
+		if !validator.Permittedvalues(name, includeList...) {
+			val.AddError("include", "invalid include value: "+name)
+			break
+		}
+	}
+	val.Check(validator.Unique(proj.include), "include", "must not contain duplicate values")
+
+	return proj
+}
+
+// Check if the related resource has been requested by the client
+func (proj *projection) includes(name string) bool {
+	return validator.Permittedvalues(name, proj.include...)
+}
+
+// Add the related resource to be embedded in the resource with the given ID
+func (proj *projection) embed(name string, id int64, value interface{}) {
+
+	if _, found := proj.related[name]; !found {
+		proj.related[name] = make(map[int64]interface{})
+	}
+
+	proj.related[name][id] = value
+}
+
+// Apply the projection to the envelope. Each projected resource is converted into a generic
+// json object so that the related resources can be embedded and the unrequested fields dropped
+func (proj *projection) apply(resp envelope) (envelope, error) {
+
+	projected := make(envelope, len(resp))
+
+	for key, value := range resp {
+		projected[key] = value
+
+		if !validator.Permittedvalues(key, proj.resources...) {
+			continue
+		}
+
+		// Convert the resource into a generic json value
+		js, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+
+		var generic interface{}
+
+		dec := json.NewDecoder(bytes.NewReader(js))
+		dec.UseNumber()
+
+		if err = dec.Decode(&generic); err != nil {
+			return nil, err
+		}
+
+		// A resource may either be a single object or a list of objects
+		switch resource := generic.(type) {
+		case map[string]interface{}:
+			proj.project(resource)
+		case []interface{}:
+			for _, item := range resource {
+				if obj, ok := item.(map[string]interface{}); ok {
+					proj.project(obj)
+				}
+			}
+		}
+
+		projected[key] = generic
+	}
+
+	return projected, nil
+}
+
+// Embed the related resources and remove the fields not requested from a single json object
+func (proj *projection) project(obj map[string]interface{}) {
+
+	// Embed the related resources
+	if num, ok := obj["id"].(json.Number); ok {
+		if id, err := num.Int64(); err == nil {
+			for _, name := range proj.include {
+				obj[name] = proj.related[name][id]
+			}
+		}
+	}
+
+	// Return all the fields if no fields were requested
+	if len(proj.fields) == 0 {
+		return
+	}
+
+	for field := range obj {
+		if !validator.Permittedvalues(field, proj.fields...) && !proj.includes(field) {
+			delete(obj, field)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+func TestReadProjection(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		query  string
+		errors map[string]string
+	}{
+		{"no projection", "", map[string]string{}},
+		{"valid fields and include", "fields=id,title&include=credits", map[string]string{}},
+		{"unknown field", "fields=id,budget", map[string]string{"fields": "invalid field value: budget"}},
+		{"duplicate field", "fields=id,id", map[string]string{"fields": "must not contain duplicate values"}},
+		{"unknown include", "include=reviews", map[string]string{"include": "invalid include value: reviews"}},
+		{"duplicate include", "include=credits,credits", map[string]string{"include": "must not contain duplicate values"}},
+	}
+
+	app := &application{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			val := validator.NewValidator()
+			app.readProjection(query, data.MovieFields, movieIncludes, val, "movie")
+
+			if !reflect.DeepEqual(val.Errors, tt.errors) {
+				t.Errorf("got errors %v; want %v", val.Errors, tt.errors)
+			}
+		})
+	}
+}
+
+func TestProjectionApply(t *testing.T) {
+
+	movies := []map[string]interface{}{
+		{"id": 1, "title": "Alien", "year": 1979},
+		{"id": 2, "title": "Heat", "year": 1995},
+	}
+
+	tests := []struct {
+		name    string
+		fields  []string
+		include []string
+		related map[string]map[int64]interface{}
+		want    string
+	}{
+		{
+			name: "all fields",
+			want: `{"count":2,"movies":[{"id":1,"title":"Alien","year":1979},{"id":2,"title":"Heat","year":1995}]}`,
+		},
+		{
+			name:   "sparse fieldset",
+			fields: []string{"title"},
+			want:   `{"count":2,"movies":[{"title":"Alien"},{"title":"Heat"}]}`,
+		},
+		{
+			name:    "embedded relation",
+			fields:  []string{"id"},
+			include: []string{"credits"},
+			related: map[string]map[int64]interface{}{"credits": {1: []string{"Ridley Scott"}}},
+			want:    `{"count":2,"movies":[{"credits":["Ridley Scott"],"id":1},{"credits":null,"id":2}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			proj := &projection{resources: []string{"movies"}, fields: tt.fields, include: tt.include, related: tt.related}
+
+			projected, err := proj.apply(envelope{"movies": movies, "count": 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			js, err := json.Marshal(projected)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(js) != tt.want {
+				t.Errorf("got %s; want %s", js, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// Read the movie identified by the ID parameter. An error response is sent if it could not be read
+func (app *application) readMovie(w http.ResponseWriter, r *http.Request) (*data.Movies, bool) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	movie, err := app.modelsFor(r).Movies.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrParentalRestricted):
+			app.parentalRestrictedResponse(w, r)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return nil, false
+	}
+
+	return movie, true
+}
+
+// Show the aggregate ratings of a movie, along with the rating of the user and the selected profile if authenticated
+func (app *application) showRatingsHandler(w http.ResponseWriter, r *http.Request) {
+
+	movie, ok := app.readMovie(w, r)
+	if !ok {
+		return
+	}
+
+	summary, err := app.models.Ratings.GetSummary(r.Context(), movie.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	resp := envelope{"ratings": summary}
+
+	user := app.contextGetUser(r)
+	if !user.IsAnonymous() {
+		rating, err := app.models.Ratings.Get(r.Context(), user.ID, app.contextGetProfileID(r), movie.ID)
+		switch {
+		case err == nil:
+			resp["my_rating"] = rating
+		case !errors.Is(err, data.ErrRecordNotFound):
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJsonResponse(w, r, resp, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Rate a movie for the selected profile. An existing rating of the user and profile is replaced
+func (app *application) putRatingHandler(w http.ResponseWriter, r *http.Request) {
+
+	movie, ok := app.readMovie(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Rating int32 `json:"rating"`
+	}
+
+	err := app.readJsonRequest(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	rating := &data.Rating{
+		UserID:    app.contextGetUser(r).ID,
+		ProfileID: app.contextGetProfileID(r),
+		MovieID:   movie.ID,
+		Rating:    input.Rating,
+	}
+
+	val := validator.NewValidator()
+
+	if data.ValidateRating(val, rating); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	err = app.models.Ratings.Upsert(r.Context(), rating)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.recordMovieEvent(movie.ID, data.EventRating)
+
+	err = app.writeJsonResponse(w, r, envelope{"rating": rating}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Remove the rating of a movie by the user and the selected profile
+func (app *application) deleteRatingHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	err = app.models.Ratings.Delete(r.Context(), app.contextGetUser(r).ID, app.contextGetProfileID(r), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"message": "rating successfully deleted"}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// Maximum number of recommendations returned at a time
+const maxRecommendations = 50
+
+// List the movies recommended to the authenticated user from their ratings and rentals.
+// Users without any history get the popular movies instead
+func (app *application) listRecommendationsHandler(w http.ResponseWriter, r *http.Request) {
+
+	val := validator.NewValidator()
+
+	limit := app.readInt(r.URL.Query(), "limit", 20, val)
+
+	val.Check(limit > 0, "limit", "must be greater than zero")
+	val.Check(limit <= maxRecommendations, "limit", "must not be more than 50")
+
+	if !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	recommendations, err := app.modelsFor(r).Recommendations.GetForUser(r.Context(), app.contextGetUser(r).ID, limit)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"recommendations": recommendations}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
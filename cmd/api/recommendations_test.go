@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+func TestListRecommendationsValidation(t *testing.T) {
+
+	app := newTestApplication(t)
+
+	_, token := addTestUser(t, app, data.DefaultTenantID, "viewer@example.com")
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		status int
+	}{
+		{"anonymous user", "/v1/users/me/recommendations", "", http.StatusUnauthorized},
+		{"zero limit", "/v1/users/me/recommendations?limit=0", token, http.StatusUnprocessableEntity},
+		{"limit above the maximum", "/v1/users/me/recommendations?limit=51", token, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			res, body := app.testRequest(t, testRequest{method: http.MethodGet, path: tt.path, token: tt.token})
+			if res.StatusCode != tt.status {
+				t.Errorf("got status %d; want %d (%v)", res.StatusCode, tt.status, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/jsonlog"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// Settings which take effect when the configuration is reloaded on SIGHUP. The other settings, i.e. the
+// port, the database connection and pool, the tenant resolution, the pricing rules, the hold webhook,
+// the image storage and the catalogue provider, only take effect on a restart. The feature flags are the
+// feature toggles of the tenants, kept in the database rather than in the settings, and the configurations
+// of the tenants are read again on a reload as well
+var reloadableSettings = []string{"tps", "burst", "enable-rate-limit", "log-level", "cors-trusted-origins",
+	"db-timeout", "db-timeouts", "similarity-refresh", "recommendation-refresh", "popularity-refresh", "enrich-interval"}
+
+// Intervals between the runs of the background jobs. 0 disables the job
+type jobIntervals struct {
+	similarityRefresh     time.Duration
+	recommendationRefresh time.Duration
+	popularityRefresh     time.Duration
+	enrich                time.Duration
+}
+
+func newJobIntervals(conf configuration) jobIntervals {
+	return jobIntervals{
+		similarityRefresh:     conf.similarityRefresh,
+		recommendationRefresh: conf.recommendationRefresh,
+		popularityRefresh:     conf.popularityRefresh,
+		enrich:                conf.enrich.interval,
+	}
+}
+
+// Settings which are swapped while the server is running, when the configuration is reloaded
+type liveConfig struct {
+	rateLimiter atomic.Value // rateLimiterConfig
+	intervals   atomic.Value // jobIntervals
+	corsOrigins atomic.Value // []string
+
+	mutx     sync.Mutex
+	settings map[string]string // Settings in effect, to log the changes made by the next reload
+	reloaded chan struct{}     // Closed when the configuration is reloaded, and replaced by a new one
+}
+
+func newLiveConfig(conf configuration, flags *flag.FlagSet) *liveConfig {
+
+	live := &liveConfig{settings: settingStrings(flags), reloaded: make(chan struct{})}
+	live.rateLimiter.Store(conf.rateLimiter)
+	live.intervals.Store(newJobIntervals(conf))
+	live.corsOrigins.Store(strings.Fields(conf.corsTrustedOrigins))
+
+	return live
+}
+
+// Get the rate limiting of the clients currently in effect
+func (app *application) rateLimits() rateLimiterConfig {
+	return app.live.rateLimiter.Load().(rateLimiterConfig)
+}
+
+// Get the trusted CORS origins currently in effect
+func (app *application) corsTrustedOrigins() []string {
+	return app.live.corsOrigins.Load().([]string)
+}
+
+// Get the intervals of the background jobs currently in effect
+func (app *application) jobIntervals() jobIntervals {
+	return app.live.intervals.Load().(jobIntervals)
+}
+
+// Get a channel which is closed by the next reload of the configuration
+func (app *application) nextReload() <-chan struct{} {
+
+	app.live.mutx.Lock()
+	defer app.live.mutx.Unlock()
+
+	return app.live.reloaded
+}
+
+// Check if the configuration has been reloaded since the channel was got from nextReload
+func reloadedSince(reload <-chan struct{}) bool {
+
+	select {
+	case <-reload:
+		return true
+	default:
+		return false
+	}
+}
+
+// Get the settings by name as they were given. The files of the secrets are left out, having been read
+// into the secrets
+func settingStrings(flags *flag.FlagSet) map[string]string {
+
+	settings := make(map[string]string)
+
+	flags.VisitAll(func(f *flag.Flag) {
+		if f.Name != "config" && settingOf(f.Name) == f.Name {
+			settings[f.Name] = f.Value.String()
+		}
+	})
+
+	return settings
+}
+
+// Read the configuration again from the arguments, the same way as at startup, and apply the reloadable
+// settings. The requests in progress carry on with the settings they started with, and the runs of the
+// jobs in progress carry on to their end. The changed settings are logged, and an invalid configuration
+// is rejected as a whole
+func (app *application) reloadConfig(args []string) error {
+
+	var conf configuration
+
+	flags := flag.NewFlagSet("reload", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	defineFlags(flags, &conf)
+
+	if err := loadConfig(flags, args); err != nil {
+		return err
+	}
+
+	if err := validateConfig(conf); err != nil {
+		return err
+	}
+
+	// Validated above
+	operationTimeouts, _ := data.ParseTimeouts(conf.dbDetails.queryTimeouts)
+	logLevel, _ := jsonlog.ParseLevel(conf.logLevel)
+
+	app.live.mutx.Lock()
+	defer app.live.mutx.Unlock()
+
+	settings := settingStrings(flags)
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	changed := 0
+
+	for _, name := range names {
+		previous, current := app.live.settings[name], settings[name]
+		if previous == current {
+			continue
+		}
+
+		changed++
+
+		if isSecret(name) {
+			previous, current = "REDACTED", "REDACTED"
+		}
+
+		message := "setting changed"
+		if !validator.Permittedvalues(name, reloadableSettings...) {
+			message = "setting changed, restart the server to apply it"
+		}
+
+		app.logger.PrintInfo(message, map[string]string{
+			"setting":  name,
+			"previous": previous,
+			"current":  current,
+		})
+	}
+
+	// Swap the reloadable settings. The other settings stay as they were until a restart, so that their
+	// changes are logged by the next reloads as well
+	for _, name := range reloadableSettings {
+		app.live.settings[name] = settings[name]
+	}
+
+	app.live.rateLimiter.Store(conf.rateLimiter)
+	app.logger.SetLevel(logLevel)
+	data.SetTimeouts(data.Timeouts{Default: conf.dbDetails.queryTimeout, Operations: operationTimeouts})
+	app.live.intervals.Store(newJobIntervals(conf))
+	app.live.corsOrigins.Store(strings.Fields(conf.corsTrustedOrigins))
+
+	// Wake up the jobs waiting for their next run and drop the cached tenants
+	close(app.live.reloaded)
+	app.live.reloaded = make(chan struct{})
+
+	app.logger.PrintInfo("reloaded configuration", map[string]string{
+		"changed": strconv.Itoa(changed),
+	})
+
+	return nil
+}
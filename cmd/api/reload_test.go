@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+func TestReloadConfig(t *testing.T) {
+
+	app := newTestApplication(t)
+	defer data.SetTimeouts(data.DefaultTimeouts)
+
+	args := []string{"-tps=10", "-burst=20", "-similarity-refresh=5m", "-enrich-interval=1h", "-popularity-refresh=0"}
+
+	if err := app.reloadConfig(args); err != nil {
+		t.Fatal(err)
+	}
+
+	if limits := app.rateLimits(); limits.tps != 10 || limits.burstLimit != 20 {
+		t.Errorf("got tps %v and burst %d; want 10 and 20", limits.tps, limits.burstLimit)
+	}
+
+	want := jobIntervals{similarityRefresh: 5 * time.Minute, recommendationRefresh: 6 * time.Hour, enrich: time.Hour}
+	if got := app.jobIntervals(); got != want {
+		t.Errorf("got intervals %+v; want %+v", got, want)
+	}
+
+	// An invalid configuration is rejected as a whole
+	if err := app.reloadConfig([]string{"-tps=20", "-similarity-refresh=-1m"}); err == nil {
+		t.Fatal("got the invalid configuration reloaded; want an error")
+	}
+
+	if limits := app.rateLimits(); limits.tps != 10 {
+		t.Errorf("got tps %v after the rejected reload; want 10", limits.tps)
+	}
+
+	if got := app.jobIntervals(); got != want {
+		t.Errorf("got intervals %+v after the rejected reload; want %+v", got, want)
+	}
+}
+
+func TestReloadRestartOnlySettings(t *testing.T) {
+
+	app := newTestApplication(t)
+	defer data.SetTimeouts(data.DefaultTimeouts)
+
+	port := app.live.settings["port"]
+
+	for i := 0; i < 2; i++ {
+		if err := app.reloadConfig([]string{"-port=9999", "-tps=10"}); err != nil {
+			t.Fatal(err)
+		}
+
+		// The port is only changed by a restart, so it is still reported as changed by the next reload
+		if got := app.live.settings["port"]; got != port {
+			t.Errorf("reload %d: got port %s in effect; want %s until a restart", i+1, got, port)
+		}
+
+		if got := app.live.settings["tps"]; got != "10" {
+			t.Errorf("reload %d: got tps %s in effect; want 10", i+1, got)
+		}
+	}
+}
+
+func TestReloadCORSOrigins(t *testing.T) {
+
+	app := newTestApplication(t)
+	defer data.SetTimeouts(data.DefaultTimeouts)
+
+	handler := app.enableCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// Send a request from the origin, returning the origin allowed and the status of the response
+	request := func(method, origin string) (string, int) {
+
+		r := httptest.NewRequest(method, "/v1/movies", nil)
+		r.Header.Set("Origin", origin)
+
+		if method == http.MethodOptions {
+			r.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w.Header().Get("Access-Control-Allow-Origin"), w.Code
+	}
+
+	if allowed, _ := request(http.MethodGet, "https://shop.example.com"); allowed != "" {
+		t.Errorf("got origin %q allowed before it is trusted; want none", allowed)
+	}
+
+	err := app.reloadConfig([]string{"-cors-trusted-origins=https://shop.example.com https://admin.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		origin  string
+		allowed string
+		status  int
+	}{
+		{"trusted origin", http.MethodGet, "https://shop.example.com", "https://shop.example.com", http.StatusNoContent},
+		{"preflight of a trusted origin", http.MethodOptions, "https://admin.example.com", "https://admin.example.com", http.StatusOK},
+		{"untrusted origin", http.MethodGet, "https://evil.example.com", "", http.StatusNoContent},
+		{"preflight of an untrusted origin", http.MethodOptions, "https://evil.example.com", "", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, status := request(tt.method, tt.origin)
+			if allowed != tt.allowed || status != tt.status {
+				t.Errorf("got origin %q allowed and status %d; want %q and %d", allowed, status, tt.allowed, tt.status)
+			}
+		})
+	}
+
+	if err = app.reloadConfig([]string{"-cors-trusted-origins=shop.example.com"}); err == nil {
+		t.Error("got an origin without a scheme reloaded; want an error")
+	}
+}
+
+func TestReloadTenants(t *testing.T) {
+
+	app := newTestApplication(t)
+	tenants := app.models.Tenants.(data.MemoryTenantModel)
+
+	// Handler reporting the configuration of the tenant of the request
+	var config data.TenantConfig
+
+	handler := app.resolveTenant(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		config = app.contextGetTenant(r).Config
+	}))
+
+	request := func() data.TenantConfig {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/movies", nil))
+		return config
+	}
+
+	if request().EnrichDisabled {
+		t.Fatal("got enrichment disabled; want it enabled")
+	}
+
+	tenant, err := tenants.GetBySlug(context.Background(), "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenant.Config.EnrichDisabled = true
+	tenants.Replace(tenant)
+
+	if request().EnrichDisabled {
+		t.Error("got the tenant read again before its cache expired; want the cached tenant")
+	}
+
+	if err = app.reloadConfig(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !request().EnrichDisabled {
+		t.Error("got the cached tenant after the reload; want the tenant read again")
+	}
+}
+
+func TestRunPeriodicallyReload(t *testing.T) {
+
+	app := newTestApplication(t)
+	defer data.SetTimeouts(data.DefaultTimeouts)
+
+	if err := app.reloadConfig([]string{"-popularity-refresh=0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctxt, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs := make(chan struct{}, 10)
+	stopped := make(chan struct{})
+
+	go func() {
+		app.runPeriodically(ctxt, "test", func() time.Duration { return app.jobIntervals().popularityRefresh }, func(context.Context) error {
+			runs <- struct{}{}
+			return nil
+		})
+
+		close(stopped)
+	}()
+
+	// The job is idle while disabled
+	select {
+	case <-runs:
+		t.Fatal("got the disabled job run; want it idle")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := app.reloadConfig([]string{"-popularity-refresh=10ms"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-runs:
+		case <-time.After(time.Second):
+			t.Fatalf("got %d runs after the interval was set; want 2", i)
+		}
+	}
+
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("got the job running after the context was cancelled; want it stopped")
+	}
+}
+
+func TestRunPeriodicallyUnchangedInterval(t *testing.T) {
+
+	app := newTestApplication(t)
+	defer data.SetTimeouts(data.DefaultTimeouts)
+
+	if err := app.reloadConfig([]string{"-popularity-refresh=300ms"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctxt, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs := make(chan time.Time, 10)
+
+	go app.runPeriodically(ctxt, "test", func() time.Duration { return app.jobIntervals().popularityRefresh }, func(context.Context) error {
+		runs <- time.Now()
+		return nil
+	})
+
+	start := <-runs
+
+	// A reload leaving the interval as it was keeps the job on its schedule
+	time.Sleep(200 * time.Millisecond)
+
+	if err := app.reloadConfig([]string{"-popularity-refresh=300ms", "-tps=10"}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case next := <-runs:
+		if elapsed := next.Sub(start); elapsed > 450*time.Millisecond {
+			t.Errorf("got the next run %v after the first one; want it on the 300ms schedule", elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("got no run after the reload; want the job to carry on")
+	}
+}
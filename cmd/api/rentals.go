@@ -0,0 +1,246 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// Rent out a copy of a movie from a store to the authenticated user
+func (app *application) checkoutRentalHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		MovieID int64  `json:"movie_id"`
+		StoreID int64  `json:"store_id"`
+		Format  string `json:"format"`
+	}
+
+	err := app.readJsonRequest(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	val := validator.NewValidator()
+
+	if data.ValidateCheckout(val, input.MovieID, input.StoreID, input.Format); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	// The movie and the store must belong to the tenant
+	_, err = app.modelsFor(r).Movies.Get(r.Context(), input.MovieID)
+	switch {
+	case errors.Is(err, data.ErrParentalRestricted):
+		app.parentalRestrictedResponse(w, r)
+		return
+	case errors.Is(err, data.ErrRecordNotFound):
+		val.AddError("movie_id", "does not exist")
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	_, err = app.modelsFor(r).Stores.Get(r.Context(), input.StoreID)
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		val.AddError("store_id", "does not exist")
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	rental, err := app.models.Rentals.Checkout(r.Context(), app.contextGetUser(r).ID, input.MovieID, input.StoreID, input.Format)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoCopyAvailable):
+			app.errorResponse(w, r, http.StatusConflict, "no copy of the movie is available in this store")
+		case errors.Is(err, data.ErrRentalLimitReached):
+			app.errorResponse(w, r, http.StatusConflict, "the household has reached its limit of rentals out at a time")
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	app.recordMovieEvent(rental.MovieID, data.EventRental)
+
+	header := make(http.Header)
+	header.Set("Location", fmt.Sprintf("/v1/rentals/%d", rental.ID))
+
+	err = app.writeJsonResponse(w, r, envelope{"rental": rental}, header, http.StatusCreated)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) showRentalHandler(w http.ResponseWriter, r *http.Request) {
+
+	rental, ok := app.readRental(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJsonResponse(w, r, envelope{"rental": rental}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) returnRentalHandler(w http.ResponseWriter, r *http.Request) {
+
+	rental, ok := app.readRental(w, r)
+	if !ok {
+		return
+	}
+
+	hold, err := app.models.Rentals.Return(r.Context(), rental)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrAlreadyReturned):
+			app.errorResponse(w, r, http.StatusConflict, "the rental has already been returned")
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	// The copy has been set aside for the next hold in the queue
+	if hold != nil {
+		app.notifyHoldsReady(hold)
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"rental": rental}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) renewRentalHandler(w http.ResponseWriter, r *http.Request) {
+
+	rental, ok := app.readRental(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.Rentals.Renew(r.Context(), rental)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrAlreadyReturned):
+			app.failedValidations(w, r, map[string]string{"rental": "has already been returned"})
+		case errors.Is(err, data.ErrRenewalLimit):
+			app.failedValidations(w, r, map[string]string{"rental": fmt.Sprintf("can not be renewed more than %d times", data.MaxRenewals)})
+		case errors.Is(err, data.ErrRentalIsOverdue):
+			app.failedValidations(w, r, map[string]string{"rental": "is overdue and must be returned"})
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictError(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"rental": rental}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// List the rentals of the authenticated user, optionally by status=active|returned|overdue
+func (app *application) listUserRentalsHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		Status string
+		data.Filters
+	}
+
+	val := validator.NewValidator()
+
+	queryString := r.URL.Query()
+
+	input.Status = app.readString(queryString, "status", "")
+
+	input.Filters.Page = app.readInt(queryString, "page", 1, val)
+	input.Filters.PageSize = app.readInt(queryString, "page_size", 10, val)
+	input.Filters.Sort = app.readString(queryString, "sort", "-rented_at")
+	input.Filters.SortList = []string{"id", "rented_at", "due_at", "-id", "-rented_at", "-due_at"}
+
+	data.ValidateFilters(val, &input.Filters)
+
+	if input.Status != "" {
+		val.Check(validator.Permittedvalues(input.Status, "active", "returned", "overdue"), "status", "invalid status value")
+	}
+
+	if !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	rentals, metadata, err := app.models.Rentals.GetAllForUser(r.Context(), app.contextGetUser(r).ID, input.Status, input.Filters)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"metadata": metadata, "rentals": rentals}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Read the rental identified by the ID parameter. Rentals are only visible to the renting user
+// and to the store staff. An error response is sent if the rental could not be read
+func (app *application) readRental(w http.ResponseWriter, r *http.Request) (*data.Rental, bool) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	rental, err := app.models.Rentals.Get(r.Context(), id)
+	if err == nil {
+		// The rentals of the stores of the other tenants are not visible
+		_, err = app.modelsFor(r).Stores.Get(r.Context(), rental.StoreID)
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return nil, false
+	}
+
+	user := app.contextGetUser(r)
+
+	if rental.UserID != user.ID {
+		permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return nil, false
+		}
+
+		// Do not reveal the rentals of other users
+		if !permissions.Include(data.PermissionManageInventory) {
+			app.notFound(w, r)
+			return nil, false
+		}
+	}
+
+	return rental, true
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+func TestCheckoutRentalReferences(t *testing.T) {
+
+	app := newTestApplication(t)
+
+	addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"science-fiction"}})
+
+	// A movie of another tenant, which must not be found by the customers of the default tenant
+	other := addTestTenant(t, app, "other")
+	addTestMovie(t, app, other, &data.Movies{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"crime"}})
+
+	_, staff := addTestUser(t, app, data.DefaultTenantID, "staff@example.com", data.PermissionManageInventory)
+	_, customer := addTestUser(t, app, data.DefaultTenantID, "customer@example.com")
+
+	res, body := app.testRequest(t, testRequest{method: http.MethodPost, path: "/v1/stores", body: map[string]interface{}{"name": "Main Street"}, token: staff})
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("create store: got status %d; want %d (%v)", res.StatusCode, http.StatusCreated, body)
+	}
+
+	tests := []struct {
+		name   string
+		rental map[string]interface{}
+		errors map[string]interface{}
+	}{
+		{"unknown movie", map[string]interface{}{"movie_id": 9, "store_id": 1, "format": "dvd"}, map[string]interface{}{"movie_id": "does not exist"}},
+		{"movie of another tenant", map[string]interface{}{"movie_id": 2, "store_id": 1, "format": "dvd"}, map[string]interface{}{"movie_id": "does not exist"}},
+		{"unknown store", map[string]interface{}{"movie_id": 1, "store_id": 9, "format": "dvd"}, map[string]interface{}{"store_id": "does not exist"}},
+		{"unknown movie and store", map[string]interface{}{"movie_id": 9, "store_id": 9, "format": "dvd"}, map[string]interface{}{"movie_id": "does not exist", "store_id": "does not exist"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			res, body := app.testRequest(t, testRequest{method: http.MethodPost, path: "/v1/rentals", body: tt.rental, token: customer})
+			if res.StatusCode != http.StatusUnprocessableEntity {
+				t.Fatalf("got status %d; want %d (%v)", res.StatusCode, http.StatusUnprocessableEntity, body)
+			}
+
+			errors, _ := body["error"].(map[string]interface{})
+			for field, message := range tt.errors {
+				if errors[field] != message {
+					t.Errorf("got %s error %v; want %q", field, errors[field], message)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// List the reviews of a movie. Only the approved reviews are listed, except for
+// moderators who can list the reviews of any status
+func (app *application) listReviewsHandler(w http.ResponseWriter, r *http.Request) {
+
+	movie, ok := app.readMovie(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Status string
+		data.Filters
+	}
+
+	val := validator.NewValidator()
+
+	queryString := r.URL.Query()
+
+	input.Status = app.readString(queryString, "status", data.ReviewApproved)
+
+	input.Filters.Page = app.readInt(queryString, "page", 1, val)
+	input.Filters.PageSize = app.readInt(queryString, "page_size", 10, val)
+	input.Filters.Sort = app.readString(queryString, "sort", "-created_at")
+	input.Filters.SortList = []string{"id", "created_at", "-id", "-created_at"}
+
+	data.ValidateFilters(val, &input.Filters)
+	val.Check(validator.Permittedvalues(input.Status, data.ReviewStatuses...), "status", "invalid status value")
+
+	if !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	// Reviews pending moderation are only visible to the moderators
+	if input.Status != data.ReviewApproved {
+		moderator, err := app.isModerator(r)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if !moderator {
+			app.notPermittedResponse(w, r)
+			return
+		}
+	}
+
+	reviews, metadata, err := app.models.Reviews.GetAllForMovie(r.Context(), movie.ID, input.Status, input.Filters)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"metadata": metadata, "reviews": reviews}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Write a review of a movie. The review is visible once it has been approved by a moderator
+func (app *application) createReviewHandler(w http.ResponseWriter, r *http.Request) {
+
+	movie, ok := app.readMovie(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Body string `json:"body"`
+	}
+
+	err := app.readJsonRequest(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	review := &data.Review{
+		UserID:   user.ID,
+		UserName: user.Name,
+		MovieID:  movie.ID,
+		Body:     input.Body,
+		Status:   data.ReviewPending,
+	}
+
+	val := validator.NewValidator()
+
+	if data.ValidateReview(val, review); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	err = app.models.Reviews.Insert(r.Context(), review)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateReview):
+			val.AddError("movie_id", "you have already reviewed this movie")
+			app.failedValidations(w, r, val.Errors)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"review": review}, nil, http.StatusCreated)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Edit a review. The author can change the body, which sends the review back for moderation.
+// Moderators can change the status.
+func (app *application) editReviewHandler(w http.ResponseWriter, r *http.Request) {
+
+	review, ok := app.readReview(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Body   *string `json:"body"`
+		Status *string `json:"status"`
+	}
+
+	err := app.readJsonRequest(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	moderator, err := app.isModerator(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	if input.Body != nil {
+		if review.UserID != user.ID {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		review.Body = *input.Body
+		review.Status = data.ReviewPending
+	}
+
+	if input.Status != nil {
+		if !moderator {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		review.Status = *input.Status
+	}
+
+	val := validator.NewValidator()
+
+	if data.ValidateReview(val, review); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	err = app.models.Reviews.Update(r.Context(), review)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictError(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"review": review}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Delete a review. Only the author or a moderator can delete a review
+func (app *application) deleteReviewHandler(w http.ResponseWriter, r *http.Request) {
+
+	review, ok := app.readReview(w, r)
+	if !ok {
+		return
+	}
+
+	if review.UserID != app.contextGetUser(r).ID {
+		moderator, err := app.isModerator(r)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if !moderator {
+			app.notPermittedResponse(w, r)
+			return
+		}
+	}
+
+	err := app.models.Reviews.Delete(r.Context(), review.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"message": "review successfully deleted"}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Read the review identified by the movie and review ID parameters.
+// An error response is sent if it could not be read
+func (app *application) readReview(w http.ResponseWriter, r *http.Request) (*data.Review, bool) {
+
+	// The reviews of the movies of the other tenants are not visible
+	movie, ok := app.readMovie(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	id, err := app.readNamedIDParam(r, "review_id")
+	if err != nil {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	review, err := app.models.Reviews.Get(r.Context(), movie.ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return nil, false
+	}
+
+	return review, true
+}
+
+// Check if the user of the request can moderate the reviews
+func (app *application) isModerator(r *http.Request) (bool, error) {
+
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		return false, nil
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return permissions.Include(data.PermissionModerateReviews), nil
+}
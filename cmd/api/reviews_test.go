@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+func TestIsModerator(t *testing.T) {
+
+	app := newTestApplication(t)
+
+	moderator, _ := addTestUser(t, app, data.DefaultTenantID, "moderator@example.com", data.PermissionModerateReviews)
+	reviewer, _ := addTestUser(t, app, data.DefaultTenantID, "reviewer@example.com")
+
+	tests := []struct {
+		name string
+		user *data.User
+		want bool
+	}{
+		{"moderator", moderator, true},
+		{"reviewer", reviewer, false},
+		{"anonymous user", data.AnonymousUser, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			r := app.contextSetUser(httptest.NewRequest("GET", "/", nil), tt.user)
+
+			got, err := app.isModerator(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/narinderv/blockbuster/internal/data"
 )
 
 func (app *application) routes() http.Handler {
@@ -22,8 +23,8 @@ func (app *application) routes() http.Handler {
 	// Add a new movie
 	router.HandlerFunc(http.MethodPost, "/v1/movies", app.createMovieHandler)
 
-	// View details of a particular movie
-	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", app.showMovieHandler)
+	// View details of a particular movie, or the trending movies
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", app.staticParam("id", "trending", app.listTrendingMoviesHandler, app.showMovieHandler))
 
 	// View list of Movies
 	router.HandlerFunc(http.MethodGet, "/v1/movies", app.listMoviesHandler)
@@ -35,8 +36,121 @@ func (app *application) routes() http.Handler {
 	// Delete Movie
 	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id", app.deleteMovieHandler)
 
+	// Cast and crew of a movie
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/credits", app.listCreditsHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/credits", app.createCreditHandler)
+	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id/credits/:credit_id", app.deleteCreditHandler)
+
+	// Ratings and reviews of a movie
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/ratings", app.showRatingsHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/movies/:id/ratings", app.requireAuthenticatedUser(app.putRatingHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id/ratings", app.requireAuthenticatedUser(app.deleteRatingHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/reviews", app.listReviewsHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/reviews", app.requireAuthenticatedUser(app.createReviewHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/movies/:id/reviews/:review_id", app.requireAuthenticatedUser(app.editReviewHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id/reviews/:review_id", app.requireAuthenticatedUser(app.deleteReviewHandler))
+
+	// Copies of a movie held by the stores
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/inventory", app.listMovieInventoryHandler)
+
+	// Artwork of a movie
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/images", app.listMovieImagesHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/images", app.requirePermission(data.PermissionWriteMovies, app.uploadMovieImageHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/images/:id", app.serveImageHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/images/:id/thumbnail", app.serveThumbnailHandler)
+	router.HandlerFunc(http.MethodDelete, "/v1/images/:id", app.requirePermission(data.PermissionWriteMovies, app.deleteImageHandler))
+
+	// Fill the missing metadata of a movie from the catalogue provider
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/enrich", app.requirePermission(data.PermissionWriteMovies, app.enrichMovieHandler))
+
+	// Movies similar to a movie
+	router.HandlerFunc(http.MethodGet, "/v1/movies/:id/similar", app.listSimilarMoviesHandler)
+
+	// Holds on a movie while all the copies are out
+	router.HandlerFunc(http.MethodPost, "/v1/movies/:id/holds", app.requireAuthenticatedUser(app.createHoldHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/holds/:id", app.requireAuthenticatedUser(app.showHoldHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/holds/:id", app.requireAuthenticatedUser(app.cancelHoldHandler))
+
+	// Stores and their inventory
+	router.HandlerFunc(http.MethodGet, "/v1/stores", app.listStoresHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/stores", app.requirePermission(data.PermissionManageInventory, app.createStoreHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/stores/:id", app.showStoreHandler)
+	router.HandlerFunc(http.MethodPatch, "/v1/stores/:id", app.requirePermission(data.PermissionManageInventory, app.editStoreHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/stores/:id", app.requirePermission(data.PermissionManageInventory, app.deleteStoreHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/inventory", app.requirePermission(data.PermissionManageInventory, app.createInventoryItemHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/inventory/:id", app.showInventoryItemHandler)
+	router.HandlerFunc(http.MethodPatch, "/v1/inventory/:id", app.requirePermission(data.PermissionManageInventory, app.editInventoryItemHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/inventory/:id", app.requirePermission(data.PermissionManageInventory, app.deleteInventoryItemHandler))
+
+	// Franchises and series of movies
+	router.HandlerFunc(http.MethodGet, "/v1/collections", app.listCollectionsHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/collections", app.createCollectionHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/collections/:id", app.showCollectionHandler)
+	router.HandlerFunc(http.MethodPatch, "/v1/collections/:id", app.editCollectionHandler)
+	router.HandlerFunc(http.MethodDelete, "/v1/collections/:id", app.deleteCollectionHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/collections/:id/movies/:movie_id", app.putCollectionMovieHandler)
+	router.HandlerFunc(http.MethodDelete, "/v1/collections/:id/movies/:movie_id", app.removeCollectionMovieHandler)
+
+	// Genre taxonomy, shared by all the tenants
+	router.HandlerFunc(http.MethodGet, "/v1/genres", app.listGenresHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/genres", app.createGenreHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/genres/:id", app.showGenreHandler)
+	router.HandlerFunc(http.MethodPatch, "/v1/genres/:id", app.editGenreHandler)
+	router.HandlerFunc(http.MethodDelete, "/v1/genres/:id", app.deleteGenreHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/genres/:id/merge", app.mergeGenreHandler)
+
+	// People catalogue, shared by all the tenants
+	router.HandlerFunc(http.MethodGet, "/v1/people", app.listPeopleHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/people", app.createPersonHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/people/:id", app.showPersonHandler)
+	router.HandlerFunc(http.MethodPatch, "/v1/people/:id", app.editPersonHandler)
+	router.HandlerFunc(http.MethodDelete, "/v1/people/:id", app.deletePersonHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/people/:id/filmography", app.showFilmographyHandler)
+
+	// Watchlists and custom lists of the users
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/lists", app.requireAuthenticatedUser(app.listUserListsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/lists", app.requireAuthenticatedUser(app.createListHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/lists/:id", app.showListHandler)
+	router.HandlerFunc(http.MethodPatch, "/v1/lists/:id", app.requireAuthenticatedUser(app.editListHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/lists/:id", app.requireAuthenticatedUser(app.deleteListHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/lists/:id/items", app.requireAuthenticatedUser(app.addListItemHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/lists/:id/items/:movie_id", app.requireAuthenticatedUser(app.moveListItemHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/lists/:id/items/:movie_id", app.requireAuthenticatedUser(app.removeListItemHandler))
+
 	// User Handler
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
 
-	return app.recoverFromPanic(app.rateLimit(router))
+	// Rentals
+	router.HandlerFunc(http.MethodPost, "/v1/rentals", app.requireAuthenticatedUser(app.checkoutRentalHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/rentals/:id", app.requireAuthenticatedUser(app.showRentalHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/rentals/:id/return", app.requireAuthenticatedUser(app.returnRentalHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/rentals/:id/renew", app.requireAuthenticatedUser(app.renewRentalHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/rentals", app.requireAuthenticatedUser(app.listUserRentalsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/balance", app.requireAuthenticatedUser(app.showUserBalanceHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/holds", app.requireAuthenticatedUser(app.listUserHoldsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/recommendations", app.requireAuthenticatedUser(app.listRecommendationsHandler))
+
+	// Parental controls of the users
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/parental-controls", app.requireAuthenticatedUser(app.showParentalControlsHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/users/me/parental-controls", app.requireAuthenticatedUser(app.putParentalControlsHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/parental-controls", app.requireAuthenticatedUser(app.deleteParentalControlsHandler))
+
+	// Households sharing a membership and their viewer profiles
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/household", app.requireAuthenticatedUser(app.showUserHouseholdHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/users/me/household", app.requireAuthenticatedUser(app.editUserHouseholdHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/profiles", app.requireAuthenticatedUser(app.listProfilesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/profiles", app.requireAuthenticatedUser(app.createProfileHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/profiles/:id", app.requireAuthenticatedUser(app.showProfileHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/users/me/profiles/:id", app.requireAuthenticatedUser(app.editProfileHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/profiles/:id", app.requireAuthenticatedUser(app.deleteProfileHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/users/me/profiles/:id/parental-controls", app.requireAuthenticatedUser(app.putProfileParentalControlsHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/profiles/:id/parental-controls", app.requireAuthenticatedUser(app.deleteProfileParentalControlsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/households/:id", app.requirePermission(data.PermissionManageHouseholds, app.showHouseholdHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/households/:id", app.requirePermission(data.PermissionManageHouseholds, app.editHouseholdHandler))
+
+	// Authentication
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/profile", app.requireAuthenticatedUser(app.switchProfileHandler))
+
+	return app.recoverFromPanic(app.enableCORS(app.resolveTenant(app.rateLimit(app.authenticate(app.selectProfile(app.applyParentalControls(router)))))))
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,6 +14,16 @@ import (
 
 func (app *application) startServer() error {
 
+	// Lifetime of the server. The background jobs and the background tasks of the handlers are
+	// stopped along with the server
+	serverCtxt, stopServer := context.WithCancel(context.Background())
+	defer stopServer()
+
+	// The requests are cancelled as soon as the server starts shutting down, which stops their
+	// database operations instead of waiting for them
+	requestsCtxt, cancelRequests := context.WithCancel(serverCtxt)
+	defer cancelRequests()
+
 	// Create a new HTTP Server
 	httpServer := &http.Server{
 		Addr:         fmt.Sprintf(":%d", app.config.port),
@@ -20,6 +31,9 @@ func (app *application) startServer() error {
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return contextWithShutdown(requestsCtxt)
+		},
 	}
 
 	// Create a channel to receive the response of Shutdown
@@ -41,6 +55,8 @@ func (app *application) startServer() error {
 			"signal": sig.String(),
 		})
 
+		cancelRequests()
+
 		// Create a timeout context
 		ctxt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -50,6 +66,30 @@ func (app *application) startServer() error {
 		shutdownChannel <- httpServer.Shutdown(ctxt)
 	}()
 
+	// Go routine for reloading the configuration on SIGHUP, for as long as the server runs
+	hupChannel := make(chan os.Signal, 1)
+	signal.Notify(hupChannel, syscall.SIGHUP)
+	defer signal.Stop(hupChannel)
+
+	go func() {
+		for range hupChannel {
+			app.logger.PrintInfo("reloading configuration", map[string]string{
+				"signal": syscall.SIGHUP.String(),
+			})
+
+			if err := app.reloadConfig(os.Args[1:]); err != nil {
+				app.logger.PrintError(err, nil)
+			}
+		}
+	}()
+
+	app.tasks = newBackgroundTasks(serverCtxt, maxBackgroundTasks)
+
+	jobsCtxt, stopJobs := context.WithCancel(serverCtxt)
+	defer stopJobs()
+
+	app.startJobs(jobsCtxt)
+
 	app.logger.PrintInfo("starting server", map[string]string{
 		"addr":    httpServer.Addr,
 		"env":     app.config.env,
@@ -72,6 +112,11 @@ func (app *application) startServer() error {
 		return err
 	}
 
+	// No request is left to start background tasks, so the jobs are stopped and the tasks
+	// are given a last chance to finish before the database is closed
+	stopJobs()
+	app.tasks.stop(backgroundTasksTimeout)
+
 	// We reach here only on server shutdown. Log the status
 	app.logger.PrintInfo("server shut down", map[string]string{
 		"addr": httpServer.Addr,
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCancelledRequests(t *testing.T) {
+
+	app := newTestApplication(t)
+
+	// Base contexts of the server, alive and cancelled by the shutdown
+	serving := contextWithShutdown(context.Background())
+
+	shutdown, cancelShutdown := context.WithCancel(context.Background())
+	shuttingDown := contextWithShutdown(shutdown)
+	cancelShutdown()
+
+	tests := []struct {
+		name string
+		base context.Context
+		want int
+	}{
+		{"client went away", serving, statusClientClosedRequest},
+		{"server shutting down", shuttingDown, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The request is cancelled along with its base context, or by the client going away
+			ctxt, cancel := context.WithCancel(tt.base)
+			cancel()
+
+			r := httptest.NewRequest(http.MethodGet, "/v1/movies", nil).WithContext(ctxt)
+			w := httptest.NewRecorder()
+
+			app.serverError(w, r, context.Canceled)
+
+			if w.Code != tt.want {
+				t.Errorf("got status %d; want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
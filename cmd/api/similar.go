@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// List the movies most similar to a movie, from the similarities precomputed by the background job
+func (app *application) listSimilarMoviesHandler(w http.ResponseWriter, r *http.Request) {
+
+	movie, ok := app.readMovie(w, r)
+	if !ok {
+		return
+	}
+
+	val := validator.NewValidator()
+
+	limit := app.readInt(r.URL.Query(), "limit", 10, val)
+
+	val.Check(limit > 0, "limit", "must be greater than zero")
+	val.Check(limit <= data.MaxSimilarMovies, "limit", fmt.Sprintf("must not be more than %d", data.MaxSimilarMovies))
+
+	if !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	similar, err := app.modelsFor(r).Similarities.GetForMovie(r.Context(), movie.ID, limit)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"movie_id": movie.ID, "similar": similar}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+func TestListSimilarMoviesValidation(t *testing.T) {
+
+	app := newTestApplication(t)
+
+	addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"science-fiction"}})
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"zero limit", "/v1/movies/1/similar?limit=0", http.StatusUnprocessableEntity},
+		{"limit above the maximum", "/v1/movies/1/similar?limit=51", http.StatusUnprocessableEntity},
+		{"unknown movie", "/v1/movies/2/similar", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			res, body := app.testRequest(t, testRequest{method: http.MethodGet, path: tt.path})
+			if res.StatusCode != tt.status {
+				t.Errorf("got status %d; want %d (%v)", res.StatusCode, tt.status, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+func (app *application) listStoresHandler(w http.ResponseWriter, r *http.Request) {
+
+	stores, err := app.modelsFor(r).Stores.GetAll(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"stores": stores}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) createStoreHandler(w http.ResponseWriter, r *http.Request) {
+
+	var request struct {
+		Name    string `json:"name"`
+		Address string `json:"address"`
+		City    string `json:"city"`
+	}
+
+	err := app.readJsonRequest(w, r, &request)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	store := &data.Store{
+		Name:    request.Name,
+		Address: request.Address,
+		City:    request.City,
+	}
+
+	val := validator.NewValidator()
+
+	if data.ValidateStore(val, store); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	err = app.modelsFor(r).Stores.Insert(r.Context(), store)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	header := make(http.Header)
+	header.Set("Location", fmt.Sprintf("/v1/stores/%d", store.ID))
+
+	err = app.writeJsonResponse(w, r, envelope{"store": store}, header, http.StatusCreated)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) showStoreHandler(w http.ResponseWriter, r *http.Request) {
+
+	store, ok := app.readStore(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJsonResponse(w, r, envelope{"store": store}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) editStoreHandler(w http.ResponseWriter, r *http.Request) {
+
+	store, ok := app.readStore(w, r)
+	if !ok {
+		return
+	}
+
+	// All members are pointers to check whether the values have been provided by the user or not
+	var request struct {
+		Name    *string `json:"name"`
+		Address *string `json:"address"`
+		City    *string `json:"city"`
+	}
+
+	err := app.readJsonRequest(w, r, &request)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if request.Name != nil {
+		store.Name = *request.Name
+	}
+
+	if request.Address != nil {
+		store.Address = *request.Address
+	}
+
+	if request.City != nil {
+		store.City = *request.City
+	}
+
+	val := validator.NewValidator()
+
+	if data.ValidateStore(val, store); !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	err = app.modelsFor(r).Stores.Update(r.Context(), store)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictError(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"store": store}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) deleteStoreHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	err = app.modelsFor(r).Stores.Delete(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		case errors.Is(err, data.ErrStoreInUse):
+			app.errorResponse(w, r, http.StatusConflict, "the store still holds inventory and can not be deleted")
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"message": "store successfully deleted"}, nil, http.StatusOK)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// Read the store identified by the ID parameter. An error response is sent if it could not be read
+func (app *application) readStore(w http.ResponseWriter, r *http.Request) (*data.Store, bool) {
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	store, err := app.modelsFor(r).Stores.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return nil, false
+	}
+
+	return store, true
+}
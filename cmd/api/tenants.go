@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+// Time for which a resolved tenant is reused before being read again, so that the tenants
+// are not read on every request while changes to their configuration still get picked up
+const tenantCacheTTL = time.Minute
+
+// Slugs are valid DNS labels, so that they can be used as subdomains
+var tenantSlugRX = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// Resolve the tenant of the request from the tenant header, or else the subdomain of the configured
+// domain, falling back to the default tenant. The tenant is stored in the request context
+func (app *application) resolveTenant(nxtHandler http.Handler) http.Handler {
+
+	// Cache of the resolved tenants by their slug. The tenants are read again once the configuration is reloaded
+	type cachedTenant struct {
+		tenant *data.Tenant
+		expiry time.Time
+		reload <-chan struct{}
+	}
+
+	var (
+		mutx    sync.Mutex
+		tenants = make(map[string]*cachedTenant)
+	)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		// The response varies depending upon the tenant header
+		w.Header().Add("Vary", app.config.tenant.header)
+
+		slug := strings.ToLower(app.tenantSlug(r))
+		if slug == "" {
+			app.tenantRequiredResponse(w, r)
+			return
+		}
+
+		if !tenantSlugRX.MatchString(slug) {
+			app.unknownTenantResponse(w, r)
+			return
+		}
+
+		mutx.Lock()
+		cached, found := tenants[slug]
+		mutx.Unlock()
+
+		if !found || time.Now().After(cached.expiry) || reloadedSince(cached.reload) {
+			reload := app.nextReload()
+
+			tenant, err := app.models.Tenants.GetBySlug(r.Context(), slug)
+			if err != nil {
+				switch {
+				case errors.Is(err, data.ErrRecordNotFound):
+					app.unknownTenantResponse(w, r)
+				default:
+					app.serverError(w, r, err)
+				}
+
+				return
+			}
+
+			cached = &cachedTenant{tenant: tenant, expiry: time.Now().Add(tenantCacheTTL), reload: reload}
+
+			mutx.Lock()
+			tenants[slug] = cached
+			mutx.Unlock()
+		}
+
+		r = app.contextSetTenant(r, cached.tenant)
+
+		nxtHandler.ServeHTTP(w, r)
+	})
+}
+
+// Get the slug of the tenant named by the request, or the default tenant if none is named
+func (app *application) tenantSlug(r *http.Request) string {
+
+	if slug := r.Header.Get(app.config.tenant.header); slug != "" {
+		return slug
+	}
+
+	if app.config.tenant.domain != "" {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+
+		if subdomain := strings.TrimSuffix(host, "."+app.config.tenant.domain); subdomain != host {
+			return subdomain
+		}
+	}
+
+	return app.config.tenant.defaultSlug
+}
+
+// Get the models scoped to the tenant of the request, with the movies restricted by the parental
+// limit in effect for the request left out, wherever the models return movies
+func (app *application) modelsFor(r *http.Request) data.Models {
+
+	limit := app.contextGetParentalLimit(r)
+
+	models := app.models.ForTenant(app.contextGetTenant(r).ID)
+	models.Movies = models.Movies.WithLimit(limit)
+	models.Credits = models.Credits.WithLimit(limit)
+	models.Similarities.Limit = limit
+	models.Recommendations.Limit = limit
+	models.Lists.Limit = limit
+
+	return models
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+func TestCrossTenantRequests(t *testing.T) {
+
+	app := newTestApplication(t)
+	ctxt := context.Background()
+
+	movie := addTestMovie(t, app, data.DefaultTenantID, &data.Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"science-fiction"}})
+
+	store := &data.Store{Name: "Main Street"}
+	if err := app.models.Stores.Insert(ctxt, store); err != nil {
+		t.Fatal(err)
+	}
+
+	item := &data.InventoryItem{MovieID: movie.ID, StoreID: store.ID, Format: "dvd", Condition: "good", Barcode: "A-1", Status: data.InventoryAvailable}
+	if err := app.models.Inventory.Insert(ctxt, item); err != nil {
+		t.Fatal(err)
+	}
+
+	credit := &data.Credit{MovieID: movie.ID, PersonID: 1, Role: "director"}
+	if err := app.models.Credits.Insert(ctxt, credit); err != nil {
+		t.Fatal(err)
+	}
+
+	movieImage := &data.MovieImage{MovieID: movie.ID, Kind: "poster", ContentType: "image/png", StorageKey: "movies/1/poster.png"}
+	if err := app.models.Images.Insert(ctxt, movieImage); err != nil {
+		t.Fatal(err)
+	}
+
+	// Staff of another tenant holding every permission on their own catalogue
+	other := addTestTenant(t, app, "other")
+	_, token := addTestUser(t, app, other, "staff@example.com", data.PermissionWriteMovies, data.PermissionManageInventory)
+
+	header := http.Header{"X-Tenant": []string{"other"}}
+
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodDelete, "/v1/inventory/1"},
+		{http.MethodGet, "/v1/inventory/1"},
+		{http.MethodDelete, "/v1/movies/1/credits/1"},
+		{http.MethodGet, "/v1/images/1"},
+		{http.MethodGet, "/v1/images/1/thumbnail"},
+		{http.MethodDelete, "/v1/images/1"},
+	}
+
+	for _, tt := range tests {
+
+		res, _ := app.testRequest(t, testRequest{method: tt.method, path: tt.path, token: token, header: header})
+		if res.StatusCode != http.StatusNotFound {
+			t.Errorf("%s %s: got status %d; want %d", tt.method, tt.path, res.StatusCode, http.StatusNotFound)
+		}
+	}
+
+	// Nothing of the default tenant has been touched
+	if _, err := app.models.Inventory.Get(ctxt, item.ID); err != nil {
+		t.Errorf("get inventory item: got error %v; want none", err)
+	}
+
+	if _, err := app.models.Images.Get(ctxt, movieImage.ID); err != nil {
+		t.Errorf("get image: got error %v; want none", err)
+	}
+
+	credits, err := app.models.Credits.GetForMovies(ctxt, []int64{movie.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(credits[movie.ID]) != 1 {
+		t.Errorf("got %d credits; want 1", len(credits[movie.ID]))
+	}
+}
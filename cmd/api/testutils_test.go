@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/jsonlog"
+	"github.com/narinderv/blockbuster/internal/pricing"
+	"github.com/narinderv/blockbuster/internal/storage"
+)
+
+// Create an application with the default configuration, the models kept in memory and the images
+// stored in a temporary directory. The clients are not rate limited
+func newTestApplication(t *testing.T) *application {
+
+	t.Helper()
+
+	var conf configuration
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	defineFlags(flags, &conf)
+
+	if err := flags.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	conf.rateLimiter.enabled = false
+
+	pricingEngine, err := pricing.New(pricing.DefaultRules(), data.InventoryFormats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imageStorage, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &application{
+		config:  conf,
+		logger:  jsonlog.New(io.Discard, jsonlog.LevelOff),
+		models:  data.NewMemoryModels(),
+		pricing: pricingEngine,
+		storage: imageStorage,
+		live:    newLiveConfig(conf, flags),
+
+		pinAttempts: newPinAttempts(),
+		tasks:       newBackgroundTasks(context.Background(), maxBackgroundTasks),
+	}
+}
+
+// Add a user of the tenant holding the permissions, returning the authentication token of the user
+func addTestUser(t *testing.T, app *application, tenantID int64, email string, permissions ...string) (*data.User, string) {
+
+	t.Helper()
+
+	ctxt := context.Background()
+
+	user := &data.User{Name: "Test User", Email: email, Activated: true}
+
+	if err := user.Password.SetPasswordHash("pa55word1234"); err != nil {
+		t.Fatal(err)
+	}
+
+	users := app.models.Users.ForTenant(tenantID)
+
+	if err := users.Insert(ctxt, user); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.models.Permissions.AddForUser(ctxt, user.ID, permissions...); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tokens are 26 characters long
+	plaintext := fmt.Sprintf("%026d", user.ID)
+	hash := sha256.Sum256([]byte(plaintext))
+
+	app.models.Users.(*data.MemoryUserModel).AddToken(&data.Token{
+		Hash:   hash[:],
+		UserID: user.ID,
+		Expiry: time.Now().Add(time.Hour),
+		Scope:  data.ScopeAuthentication,
+	})
+
+	return user, plaintext
+}
+
+// Add a tenant, returning its ID
+func addTestTenant(t *testing.T, app *application, slug string) int64 {
+
+	t.Helper()
+
+	tenant := &data.Tenant{Slug: slug, Name: slug}
+	app.models.Tenants.(data.MemoryTenantModel).Add(tenant)
+
+	return tenant.ID
+}
+
+// Add a movie of the tenant
+func addTestMovie(t *testing.T, app *application, tenantID int64, movie *data.Movies) *data.Movies {
+
+	t.Helper()
+
+	if err := app.models.Movies.ForTenant(tenantID).Insert(context.Background(), movie); err != nil {
+		t.Fatal(err)
+	}
+
+	return movie
+}
+
+// Request sent through the routes of the application
+type testRequest struct {
+	method string
+	path   string
+	body   interface{} // Sent as JSON if not nil
+	token  string      // Authentication token, if any
+	header http.Header
+}
+
+// Send the request through the routes of the application, returning the response and its decoded JSON body
+func (app *application) testRequest(t *testing.T, req testRequest) (*http.Response, map[string]interface{}) {
+
+	t.Helper()
+
+	var body io.Reader
+
+	if req.body != nil {
+		js, err := json.Marshal(req.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body = bytes.NewReader(js)
+	}
+
+	r := httptest.NewRequest(req.method, req.path, body)
+
+	for name, values := range req.header {
+		r.Header[name] = values
+	}
+
+	if req.token != "" {
+		r.Header.Set("Authorization", "Bearer "+req.token)
+	}
+
+	w := httptest.NewRecorder()
+	app.routes().ServeHTTP(w, r)
+
+	res := w.Result()
+
+	var decoded map[string]interface{}
+
+	if w.Body.Len() > 0 && res.Header.Get("Content-Type") == "application/json" {
+		if err := json.Unmarshal(w.Body.Bytes(), &decoded); err != nil {
+			t.Fatalf("%s %s: invalid JSON response: %v", req.method, req.path, err)
+		}
+	}
+
+	return res, decoded
+}
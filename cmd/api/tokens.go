@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/data"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// Validity of the authentication tokens
+const authTokenTTL = 24 * time.Hour
+
+func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+
+	// Request structure
+	var input struct {
+		Email     string `json:"email"`
+		Password  string `json:"password"`
+		ProfileID int64  `json:"profile_id"`
+	}
+
+	err := app.readJsonRequest(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	// Validate the credentials
+	val := validator.NewValidator()
+
+	data.ValidateEmail(val, input.Email)
+	data.ValidatePassword(val, input.Password)
+
+	if !val.IsValid() {
+		app.failedValidations(w, r, val.Errors)
+		return
+	}
+
+	// Get the user for the email
+	users := app.modelsFor(r).Users
+
+	user, err := users.GetByEmail(r.Context(), input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidCredentialsResponse(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	// Check the password
+	match, err := user.Password.MatchPassword(input.Password)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !match {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	// Generate a new authentication token, optionally bound to a profile of the household
+	app.issueAuthenticationToken(w, r, user, input.ProfileID)
+}
+
+// Switch to another profile of the household, or back to the account itself with a zero profile ID,
+// by issuing a new authentication token bound to it. Leaving a profile with a parental limit requires
+// the parental PIN, as the limit would be lifted otherwise
+func (app *application) switchProfileHandler(w http.ResponseWriter, r *http.Request) {
+
+	var input struct {
+		ProfileID int64 `json:"profile_id"`
+	}
+
+	err := app.readJsonRequest(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	current := app.contextGetProfile(r)
+
+	if current != nil && current.ParentalLimit != nil && current.ID != input.ProfileID && !app.contextGetParentalOverride(r) {
+		app.errorResponse(w, r, http.StatusForbidden, "the parental PIN must be sent in the "+parentalPinHeader+" header to leave a profile with parental controls")
+		return
+	}
+
+	app.issueAuthenticationToken(w, r, app.contextGetUser(r), input.ProfileID)
+}
+
+// Issue an authentication token for the user, bound to the profile of the household unless the profile ID is zero
+func (app *application) issueAuthenticationToken(w http.ResponseWriter, r *http.Request, user *data.User, profileID int64) {
+
+	if profileID != 0 {
+		_, err := app.models.Profiles.Get(r.Context(), user.HouseholdID, profileID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.failedValidations(w, r, map[string]string{"profile_id": "must be a profile of the household"})
+			default:
+				app.serverError(w, r, err)
+			}
+
+			return
+		}
+	}
+
+	token, err := app.models.Tokens.NewForProfile(r.Context(), user.ID, profileID, authTokenTTL, data.ScopeAuthentication)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.writeJsonResponse(w, r, envelope{"authentication_token": token}, nil, http.StatusCreated)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+func TestCreateAuthenticationTokenHandler(t *testing.T) {
+
+	app := newTestApplication(t)
+
+	user, _ := addTestUser(t, app, data.DefaultTenantID, "parent@example.com")
+
+	profile := &data.Profile{HouseholdID: user.HouseholdID, Name: "Kids"}
+	if err := app.models.Profiles.Insert(context.Background(), profile); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		body      map[string]interface{}
+		status    int
+		profileID interface{}
+	}{
+		{"account", map[string]interface{}{"email": "parent@example.com", "password": "pa55word1234"}, http.StatusCreated, nil},
+		{"profile", map[string]interface{}{"email": "parent@example.com", "password": "pa55word1234", "profile_id": profile.ID}, http.StatusCreated, float64(profile.ID)},
+		{"profile of another household", map[string]interface{}{"email": "parent@example.com", "password": "pa55word1234", "profile_id": 9}, http.StatusUnprocessableEntity, nil},
+		{"wrong password", map[string]interface{}{"email": "parent@example.com", "password": "wrong-password"}, http.StatusUnauthorized, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			res, body := app.testRequest(t, testRequest{method: http.MethodPost, path: "/v1/tokens/authentication", body: tt.body})
+			if res.StatusCode != tt.status {
+				t.Fatalf("got status %d; want %d (%v)", res.StatusCode, tt.status, body)
+			}
+
+			if tt.status != http.StatusCreated {
+				return
+			}
+
+			token := body["authentication_token"].(map[string]interface{})
+			if token["profile_id"] != tt.profileID {
+				t.Errorf("got profile %v; want %v", token["profile_id"], tt.profileID)
+			}
+		})
+	}
+}
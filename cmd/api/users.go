@@ -47,8 +47,20 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Insert the user into the dataabse
-	err = app.models.Users.Insert(user)
+	// Insert the user into the dataabse along with the authentication token of the user, so that
+	// a failed registration leaves nothing behind and can be tried again
+	var token *data.Token
+
+	err = app.modelsFor(r).WithTx(r.Context(), func(tx data.Models) error {
+
+		if err := tx.Users.Insert(r.Context(), user); err != nil {
+			return err
+		}
+
+		var err error
+		token, err = tx.Tokens.New(r.Context(), user.ID, authTokenTTL, data.ScopeAuthentication)
+		return err
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail):
@@ -62,7 +74,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Send response
-	err = app.writeJsonResponse(w, envelope{"user": user}, nil, http.StatusCreated)
+	err = app.writeJsonResponse(w, r, envelope{"user": user, "authentication_token": token}, nil, http.StatusCreated)
 	if err != nil {
 		app.serverError(w, r, err)
 	}
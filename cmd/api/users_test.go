@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/data"
+)
+
+func TestRegisterUserHandler(t *testing.T) {
+
+	app := newTestApplication(t)
+
+	addTestUser(t, app, data.DefaultTenantID, "taken@example.com")
+
+	tests := []struct {
+		name   string
+		body   map[string]interface{}
+		status int
+	}{
+		{"new user", map[string]interface{}{"name": "New User", "email": "new@example.com", "password": "pa55word1234"}, http.StatusCreated},
+		{"email taken", map[string]interface{}{"name": "Other User", "email": "taken@example.com", "password": "pa55word1234"}, http.StatusUnprocessableEntity},
+		{"short password", map[string]interface{}{"name": "New User", "email": "short@example.com", "password": "pa55"}, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			res, body := app.testRequest(t, testRequest{method: http.MethodPost, path: "/v1/users", body: tt.body})
+			if res.StatusCode != tt.status {
+				t.Fatalf("got status %d; want %d (%v)", res.StatusCode, tt.status, body)
+			}
+
+			if tt.status != http.StatusCreated {
+				return
+			}
+
+			// The user is signed in with the token issued along with the registration
+			token := body["authentication_token"].(map[string]interface{})
+
+			res, body = app.testRequest(t, testRequest{method: http.MethodGet, path: "/v1/users/me/profiles", token: token["token"].(string)})
+			if res.StatusCode != http.StatusOK {
+				t.Errorf("got status %d with the token of the registration; want %d (%v)", res.StatusCode, http.StatusOK, body)
+			}
+		})
+	}
+}
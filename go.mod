@@ -1,6 +1,6 @@
 module github.com/narinderv/blockbuster
 
-go 1.17
+go 1.19
 
 require github.com/julienschmidt/httprouter v1.3.0
 
@@ -8,4 +8,8 @@ require github.com/lib/pq v1.10.7
 
 require golang.org/x/time v0.3.0
 
-require golang.org/x/crypto v0.6.0 // indirect
+require golang.org/x/crypto v0.6.0
+
+require github.com/BurntSushi/toml v1.2.1
+
+require gopkg.in/yaml.v3 v3.0.1
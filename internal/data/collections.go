@@ -0,0 +1,290 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+var (
+	ErrDuplicateCollectionPosition = errors.New("duplicate collection position")
+)
+
+// A franchise or series of movies e.g. the sequels of a movie, in their order within the collection
+type Collection struct {
+	ID          int64     `json:"id"`
+	CreatedAt   time.Time `json:"-"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	MovieCount  int32     `json:"movie_count"` // Read only
+	Version     int32     `json:"info_version"`
+}
+
+// Collection a movie belongs to, along with the position of the movie within the collection
+type CollectionMembership struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Position int32  `json:"position"`
+}
+
+// Collections of a tenant. Only the collections of the tenant are ever read or written
+type CollectionModel struct {
+	DB       DBTX
+	TenantID int64
+}
+
+func (m CollectionModel) ForTenant(tenantID int64) CollectionRepository {
+	m.TenantID = tenantID
+	return m
+}
+
+func (m CollectionModel) Insert(ctxt context.Context, collection *Collection) error {
+
+	query := `INSERT INTO collections (name, description, tenant_id)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at, version`
+
+	args := []interface{}{collection.Name, collection.Description, m.TenantID}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "collections.insert")
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctxt, query, args...).Scan(&collection.ID, &collection.CreatedAt, &collection.Version)
+}
+
+func (m CollectionModel) Get(ctxt context.Context, id int64) (*Collection, error) {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT c.id, c.created_at, c.name, c.description,
+	(SELECT count(*) FROM collection_movies WHERE collection_id = c.id), c.version
+	FROM collections c
+	WHERE c.id = $1 AND c.tenant_id = $2`
+
+	var collection Collection
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "collections.get")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, id, m.TenantID).Scan(&collection.ID, &collection.CreatedAt, &collection.Name,
+		&collection.Description, &collection.MovieCount, &collection.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &collection, nil
+}
+
+// Get all the collections ordered by their name
+func (m CollectionModel) GetAll(ctxt context.Context) ([]*Collection, error) {
+
+	query := `SELECT c.id, c.created_at, c.name, c.description,
+	(SELECT count(*) FROM collection_movies WHERE collection_id = c.id), c.version
+	FROM collections c
+	WHERE c.tenant_id = $1
+	ORDER BY c.name, c.id`
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "collections.get_all")
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctxt, query, m.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	collections := []*Collection{}
+
+	for rows.Next() {
+		var collection Collection
+
+		err = rows.Scan(&collection.ID, &collection.CreatedAt, &collection.Name, &collection.Description,
+			&collection.MovieCount, &collection.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		collections = append(collections, &collection)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
+
+func (m CollectionModel) Update(ctxt context.Context, collection *Collection) error {
+
+	query := `UPDATE collections
+	SET name = $1, description = $2, version = version + 1
+	WHERE id = $3 AND version = $4 AND tenant_id = $5
+	RETURNING version`
+
+	args := []interface{}{collection.Name, collection.Description, collection.ID, collection.Version, m.TenantID}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "collections.update")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, args...).Scan(&collection.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete a collection. Its movies are kept and just no longer belong to a collection
+func (m CollectionModel) Delete(ctxt context.Context, id int64) error {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := "DELETE FROM collections WHERE id = $1 AND tenant_id = $2"
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "collections.delete")
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctxt, query, id, m.TenantID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Put the movie at the given position of the collection. A movie already in a collection, this one
+// or another, is moved. The collection and the movie are expected to belong to the tenant
+func (m CollectionModel) SetMovie(ctxt context.Context, collectionID, movieID int64, position int32) error {
+
+	query := `INSERT INTO collection_movies (collection_id, movie_id, position)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (movie_id) DO UPDATE SET collection_id = EXCLUDED.collection_id, position = EXCLUDED.position`
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "collections.set_movie")
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctxt, query, collectionID, movieID, position)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "collection_movies_position_key"`:
+			return ErrDuplicateCollectionPosition
+		case isForeignKeyViolation(err):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Remove the movie from the collection
+func (m CollectionModel) RemoveMovie(ctxt context.Context, collectionID, movieID int64) error {
+
+	query := "DELETE FROM collection_movies WHERE collection_id = $1 AND movie_id = $2"
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "collections.remove_movie")
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctxt, query, collectionID, movieID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Get the collections the movies belong to, by movie ID. Movies without a collection are left out
+func (m CollectionModel) GetForMovies(ctxt context.Context, movieIDs []int64) (map[int64]*CollectionMembership, error) {
+
+	query := `
+		SELECT cm.movie_id, c.id, c.name, cm.position
+		FROM collection_movies cm
+		INNER JOIN collections c ON c.id = cm.collection_id
+		WHERE cm.movie_id = ANY($1)`
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "collections.get_for_movies")
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctxt, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	memberships := make(map[int64]*CollectionMembership)
+
+	for rows.Next() {
+		var movieID int64
+		var membership CollectionMembership
+
+		err = rows.Scan(&movieID, &membership.ID, &membership.Name, &membership.Position)
+		if err != nil {
+			return nil, err
+		}
+
+		memberships[movieID] = &membership
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return memberships, nil
+}
+
+func ValidateCollection(val *validator.Validator, collection *Collection) {
+
+	val.Check(collection.Name != "", "name", "must be provided")
+	val.Check(len(collection.Name) <= validator.MAX_LEN, "name", "must not be more than 500 bytes")
+
+	val.Check(len(collection.Description) <= MaxPlotLength, "description", "must not be more than 5000 bytes")
+}
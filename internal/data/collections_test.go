@@ -0,0 +1,105 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCollectionMembership(t *testing.T) {
+
+	models := newTestModels(t)
+	ctxt := context.Background()
+
+	alien := addTestMovie(t, models, DefaultTenantID, &Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"horror"}})
+	aliens := addTestMovie(t, models, DefaultTenantID, &Movies{Title: "Aliens", Year: 1986, Runtime: 137, Genres: []string{"action"}})
+	prometheus := addTestMovie(t, models, DefaultTenantID, &Movies{Title: "Prometheus", Year: 2012, Runtime: 124, Genres: []string{"horror"}})
+
+	saga := &Collection{Name: "Alien"}
+	prequels := &Collection{Name: "Alien prequels"}
+
+	for _, collection := range []*Collection{saga, prequels} {
+		if err := models.Collections.Insert(ctxt, collection); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	members := []struct {
+		movie    *Movies
+		position int32
+	}{
+		{aliens, 2},
+		{alien, 1},
+		{prometheus, 3},
+	}
+
+	for _, member := range members {
+		if err := models.Collections.SetMovie(ctxt, saga.ID, member.movie.ID, member.position); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := models.Collections.SetMovie(ctxt, saga.ID, prometheus.ID, 1); !errors.Is(err, ErrDuplicateCollectionPosition) {
+		t.Errorf("set movie at a taken position: got error %v; want %v", err, ErrDuplicateCollectionPosition)
+	}
+
+	if err := models.Collections.SetMovie(ctxt, saga.ID+prequels.ID, alien.ID, 4); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("set movie of an unknown collection: got error %v; want %v", err, ErrRecordNotFound)
+	}
+
+	// A movie belongs to one collection only, so setting it in another one moves it
+	if err := models.Collections.SetMovie(ctxt, prequels.ID, prometheus.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// The movies of the collection are searched in the order of their position
+	search := func(collectionID int64) []string {
+
+		t.Helper()
+
+		movies, _, err := models.Movies.GetAll(ctxt, MovieSearch{CollectionID: collectionID},
+			Filters{Page: 1, PageSize: 20, Sort: "position", SortList: []string{"position"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		titles := []string{}
+		for _, movie := range movies {
+			titles = append(titles, movie.Title)
+		}
+
+		return titles
+	}
+
+	if got, want := search(saga.ID), []string{"Alien", "Aliens"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("movies of the saga: got %v; want %v", got, want)
+	}
+
+	if got, want := search(prequels.ID), []string{"Prometheus"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("movies of the prequels: got %v; want %v", got, want)
+	}
+
+	collection, err := models.Collections.Get(ctxt, saga.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if collection.MovieCount != 2 {
+		t.Errorf("got %d movies in the saga; want 2", collection.MovieCount)
+	}
+
+	// Deleting a collection releases its movies
+	if err = models.Collections.Delete(ctxt, prequels.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	memberships, err := models.Collections.GetForMovies(ctxt, []int64{alien.ID, prometheus.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(memberships) != 1 || memberships[alien.ID] == nil || memberships[alien.ID].Position != 1 {
+		t.Errorf("got memberships %v; want Alien at position 1 only", memberships)
+	}
+}
@@ -0,0 +1,213 @@
+package data
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+var (
+	ErrDuplicateCredit = errors.New("duplicate credit")
+)
+
+// Roles a person can be credited for in a movie
+var CreditRoles = []string{"director", "writer", "producer", "actor", "composer", "cinematographer", "editor"}
+
+// Credit of a person in a movie. The person and movie details are filled in
+// depending upon whether the credits are listed for a movie or for a person
+type Credit struct {
+	ID           int64  `json:"id"`
+	MovieID      int64  `json:"movie_id"`
+	MovieTitle   string `json:"movie_title,omitempty"`
+	MovieYear    int32  `json:"movie_year,omitempty"`
+	PersonID     int64  `json:"person_id"`
+	PersonName   string `json:"person_name,omitempty"`
+	Role         string `json:"role"`
+	Character    string `json:"character,omitempty"`
+	BillingOrder int32  `json:"billing_order"`
+}
+
+// Credits of the movies. The filmographies only list the movies of the tenant which are not restricted
+// by the parental limit, if any
+type CreditModel struct {
+	DB       DBTX
+	TenantID int64
+	Limit    *ParentalLimit
+}
+
+func (m CreditModel) ForTenant(tenantID int64) CreditRepository {
+	m.TenantID = tenantID
+	return m
+}
+
+func (m CreditModel) WithLimit(limit *ParentalLimit) CreditRepository {
+	m.Limit = limit
+	return m
+}
+
+func (m CreditModel) Insert(ctxt context.Context, credit *Credit) error {
+
+	// Insert query
+	query := `INSERT INTO movie_credits (movie_id, person_id, role, character_name, billing_order)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id`
+
+	// Argumets to the query
+	args := []interface{}{credit.MovieID, credit.PersonID, credit.Role, credit.Character, credit.BillingOrder}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "credits.insert")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, args...).Scan(&credit.ID)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "movie_credits_unique"`:
+			return ErrDuplicateCredit
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete a credit of the given movie
+func (m CreditModel) Delete(ctxt context.Context, movieID, id int64) error {
+
+	// Validate if ID is valid
+	if movieID < 1 || id < 1 {
+		return ErrRecordNotFound
+	}
+
+	// Query
+	query := "DELETE FROM movie_credits WHERE id = $1 AND movie_id = $2"
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "credits.delete")
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctxt, query, id, movieID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Get the credits for a list of movies, keyed by the movie ID, in billing order
+func (m CreditModel) GetForMovies(ctxt context.Context, movieIDs []int64) (map[int64][]*Credit, error) {
+
+	query := `
+		SELECT c.id, c.movie_id, c.person_id, p.name, c.role, c.character_name, c.billing_order
+		FROM movie_credits c
+		INNER JOIN people p ON p.id = c.person_id
+		WHERE c.movie_id = ANY($1)
+		ORDER BY c.movie_id, c.billing_order, c.id`
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "credits.get_for_movies")
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctxt, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	credits := make(map[int64][]*Credit)
+
+	for rows.Next() {
+		var credit Credit
+
+		err = rows.Scan(&credit.ID, &credit.MovieID, &credit.PersonID, &credit.PersonName,
+			&credit.Role, &credit.Character, &credit.BillingOrder)
+		if err != nil {
+			return nil, err
+		}
+
+		credits[credit.MovieID] = append(credits[credit.MovieID], &credit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return credits, nil
+}
+
+// Get all the credits of a person in the movies of the tenant along with the movie details, latest movies first
+func (m CreditModel) GetFilmography(ctxt context.Context, personID int64) ([]*Credit, error) {
+
+	query := `
+		SELECT c.id, c.movie_id, m.title, m.year, c.person_id, c.role, c.character_name, c.billing_order
+		FROM movie_credits c
+		INNER JOIN movies m ON m.id = c.movie_id
+		WHERE c.person_id = $1 AND m.tenant_id = $2
+		AND (m.certifications->>$3 = ANY($4::text[]) OR $4 IS NULL)
+		ORDER BY m.year DESC, m.title, c.id`
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "credits.get_filmography")
+	defer cancel()
+
+	limitCountry, permitted := parentalLimitArgs(m.Limit)
+
+	rows, err := m.DB.QueryContext(ctxt, query, personID, m.TenantID, limitCountry, permitted)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	credits := []*Credit{}
+
+	for rows.Next() {
+		var credit Credit
+
+		err = rows.Scan(&credit.ID, &credit.MovieID, &credit.MovieTitle, &credit.MovieYear,
+			&credit.PersonID, &credit.Role, &credit.Character, &credit.BillingOrder)
+		if err != nil {
+			return nil, err
+		}
+
+		credits = append(credits, &credit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return credits, nil
+}
+
+func ValidateCredit(val *validator.Validator, credit *Credit) {
+
+	// Person
+	val.Check(credit.PersonID > 0, "person_id", "must be provided")
+
+	// Role
+	val.Check(credit.Role != "", "role", "must be provided")
+	val.Check(validator.Permittedvalues(credit.Role, CreditRoles...), "role", "invalid role value")
+
+	// Character is only applicable to the cast
+	val.Check(len(credit.Character) <= validator.MAX_LEN, "character", "must not be more than 500 bytes")
+	if credit.Role != "actor" {
+		val.Check(credit.Character == "", "character", "must only be provided for actors")
+	}
+
+	// Billing order
+	val.Check(credit.BillingOrder >= 0, "billing_order", "must not be negative")
+}
@@ -0,0 +1,125 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCreditsForMovies(t *testing.T) {
+
+	models := newTestModels(t)
+	ctxt := context.Background()
+
+	alien := addTestMovie(t, models, DefaultTenantID, &Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"horror"}})
+	aliens := addTestMovie(t, models, DefaultTenantID, &Movies{Title: "Aliens", Year: 1986, Runtime: 137, Genres: []string{"action"}})
+
+	weaver := &Person{Name: "Sigourney Weaver"}
+	skerritt := &Person{Name: "Tom Skerritt"}
+
+	for _, person := range []*Person{weaver, skerritt} {
+		if err := models.People.Insert(ctxt, person); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	credits := []*Credit{
+		{MovieID: alien.ID, PersonID: skerritt.ID, Role: "actor", Character: "Dallas", BillingOrder: 2},
+		{MovieID: alien.ID, PersonID: weaver.ID, Role: "actor", Character: "Ripley", BillingOrder: 1},
+		{MovieID: aliens.ID, PersonID: weaver.ID, Role: "actor", Character: "Ripley", BillingOrder: 1},
+	}
+
+	for _, credit := range credits {
+		if err := models.Credits.Insert(ctxt, credit); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	duplicate := &Credit{MovieID: alien.ID, PersonID: weaver.ID, Role: "actor", Character: "Ripley"}
+	if err := models.Credits.Insert(ctxt, duplicate); !errors.Is(err, ErrDuplicateCredit) {
+		t.Errorf("insert duplicate credit: got error %v; want %v", err, ErrDuplicateCredit)
+	}
+
+	// A credit is only deleted through its own movie
+	if err := models.Credits.Delete(ctxt, aliens.ID, credits[0].ID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("delete credit through another movie: got error %v; want %v", err, ErrRecordNotFound)
+	}
+
+	byMovie, err := models.Credits.GetForMovies(ctxt, []int64{alien.ID, aliens.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, credit := range byMovie[alien.ID] {
+		got = append(got, credit.PersonName)
+	}
+
+	if want := []string{"Sigourney Weaver", "Tom Skerritt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("credits of Alien: got %v; want %v in billing order", got, want)
+	}
+
+	if len(byMovie[aliens.ID]) != 1 {
+		t.Errorf("credits of Aliens: got %d; want 1", len(byMovie[aliens.ID]))
+	}
+
+	// Deleting a person deletes their credits
+	if err = models.People.Delete(ctxt, weaver.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	byMovie, err = models.Credits.GetForMovies(ctxt, []int64{alien.ID, aliens.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(byMovie[alien.ID]) != 1 || len(byMovie[aliens.ID]) != 0 {
+		t.Errorf("got %d and %d credits; want 1 and 0 once the person is deleted", len(byMovie[alien.ID]), len(byMovie[aliens.ID]))
+	}
+}
+
+func TestFilmographyForTenant(t *testing.T) {
+
+	models := newTestModels(t)
+	ctxt := context.Background()
+
+	otherTenant := addTestTenant(t, models, "other")
+
+	alien := addTestMovie(t, models, DefaultTenantID, &Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"horror"}})
+	heat := addTestMovie(t, models, otherTenant, &Movies{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"crime"}})
+
+	person := &Person{Name: "Ridley Scott"}
+	if err := models.People.Insert(ctxt, person); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, movie := range []*Movies{alien, heat} {
+		if err := models.Credits.Insert(ctxt, &Credit{MovieID: movie.ID, PersonID: person.ID, Role: "director"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		tenantID int64
+		want     string
+	}{
+		{"default tenant", DefaultTenantID, "Alien"},
+		{"other tenant", otherTenant, "Heat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			credits, err := models.ForTenant(tt.tenantID).Credits.GetFilmography(ctxt, person.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(credits) != 1 || credits[0].MovieTitle != tt.want {
+				t.Errorf("got %d credits; want the credit of %s only", len(credits), tt.want)
+			}
+		})
+	}
+}
@@ -14,6 +14,9 @@ type Metadata struct {
 	FirstPage    int `json:"first_page,omitempty"`
 	LastPage     int `json:"last_page,omitempty"`
 	TotalRecords int `json:"total_records,omitempty"`
+
+	// Parental limit by which the records were filtered, if any
+	ParentalLimit *ParentalLimit `json:"parental_limit,omitempty"`
 }
 
 type Filters struct {
@@ -21,6 +24,7 @@ type Filters struct {
 	PageSize int
 	Sort     string
 	SortList []string // List of allowed values for the sort field
+	Fields   []string // Fields to be returned, validated by ValidateFields. Empty means all the fields
 }
 
 func (filter Filters) getSortColumn() string {
@@ -66,6 +70,19 @@ func ValidateFilters(val *validator.Validator, filters *Filters) {
 	val.Check(validator.Permittedvalues(filters.Sort, filters.SortList...), "sort", "invalid sort value")
 }
 
+// Validate the sparse fieldset requested by the client against the allowed fields
+func ValidateFields(val *validator.Validator, fields []string, fieldList []string) {
+
+	for _, field := range fields {
+		if !validator.Permittedvalues(field, fieldList...) {
+			val.AddError("fields", "invalid field value: "+field)
+			return
+		}
+	}
+
+	val.Check(validator.Unique(fields), "fields", "must not contain duplicate values")
+}
+
 func CalculateMetadata(totalRecords, page, pageSize int) Metadata {
 	if totalRecords == 0 {
 		return Metadata{}
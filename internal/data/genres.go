@@ -0,0 +1,394 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+var (
+	ErrDuplicateSlug = errors.New("duplicate slug")
+	ErrGenreInUse    = errors.New("genre in use")
+)
+
+var slugRX = regexp.MustCompile("^[a-z0-9]+(-[a-z0-9]+)*$")
+var nonSlugRX = regexp.MustCompile("[^a-z0-9]+")
+
+// A genre of the taxonomy, which is shared by all the tenants. Movies refer to the genres by their slug
+type Genre struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	Aliases   []string  `json:"aliases"`
+	Version   int32     `json:"info_version"`
+}
+
+type GenreModel struct {
+	DB DBTX
+}
+
+// Convert a genre name or alias into its slug form e.g. "Sci Fi" into "sci-fi"
+func Slugify(value string) string {
+	return strings.Trim(nonSlugRX.ReplaceAllString(strings.ToLower(value), "-"), "-")
+}
+
+// Lookup of the genres by their slug, name and aliases
+type GenreTaxonomy struct {
+	genres map[string]*Genre
+}
+
+func NewGenreTaxonomy(genres []*Genre) *GenreTaxonomy {
+
+	taxonomy := &GenreTaxonomy{genres: make(map[string]*Genre)}
+
+	for _, genre := range genres {
+		taxonomy.genres[genre.Slug] = genre
+		taxonomy.genres[Slugify(genre.Name)] = genre
+
+		for _, alias := range genre.Aliases {
+			taxonomy.genres[Slugify(alias)] = genre
+		}
+	}
+
+	return taxonomy
+}
+
+// Get the genre matching the given slug, name or alias
+func (taxonomy *GenreTaxonomy) Lookup(value string) (*Genre, bool) {
+	genre, found := taxonomy.genres[Slugify(value)]
+	return genre, found
+}
+
+// Replace the given genre names and aliases with the canonical slugs.
+// Unknown values are returned as is.
+func (taxonomy *GenreTaxonomy) Canonicalize(values []string) []string {
+
+	slugs := make([]string, 0, len(values))
+
+	for _, value := range values {
+		if genre, found := taxonomy.Lookup(value); found {
+			value = genre.Slug
+		}
+
+		slugs = append(slugs, value)
+	}
+
+	return slugs
+}
+
+func (m GenreModel) Insert(ctxt context.Context, genre *Genre) error {
+
+	// Insert query
+	query := `INSERT INTO genres (slug, name, aliases)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at, version`
+
+	// Argumets to the query
+	args := []interface{}{genre.Slug, genre.Name, pq.Array(genre.Aliases)}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "genres.insert")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, args...).Scan(&genre.ID, &genre.CreatedAt, &genre.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "genres_slug_key"`:
+			return ErrDuplicateSlug
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m GenreModel) Get(ctxt context.Context, id int64) (*Genre, error) {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT id, created_at, slug, name, aliases, version
+	FROM genres
+	WHERE id = $1`
+
+	var genre Genre
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "genres.get")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, id).Scan(&genre.ID, &genre.CreatedAt, &genre.Slug,
+		&genre.Name, pq.Array(&genre.Aliases), &genre.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &genre, nil
+}
+
+// Get all the genres ordered by their name
+func (m GenreModel) GetAll(ctxt context.Context) ([]*Genre, error) {
+
+	query := `SELECT id, created_at, slug, name, aliases, version
+	FROM genres
+	ORDER BY name, id`
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "genres.get_all")
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctxt, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	genres := []*Genre{}
+
+	for rows.Next() {
+		var genre Genre
+
+		err = rows.Scan(&genre.ID, &genre.CreatedAt, &genre.Slug, &genre.Name, pq.Array(&genre.Aliases), &genre.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		genres = append(genres, &genre)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return genres, nil
+}
+
+// Get the taxonomy of all the genres for validating and canonicalizing the movie genres
+func (m GenreModel) GetTaxonomy(ctxt context.Context) (*GenreTaxonomy, error) {
+
+	genres, err := m.GetAll(ctxt)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewGenreTaxonomy(genres), nil
+}
+
+// Update the genre. If the slug has changed, the movies are updated to the new slug as well
+func (m GenreModel) Update(ctxt context.Context, genre *Genre, oldSlug string) error {
+
+	// Create a DB context to timeout the queries if they exceed a certian duration
+	ctxt, cancel := withTimeout(ctxt, "genres.update")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctxt, nil)
+	if err != nil {
+		return err
+	}
+
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	query := `UPDATE genres
+	SET slug = $1, name = $2, aliases = $3, version = version + 1
+	WHERE id = $4 AND version = $5
+	RETURNING version`
+
+	args := []interface{}{genre.Slug, genre.Name, pq.Array(genre.Aliases), genre.ID, genre.Version}
+
+	err = tx.QueryRowContext(ctxt, query, args...).Scan(&genre.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "genres_slug_key"`:
+			return ErrDuplicateSlug
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	// Rename the slug in the movies
+	if oldSlug != genre.Slug {
+		query = `UPDATE movies
+		SET genres = array_replace(genres, $1, $2), version = version + 1
+		WHERE genres @> ARRAY[$1]`
+
+		_, err = tx.ExecContext(ctxt, query, oldSlug, genre.Slug)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Delete a genre. Genres still used by movies can not be deleted, they should be merged instead
+func (m GenreModel) Delete(ctxt context.Context, id int64) error {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM genres g
+	WHERE id = $1
+	AND NOT EXISTS (SELECT 1 FROM movies WHERE genres @> ARRAY[g.slug])
+	RETURNING id`
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "genres.delete")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, id).Scan(&id)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	// Nothing was deleted. Check whether the genre does not exist or is still in use
+	if _, err = m.Get(ctxt, id); err != nil {
+		return err
+	}
+
+	return ErrGenreInUse
+}
+
+// Merge the source genre into the target genre. The movies having the source genre are rewritten
+// to the target genre and the slug, name and aliases of the source are added as aliases of the target.
+// The number of movies rewritten is returned.
+func (m GenreModel) Merge(ctxt context.Context, source, target *Genre) (int64, error) {
+
+	// Create a DB context to timeout the queries if they exceed a certian duration
+	ctxt, cancel := withTimeout(ctxt, "genres.merge")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctxt, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	// Remove the source genre from the database, checking that it has not been modified in the meantime
+	query := "DELETE FROM genres WHERE id = $1 AND version = $2"
+
+	res, err := tx.ExecContext(ctxt, query, source.ID, source.Version)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if rowsAffected == 0 {
+		return 0, ErrEditConflict
+	}
+
+	// Add the source as aliases of the target
+	aliases := append([]string{}, target.Aliases...)
+	for _, alias := range append([]string{source.Slug, source.Name}, source.Aliases...) {
+		if Slugify(alias) != target.Slug && !validator.Permittedvalues(alias, aliases...) {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	query = `UPDATE genres
+	SET aliases = $1, version = version + 1
+	WHERE id = $2 AND version = $3
+	RETURNING version`
+
+	err = tx.QueryRowContext(ctxt, query, pq.Array(aliases), target.ID, target.Version).Scan(&target.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrEditConflict
+		default:
+			return 0, err
+		}
+	}
+
+	target.Aliases = aliases
+
+	// Rewrite the movies. Movies having both the genres only lose the source genre
+	query = `UPDATE movies
+	SET genres = CASE WHEN genres @> ARRAY[$2] THEN array_remove(genres, $1) ELSE array_replace(genres, $1, $2) END,
+	version = version + 1
+	WHERE genres @> ARRAY[$1]`
+
+	res, err = tx.ExecContext(ctxt, query, source.Slug, target.Slug)
+	if err != nil {
+		return 0, err
+	}
+
+	rewritten, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return rewritten, tx.Commit()
+}
+
+func ValidateGenre(val *validator.Validator, genre *Genre) {
+
+	// Slug
+	val.Check(genre.Slug != "", "slug", "must be provided")
+	val.Check(len(genre.Slug) <= 100, "slug", "must not be more than 100 bytes")
+	val.Check(validator.MatchPattern(genre.Slug, slugRX), "slug", "must only contain lowercase letters, digits and hyphens")
+
+	// Name
+	val.Check(genre.Name != "", "name", "must be provided")
+	val.Check(len(genre.Name) <= 100, "name", "must not be more than 100 bytes")
+
+	// Aliases
+	val.Check(len(genre.Aliases) <= 20, "aliases", "must not contain more than 20 aliases")
+	val.Check(validator.Unique(genre.Aliases), "aliases", "must not contain duplicate values")
+
+	for _, alias := range genre.Aliases {
+		if Slugify(alias) == "" {
+			val.AddError("aliases", "must not contain blank values")
+			break
+		}
+	}
+}
+
+// Check that the slug, name and aliases of the genre do not match any other genre of the taxonomy
+func ValidateGenreUnique(val *validator.Validator, genre *Genre, taxonomy *GenreTaxonomy) {
+
+	if other, found := taxonomy.Lookup(genre.Slug); found && other.ID != genre.ID {
+		val.AddError("slug", "matches the genre "+other.Slug)
+	}
+
+	if other, found := taxonomy.Lookup(genre.Name); found && other.ID != genre.ID {
+		val.AddError("name", "matches the genre "+other.Slug)
+	}
+
+	for _, alias := range genre.Aliases {
+		if other, found := taxonomy.Lookup(alias); found && other.ID != genre.ID {
+			val.AddError("aliases", "alias "+alias+" matches the genre "+other.Slug)
+			break
+		}
+	}
+}
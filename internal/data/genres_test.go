@@ -0,0 +1,81 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+func TestSlugify(t *testing.T) {
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Drama", "drama"},
+		{"Sci Fi", "sci-fi"},
+		{"Science-Fiction", "science-fiction"},
+		{"  Film Noir!  ", "film-noir"},
+		{"Action & Adventure", "action-adventure"},
+		{"---", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Slugify(tt.value); got != tt.want {
+			t.Errorf("Slugify(%q) = %q; want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestGenreTaxonomyCanonicalize(t *testing.T) {
+
+	taxonomy := NewGenreTaxonomy([]*Genre{
+		{ID: 1, Slug: "science-fiction", Name: "Science Fiction", Aliases: []string{"Sci-Fi", "SF"}},
+		{ID: 2, Slug: "drama", Name: "Drama"},
+	})
+
+	got := taxonomy.Canonicalize([]string{"Sci Fi", "sf", "Science Fiction", "DRAMA", "western"})
+	want := []string{"science-fiction", "science-fiction", "science-fiction", "drama", "western"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+}
+
+func TestValidateGenre(t *testing.T) {
+
+	taxonomy := NewGenreTaxonomy([]*Genre{
+		{ID: 1, Slug: "science-fiction", Name: "Science Fiction", Aliases: []string{"Sci-Fi"}},
+	})
+
+	tests := []struct {
+		name   string
+		genre  Genre
+		errors map[string]string
+	}{
+		{"valid genre", Genre{Slug: "drama", Name: "Drama", Aliases: []string{"Melodrama"}}, map[string]string{}},
+		{"editing the same genre", Genre{ID: 1, Slug: "science-fiction", Name: "Science Fiction", Aliases: []string{"Sci-Fi"}}, map[string]string{}},
+		{"missing slug", Genre{Name: "Drama"}, map[string]string{"slug": "must be provided"}},
+		{"invalid slug", Genre{Slug: "Drama", Name: "Drama"}, map[string]string{"slug": "must only contain lowercase letters, digits and hyphens"}},
+		{"duplicate aliases", Genre{Slug: "drama", Name: "Drama", Aliases: []string{"a", "a"}}, map[string]string{"aliases": "must not contain duplicate values"}},
+		{"blank alias", Genre{Slug: "drama", Name: "Drama", Aliases: []string{"--"}}, map[string]string{"aliases": "must not contain blank values"}},
+		{"slug of another genre", Genre{Slug: "sci-fi", Name: "Space"}, map[string]string{"slug": "matches the genre science-fiction"}},
+		{"name of another genre", Genre{Slug: "space", Name: "science fiction"}, map[string]string{"name": "matches the genre science-fiction"}},
+		{"alias of another genre", Genre{Slug: "space", Name: "Space", Aliases: []string{"SCI FI"}}, map[string]string{"aliases": "alias SCI FI matches the genre science-fiction"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			val := validator.NewValidator()
+
+			ValidateGenre(val, &tt.genre)
+			ValidateGenreUnique(val, &tt.genre, taxonomy)
+
+			if !reflect.DeepEqual(val.Errors, tt.errors) {
+				t.Errorf("got errors %v; want %v", val.Errors, tt.errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,434 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+var (
+	ErrDuplicateHold = errors.New("duplicate hold")
+	ErrHoldClosed    = errors.New("hold is closed")
+	ErrCopyAvailable = errors.New("copy available")
+)
+
+// Statuses of a hold. Waiting holds are queued first in first out per movie and store.
+// A returned copy is set aside for the next waiting hold, which is then ready for pickup
+// until the pickup window ends.
+const (
+	HoldWaiting   = "waiting"
+	HoldReady     = "ready"
+	HoldFulfilled = "fulfilled"
+	HoldExpired   = "expired"
+	HoldCancelled = "cancelled"
+)
+
+// Time for which a copy is set aside for a ready hold
+const HoldPickupWindow = 48 * time.Hour
+
+// Reservation of a movie at a store by a user, while all the copies are rented out
+type Hold struct {
+	ID              int64      `json:"id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UserID          int64      `json:"user_id"`
+	MovieID         int64      `json:"movie_id"`
+	StoreID         int64      `json:"store_id"`
+	Format          string     `json:"format,omitempty"`
+	Status          string     `json:"status"`
+	InventoryItemID *int64     `json:"inventory_item_id,omitempty"`
+	ReadyAt         *time.Time `json:"ready_at,omitempty"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	Position        int32      `json:"position,omitempty"` // Position in the queue of the waiting holds
+	Version         int32      `json:"info_version"`
+}
+
+type HoldModel struct {
+	DB DBTX
+}
+
+// Columns of the hold queries, including the queue position of the waiting holds
+const holdColumns = `h.id, h.created_at, h.user_id, h.movie_id, h.store_id, h.format, h.status,
+	h.inventory_item_id, h.ready_at, h.expires_at, h.version,
+	CASE WHEN h.status = 'waiting' THEN (
+		SELECT count(*) FROM holds q
+		WHERE q.movie_id = h.movie_id AND q.store_id = h.store_id AND q.status = 'waiting'
+		AND (q.created_at, q.id) <= (h.created_at, h.id)
+	) ELSE 0 END`
+
+func (hold *Hold) scanDest() []interface{} {
+	return []interface{}{&hold.ID, &hold.CreatedAt, &hold.UserID, &hold.MovieID, &hold.StoreID, &hold.Format,
+		&hold.Status, &hold.InventoryItemID, &hold.ReadyAt, &hold.ExpiresAt, &hold.Version, &hold.Position}
+}
+
+// Place a hold. Holds can only be placed when no copy of the movie is available at the store
+func (m HoldModel) Insert(ctxt context.Context, hold *Hold) error {
+
+	// Create a DB context to timeout the queries if they exceed a certian duration
+	ctxt, cancel := withTimeout(ctxt, "holds.insert")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctxt, nil)
+	if err != nil {
+		return err
+	}
+
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	// A copy returned meanwhile would otherwise become available with the hold left waiting for it
+	if err = lockHoldQueue(ctxt, tx, hold.MovieID, hold.StoreID); err != nil {
+		return err
+	}
+
+	var available bool
+
+	query := `SELECT EXISTS (
+		SELECT 1 FROM inventory_items
+		WHERE movie_id = $1 AND store_id = $2 AND status = 'available' AND (format = $3 OR $3 = '')
+	)`
+
+	err = tx.QueryRowContext(ctxt, query, hold.MovieID, hold.StoreID, hold.Format).Scan(&available)
+	if err != nil {
+		return err
+	}
+
+	if available {
+		return ErrCopyAvailable
+	}
+
+	query = `INSERT INTO holds (user_id, movie_id, store_id, format)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at, status, version`
+
+	args := []interface{}{hold.UserID, hold.MovieID, hold.StoreID, hold.Format}
+
+	err = tx.QueryRowContext(ctxt, query, args...).Scan(&hold.ID, &hold.CreatedAt, &hold.Status, &hold.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "holds_open_user_idx"`:
+			return ErrDuplicateHold
+		default:
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m HoldModel) Get(ctxt context.Context, id int64) (*Hold, error) {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT ` + holdColumns + `
+	FROM holds h
+	WHERE h.id = $1`
+
+	var hold Hold
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "holds.get")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, id).Scan(hold.scanDest()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &hold, nil
+}
+
+// Get the open holds of the user, oldest first
+func (m HoldModel) GetAllForUser(ctxt context.Context, userID int64) ([]*Hold, error) {
+
+	query := `SELECT ` + holdColumns + `
+	FROM holds h
+	WHERE h.user_id = $1 AND h.status IN ('waiting', 'ready')
+	ORDER BY h.created_at, h.id`
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "holds.get_all_for_user")
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctxt, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	holds := []*Hold{}
+
+	for rows.Next() {
+		var hold Hold
+
+		if err = rows.Scan(hold.scanDest()...); err != nil {
+			return nil, err
+		}
+
+		holds = append(holds, &hold)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return holds, nil
+}
+
+// Cancel an open hold. A copy set aside for the hold goes to the next waiting hold.
+// The hold which became ready in its place, if any, is returned.
+func (m HoldModel) Cancel(ctxt context.Context, hold *Hold) (*Hold, error) {
+
+	if hold.Status != HoldWaiting && hold.Status != HoldReady {
+		return nil, ErrHoldClosed
+	}
+
+	// Create a DB context to timeout the queries if they exceed a certian duration
+	ctxt, cancel := withTimeout(ctxt, "holds.cancel")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctxt, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	query := `UPDATE holds
+	SET status = 'cancelled', version = version + 1
+	WHERE id = $1 AND version = $2
+	RETURNING status, version`
+
+	err = tx.QueryRowContext(ctxt, query, hold.ID, hold.Version).Scan(&hold.Status, &hold.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrEditConflict
+		default:
+			return nil, err
+		}
+	}
+
+	hold.Position = 0
+
+	// Pass on the copy set aside for the hold
+	var next *Hold
+
+	if hold.InventoryItemID != nil {
+		next, err = assignCopy(ctxt, tx, *hold.InventoryItemID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return next, tx.Commit()
+}
+
+// Expire the ready holds whose pickup window has ended. The copies set aside for them go to the next
+// waiting holds, which are returned.
+func (m HoldModel) ExpireReady(ctxt context.Context) ([]*Hold, error) {
+
+	// Create a DB context to timeout the queries if they exceed a certian duration
+	ctxt, cancel := withTimeout(ctxt, "holds.expire_ready")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctxt, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	query := `UPDATE holds
+	SET status = 'expired', version = version + 1
+	WHERE status = 'ready' AND expires_at <= NOW()
+	RETURNING inventory_item_id`
+
+	rows, err := tx.QueryContext(ctxt, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var itemIDs []int64
+
+	for rows.Next() {
+		var itemID sql.NullInt64
+
+		if err = rows.Scan(&itemID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		if itemID.Valid {
+			itemIDs = append(itemIDs, itemID.Int64)
+		}
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ready := []*Hold{}
+
+	for _, itemID := range itemIDs {
+		next, err := assignCopy(ctxt, tx, itemID)
+		if err != nil {
+			return nil, err
+		}
+
+		if next != nil {
+			ready = append(ready, next)
+		}
+	}
+
+	return ready, tx.Commit()
+}
+
+// Lock the queue of the holds on the movie at the store until the end of the transaction. Placing a hold
+// and passing on a copy take the lock, so that a hold is never left waiting while the copy becomes available.
+// The ids are folded into the two keys of the lock, as a clash only makes unrelated queues wait on each other
+func lockHoldQueue(ctxt context.Context, tx Tx, movieID, storeID int64) error {
+
+	query := `SELECT pg_advisory_xact_lock(($1 % 2147483648)::integer, ($2 % 2147483648)::integer)`
+
+	_, err := tx.ExecContext(ctxt, query, movieID, storeID)
+	return err
+}
+
+// Set aside a copy which has been returned or released for the next waiting hold, in the order the holds
+// were placed. If no hold is waiting for the copy, it becomes available. The hold which became ready,
+// if any, is returned.
+func assignCopy(ctxt context.Context, tx Tx, itemID int64) (*Hold, error) {
+
+	// Lock the copy
+	var item InventoryItem
+
+	query := `SELECT id, movie_id, store_id, format
+	FROM inventory_items
+	WHERE id = $1
+	FOR UPDATE`
+
+	err := tx.QueryRowContext(ctxt, query, itemID).Scan(&item.ID, &item.MovieID, &item.StoreID, &item.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = lockHoldQueue(ctxt, tx, item.MovieID, item.StoreID); err != nil {
+		return nil, err
+	}
+
+	// Lock the whole queue in its order, waiting for the holds locked by other transactions. Skipping them,
+	// or locking only the head of the queue, could hand the copy to a later hold while an earlier one is
+	// locked: a head which is no longer waiting once its lock is released is left out, and the next one taken
+	var holdID int64
+
+	query = `SELECT id FROM holds
+	WHERE movie_id = $1 AND store_id = $2 AND status = 'waiting' AND (format = $3 OR format = '')
+	ORDER BY created_at, id
+	FOR UPDATE`
+
+	rows, err := tx.QueryContext(ctxt, query, item.MovieID, item.StoreID, item.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	if rows.Next() {
+		err = rows.Scan(&holdID)
+	}
+
+	rows.Close()
+
+	if err == nil {
+		err = rows.Err()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Make the next waiting hold ready
+	query = `UPDATE holds
+	SET status = 'ready', inventory_item_id = $1, ready_at = NOW(), expires_at = NOW() + make_interval(secs => $2),
+	version = version + 1
+	WHERE id = $3
+	RETURNING id, created_at, user_id, movie_id, store_id, format, status, inventory_item_id, ready_at, expires_at, version`
+
+	args := []interface{}{item.ID, int64(HoldPickupWindow.Seconds()), holdID}
+
+	var hold Hold
+
+	err = tx.QueryRowContext(ctxt, query, args...).Scan(&hold.ID, &hold.CreatedAt, &hold.UserID, &hold.MovieID,
+		&hold.StoreID, &hold.Format, &hold.Status, &hold.InventoryItemID, &hold.ReadyAt, &hold.ExpiresAt, &hold.Version)
+
+	status := InventoryOnHold
+	next := &hold
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		status = InventoryAvailable
+		next = nil
+	case err != nil:
+		return nil, err
+	}
+
+	query = `UPDATE inventory_items
+	SET status = $1, version = version + 1
+	WHERE id = $2`
+
+	_, err = tx.ExecContext(ctxt, query, status, item.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
+
+// Claim the copy set aside for the ready hold of the user, as part of a checkout of the format.
+// Zero is returned if the user has no ready hold for the movie at the store.
+func claimHeldCopy(ctxt context.Context, tx Tx, userID, movieID, storeID int64, format string) (int64, error) {
+
+	query := `UPDATE holds h
+	SET status = 'fulfilled', version = h.version + 1
+	FROM inventory_items i
+	WHERE i.id = h.inventory_item_id AND (i.format = $4 OR $4 = '')
+	AND h.user_id = $1 AND h.movie_id = $2 AND h.store_id = $3 AND h.status = 'ready' AND h.expires_at > NOW()
+	RETURNING h.inventory_item_id`
+
+	var itemID int64
+
+	err := tx.QueryRowContext(ctxt, query, userID, movieID, storeID, format).Scan(&itemID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return 0, nil
+	case err != nil:
+		return 0, err
+	}
+
+	return itemID, nil
+}
+
+func ValidateHold(val *validator.Validator, hold *Hold) {
+
+	val.Check(hold.MovieID > 0, "movie_id", "must be provided")
+	val.Check(hold.StoreID > 0, "store_id", "must be provided")
+
+	// Format is optional
+	if hold.Format != "" {
+		val.Check(validator.Permittedvalues(hold.Format, InventoryFormats...), "format", "invalid format value")
+	}
+}
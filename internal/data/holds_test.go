@@ -0,0 +1,311 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+func TestValidateHold(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		hold   Hold
+		errors map[string]string
+	}{
+		{"any format", Hold{MovieID: 1, StoreID: 1}, map[string]string{}},
+		{"given format", Hold{MovieID: 1, StoreID: 1, Format: "blu-ray"}, map[string]string{}},
+		{"missing movie", Hold{StoreID: 1}, map[string]string{"movie_id": "must be provided"}},
+		{"missing store", Hold{MovieID: 1}, map[string]string{"store_id": "must be provided"}},
+		{"unknown format", Hold{MovieID: 1, StoreID: 1, Format: "laserdisc"}, map[string]string{"format": "invalid format value"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			val := validator.NewValidator()
+			ValidateHold(val, &tt.hold)
+
+			if !reflect.DeepEqual(val.Errors, tt.errors) {
+				t.Errorf("got errors %v; want %v", val.Errors, tt.errors)
+			}
+		})
+	}
+}
+
+// Movie rented out from a store with its only copy, along with the rental of the copy
+type holdFixture struct {
+	models Models
+	users  []*User
+	movie  *Movies
+	store  *Store
+	rental *Rental
+}
+
+// Rent out the only copy of a movie and have each of the other users place a hold on it, in order
+func newHoldFixture(t *testing.T, holders int) holdFixture {
+
+	t.Helper()
+
+	ctxt := context.Background()
+	models := newTestModels(t)
+
+	fixture := holdFixture{models: models}
+
+	tenantID := addTestTenant(t, models, "holds")
+
+	for i := 0; i <= holders; i++ {
+		fixture.users = append(fixture.users, addTestUser(t, models, tenantID, fmt.Sprintf("user%d@example.com", i)))
+	}
+
+	fixture.movie = addTestMovie(t, models, tenantID, &Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"horror"}})
+	fixture.store = addTestStore(t, models, tenantID, "Main Street")
+
+	addTestCopy(t, models, fixture.movie.ID, fixture.store.ID, "A-1", InventoryAvailable)
+
+	rental, err := models.Rentals.Checkout(ctxt, fixture.users[0].ID, fixture.movie.ID, fixture.store.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixture.rental = rental
+
+	for _, user := range fixture.users[1:] {
+		hold := &Hold{UserID: user.ID, MovieID: fixture.movie.ID, StoreID: fixture.store.ID}
+
+		if err = models.Holds.Insert(ctxt, hold); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return fixture
+}
+
+// Get the status of the open hold of the user, or an empty status if the user has none
+func (fixture holdFixture) holdStatus(t *testing.T, user *User) string {
+
+	t.Helper()
+
+	holds, err := fixture.models.Holds.GetAllForUser(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(holds) == 0 {
+		return ""
+	}
+
+	return holds[0].Status
+}
+
+func TestHoldQueueOrder(t *testing.T) {
+
+	fixture := newHoldFixture(t, 2)
+	first, second := fixture.users[1], fixture.users[2]
+
+	holds, err := fixture.models.Holds.GetAllForUser(context.Background(), second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(holds) != 1 || holds[0].Position != 2 {
+		t.Fatalf("got %d holds; want one hold at position 2", len(holds))
+	}
+
+	// The copy returned goes to the older hold
+	ready, err := fixture.models.Rentals.Return(context.Background(), fixture.rental)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ready == nil || ready.UserID != first.ID {
+		t.Fatalf("got ready hold %+v; want the hold of the first user", ready)
+	}
+
+	if got := fixture.holdStatus(t, first); got != HoldReady {
+		t.Errorf("first hold: got status %q; want %q", got, HoldReady)
+	}
+
+	if got := fixture.holdStatus(t, second); got != HoldWaiting {
+		t.Errorf("second hold: got status %q; want %q", got, HoldWaiting)
+	}
+
+	item, err := fixture.models.Inventory.Get(context.Background(), fixture.rental.InventoryItemID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if item.Status != InventoryOnHold {
+		t.Errorf("copy: got status %q; want %q", item.Status, InventoryOnHold)
+	}
+}
+
+func TestHoldQueueLockedHead(t *testing.T) {
+
+	fixture := newHoldFixture(t, 2)
+	first := fixture.users[1]
+
+	// Another transaction holds a lock on the head of the queue while the copy is returned
+	tx, err := fixture.models.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Exec("SELECT id FROM holds WHERE user_id = $1 FOR UPDATE", first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		hold *Hold
+		err  error
+	}
+
+	done := make(chan result, 1)
+
+	go func() {
+		hold, err := fixture.models.Rentals.Return(context.Background(), fixture.rental)
+		done <- result{hold, err}
+	}()
+
+	// The return waits for the head of the queue instead of passing it over
+	select {
+	case res := <-done:
+		t.Fatalf("return did not wait for the locked hold: got ready hold %+v, error %v", res.hold, res.err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+
+	if res.hold == nil || res.hold.UserID != first.ID {
+		t.Errorf("got ready hold %+v; want the hold of the first user", res.hold)
+	}
+}
+
+func TestHoldPlacedDuringReturn(t *testing.T) {
+
+	fixture := newHoldFixture(t, 0)
+	renter := fixture.users[0]
+
+	returning := make(chan struct{})
+	finish := make(chan struct{})
+	returned := make(chan error, 1)
+
+	// The copy is returned in a unit of work which is held open, with no hold yet waiting for it
+	go func() {
+		returned <- fixture.models.WithTxOptions(context.Background(), nil, func(tx Models) error {
+
+			if _, err := tx.Rentals.Return(context.Background(), fixture.rental); err != nil {
+				return err
+			}
+
+			close(returning)
+			<-finish
+
+			return nil
+		})
+	}()
+
+	select {
+	case <-returning:
+	case err := <-returned:
+		t.Fatal(err)
+	}
+
+	type result struct {
+		hold *Hold
+		err  error
+	}
+
+	placed := make(chan result, 1)
+
+	go func() {
+		hold := &Hold{UserID: renter.ID, MovieID: fixture.movie.ID, StoreID: fixture.store.ID}
+		placed <- result{hold, fixture.models.Holds.Insert(context.Background(), hold)}
+	}()
+
+	// The hold waits for the return instead of queueing behind a copy which is about to be available
+	select {
+	case res := <-placed:
+		t.Fatalf("hold did not wait for the return: got hold %+v, error %v", res.hold, res.err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	close(finish)
+
+	if err := <-returned; err != nil {
+		t.Fatal(err)
+	}
+
+	if res := <-placed; !errors.Is(res.err, ErrCopyAvailable) {
+		t.Errorf("got hold %+v, error %v; want %v", res.hold, res.err, ErrCopyAvailable)
+	}
+}
+
+func TestHoldExpiryPromotesNext(t *testing.T) {
+
+	fixture := newHoldFixture(t, 2)
+	first, second := fixture.users[1], fixture.users[2]
+
+	ctxt := context.Background()
+
+	if _, err := fixture.models.Rentals.Return(ctxt, fixture.rental); err != nil {
+		t.Fatal(err)
+	}
+
+	// The pickup window of the ready hold ends
+	_, err := fixture.models.db.Exec("UPDATE holds SET expires_at = NOW() - INTERVAL '1 minute' WHERE user_id = $1", first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ready, err := fixture.models.Holds.ExpireReady(ctxt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ready) != 1 || ready[0].UserID != second.ID {
+		t.Fatalf("got %d ready holds; want the hold of the second user", len(ready))
+	}
+
+	if got := fixture.holdStatus(t, first); got != "" {
+		t.Errorf("first hold: got status %q; want the hold closed", got)
+	}
+
+	if got := fixture.holdStatus(t, second); got != HoldReady {
+		t.Errorf("second hold: got status %q; want %q", got, HoldReady)
+	}
+
+	// With no hold left waiting, the copy becomes available once the second hold expires too
+	_, err = fixture.models.db.Exec("UPDATE holds SET expires_at = NOW() - INTERVAL '1 minute' WHERE user_id = $1", second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ready, err = fixture.models.Holds.ExpireReady(ctxt); err != nil || len(ready) != 0 {
+		t.Fatalf("got %d ready holds, error %v; want none", len(ready), err)
+	}
+
+	item, err := fixture.models.Inventory.Get(ctxt, fixture.rental.InventoryItemID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if item.Status != InventoryAvailable {
+		t.Errorf("copy: got status %q; want %q", item.Status, InventoryAvailable)
+	}
+}
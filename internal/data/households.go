@@ -0,0 +1,137 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+var (
+	ErrRentalLimitReached = errors.New("household rental limit reached")
+)
+
+// Household sharing a rental membership. Every user belongs to a household, which is created along
+// with the user. The rentals of all the members count towards the limit of the household
+type Household struct {
+	ID               int64     `json:"id"`
+	CreatedAt        time.Time `json:"created_at"`
+	Name             string    `json:"name"`
+	MaxActiveRentals int32     `json:"max_active_rentals"`
+	ActiveRentals    int32     `json:"active_rentals"` // Read only
+	Version          int32     `json:"info_version"`
+}
+
+// Households of a tenant
+type HouseholdModel struct {
+	DB       DBTX
+	TenantID int64
+}
+
+func (m HouseholdModel) Get(ctxt context.Context, id int64) (*Household, error) {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT h.id, h.created_at, h.name, h.max_active_rentals,
+	(SELECT count(*) FROM rentals r INNER JOIN users u ON u.id = r.user_id
+		WHERE u.household_id = h.id AND r.returned_at IS NULL), h.version
+	FROM households h
+	WHERE h.id = $1 AND h.tenant_id = $2`
+
+	var household Household
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "households.get")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, id, m.TenantID).Scan(&household.ID, &household.CreatedAt, &household.Name,
+		&household.MaxActiveRentals, &household.ActiveRentals, &household.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &household, nil
+}
+
+func (m HouseholdModel) Update(ctxt context.Context, household *Household) error {
+
+	query := `UPDATE households
+	SET name = $1, max_active_rentals = $2, version = version + 1
+	WHERE id = $3 AND version = $4 AND tenant_id = $5
+	RETURNING version`
+
+	args := []interface{}{household.Name, household.MaxActiveRentals, household.ID, household.Version, m.TenantID}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "households.update")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, args...).Scan(&household.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Check, as part of the checkout transaction, that the household of the user may rent out another
+// copy. The household is locked so that concurrent checkouts of its members cannot both take the
+// last rental left
+func checkHouseholdRentalLimit(ctxt context.Context, tx Tx, userID int64) error {
+
+	query := `SELECT h.id, h.max_active_rentals
+	FROM households h
+	INNER JOIN users u ON u.household_id = h.id
+	WHERE u.id = $1
+	FOR UPDATE OF h`
+
+	var householdID int64
+	var maxActiveRentals int32
+
+	err := tx.QueryRowContext(ctxt, query, userID).Scan(&householdID, &maxActiveRentals)
+	if err != nil {
+		return err
+	}
+
+	query = `SELECT count(*)
+	FROM rentals r
+	INNER JOIN users u ON u.id = r.user_id
+	WHERE u.household_id = $1 AND r.returned_at IS NULL`
+
+	var activeRentals int32
+
+	err = tx.QueryRowContext(ctxt, query, householdID).Scan(&activeRentals)
+	if err != nil {
+		return err
+	}
+
+	if activeRentals >= maxActiveRentals {
+		return ErrRentalLimitReached
+	}
+
+	return nil
+}
+
+func ValidateHousehold(val *validator.Validator, household *Household) {
+
+	val.Check(household.Name != "", "name", "must be provided")
+	val.Check(len(household.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	val.Check(household.MaxActiveRentals >= 1, "max_active_rentals", "must be atleast 1")
+	val.Check(household.MaxActiveRentals <= 100, "max_active_rentals", "must not be more than 100")
+}
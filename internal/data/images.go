@@ -0,0 +1,166 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Kinds of the artwork of a movie
+var ImageKinds = []string{"poster", "still"}
+
+// Artwork of a movie. The image and its thumbnail are kept in the object storage under the keys
+type MovieImage struct {
+	ID           int64     `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	MovieID      int64     `json:"movie_id"`
+	Kind         string    `json:"kind"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	Size         int64     `json:"size"`
+	StorageKey   string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+}
+
+// Fill in the URLs at which the image and its thumbnail are served
+func (image *MovieImage) setURLs() {
+	image.URL = fmt.Sprintf("/v1/images/%d", image.ID)
+	image.ThumbnailURL = fmt.Sprintf("/v1/images/%d/thumbnail", image.ID)
+}
+
+type MovieImageModel struct {
+	DB DBTX
+}
+
+func (m MovieImageModel) Insert(ctxt context.Context, image *MovieImage) error {
+
+	query := `INSERT INTO movie_images (movie_id, kind, content_type, width, height, size, storage_key, thumbnail_key)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id, created_at`
+
+	args := []interface{}{image.MovieID, image.Kind, image.ContentType, image.Width, image.Height, image.Size,
+		image.StorageKey, image.ThumbnailKey}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "images.insert")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, args...).Scan(&image.ID, &image.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	image.setURLs()
+
+	return nil
+}
+
+func (m MovieImageModel) Get(ctxt context.Context, id int64) (*MovieImage, error) {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT id, created_at, movie_id, kind, content_type, width, height, size, storage_key, thumbnail_key
+	FROM movie_images
+	WHERE id = $1`
+
+	var image MovieImage
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "images.get")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, id).Scan(&image.ID, &image.CreatedAt, &image.MovieID, &image.Kind,
+		&image.ContentType, &image.Width, &image.Height, &image.Size, &image.StorageKey, &image.ThumbnailKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	image.setURLs()
+
+	return &image, nil
+}
+
+// Get the images of a movie, posters first and then the stills, oldest first
+func (m MovieImageModel) GetAllForMovie(ctxt context.Context, movieID int64) ([]*MovieImage, error) {
+
+	query := `SELECT id, created_at, movie_id, kind, content_type, width, height, size, storage_key, thumbnail_key
+	FROM movie_images
+	WHERE movie_id = $1
+	ORDER BY kind, id`
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "images.get_all_for_movie")
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctxt, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	images := []*MovieImage{}
+
+	for rows.Next() {
+		var image MovieImage
+
+		err = rows.Scan(&image.ID, &image.CreatedAt, &image.MovieID, &image.Kind, &image.ContentType,
+			&image.Width, &image.Height, &image.Size, &image.StorageKey, &image.ThumbnailKey)
+		if err != nil {
+			return nil, err
+		}
+
+		image.setURLs()
+
+		images = append(images, &image)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+func (m MovieImageModel) Delete(ctxt context.Context, id int64) error {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM movie_images WHERE id = $1`
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "images.delete")
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctxt, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
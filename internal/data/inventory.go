@@ -0,0 +1,269 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+var (
+	ErrDuplicateBarcode = errors.New("duplicate barcode")
+)
+
+// Formats, conditions and statuses of the rentable copies
+var (
+	InventoryFormats    = []string{"dvd", "blu-ray", "4k"}
+	InventoryConditions = []string{"new", "good", "fair", "poor", "damaged"}
+	InventoryStatuses   = []string{InventoryAvailable, InventoryRented, InventoryOnHold, InventoryRetired}
+)
+
+const (
+	InventoryAvailable = "available"
+	InventoryRented    = "rented"
+	InventoryOnHold    = "on_hold" // Set aside for a ready hold
+	InventoryRetired   = "retired"
+)
+
+// A physical, rentable copy of a movie held by a store
+type InventoryItem struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	MovieID   int64     `json:"movie_id"`
+	StoreID   int64     `json:"store_id"`
+	Format    string    `json:"format"`
+	Condition string    `json:"condition"`
+	Barcode   string    `json:"barcode"`
+	Status    string    `json:"status"`
+	Version   int32     `json:"info_version"`
+}
+
+// Number of copies of a movie in a store, per format
+type Availability struct {
+	StoreID   int64  `json:"store_id"`
+	StoreName string `json:"store_name"`
+	Format    string `json:"format"`
+	Available int32  `json:"available"`
+	Total     int32  `json:"total"`
+}
+
+type InventoryModel struct {
+	DB DBTX
+}
+
+func (m InventoryModel) Insert(ctxt context.Context, item *InventoryItem) error {
+
+	query := `INSERT INTO inventory_items (movie_id, store_id, format, condition, barcode, status)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at, version`
+
+	args := []interface{}{item.MovieID, item.StoreID, item.Format, item.Condition, item.Barcode, item.Status}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "inventory.insert")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, args...).Scan(&item.ID, &item.CreatedAt, &item.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "inventory_items_barcode_key"`:
+			return ErrDuplicateBarcode
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m InventoryModel) Get(ctxt context.Context, id int64) (*InventoryItem, error) {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT id, created_at, movie_id, store_id, format, condition, barcode, status, version
+	FROM inventory_items
+	WHERE id = $1`
+
+	var item InventoryItem
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "inventory.get")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, id).Scan(&item.ID, &item.CreatedAt, &item.MovieID, &item.StoreID,
+		&item.Format, &item.Condition, &item.Barcode, &item.Status, &item.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &item, nil
+}
+
+func (m InventoryModel) Update(ctxt context.Context, item *InventoryItem) error {
+
+	query := `UPDATE inventory_items
+	SET store_id = $1, format = $2, condition = $3, barcode = $4, status = $5, version = version + 1
+	WHERE id = $6 AND version = $7
+	RETURNING version`
+
+	args := []interface{}{item.StoreID, item.Format, item.Condition, item.Barcode, item.Status, item.ID, item.Version}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "inventory.update")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, args...).Scan(&item.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "inventory_items_barcode_key"`:
+			return ErrDuplicateBarcode
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m InventoryModel) Delete(ctxt context.Context, id int64) error {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := "DELETE FROM inventory_items WHERE id = $1"
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "inventory.delete")
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctxt, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Get the copies of a movie, optionally only the ones held by the given store
+func (m InventoryModel) GetAllForMovie(ctxt context.Context, movieID, storeID int64) ([]*InventoryItem, error) {
+
+	query := `SELECT id, created_at, movie_id, store_id, format, condition, barcode, status, version
+	FROM inventory_items
+	WHERE movie_id = $1 AND (store_id = $2 OR $2 = 0)
+	ORDER BY store_id, format, id`
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "inventory.get_all_for_movie")
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctxt, query, movieID, storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	items := []*InventoryItem{}
+
+	for rows.Next() {
+		var item InventoryItem
+
+		err = rows.Scan(&item.ID, &item.CreatedAt, &item.MovieID, &item.StoreID, &item.Format,
+			&item.Condition, &item.Barcode, &item.Status, &item.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, &item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// Get the number of available and total copies of a movie per store and format.
+// Retired copies are not counted.
+func (m InventoryModel) GetAvailability(ctxt context.Context, movieID int64) ([]*Availability, error) {
+
+	query := `
+		SELECT s.id, s.name, i.format, count(*) FILTER (WHERE i.status = 'available'), count(*)
+		FROM inventory_items i
+		INNER JOIN stores s ON s.id = i.store_id
+		WHERE i.movie_id = $1 AND i.status <> 'retired'
+		GROUP BY s.id, s.name, i.format
+		ORDER BY s.name, s.id, i.format`
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "inventory.get_availability")
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctxt, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	availability := []*Availability{}
+
+	for rows.Next() {
+		var avail Availability
+
+		err = rows.Scan(&avail.StoreID, &avail.StoreName, &avail.Format, &avail.Available, &avail.Total)
+		if err != nil {
+			return nil, err
+		}
+
+		availability = append(availability, &avail)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return availability, nil
+}
+
+func ValidateInventoryItem(val *validator.Validator, item *InventoryItem) {
+
+	// Movie and store
+	val.Check(item.MovieID > 0, "movie_id", "must be provided")
+	val.Check(item.StoreID > 0, "store_id", "must be provided")
+
+	// Format and condition
+	val.Check(validator.Permittedvalues(item.Format, InventoryFormats...), "format", "invalid format value")
+	val.Check(validator.Permittedvalues(item.Condition, InventoryConditions...), "condition", "invalid condition value")
+
+	// Barcode
+	val.Check(item.Barcode != "", "barcode", "must be provided")
+	val.Check(len(item.Barcode) <= 100, "barcode", "must not be more than 100 bytes")
+
+	// Status
+	val.Check(validator.Permittedvalues(item.Status, InventoryStatuses...), "status", "invalid status value")
+}
@@ -0,0 +1,42 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+func TestValidateInventoryItem(t *testing.T) {
+
+	valid := InventoryItem{MovieID: 1, StoreID: 1, Format: "blu-ray", Condition: "good", Barcode: "A-1", Status: InventoryAvailable}
+
+	tests := []struct {
+		name   string
+		modify func(item *InventoryItem)
+		errors map[string]string
+	}{
+		{"valid copy", func(item *InventoryItem) {}, map[string]string{}},
+		{"missing movie", func(item *InventoryItem) { item.MovieID = 0 }, map[string]string{"movie_id": "must be provided"}},
+		{"missing store", func(item *InventoryItem) { item.StoreID = 0 }, map[string]string{"store_id": "must be provided"}},
+		{"unknown format", func(item *InventoryItem) { item.Format = "vhs" }, map[string]string{"format": "invalid format value"}},
+		{"unknown condition", func(item *InventoryItem) { item.Condition = "mint" }, map[string]string{"condition": "invalid condition value"}},
+		{"missing barcode", func(item *InventoryItem) { item.Barcode = "" }, map[string]string{"barcode": "must be provided"}},
+		{"unknown status", func(item *InventoryItem) { item.Status = "lost" }, map[string]string{"status": "invalid status value"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			item := valid
+			tt.modify(&item)
+
+			val := validator.NewValidator()
+			ValidateInventoryItem(val, &item)
+
+			if !reflect.DeepEqual(val.Errors, tt.errors) {
+				t.Errorf("got errors %v; want %v", val.Errors, tt.errors)
+			}
+		})
+	}
+}
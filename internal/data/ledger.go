@@ -0,0 +1,158 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/pricing"
+)
+
+// Kinds of the ledger entries. Charges are positive amounts, payments and credits are negative
+const (
+	LedgerRental     = "rental"
+	LedgerRenewal    = "renewal"
+	LedgerLateFee    = "late_fee"
+	LedgerPayment    = "payment"
+	LedgerAdjustment = "adjustment"
+)
+
+// An entry of the balance ledger of a user. Amounts are in the minor units of the currency
+type LedgerEntry struct {
+	ID          int64         `json:"id"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UserID      int64         `json:"user_id"`
+	RentalID    *int64        `json:"rental_id,omitempty"`
+	Kind        string        `json:"kind"`
+	Amount      pricing.Money `json:"amount"`
+	Currency    string        `json:"currency"`
+	Description string        `json:"description,omitempty"`
+}
+
+// Balance of a user, or of all the members of a household. A positive amount is owed
+type Balance struct {
+	UserID      int64         `json:"user_id,omitempty"`
+	HouseholdID int64         `json:"household_id,omitempty"`
+	Amount      pricing.Money `json:"amount"`
+	Currency    string        `json:"currency"`
+}
+
+// Common interface of DBTX and Tx, so that ledger entries can be
+// recorded in the same transaction as the operation being charged for
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type LedgerModel struct {
+	DB DBTX
+}
+
+// Record an entry in the ledger
+func insertLedgerEntry(ctxt context.Context, q queryer, entry *LedgerEntry) error {
+
+	query := `INSERT INTO ledger_entries (user_id, rental_id, kind, amount, currency, description)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at`
+
+	args := []interface{}{entry.UserID, entry.RentalID, entry.Kind, entry.Amount, entry.Currency, entry.Description}
+
+	return q.QueryRowContext(ctxt, query, args...).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+func (m LedgerModel) Insert(ctxt context.Context, entry *LedgerEntry) error {
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "ledger.insert")
+	defer cancel()
+
+	return insertLedgerEntry(ctxt, m.DB, entry)
+}
+
+// Get the balance of the user in the given currency
+func (m LedgerModel) GetBalance(ctxt context.Context, userID int64, currency string) (*Balance, error) {
+
+	query := `SELECT COALESCE(sum(amount), 0)
+	FROM ledger_entries
+	WHERE user_id = $1 AND currency = $2`
+
+	balance := Balance{UserID: userID, Currency: currency}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "ledger.get_balance")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, userID, currency).Scan(&balance.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return &balance, nil
+}
+
+// Get the balance of all the members of the household in the given currency
+func (m LedgerModel) GetHouseholdBalance(ctxt context.Context, householdID int64, currency string) (*Balance, error) {
+
+	query := `SELECT COALESCE(sum(l.amount), 0)
+	FROM ledger_entries l
+	INNER JOIN users u ON u.id = l.user_id
+	WHERE u.household_id = $1 AND l.currency = $2`
+
+	balance := Balance{HouseholdID: householdID, Currency: currency}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "ledger.get_household_balance")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, householdID, currency).Scan(&balance.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return &balance, nil
+}
+
+// Get the ledger entries of the user
+func (m LedgerModel) GetAllForUser(ctxt context.Context, userID int64, filters Filters) ([]*LedgerEntry, Metadata, error) {
+
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, created_at, user_id, rental_id, kind, amount, currency, description
+		FROM ledger_entries
+		WHERE user_id = $1
+		ORDER BY %s %s, id
+		LIMIT $2 OFFSET $3`, filters.getSortColumn(), filters.getSortDirection())
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "ledger.get_all_for_user")
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctxt, query, userID, filters.getLimit(), filters.getOffset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	entries := []*LedgerEntry{}
+	totalRecords := 0
+
+	for rows.Next() {
+		var entry LedgerEntry
+
+		err = rows.Scan(&totalRecords, &entry.ID, &entry.CreatedAt, &entry.UserID, &entry.RentalID,
+			&entry.Kind, &entry.Amount, &entry.Currency, &entry.Description)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return entries, metadata, nil
+}
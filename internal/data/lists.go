@@ -0,0 +1,394 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+var (
+	ErrDuplicateListItem = errors.New("duplicate list item")
+)
+
+// A list of movies kept by a user e.g. a watchlist, either for one of the profiles of the household
+// or for the user's own use. Private lists are only visible to their owner
+type List struct {
+	ID          int64     `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UserID      int64     `json:"user_id"`
+	ProfileID   int64     `json:"profile_id,omitempty"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Public      bool      `json:"public"`
+	ItemCount   int32     `json:"item_count"` // Read only
+	Version     int32     `json:"info_version"`
+}
+
+// Lists of the users. Only the lists of the users of the tenant are read by Get, and the movies of the
+// lists restricted by the parental limit, if any, are left out of their items
+type ListModel struct {
+	DB       DBTX
+	TenantID int64
+	Limit    *ParentalLimit
+}
+
+func (m ListModel) Insert(ctxt context.Context, list *List) error {
+
+	query := `INSERT INTO lists (user_id, profile_id, name, description, public)
+	VALUES ($1, NULLIF($2::bigint, 0), $3, $4, $5)
+	RETURNING id, created_at, version`
+
+	args := []interface{}{list.UserID, list.ProfileID, list.Name, list.Description, list.Public}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "lists.insert")
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctxt, query, args...).Scan(&list.ID, &list.CreatedAt, &list.Version)
+}
+
+func (m ListModel) Get(ctxt context.Context, id int64) (*List, error) {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT l.id, l.created_at, l.user_id, COALESCE(l.profile_id, 0), l.name, l.description, l.public,
+	(SELECT count(*) FROM list_items WHERE list_id = l.id), l.version
+	FROM lists l
+	INNER JOIN users u ON u.id = l.user_id
+	WHERE l.id = $1 AND u.tenant_id = $2`
+
+	var list List
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "lists.get")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, id, m.TenantID).Scan(&list.ID, &list.CreatedAt, &list.UserID, &list.ProfileID,
+		&list.Name, &list.Description, &list.Public, &list.ItemCount, &list.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &list, nil
+}
+
+// Get the lists of a user for the profile. A profile ID of zero gets the user's own lists
+func (m ListModel) GetAllForUser(ctxt context.Context, userID, profileID int64, filters Filters) ([]*List, Metadata, error) {
+
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), l.id, l.created_at, l.user_id, COALESCE(l.profile_id, 0), l.name, l.description, l.public,
+		(SELECT count(*) FROM list_items WHERE list_id = l.id), l.version
+		FROM lists l
+		WHERE l.user_id = $1 AND COALESCE(l.profile_id, 0) = $2
+		ORDER BY l.%s %s, l.id
+		LIMIT $3 OFFSET $4`, filters.getSortColumn(), filters.getSortDirection())
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "lists.get_all_for_user")
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctxt, query, userID, profileID, filters.getLimit(), filters.getOffset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	lists := []*List{}
+	totalRecords := 0
+
+	for rows.Next() {
+		var list List
+
+		err = rows.Scan(&totalRecords, &list.ID, &list.CreatedAt, &list.UserID, &list.ProfileID, &list.Name, &list.Description,
+			&list.Public, &list.ItemCount, &list.Version)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		lists = append(lists, &list)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return lists, metadata, nil
+}
+
+func (m ListModel) Update(ctxt context.Context, list *List) error {
+
+	query := `UPDATE lists
+	SET name = $1, description = $2, public = $3, version = version + 1
+	WHERE id = $4 AND version = $5
+	RETURNING version`
+
+	args := []interface{}{list.Name, list.Description, list.Public, list.ID, list.Version}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "lists.update")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, args...).Scan(&list.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m ListModel) Delete(ctxt context.Context, id int64) error {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM lists WHERE id = $1`
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "lists.delete")
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctxt, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Get the movies of the list, ordered by their position unless sorted otherwise.
+// Only the fields requested in the sparse fieldset of the filters are populated
+func (m ListModel) GetItems(ctxt context.Context, listID int64, filters Filters) ([]*Movies, Metadata, error) {
+
+	columns, _ := (&Movies{}).projection(filters.Fields)
+
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), %s
+		FROM list_items li
+		INNER JOIN %s ON movies.id = li.movie_id
+		WHERE li.list_id = $1
+		AND (movies.certifications->>$4 = ANY($5::text[]) OR $5 IS NULL)
+		ORDER BY %s %s, li.position, id
+		LIMIT $2 OFFSET $3`, strings.Join(columns, ", "), movieSource, filters.getSortColumn(), filters.getSortDirection())
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "lists.get_items")
+	defer cancel()
+
+	limitCountry, permitted := parentalLimitArgs(m.Limit)
+
+	rows, err := m.DB.QueryContext(ctxt, query, listID, filters.getLimit(), filters.getOffset(), limitCountry, permitted)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	movies := []*Movies{}
+	totalRecords := 0
+
+	for rows.Next() {
+		var movie Movies
+
+		_, dest := movie.projection(filters.Fields)
+
+		if err = rows.Scan(append([]interface{}{&totalRecords}, dest...)...); err != nil {
+			return nil, Metadata{}, err
+		}
+
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return movies, metadata, nil
+}
+
+// Add a movie to the list at the given position, moving the later movies down.
+// A position of zero, or past the end of the list, adds the movie at the end
+func (m ListModel) AddItem(ctxt context.Context, listID, movieID int64, position int32) error {
+
+	return m.withLockedList(ctxt, listID, func(ctxt context.Context, tx Tx, count int32) error {
+
+		if position < 1 || position > count+1 {
+			position = count + 1
+		}
+
+		query := `INSERT INTO list_items (list_id, movie_id, position)
+		VALUES ($1, $2, $3)`
+
+		_, err := tx.ExecContext(ctxt, query, listID, movieID, position)
+		if err != nil {
+			switch {
+			case err.Error() == `pq: duplicate key value violates unique constraint "list_items_pkey"`:
+				return ErrDuplicateListItem
+			case isForeignKeyViolation(err):
+				return ErrRecordNotFound
+			default:
+				return err
+			}
+		}
+
+		return renumberListItems(ctxt, tx, listID, movieID, position)
+	})
+}
+
+// Move a movie of the list to the given position. A position past the end of the list moves the movie to the end
+func (m ListModel) MoveItem(ctxt context.Context, listID, movieID int64, position int32) error {
+
+	return m.withLockedList(ctxt, listID, func(ctxt context.Context, tx Tx, count int32) error {
+
+		if position > count {
+			position = count
+		}
+
+		query := `UPDATE list_items
+		SET position = $1
+		WHERE list_id = $2 AND movie_id = $3`
+
+		res, err := tx.ExecContext(ctxt, query, position, listID, movieID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+
+		return renumberListItems(ctxt, tx, listID, movieID, position)
+	})
+}
+
+// Remove a movie from the list, moving the later movies up
+func (m ListModel) RemoveItem(ctxt context.Context, listID, movieID int64) error {
+
+	return m.withLockedList(ctxt, listID, func(ctxt context.Context, tx Tx, count int32) error {
+
+		query := `DELETE FROM list_items WHERE list_id = $1 AND movie_id = $2`
+
+		res, err := tx.ExecContext(ctxt, query, listID, movieID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+
+		return renumberListItems(ctxt, tx, listID, 0, 0)
+	})
+}
+
+// Run the function in a transaction holding a lock on the list, so that concurrent changes
+// to the order of the list are serialised. The function is passed the number of movies in the list
+func (m ListModel) withLockedList(ctxt context.Context, listID int64, fn func(context.Context, Tx, int32) error) error {
+
+	// Create a DB context to timeout the queries if they exceed a certian duration
+	ctxt, cancel := withTimeout(ctxt, "lists.with_locked_list")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctxt, nil)
+	if err != nil {
+		return err
+	}
+
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	query := `SELECT id FROM lists WHERE id = $1 FOR UPDATE`
+
+	err = tx.QueryRowContext(ctxt, query, listID).Scan(&listID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	var count int32
+
+	query = `SELECT count(*) FROM list_items WHERE list_id = $1`
+
+	if err = tx.QueryRowContext(ctxt, query, listID).Scan(&count); err != nil {
+		return err
+	}
+
+	if err = fn(ctxt, tx, count); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Number the movies of the list from 1 in their current order, keeping the given movie at the given position.
+// This closes the gaps left by removed movies and makes room for the placed movie
+func renumberListItems(ctxt context.Context, tx Tx, listID, movieID int64, position int32) error {
+
+	query := `WITH others AS (
+		SELECT movie_id, row_number() OVER (ORDER BY position, added_at, movie_id) AS rn
+		FROM list_items
+		WHERE list_id = $1 AND movie_id <> $2
+	)
+	UPDATE list_items li
+	SET position = CASE WHEN $3::integer > 0 AND o.rn >= $3::integer THEN o.rn + 1 ELSE o.rn END
+	FROM others o
+	WHERE li.list_id = $1 AND li.movie_id = o.movie_id`
+
+	_, err := tx.ExecContext(ctxt, query, listID, movieID, position)
+
+	return err
+}
+
+func ValidateList(val *validator.Validator, list *List) {
+
+	val.Check(list.Name != "", "name", "must be provided")
+	val.Check(len(list.Name) <= 200, "name", "must not be more than 200 bytes")
+	val.Check(len(list.Description) <= validator.MAX_LEN, "description", "must not be more than 500 bytes")
+}
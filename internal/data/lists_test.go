@@ -0,0 +1,143 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestListItemOrder(t *testing.T) {
+
+	models := newTestModels(t)
+	ctxt := context.Background()
+
+	user := addTestUser(t, models, DefaultTenantID, "owner@example.com")
+
+	movies := make(map[string]*Movies)
+	for _, title := range []string{"Alien", "Brazil", "Casablanca", "Dune"} {
+		movies[title] = addTestMovie(t, models, DefaultTenantID, &Movies{Title: title, Year: 1980, Runtime: 100, Genres: []string{"drama"}})
+	}
+
+	list := &List{UserID: user.ID, Name: "Watchlist"}
+	if err := models.Lists.Insert(ctxt, list); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, title := range []string{"Alien", "Brazil", "Casablanca"} {
+		if err := models.Lists.AddItem(ctxt, list.ID, movies[title].ID, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func() error
+		want   []string
+	}{
+		{"insert in the middle", func() error { return models.Lists.AddItem(ctxt, list.ID, movies["Dune"].ID, 2) },
+			[]string{"Alien", "Dune", "Brazil", "Casablanca"}},
+		{"move to the top", func() error { return models.Lists.MoveItem(ctxt, list.ID, movies["Casablanca"].ID, 1) },
+			[]string{"Casablanca", "Alien", "Dune", "Brazil"}},
+		{"move past the end", func() error { return models.Lists.MoveItem(ctxt, list.ID, movies["Alien"].ID, 10) },
+			[]string{"Casablanca", "Dune", "Brazil", "Alien"}},
+		{"remove", func() error { return models.Lists.RemoveItem(ctxt, list.ID, movies["Dune"].ID) },
+			[]string{"Casablanca", "Brazil", "Alien"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			if err := tt.change(); err != nil {
+				t.Fatal(err)
+			}
+
+			items, _, err := models.Lists.GetItems(ctxt, list.ID, Filters{Page: 1, PageSize: 20, Sort: "position", SortList: []string{"position"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, movie := range items {
+				got = append(got, movie.Title)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := models.Lists.AddItem(ctxt, list.ID, movies["Alien"].ID, 0); !errors.Is(err, ErrDuplicateListItem) {
+		t.Errorf("add movie already in the list: got error %v; want %v", err, ErrDuplicateListItem)
+	}
+
+	if err := models.Lists.MoveItem(ctxt, list.ID, movies["Dune"].ID, 1); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("move movie not in the list: got error %v; want %v", err, ErrRecordNotFound)
+	}
+
+	got, err := models.Lists.Get(ctxt, list.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.ItemCount != 3 {
+		t.Errorf("got %d items; want 3", got.ItemCount)
+	}
+}
+
+func TestListGetForTenant(t *testing.T) {
+
+	models := newTestModels(t)
+	ctxt := context.Background()
+
+	otherTenant := addTestTenant(t, models, "other")
+	user := addTestUser(t, models, DefaultTenantID, "owner@example.com")
+
+	list := &List{UserID: user.ID, Name: "Favourites", Public: true}
+	if err := models.Lists.Insert(ctxt, list); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := models.ForTenant(DefaultTenantID).Lists.Get(ctxt, list.ID); err != nil {
+		t.Errorf("got error %v for the tenant of the owner; want the list", err)
+	}
+
+	if _, err := models.ForTenant(otherTenant).Lists.Get(ctxt, list.ID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("got error %v for another tenant; want %v", err, ErrRecordNotFound)
+	}
+}
+
+func TestListItemsParentalLimit(t *testing.T) {
+
+	models := newTestModels(t)
+	ctxt := context.Background()
+
+	user := addTestUser(t, models, DefaultTenantID, "owner@example.com")
+
+	toyStory := addTestMovie(t, models, DefaultTenantID, &Movies{Title: "Toy Story", Year: 1995, Runtime: 81, Genres: []string{"animation"}, Certifications: map[string]string{"US": "G"}})
+	heat := addTestMovie(t, models, DefaultTenantID, &Movies{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"crime"}, Certifications: map[string]string{"US": "R"}})
+
+	list := &List{UserID: user.ID, Name: "Favourites"}
+	if err := models.Lists.Insert(ctxt, list); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, movie := range []*Movies{toyStory, heat} {
+		if err := models.Lists.AddItem(ctxt, list.ID, movie.ID, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	restricted := models.Lists
+	restricted.Limit = &ParentalLimit{Country: "US", MaxCertification: "PG-13"}
+
+	movies, metadata, err := restricted.GetItems(ctxt, list.ID, Filters{Page: 1, PageSize: 20, Sort: "position", SortList: []string{"position"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(movies) != 1 || movies[0].ID != toyStory.ID || metadata.TotalRecords != 1 {
+		t.Errorf("got %d movies of %d; want Toy Story only", len(movies), metadata.TotalRecords)
+	}
+}
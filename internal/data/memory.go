@@ -0,0 +1,808 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Models keeping only the movies, the users, their tokens and profiles and the resources the movie handlers
+// touch in memory, for exercising those handlers without a database. The people, ratings, reviews, rentals,
+// ledger, holds, lists, similarities, recommendations and households are not kept in memory: their models
+// have no database, so any call on them panics
+func NewMemoryModels() Models {
+
+	movies := NewMemoryMovieModel()
+	users := NewMemoryUserModel()
+	stores := NewMemoryStoreModel()
+	credits := NewMemoryCreditModel(movies)
+	collections := NewMemoryCollectionModel()
+
+	// The movies are searched by their credits, copies and collections
+	movies.credits = credits.store
+	movies.copies = stores.store
+	movies.collections = collections.store
+
+	return Models{
+		Movies:      movies,
+		Users:       users,
+		Tokens:      NewMemoryTokenModel(users),
+		Credits:     credits,
+		Genres:      NewMemoryGenreModel(movies),
+		Permissions: NewMemoryPermissionModel(),
+		Stores:      stores,
+		Inventory:   NewMemoryInventoryModel(stores),
+		Images:      NewMemoryMovieImageModel(),
+		Tenants:     NewMemoryTenantModel(),
+		Collections: collections,
+		Popularity:  NewMemoryPopularityModel(movies),
+		Profiles:    NewMemoryProfileModel(),
+	}
+}
+
+// Movie as stored in memory, along with the columns which are not part of the movie
+type memoryMovie struct {
+	tenantID   int64
+	movie      Movies
+	enriched   bool
+	externalID string
+
+	enrichFailures int32
+	enrichRetryAt  time.Time
+}
+
+type memoryMovieStore struct {
+	sync.Mutex
+	lastID int64
+	movies map[int64]*memoryMovie
+}
+
+// Movies of a tenant kept in memory. Behaves like MovieModel, except that the movies are never rated,
+// the events are not bucketed by the hour and no movie is ever trending. The movies are searched by
+// person, store and collection in the credits, copies and collections wired in by NewMemoryModels,
+// and without them those searches find no movies
+type MemoryMovieModel struct {
+	store       *memoryMovieStore
+	credits     *memoryCreditStore
+	copies      *memoryStoreStore
+	collections *memoryCollectionStore
+	TenantID    int64
+	Limit       *ParentalLimit
+}
+
+func NewMemoryMovieModel() MemoryMovieModel {
+	return MemoryMovieModel{
+		store:    &memoryMovieStore{movies: make(map[int64]*memoryMovie)},
+		TenantID: DefaultTenantID,
+	}
+}
+
+func (m MemoryMovieModel) ForTenant(tenantID int64) MovieRepository {
+	m.TenantID = tenantID
+	return m
+}
+
+func (m MemoryMovieModel) WithLimit(limit *ParentalLimit) MovieRepository {
+	m.Limit = limit
+	return m
+}
+
+// Copy of the movie sharing nothing with it, so that the stored movies can not be changed by the callers
+func copyMovie(movie *Movies) *Movies {
+
+	dup := *movie
+	dup.Genres = copyStrings(movie.Genres)
+	dup.SpokenLanguages = copyStrings(movie.SpokenLanguages)
+	dup.ProductionCountries = copyStrings(movie.ProductionCountries)
+	dup.Certifications = copyStringMap(movie.Certifications)
+	dup.ReleaseDates = copyStringMap(movie.ReleaseDates)
+
+	return &dup
+}
+
+func copyStrings(values []string) []string {
+
+	if values == nil {
+		return nil
+	}
+
+	return append([]string{}, values...)
+}
+
+func copyStringMap(values map[string]string) map[string]string {
+
+	if values == nil {
+		return nil
+	}
+
+	dup := make(map[string]string, len(values))
+	for key, value := range values {
+		dup[key] = value
+	}
+
+	return dup
+}
+
+// Copy of the movie with only the requested fields populated, like the projection of the queries
+func projectMovie(movie *Movies, fields []string) *Movies {
+
+	if len(fields) == 0 {
+		return copyMovie(movie)
+	}
+
+	dup := &Movies{ID: movie.ID, CreatedAt: movie.CreatedAt}
+
+	for _, field := range fields {
+		switch field {
+		case "title":
+			dup.Title = movie.Title
+		case "year":
+			dup.Year = movie.Year
+		case "runtime":
+			dup.Runtime = movie.Runtime
+		case "genre":
+			dup.Genres = copyStrings(movie.Genres)
+		case "plot":
+			dup.Plot = movie.Plot
+		case "info_version":
+			dup.Version = movie.Version
+		case "average_rating":
+			dup.AverageRating = movie.AverageRating
+		case "rating_count":
+			dup.RatingCount = movie.RatingCount
+		case "certifications":
+			dup.Certifications = copyStringMap(movie.Certifications)
+		case "original_language":
+			dup.OriginalLanguage = movie.OriginalLanguage
+		case "spoken_languages":
+			dup.SpokenLanguages = copyStrings(movie.SpokenLanguages)
+		case "production_countries":
+			dup.ProductionCountries = copyStrings(movie.ProductionCountries)
+		case "release_dates":
+			dup.ReleaseDates = copyStringMap(movie.ReleaseDates)
+		case "popularity":
+			dup.Popularity = movie.Popularity
+		}
+	}
+
+	return dup
+}
+
+// Get the stored movie of the tenant. The store must be locked
+func (m MemoryMovieModel) find(id int64) (*memoryMovie, bool) {
+
+	stored, found := m.store.movies[id]
+	if !found || stored.tenantID != m.TenantID {
+		return nil, false
+	}
+
+	return stored, true
+}
+
+// Check if the movie is permitted by the parental limit, if any
+func (m MemoryMovieModel) permits(movie *Movies) bool {
+	return m.Limit == nil || m.Limit.Permits(movie.Certifications)
+}
+
+func (m MemoryMovieModel) Insert(ctxt context.Context, movie *Movies) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	m.store.lastID++
+
+	movie.ID = m.store.lastID
+	movie.CreatedAt = time.Now()
+	movie.Version = 1
+
+	m.store.movies[movie.ID] = &memoryMovie{tenantID: m.TenantID, movie: *copyMovie(movie)}
+
+	return nil
+}
+
+func (m MemoryMovieModel) Get(ctxt context.Context, id int64) (*Movies, error) {
+	return m.GetFields(ctxt, id, nil)
+}
+
+// Get a movie with only the requested fields populated
+func (m MemoryMovieModel) GetFields(ctxt context.Context, id int64, fields []string) (*Movies, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	stored, found := m.find(id)
+	if !found {
+		return nil, ErrRecordNotFound
+	}
+
+	if !m.permits(&stored.movie) {
+		return nil, ErrParentalRestricted
+	}
+
+	return projectMovie(&stored.movie, fields), nil
+}
+
+func (m MemoryMovieModel) Update(ctxt context.Context, movie *Movies) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	stored, found := m.find(movie.ID)
+	if !found || stored.movie.Version != movie.Version {
+		return ErrEditConflict
+	}
+
+	movie.Version++
+
+	// The read only fields are kept
+	updated := copyMovie(movie)
+	updated.CreatedAt = stored.movie.CreatedAt
+	updated.AverageRating = stored.movie.AverageRating
+	updated.RatingCount = stored.movie.RatingCount
+	updated.Popularity = stored.movie.Popularity
+
+	stored.movie = *updated
+
+	return nil
+}
+
+func (m MemoryMovieModel) Delete(ctxt context.Context, id int64) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	if _, found := m.find(id); !found {
+		return ErrRecordNotFound
+	}
+
+	delete(m.store.movies, id)
+
+	return nil
+}
+
+// Set the plot of the movie only if it has none. Reports whether the plot was set
+func (m MemoryMovieModel) FillPlot(ctxt context.Context, movie *Movies, plot string) (bool, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return false, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	stored, found := m.find(movie.ID)
+	if !found || stored.movie.Plot != "" {
+		return false, nil
+	}
+
+	stored.movie.Plot = plot
+	stored.movie.Version++
+
+	movie.Plot = plot
+	movie.Version = stored.movie.Version
+
+	return true, nil
+}
+
+// Record that the movie has been enriched from the catalogue provider
+func (m MemoryMovieModel) MarkEnriched(ctxt context.Context, id int64, externalID string) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	if stored, found := m.find(id); found {
+		stored.enriched = true
+		stored.externalID = externalID
+	}
+
+	return nil
+}
+
+// Record that the enrichment of the movie failed, so that it is not tried again until its backoff has passed
+func (m MemoryMovieModel) MarkEnrichFailed(ctxt context.Context, id int64) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	if stored, found := m.find(id); found {
+		stored.enrichFailures++
+		stored.enrichRetryAt = time.Now().Add(enrichRetryAfter(stored.enrichFailures))
+	}
+
+	return nil
+}
+
+// Get the movies which have never been enriched and are not backing off after a failure, the ones
+// which failed the fewest times first, then the oldest first
+func (m MemoryMovieModel) GetUnenriched(ctxt context.Context, limit int) ([]*Movies, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	now := time.Now()
+	due := []*memoryMovie{}
+
+	for _, stored := range m.store.movies {
+		if stored.tenantID == m.TenantID && !stored.enriched && !stored.enrichRetryAt.After(now) {
+			due = append(due, stored)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].enrichFailures != due[j].enrichFailures {
+			return due[i].enrichFailures < due[j].enrichFailures
+		}
+
+		return due[i].movie.ID < due[j].movie.ID
+	})
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	movies := make([]*Movies, 0, len(due))
+
+	for _, stored := range due {
+		movies = append(movies, copyMovie(&stored.movie))
+	}
+
+	return movies, nil
+}
+
+func (m MemoryMovieModel) GetAll(ctxt context.Context, search MovieSearch, filters Filters) ([]*Movies, Metadata, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	column, descending := filters.getSortColumn(), filters.getSortDirection() == "DESC"
+
+	// Gathered before locking the movies, as the credits lock the movies while holding their own lock
+	related := m.relatedMovies(search)
+
+	m.store.Lock()
+
+	matches := []*Movies{}
+
+	for _, stored := range m.store.movies {
+		if stored.tenantID == m.TenantID && m.permits(&stored.movie) && related.matches(stored.movie.ID) &&
+			search.matches(&stored.movie) {
+			matches = append(matches, &stored.movie)
+		}
+	}
+
+	// Sorted on the column and then on the ID, like the queries
+	sort.Slice(matches, func(i, j int) bool {
+		order := compareMovies(matches[i], matches[j], column)
+
+		// The movies are only positioned when searching a collection
+		if column == "position" {
+			order = compareFloats(float64(related.positions[matches[i].ID]), float64(related.positions[matches[j].ID]))
+		}
+
+		if order != 0 {
+			return (order < 0) != descending
+		}
+
+		return matches[i].ID < matches[j].ID
+	})
+
+	movies := []*Movies{}
+
+	for i := filters.getOffset(); i < len(matches) && len(movies) < filters.getLimit(); i++ {
+		movies = append(movies, projectMovie(matches[i], filters.Fields))
+	}
+
+	m.store.Unlock()
+
+	metadata := CalculateMetadata(len(matches), filters.Page, filters.PageSize)
+	metadata.ParentalLimit = m.Limit
+
+	return movies, metadata, nil
+}
+
+// Get the trending movies. No events are counted in memory, so no movie is ever trending
+func (m MemoryMovieModel) GetTrending(ctxt context.Context, window time.Duration, filters Filters) ([]*Movies, Metadata, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := CalculateMetadata(0, filters.Page, filters.PageSize)
+	metadata.ParentalLimit = m.Limit
+
+	return []*Movies{}, metadata, nil
+}
+
+// Movies found by searching the credits, copies and collections, which are kept apart from the movies
+// in memory. A nil set does not restrict the movies
+type relatedMovies struct {
+	credited  map[int64]bool  // Movies crediting the person searched for
+	available map[int64]bool  // Movies having an available copy in the store searched for
+	positions map[int64]int32 // Positions of the movies in the collection searched for
+}
+
+// Get the movies matching the person, store and collection searched for, the same way as the movie queries do
+func (m MemoryMovieModel) relatedMovies(search MovieSearch) relatedMovies {
+
+	var related relatedMovies
+
+	if search.PersonID != 0 {
+		related.credited = make(map[int64]bool)
+
+		if m.credits != nil {
+			m.credits.Lock()
+			for _, credit := range m.credits.credits {
+				if credit.PersonID == search.PersonID {
+					related.credited[credit.MovieID] = true
+				}
+			}
+			m.credits.Unlock()
+		}
+	}
+
+	if search.AvailableAtStore != 0 {
+		related.available = make(map[int64]bool)
+
+		if m.copies != nil {
+			m.copies.Lock()
+			for _, item := range m.copies.items {
+				if item.StoreID == search.AvailableAtStore && item.Status == InventoryAvailable {
+					related.available[item.MovieID] = true
+				}
+			}
+			m.copies.Unlock()
+		}
+	}
+
+	if search.CollectionID != 0 {
+		related.positions = make(map[int64]int32)
+
+		if m.collections != nil {
+			m.collections.Lock()
+			if stored, found := m.collections.collections[search.CollectionID]; found {
+				for movieID, position := range stored.positions {
+					related.positions[movieID] = position
+				}
+			}
+			m.collections.Unlock()
+		}
+	}
+
+	return related
+}
+
+func (related relatedMovies) matches(id int64) bool {
+
+	if related.credited != nil && !related.credited[id] {
+		return false
+	}
+
+	if related.available != nil && !related.available[id] {
+		return false
+	}
+
+	if related.positions != nil {
+		if _, found := related.positions[id]; !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Check if the movie meets the search criteria on its own columns, the same way as the movie queries do
+func (search MovieSearch) matches(movie *Movies) bool {
+
+	// Every word of the title searched for must be a word of the title
+	if search.Title != "" {
+		words := titleWords(movie.Title)
+
+		for word := range titleWords(search.Title) {
+			if !words[word] {
+				return false
+			}
+		}
+	}
+
+	for _, genre := range search.Genres {
+		if !containsString(movie.Genres, genre) {
+			return false
+		}
+	}
+
+	if search.Language != "" && movie.OriginalLanguage != search.Language && !containsString(movie.SpokenLanguages, search.Language) {
+		return false
+	}
+
+	if search.Country != "" && !containsString(movie.ProductionCountries, search.Country) {
+		return false
+	}
+
+	if search.CertificationCountry != "" {
+		certification, found := movie.Certifications[search.CertificationCountry]
+		if !found || certification != search.Certification {
+			return false
+		}
+	}
+
+	// The release dates are in the YYYY-MM-DD format, which sorts like the dates
+	releaseDate, released := movie.ReleaseDates[search.ReleaseCountry]
+
+	if search.ReleasedAfter != "" && (!released || releaseDate < search.ReleasedAfter) {
+		return false
+	}
+
+	if search.ReleasedBefore != "" && (!released || releaseDate > search.ReleasedBefore) {
+		return false
+	}
+
+	return true
+}
+
+// Get the lower cased words of a title, as split by the simple text search configuration
+func titleWords(title string) map[string]bool {
+
+	words := make(map[string]bool)
+
+	for _, word := range strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		words[word] = true
+	}
+
+	return words
+}
+
+func containsString(values []string, value string) bool {
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Compare the movies on the sort column. Returns a negative number if the first movie sorts before the
+// second one, a positive number if it sorts after it, and zero if they are equal on the column
+func compareMovies(a, b *Movies, column string) int {
+
+	switch column {
+	case "title":
+		return strings.Compare(a.Title, b.Title)
+	case "year":
+		return compareFloats(float64(a.Year), float64(b.Year))
+	case "runtime":
+		return compareFloats(float64(a.Runtime), float64(b.Runtime))
+	case "rating":
+		return compareFloats(a.AverageRating, b.AverageRating)
+	case "popularity":
+		return compareFloats(a.Popularity, b.Popularity)
+	}
+
+	// Sorting on the ID, or on the position in a collection which is not kept with the movies
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// User as stored in memory
+type memoryUser struct {
+	tenantID int64
+	user     User
+}
+
+type memoryUserStore struct {
+	sync.Mutex
+	lastID int64
+	users  map[int64]*memoryUser
+	tokens map[[sha256.Size]byte]Token
+}
+
+// Users of a tenant kept in memory. Behaves like UserModel. The tokens of the users are kept by
+// the TokenModel, so the tokens for GetForToken have to be added with AddToken
+type MemoryUserModel struct {
+	store    *memoryUserStore
+	TenantID int64
+}
+
+func NewMemoryUserModel() *MemoryUserModel {
+	return &MemoryUserModel{
+		store: &memoryUserStore{
+			users:  make(map[int64]*memoryUser),
+			tokens: make(map[[sha256.Size]byte]Token),
+		},
+		TenantID: DefaultTenantID,
+	}
+}
+
+func (m *MemoryUserModel) ForTenant(tenantID int64) UserRepository {
+	scoped := *m
+	scoped.TenantID = tenantID
+	return &scoped
+}
+
+// Copy of the user sharing nothing with it. The plaintext passwords are never stored
+func copyUser(user *User) *User {
+
+	dup := *user
+	dup.Password = password{hash: append([]byte(nil), user.Password.hash...)}
+	dup.ParentalPin = password{hash: append([]byte(nil), user.ParentalPin.hash...)}
+
+	if user.ParentalLimit != nil {
+		limit := *user.ParentalLimit
+		dup.ParentalLimit = &limit
+	}
+
+	return &dup
+}
+
+// Check if another user of the tenant has the email. Emails are case insensitive. The store must be locked
+func (m *MemoryUserModel) emailTaken(email string, userID int64) bool {
+
+	for _, stored := range m.store.users {
+		if stored.tenantID == m.TenantID && stored.user.ID != userID && strings.EqualFold(stored.user.Email, email) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *MemoryUserModel) Insert(ctxt context.Context, user *User) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	if m.emailTaken(user.Email, 0) {
+		return ErrDuplicateEmail
+	}
+
+	m.store.lastID++
+
+	// A household of its own is created along with the user
+	user.ID = m.store.lastID
+	user.CreatedAt = time.Now()
+	user.Version = 1
+	user.HouseholdID = user.ID
+
+	m.store.users[user.ID] = &memoryUser{tenantID: m.TenantID, user: *copyUser(user)}
+
+	return nil
+}
+
+func (m *MemoryUserModel) GetByEmail(ctxt context.Context, email string) (*User, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	for _, stored := range m.store.users {
+		if stored.tenantID == m.TenantID && strings.EqualFold(stored.user.Email, email) {
+			return copyUser(&stored.user), nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+func (m *MemoryUserModel) Update(ctxt context.Context, user *User) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	if m.emailTaken(user.Email, user.ID) {
+		return ErrDuplicateEmail
+	}
+
+	stored, found := m.store.users[user.ID]
+	if !found || stored.tenantID != m.TenantID || stored.user.Version != user.Version {
+		return ErrEditConflict
+	}
+
+	user.Version++
+
+	// The creation time and the household are kept
+	updated := copyUser(user)
+	updated.CreatedAt = stored.user.CreatedAt
+	updated.HouseholdID = stored.user.HouseholdID
+
+	stored.user = *updated
+
+	return nil
+}
+
+// Add a token of a user, to be found by GetForToken. Only the hash of the token is kept
+func (m *MemoryUserModel) AddToken(token *Token) {
+
+	var hash [sha256.Size]byte
+	copy(hash[:], token.Hash)
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	m.store.tokens[hash] = Token{Hash: hash[:], UserID: token.UserID, Expiry: token.Expiry, Scope: token.Scope, ProfileID: token.ProfileID}
+}
+
+// Get the user owning the given token, provided the token has not expired
+func (m *MemoryUserModel) GetForToken(ctxt context.Context, tokenScope, tokenPlaintext string) (*User, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	token, found := m.store.tokens[tokenHash]
+	if !found || token.Scope != tokenScope || !token.Expiry.After(time.Now()) {
+		return nil, ErrRecordNotFound
+	}
+
+	stored, found := m.store.users[token.UserID]
+	if !found || stored.tenantID != m.TenantID {
+		return nil, ErrRecordNotFound
+	}
+
+	user := copyUser(&stored.user)
+	user.ProfileID = token.ProfileID
+
+	return user, nil
+}
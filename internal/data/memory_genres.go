@@ -0,0 +1,255 @@
+package data
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+type memoryGenreStore struct {
+	sync.Mutex
+	lastID int64
+	genres map[int64]*Genre
+}
+
+// Genre taxonomy kept in memory. Behaves like GenreModel, renaming and merging the genres of the
+// movies kept in memory by the MemoryMovieModel it was created with
+type MemoryGenreModel struct {
+	store  *memoryGenreStore
+	movies *memoryMovieStore
+}
+
+func NewMemoryGenreModel(movies MemoryMovieModel) MemoryGenreModel {
+	return MemoryGenreModel{store: &memoryGenreStore{genres: make(map[int64]*Genre)}, movies: movies.store}
+}
+
+func copyGenre(genre *Genre) *Genre {
+	dup := *genre
+	dup.Aliases = copyStrings(genre.Aliases)
+	return &dup
+}
+
+// Check if another genre has the slug. The store must be locked
+func (m MemoryGenreModel) slugTaken(slug string, genreID int64) bool {
+
+	for _, genre := range m.store.genres {
+		if genre.ID != genreID && genre.Slug == slug {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Rewrite the genres of the movies having the genre. The movies having the replacement already only
+// lose the genre. The number of movies rewritten is returned
+func (m MemoryGenreModel) rewriteMovies(slug, replacement string) int64 {
+
+	m.movies.Lock()
+	defer m.movies.Unlock()
+
+	var rewritten int64
+
+	for _, stored := range m.movies.movies {
+		if !containsString(stored.movie.Genres, slug) {
+			continue
+		}
+
+		genres := []string{}
+		for _, genre := range stored.movie.Genres {
+			switch {
+			case genre != slug:
+				genres = append(genres, genre)
+			case !containsString(stored.movie.Genres, replacement):
+				genres = append(genres, replacement)
+			}
+		}
+
+		stored.movie.Genres = genres
+		stored.movie.Version++
+		rewritten++
+	}
+
+	return rewritten
+}
+
+func (m MemoryGenreModel) Insert(ctxt context.Context, genre *Genre) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	if m.slugTaken(genre.Slug, 0) {
+		return ErrDuplicateSlug
+	}
+
+	m.store.lastID++
+
+	genre.ID = m.store.lastID
+	genre.CreatedAt = time.Now()
+	genre.Version = 1
+
+	m.store.genres[genre.ID] = copyGenre(genre)
+
+	return nil
+}
+
+func (m MemoryGenreModel) Get(ctxt context.Context, id int64) (*Genre, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	genre, found := m.store.genres[id]
+	if !found {
+		return nil, ErrRecordNotFound
+	}
+
+	return copyGenre(genre), nil
+}
+
+// Get all the genres ordered by their name
+func (m MemoryGenreModel) GetAll(ctxt context.Context) ([]*Genre, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	genres := []*Genre{}
+
+	for _, genre := range m.store.genres {
+		genres = append(genres, copyGenre(genre))
+	}
+
+	sort.Slice(genres, func(i, j int) bool {
+		if genres[i].Name != genres[j].Name {
+			return genres[i].Name < genres[j].Name
+		}
+
+		return genres[i].ID < genres[j].ID
+	})
+
+	return genres, nil
+}
+
+func (m MemoryGenreModel) GetTaxonomy(ctxt context.Context) (*GenreTaxonomy, error) {
+
+	genres, err := m.GetAll(ctxt)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewGenreTaxonomy(genres), nil
+}
+
+// Update the genre. If the slug has changed, the movies are updated to the new slug as well
+func (m MemoryGenreModel) Update(ctxt context.Context, genre *Genre, oldSlug string) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	stored, found := m.store.genres[genre.ID]
+	if !found || stored.Version != genre.Version {
+		return ErrEditConflict
+	}
+
+	if m.slugTaken(genre.Slug, genre.ID) {
+		return ErrDuplicateSlug
+	}
+
+	genre.Version++
+
+	updated := copyGenre(genre)
+	updated.CreatedAt = stored.CreatedAt
+
+	m.store.genres[genre.ID] = updated
+
+	if oldSlug != genre.Slug {
+		m.rewriteMovies(oldSlug, genre.Slug)
+	}
+
+	return nil
+}
+
+// Delete a genre. Genres still used by movies can not be deleted
+func (m MemoryGenreModel) Delete(ctxt context.Context, id int64) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	genre, found := m.store.genres[id]
+	if !found {
+		return ErrRecordNotFound
+	}
+
+	m.movies.Lock()
+	defer m.movies.Unlock()
+
+	for _, stored := range m.movies.movies {
+		if containsString(stored.movie.Genres, genre.Slug) {
+			return ErrGenreInUse
+		}
+	}
+
+	delete(m.store.genres, id)
+
+	return nil
+}
+
+// Merge the source genre into the target genre, the same way as GenreModel does
+func (m MemoryGenreModel) Merge(ctxt context.Context, source, target *Genre) (int64, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return 0, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	storedSource, found := m.store.genres[source.ID]
+	if !found || storedSource.Version != source.Version {
+		return 0, ErrEditConflict
+	}
+
+	storedTarget, found := m.store.genres[target.ID]
+	if !found || storedTarget.Version != target.Version {
+		return 0, ErrEditConflict
+	}
+
+	aliases := append([]string{}, target.Aliases...)
+	for _, alias := range append([]string{source.Slug, source.Name}, source.Aliases...) {
+		if Slugify(alias) != target.Slug && !validator.Permittedvalues(alias, aliases...) {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	delete(m.store.genres, source.ID)
+
+	target.Aliases = aliases
+	target.Version++
+
+	storedTarget.Aliases = copyStrings(aliases)
+	storedTarget.Version = target.Version
+
+	return m.rewriteMovies(source.Slug, target.Slug), nil
+}
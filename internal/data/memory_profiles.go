@@ -0,0 +1,225 @@
+package data
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Tokens kept in memory along with the users of a MemoryUserModel. Behaves like TokenModel
+type MemoryTokenModel struct {
+	users *MemoryUserModel
+}
+
+func NewMemoryTokenModel(users *MemoryUserModel) MemoryTokenModel {
+	return MemoryTokenModel{users: users}
+}
+
+func (m MemoryTokenModel) New(ctxt context.Context, userID int64, ttl time.Duration, scope string) (*Token, error) {
+	return m.NewForProfile(ctxt, userID, 0, ttl, scope)
+}
+
+func (m MemoryTokenModel) NewForProfile(ctxt context.Context, userID, profileID int64, ttl time.Duration, scope string) (*Token, error) {
+
+	token, err := generateToken(userID, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	token.ProfileID = profileID
+
+	err = m.Insert(ctxt, token)
+	return token, err
+}
+
+func (m MemoryTokenModel) Insert(ctxt context.Context, token *Token) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.users.AddToken(token)
+
+	return nil
+}
+
+func (m MemoryTokenModel) DeleteAllForUser(ctxt context.Context, scope string, userID int64) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.users.store.Lock()
+	defer m.users.store.Unlock()
+
+	for hash, token := range m.users.store.tokens {
+		if token.Scope == scope && token.UserID == userID {
+			delete(m.users.store.tokens, hash)
+		}
+	}
+
+	return nil
+}
+
+type memoryProfileStore struct {
+	sync.Mutex
+	lastID   int64
+	profiles map[int64]*Profile
+}
+
+// Profiles of the households kept in memory. Behaves like ProfileModel, except that the households
+// themselves are not checked, as they are not kept in memory
+type MemoryProfileModel struct {
+	store *memoryProfileStore
+}
+
+func NewMemoryProfileModel() MemoryProfileModel {
+	return MemoryProfileModel{store: &memoryProfileStore{profiles: make(map[int64]*Profile)}}
+}
+
+// Copy of the profile sharing nothing with it
+func copyProfile(profile *Profile) *Profile {
+
+	dup := *profile
+
+	if profile.ParentalLimit != nil {
+		limit := *profile.ParentalLimit
+		dup.ParentalLimit = &limit
+	}
+
+	return &dup
+}
+
+// Check if another profile of the household has the name. The store must be locked
+func (m MemoryProfileModel) nameTaken(profile *Profile) bool {
+
+	for _, stored := range m.store.profiles {
+		if stored.HouseholdID == profile.HouseholdID && stored.ID != profile.ID && stored.Name == profile.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m MemoryProfileModel) Insert(ctxt context.Context, profile *Profile) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	count := 0
+	for _, stored := range m.store.profiles {
+		if stored.HouseholdID == profile.HouseholdID {
+			count++
+		}
+	}
+
+	if count >= MaxProfiles {
+		return ErrTooManyProfiles
+	}
+
+	if m.nameTaken(profile) {
+		return ErrDuplicateProfileName
+	}
+
+	m.store.lastID++
+
+	profile.ID = m.store.lastID
+	profile.CreatedAt = time.Now()
+	profile.Version = 1
+
+	m.store.profiles[profile.ID] = copyProfile(profile)
+
+	return nil
+}
+
+func (m MemoryProfileModel) Get(ctxt context.Context, householdID, id int64) (*Profile, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	profile, found := m.store.profiles[id]
+	if !found || profile.HouseholdID != householdID {
+		return nil, ErrRecordNotFound
+	}
+
+	return copyProfile(profile), nil
+}
+
+// Get the profiles of the household, in the order they were added
+func (m MemoryProfileModel) GetAllForHousehold(ctxt context.Context, householdID int64) ([]*Profile, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	profiles := []*Profile{}
+
+	for _, profile := range m.store.profiles {
+		if profile.HouseholdID == householdID {
+			profiles = append(profiles, copyProfile(profile))
+		}
+	}
+
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].ID < profiles[j].ID })
+
+	return profiles, nil
+}
+
+func (m MemoryProfileModel) Update(ctxt context.Context, profile *Profile) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	stored, found := m.store.profiles[profile.ID]
+	if !found || stored.HouseholdID != profile.HouseholdID || stored.Version != profile.Version {
+		return ErrEditConflict
+	}
+
+	if m.nameTaken(profile) {
+		return ErrDuplicateProfileName
+	}
+
+	profile.Version++
+
+	m.store.profiles[profile.ID] = copyProfile(profile)
+
+	return nil
+}
+
+// Delete a profile of the household. The tokens bound to it are left behind, but they are refused
+// once the profile is gone
+func (m MemoryProfileModel) Delete(ctxt context.Context, householdID, id int64) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	profile, found := m.store.profiles[id]
+	if !found || profile.HouseholdID != householdID {
+		return ErrRecordNotFound
+	}
+
+	delete(m.store.profiles, id)
+
+	return nil
+}
@@ -0,0 +1,588 @@
+package data
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type memoryCreditStore struct {
+	sync.Mutex
+	lastID  int64
+	credits map[int64]*Credit
+}
+
+// Credits of the movies kept in memory by a MemoryMovieModel. Behaves like CreditModel, except that
+// the people are not kept in memory, so the credits of the movies are listed without the names
+type MemoryCreditModel struct {
+	store    *memoryCreditStore
+	movies   *memoryMovieStore
+	tenantID int64
+	limit    *ParentalLimit
+}
+
+func NewMemoryCreditModel(movies MemoryMovieModel) MemoryCreditModel {
+	return MemoryCreditModel{store: &memoryCreditStore{credits: make(map[int64]*Credit)}, movies: movies.store, tenantID: DefaultTenantID}
+}
+
+func (m MemoryCreditModel) ForTenant(tenantID int64) CreditRepository {
+	m.tenantID = tenantID
+	return m
+}
+
+func (m MemoryCreditModel) WithLimit(limit *ParentalLimit) CreditRepository {
+	m.limit = limit
+	return m
+}
+
+func (m MemoryCreditModel) Insert(ctxt context.Context, credit *Credit) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	for _, stored := range m.store.credits {
+		if stored.MovieID == credit.MovieID && stored.PersonID == credit.PersonID && stored.Role == credit.Role &&
+			stored.Character == credit.Character {
+			return ErrDuplicateCredit
+		}
+	}
+
+	m.store.lastID++
+
+	credit.ID = m.store.lastID
+
+	dup := *credit
+	m.store.credits[credit.ID] = &dup
+
+	return nil
+}
+
+// Delete a credit of the given movie
+func (m MemoryCreditModel) Delete(ctxt context.Context, movieID, id int64) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	credit, found := m.store.credits[id]
+	if !found || credit.MovieID != movieID {
+		return ErrRecordNotFound
+	}
+
+	delete(m.store.credits, id)
+
+	return nil
+}
+
+// Get the credits for a list of movies, keyed by the movie ID, in billing order
+func (m MemoryCreditModel) GetForMovies(ctxt context.Context, movieIDs []int64) (map[int64][]*Credit, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	credits := make(map[int64][]*Credit)
+
+	for _, credit := range m.store.credits {
+		for _, movieID := range movieIDs {
+			if credit.MovieID == movieID {
+				dup := *credit
+				credits[movieID] = append(credits[movieID], &dup)
+			}
+		}
+	}
+
+	for _, list := range credits {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].BillingOrder != list[j].BillingOrder {
+				return list[i].BillingOrder < list[j].BillingOrder
+			}
+
+			return list[i].ID < list[j].ID
+		})
+	}
+
+	return credits, nil
+}
+
+// Get all the credits of a person in the movies of the tenant along with the movie details, latest movies first
+func (m MemoryCreditModel) GetFilmography(ctxt context.Context, personID int64) ([]*Credit, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	m.movies.Lock()
+	defer m.movies.Unlock()
+
+	credits := []*Credit{}
+
+	for _, credit := range m.store.credits {
+		stored, found := m.movies.movies[credit.MovieID]
+		if credit.PersonID != personID || !found || stored.tenantID != m.tenantID {
+			continue
+		}
+
+		if m.limit != nil && !m.limit.Permits(stored.movie.Certifications) {
+			continue
+		}
+
+		dup := *credit
+		dup.MovieTitle = stored.movie.Title
+		dup.MovieYear = stored.movie.Year
+
+		credits = append(credits, &dup)
+	}
+
+	sort.Slice(credits, func(i, j int) bool {
+		a, b := credits[i], credits[j]
+
+		switch {
+		case a.MovieYear != b.MovieYear:
+			return a.MovieYear > b.MovieYear
+		case a.MovieTitle != b.MovieTitle:
+			return a.MovieTitle < b.MovieTitle
+		}
+
+		return a.ID < b.ID
+	})
+
+	return credits, nil
+}
+
+// Collection as stored in memory, along with the positions of its movies
+type memoryCollection struct {
+	tenantID   int64
+	collection Collection
+	positions  map[int64]int32 // Position of each movie by its ID
+}
+
+type memoryCollectionStore struct {
+	sync.Mutex
+	lastID      int64
+	collections map[int64]*memoryCollection
+}
+
+// Collections of a tenant kept in memory. Behaves like CollectionModel
+type MemoryCollectionModel struct {
+	store    *memoryCollectionStore
+	TenantID int64
+}
+
+func NewMemoryCollectionModel() MemoryCollectionModel {
+	return MemoryCollectionModel{
+		store:    &memoryCollectionStore{collections: make(map[int64]*memoryCollection)},
+		TenantID: DefaultTenantID,
+	}
+}
+
+func (m MemoryCollectionModel) ForTenant(tenantID int64) CollectionRepository {
+	m.TenantID = tenantID
+	return m
+}
+
+// Get the stored collection of the tenant. The store must be locked
+func (m MemoryCollectionModel) find(id int64) (*memoryCollection, bool) {
+
+	stored, found := m.store.collections[id]
+	if !found || stored.tenantID != m.TenantID {
+		return nil, false
+	}
+
+	return stored, true
+}
+
+// Copy of the stored collection along with its number of movies
+func (stored *memoryCollection) copy() *Collection {
+
+	dup := stored.collection
+	dup.MovieCount = int32(len(stored.positions))
+
+	return &dup
+}
+
+func (m MemoryCollectionModel) Insert(ctxt context.Context, collection *Collection) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	m.store.lastID++
+
+	collection.ID = m.store.lastID
+	collection.CreatedAt = time.Now()
+	collection.Version = 1
+
+	m.store.collections[collection.ID] = &memoryCollection{
+		tenantID:   m.TenantID,
+		collection: *collection,
+		positions:  make(map[int64]int32),
+	}
+
+	return nil
+}
+
+func (m MemoryCollectionModel) Get(ctxt context.Context, id int64) (*Collection, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	stored, found := m.find(id)
+	if !found {
+		return nil, ErrRecordNotFound
+	}
+
+	return stored.copy(), nil
+}
+
+// Get all the collections ordered by their name
+func (m MemoryCollectionModel) GetAll(ctxt context.Context) ([]*Collection, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	collections := []*Collection{}
+
+	for _, stored := range m.store.collections {
+		if stored.tenantID == m.TenantID {
+			collections = append(collections, stored.copy())
+		}
+	}
+
+	sort.Slice(collections, func(i, j int) bool {
+		if collections[i].Name != collections[j].Name {
+			return collections[i].Name < collections[j].Name
+		}
+
+		return collections[i].ID < collections[j].ID
+	})
+
+	return collections, nil
+}
+
+func (m MemoryCollectionModel) Update(ctxt context.Context, collection *Collection) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	stored, found := m.find(collection.ID)
+	if !found || stored.collection.Version != collection.Version {
+		return ErrEditConflict
+	}
+
+	collection.Version++
+
+	stored.collection.Name = collection.Name
+	stored.collection.Description = collection.Description
+	stored.collection.Version = collection.Version
+
+	return nil
+}
+
+// Delete a collection. Its movies are kept and just no longer belong to a collection
+func (m MemoryCollectionModel) Delete(ctxt context.Context, id int64) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	if _, found := m.find(id); !found {
+		return ErrRecordNotFound
+	}
+
+	delete(m.store.collections, id)
+
+	return nil
+}
+
+// Put the movie at the given position of the collection. A movie already in a collection, this one
+// or another, is moved
+func (m MemoryCollectionModel) SetMovie(ctxt context.Context, collectionID, movieID int64, position int32) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	target, found := m.store.collections[collectionID]
+	if !found {
+		return ErrRecordNotFound
+	}
+
+	for id, taken := range target.positions {
+		if id != movieID && taken == position {
+			return ErrDuplicateCollectionPosition
+		}
+	}
+
+	for _, stored := range m.store.collections {
+		delete(stored.positions, movieID)
+	}
+
+	target.positions[movieID] = position
+
+	return nil
+}
+
+// Remove the movie from the collection
+func (m MemoryCollectionModel) RemoveMovie(ctxt context.Context, collectionID, movieID int64) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	stored, found := m.store.collections[collectionID]
+	if !found {
+		return ErrRecordNotFound
+	}
+
+	if _, found = stored.positions[movieID]; !found {
+		return ErrRecordNotFound
+	}
+
+	delete(stored.positions, movieID)
+
+	return nil
+}
+
+// Get the collections the movies belong to, by movie ID. Movies without a collection are left out
+func (m MemoryCollectionModel) GetForMovies(ctxt context.Context, movieIDs []int64) (map[int64]*CollectionMembership, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	memberships := make(map[int64]*CollectionMembership)
+
+	for _, stored := range m.store.collections {
+		for _, movieID := range movieIDs {
+			if position, found := stored.positions[movieID]; found {
+				memberships[movieID] = &CollectionMembership{
+					ID:       stored.collection.ID,
+					Name:     stored.collection.Name,
+					Position: position,
+				}
+			}
+		}
+	}
+
+	return memberships, nil
+}
+
+type memoryImageStore struct {
+	sync.Mutex
+	lastID int64
+	images map[int64]*MovieImage
+}
+
+// Artwork of the movies kept in memory. Behaves like MovieImageModel. The images themselves are kept
+// in the object storage
+type MemoryMovieImageModel struct {
+	store *memoryImageStore
+}
+
+func NewMemoryMovieImageModel() MemoryMovieImageModel {
+	return MemoryMovieImageModel{store: &memoryImageStore{images: make(map[int64]*MovieImage)}}
+}
+
+func (m MemoryMovieImageModel) Insert(ctxt context.Context, image *MovieImage) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	m.store.lastID++
+
+	image.ID = m.store.lastID
+	image.CreatedAt = time.Now()
+	image.setURLs()
+
+	dup := *image
+	m.store.images[image.ID] = &dup
+
+	return nil
+}
+
+func (m MemoryMovieImageModel) Get(ctxt context.Context, id int64) (*MovieImage, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	image, found := m.store.images[id]
+	if !found {
+		return nil, ErrRecordNotFound
+	}
+
+	dup := *image
+	return &dup, nil
+}
+
+// Get the images of a movie, posters first and then the stills, oldest first
+func (m MemoryMovieImageModel) GetAllForMovie(ctxt context.Context, movieID int64) ([]*MovieImage, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	images := []*MovieImage{}
+
+	for _, image := range m.store.images {
+		if image.MovieID == movieID {
+			dup := *image
+			images = append(images, &dup)
+		}
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].Kind != images[j].Kind {
+			return images[i].Kind < images[j].Kind
+		}
+
+		return images[i].ID < images[j].ID
+	})
+
+	return images, nil
+}
+
+func (m MemoryMovieImageModel) Delete(ctxt context.Context, id int64) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	if _, found := m.store.images[id]; !found {
+		return ErrRecordNotFound
+	}
+
+	delete(m.store.images, id)
+
+	return nil
+}
+
+type memoryEventStore struct {
+	sync.Mutex
+	counts map[int64]map[string]int64 // Number of events of each kind by movie ID
+}
+
+// Counts of the events of the movies kept in memory by a MemoryMovieModel. Behaves like PopularityModel,
+// except that the events are counted as they happen, so the popularity is never decayed
+type MemoryPopularityModel struct {
+	store  *memoryEventStore
+	movies *memoryMovieStore
+}
+
+func NewMemoryPopularityModel(movies MemoryMovieModel) MemoryPopularityModel {
+	return MemoryPopularityModel{store: &memoryEventStore{counts: make(map[int64]map[string]int64)}, movies: movies.store}
+}
+
+// Count an event of the movie
+func (m MemoryPopularityModel) Record(ctxt context.Context, movieID int64, kind string) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	if m.store.counts[movieID] == nil {
+		m.store.counts[movieID] = make(map[string]int64)
+	}
+
+	m.store.counts[movieID][kind]++
+
+	return nil
+}
+
+// Get the number of events of the kind counted for the movie
+func (m MemoryPopularityModel) Count(movieID int64, kind string) int64 {
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	return m.store.counts[movieID][kind]
+}
+
+// Recompute the popularity of all the movies as the weighted sum of their events. The number of movies
+// whose popularity changed is returned
+func (m MemoryPopularityModel) Refresh(ctxt context.Context) (int64, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return 0, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	m.movies.Lock()
+	defer m.movies.Unlock()
+
+	var changed int64
+
+	for id, stored := range m.movies.movies {
+		var score float64
+		for kind, count := range m.store.counts[id] {
+			score += float64(count) * EventWeights[kind]
+		}
+
+		if stored.movie.Popularity != score {
+			stored.movie.Popularity = score
+			changed++
+		}
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,375 @@
+package data
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Stores and their copies of the movies kept in memory. They are kept together so that the stores
+// holding copies are not deleted and the availability is reported with the names of the stores
+type memoryStoreStore struct {
+	sync.Mutex
+	lastStoreID int64
+	lastItemID  int64
+	stores      map[int64]*memoryStore
+	items       map[int64]*InventoryItem
+}
+
+type memoryStore struct {
+	tenantID int64
+	store    Store
+}
+
+// Stores of a tenant kept in memory. Behaves like StoreModel
+type MemoryStoreModel struct {
+	store    *memoryStoreStore
+	TenantID int64
+}
+
+func NewMemoryStoreModel() MemoryStoreModel {
+	return MemoryStoreModel{
+		store: &memoryStoreStore{
+			stores: make(map[int64]*memoryStore),
+			items:  make(map[int64]*InventoryItem),
+		},
+		TenantID: DefaultTenantID,
+	}
+}
+
+func (m MemoryStoreModel) ForTenant(tenantID int64) StoreRepository {
+	m.TenantID = tenantID
+	return m
+}
+
+// Get the stored store of the tenant. The store must be locked
+func (m MemoryStoreModel) find(id int64) (*memoryStore, bool) {
+
+	entry, found := m.store.stores[id]
+	if !found || entry.tenantID != m.TenantID {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+func (m MemoryStoreModel) Insert(ctxt context.Context, store *Store) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	m.store.lastStoreID++
+
+	store.ID = m.store.lastStoreID
+	store.CreatedAt = time.Now()
+	store.Version = 1
+
+	m.store.stores[store.ID] = &memoryStore{tenantID: m.TenantID, store: *store}
+
+	return nil
+}
+
+func (m MemoryStoreModel) Get(ctxt context.Context, id int64) (*Store, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	entry, found := m.find(id)
+	if !found {
+		return nil, ErrRecordNotFound
+	}
+
+	store := entry.store
+	return &store, nil
+}
+
+// Get all the stores ordered by their name
+func (m MemoryStoreModel) GetAll(ctxt context.Context) ([]*Store, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	stores := []*Store{}
+
+	for _, entry := range m.store.stores {
+		if entry.tenantID == m.TenantID {
+			store := entry.store
+			stores = append(stores, &store)
+		}
+	}
+
+	sort.Slice(stores, func(i, j int) bool {
+		if stores[i].Name != stores[j].Name {
+			return stores[i].Name < stores[j].Name
+		}
+
+		return stores[i].ID < stores[j].ID
+	})
+
+	return stores, nil
+}
+
+func (m MemoryStoreModel) Update(ctxt context.Context, store *Store) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	entry, found := m.find(store.ID)
+	if !found || entry.store.Version != store.Version {
+		return ErrEditConflict
+	}
+
+	store.Version++
+
+	updated := *store
+	updated.CreatedAt = entry.store.CreatedAt
+
+	entry.store = updated
+
+	return nil
+}
+
+// Delete a store. Stores still holding inventory can not be deleted
+func (m MemoryStoreModel) Delete(ctxt context.Context, id int64) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	if _, found := m.find(id); !found {
+		return ErrRecordNotFound
+	}
+
+	for _, item := range m.store.items {
+		if item.StoreID == id {
+			return ErrStoreInUse
+		}
+	}
+
+	delete(m.store.stores, id)
+
+	return nil
+}
+
+// Copies of the movies held by the stores kept in memory. Behaves like InventoryModel
+type MemoryInventoryModel struct {
+	store *memoryStoreStore
+}
+
+// Create the inventory of the stores kept in memory by the MemoryStoreModel
+func NewMemoryInventoryModel(stores MemoryStoreModel) MemoryInventoryModel {
+	return MemoryInventoryModel{store: stores.store}
+}
+
+// Check if another copy has the barcode. The store must be locked
+func (m MemoryInventoryModel) barcodeTaken(barcode string, itemID int64) bool {
+
+	for _, item := range m.store.items {
+		if item.ID != itemID && item.Barcode == barcode {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m MemoryInventoryModel) Insert(ctxt context.Context, item *InventoryItem) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	if m.barcodeTaken(item.Barcode, 0) {
+		return ErrDuplicateBarcode
+	}
+
+	m.store.lastItemID++
+
+	item.ID = m.store.lastItemID
+	item.CreatedAt = time.Now()
+	item.Version = 1
+
+	dup := *item
+	m.store.items[item.ID] = &dup
+
+	return nil
+}
+
+func (m MemoryInventoryModel) Get(ctxt context.Context, id int64) (*InventoryItem, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	item, found := m.store.items[id]
+	if !found {
+		return nil, ErrRecordNotFound
+	}
+
+	dup := *item
+	return &dup, nil
+}
+
+func (m MemoryInventoryModel) Update(ctxt context.Context, item *InventoryItem) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	stored, found := m.store.items[item.ID]
+	if !found || stored.Version != item.Version {
+		return ErrEditConflict
+	}
+
+	if m.barcodeTaken(item.Barcode, item.ID) {
+		return ErrDuplicateBarcode
+	}
+
+	item.Version++
+
+	updated := *item
+	updated.CreatedAt = stored.CreatedAt
+	updated.MovieID = stored.MovieID
+
+	m.store.items[item.ID] = &updated
+
+	return nil
+}
+
+func (m MemoryInventoryModel) Delete(ctxt context.Context, id int64) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	if _, found := m.store.items[id]; !found {
+		return ErrRecordNotFound
+	}
+
+	delete(m.store.items, id)
+
+	return nil
+}
+
+// Get the copies of a movie, optionally only the ones held by the given store
+func (m MemoryInventoryModel) GetAllForMovie(ctxt context.Context, movieID, storeID int64) ([]*InventoryItem, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	items := []*InventoryItem{}
+
+	for _, item := range m.store.items {
+		if item.MovieID == movieID && (storeID == 0 || item.StoreID == storeID) {
+			dup := *item
+			items = append(items, &dup)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		switch {
+		case items[i].StoreID != items[j].StoreID:
+			return items[i].StoreID < items[j].StoreID
+		case items[i].Format != items[j].Format:
+			return items[i].Format < items[j].Format
+		}
+
+		return items[i].ID < items[j].ID
+	})
+
+	return items, nil
+}
+
+// Get the number of available and total copies of a movie per store and format. Retired copies are not counted
+func (m MemoryInventoryModel) GetAvailability(ctxt context.Context, movieID int64) ([]*Availability, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	type key struct {
+		storeID int64
+		format  string
+	}
+
+	counts := make(map[key]*Availability)
+
+	for _, item := range m.store.items {
+		if item.MovieID != movieID || item.Status == InventoryRetired {
+			continue
+		}
+
+		k := key{item.StoreID, item.Format}
+		if counts[k] == nil {
+			counts[k] = &Availability{StoreID: item.StoreID, Format: item.Format}
+
+			if entry, found := m.store.stores[item.StoreID]; found {
+				counts[k].StoreName = entry.store.Name
+			}
+		}
+
+		counts[k].Total++
+		if item.Status == InventoryAvailable {
+			counts[k].Available++
+		}
+	}
+
+	availability := []*Availability{}
+	for _, avail := range counts {
+		availability = append(availability, avail)
+	}
+
+	sort.Slice(availability, func(i, j int) bool {
+		a, b := availability[i], availability[j]
+
+		switch {
+		case a.StoreName != b.StoreName:
+			return a.StoreName < b.StoreName
+		case a.StoreID != b.StoreID:
+			return a.StoreID < b.StoreID
+		}
+
+		return a.Format < b.Format
+	})
+
+	return availability, nil
+}
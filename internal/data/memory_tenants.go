@@ -0,0 +1,140 @@
+package data
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryTenantStore struct {
+	sync.Mutex
+	lastID  int64
+	tenants map[int64]*Tenant
+}
+
+// Tenants kept in memory. Behaves like TenantModel. The default tenant exists from the start, as it
+// does once the migrations have been applied, and the other tenants are added with Add
+type MemoryTenantModel struct {
+	store *memoryTenantStore
+}
+
+func NewMemoryTenantModel() MemoryTenantModel {
+
+	m := MemoryTenantModel{store: &memoryTenantStore{tenants: make(map[int64]*Tenant)}}
+	m.Add(&Tenant{Slug: "default", Name: "Default"})
+
+	return m
+}
+
+// Add a tenant, filling in its ID
+func (m MemoryTenantModel) Add(tenant *Tenant) {
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	m.store.lastID++
+
+	tenant.ID = m.store.lastID
+	tenant.CreatedAt = time.Now()
+	tenant.Version = 1
+
+	dup := *tenant
+	m.store.tenants[tenant.ID] = &dup
+}
+
+// Replace the tenant with the same ID, the way the configuration of a tenant is changed in the database
+func (m MemoryTenantModel) Replace(tenant *Tenant) {
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	dup := *tenant
+	m.store.tenants[tenant.ID] = &dup
+}
+
+// Get the tenant identified by the slug, ignoring the case
+func (m MemoryTenantModel) GetBySlug(ctxt context.Context, slug string) (*Tenant, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	for _, tenant := range m.store.tenants {
+		if strings.EqualFold(tenant.Slug, slug) {
+			dup := *tenant
+			return &dup, nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+func (m MemoryTenantModel) GetAll(ctxt context.Context) ([]*Tenant, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	tenants := []*Tenant{}
+
+	for _, tenant := range m.store.tenants {
+		dup := *tenant
+		tenants = append(tenants, &dup)
+	}
+
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+
+	return tenants, nil
+}
+
+type memoryPermissionStore struct {
+	sync.Mutex
+	permissions map[int64]Permissions
+}
+
+// Permissions of the users kept in memory. Behaves like PermissionModel
+type MemoryPermissionModel struct {
+	store *memoryPermissionStore
+}
+
+func NewMemoryPermissionModel() MemoryPermissionModel {
+	return MemoryPermissionModel{store: &memoryPermissionStore{permissions: make(map[int64]Permissions)}}
+}
+
+func (m MemoryPermissionModel) GetAllForUser(ctxt context.Context, userID int64) (Permissions, error) {
+
+	if err := ctxt.Err(); err != nil {
+		return nil, err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	return append(Permissions(nil), m.store.permissions[userID]...), nil
+}
+
+func (m MemoryPermissionModel) AddForUser(ctxt context.Context, userID int64, codes ...string) error {
+
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+
+	m.store.Lock()
+	defer m.store.Unlock()
+
+	for _, code := range codes {
+		if !m.store.permissions[userID].Include(code) {
+			m.store.permissions[userID] = append(m.store.permissions[userID], code)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMemoryGenreMerge(t *testing.T) {
+
+	ctxt := context.Background()
+	models := NewMemoryModels()
+
+	source := &Genre{Slug: "sci-fi", Name: "Sci-Fi"}
+	target := &Genre{Slug: "science-fiction", Name: "Science Fiction"}
+
+	for _, genre := range []*Genre{source, target} {
+		if err := models.Genres.Insert(ctxt, genre); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	alien := &Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"sci-fi"}}
+	moon := &Movies{Title: "Moon", Year: 2009, Runtime: 97, Genres: []string{"sci-fi", "science-fiction"}}
+
+	for _, movie := range []*Movies{alien, moon} {
+		if err := models.Movies.Insert(ctxt, movie); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := models.Genres.Delete(ctxt, source.ID); !errors.Is(err, ErrGenreInUse) {
+		t.Fatalf("delete used genre: got error %v; want %v", err, ErrGenreInUse)
+	}
+
+	rewritten, err := models.Genres.Merge(ctxt, source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rewritten != 2 {
+		t.Errorf("got %d movies rewritten; want 2", rewritten)
+	}
+
+	if !reflect.DeepEqual(target.Aliases, []string{"sci-fi", "Sci-Fi"}) {
+		t.Errorf("got aliases %v; want [sci-fi Sci-Fi]", target.Aliases)
+	}
+
+	for _, movie := range []*Movies{alien, moon} {
+		stored, err := models.Movies.Get(ctxt, movie.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(stored.Genres, []string{"science-fiction"}) {
+			t.Errorf("%s: got genres %v; want [science-fiction]", movie.Title, stored.Genres)
+		}
+	}
+
+	if _, err := models.Genres.Get(ctxt, source.ID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("get merged genre: got error %v; want %v", err, ErrRecordNotFound)
+	}
+}
+
+func TestMemoryStoresForTenant(t *testing.T) {
+
+	ctxt := context.Background()
+	models := NewMemoryModels()
+
+	store := &Store{Name: "Main Street"}
+	if err := models.Stores.Insert(ctxt, store); err != nil {
+		t.Fatal(err)
+	}
+
+	item := &InventoryItem{MovieID: 1, StoreID: store.ID, Format: "dvd", Barcode: "A-1", Status: InventoryAvailable}
+	if err := models.Inventory.Insert(ctxt, item); err != nil {
+		t.Fatal(err)
+	}
+
+	duplicate := &InventoryItem{MovieID: 1, StoreID: store.ID, Format: "dvd", Barcode: "A-1", Status: InventoryAvailable}
+	if err := models.Inventory.Insert(ctxt, duplicate); !errors.Is(err, ErrDuplicateBarcode) {
+		t.Errorf("insert duplicate barcode: got error %v; want %v", err, ErrDuplicateBarcode)
+	}
+
+	if _, err := models.ForTenant(2).Stores.Get(ctxt, store.ID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("get store of another tenant: got error %v; want %v", err, ErrRecordNotFound)
+	}
+
+	if err := models.Stores.Delete(ctxt, store.ID); !errors.Is(err, ErrStoreInUse) {
+		t.Errorf("delete store holding copies: got error %v; want %v", err, ErrStoreInUse)
+	}
+}
+
+func TestMemoryFilmographyForTenant(t *testing.T) {
+
+	models := NewMemoryModels()
+	ctxt := context.Background()
+
+	alien := &Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"horror"}}
+	heat := &Movies{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"crime"}}
+
+	if err := models.Movies.Insert(ctxt, alien); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := models.Movies.ForTenant(2).Insert(ctxt, heat); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, movie := range []*Movies{alien, heat} {
+		if err := models.Credits.Insert(ctxt, &Credit{MovieID: movie.ID, PersonID: 1, Role: "director"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	credits, err := models.ForTenant(2).Credits.GetFilmography(ctxt, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(credits) != 1 || credits[0].MovieID != heat.ID {
+		t.Errorf("got %d credits; want the credit of Heat only", len(credits))
+	}
+}
+
+func TestMemoryFilmographyParentalLimit(t *testing.T) {
+
+	models := NewMemoryModels()
+	ctxt := context.Background()
+
+	toyStory := &Movies{Title: "Toy Story", Year: 1995, Runtime: 81, Genres: []string{"animation"}, Certifications: map[string]string{"US": "G"}}
+	heat := &Movies{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"crime"}, Certifications: map[string]string{"US": "R"}}
+
+	for _, movie := range []*Movies{toyStory, heat} {
+		if err := models.Movies.Insert(ctxt, movie); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := models.Credits.Insert(ctxt, &Credit{MovieID: movie.ID, PersonID: 1, Role: "actor"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	credits, err := models.Credits.WithLimit(&ParentalLimit{Country: "US", MaxCertification: "PG-13"}).GetFilmography(ctxt, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(credits) != 1 || credits[0].MovieID != toyStory.ID {
+		t.Errorf("got %d credits; want the credit of Toy Story only", len(credits))
+	}
+}
+
+func TestMemoryMovieSearchRelated(t *testing.T) {
+
+	models := NewMemoryModels()
+	ctxt := context.Background()
+
+	alien := &Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"horror"}}
+	aliens := &Movies{Title: "Aliens", Year: 1986, Runtime: 137, Genres: []string{"action"}}
+	heat := &Movies{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"crime"}}
+
+	for _, movie := range []*Movies{alien, aliens, heat} {
+		if err := models.Movies.Insert(ctxt, movie); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, movie := range []*Movies{alien, aliens} {
+		if err := models.Credits.Insert(ctxt, &Credit{MovieID: movie.ID, PersonID: 1, Role: "actor", Character: "Ripley"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := &Store{Name: "Main Street"}
+	if err := models.Stores.Insert(ctxt, store); err != nil {
+		t.Fatal(err)
+	}
+
+	copies := []*InventoryItem{
+		{MovieID: alien.ID, StoreID: store.ID, Format: "dvd", Barcode: "A-1", Status: InventoryRented},
+		{MovieID: heat.ID, StoreID: store.ID, Format: "dvd", Barcode: "A-2", Status: InventoryAvailable},
+	}
+
+	for _, item := range copies {
+		if err := models.Inventory.Insert(ctxt, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	collection := &Collection{Name: "Alien"}
+	if err := models.Collections.Insert(ctxt, collection); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := models.Collections.SetMovie(ctxt, collection.ID, aliens.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := models.Collections.SetMovie(ctxt, collection.ID, alien.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		search MovieSearch
+		sort   string
+		want   []string
+	}{
+		{"person", MovieSearch{PersonID: 1}, "id", []string{"Alien", "Aliens"}},
+		{"unknown person", MovieSearch{PersonID: 2}, "id", []string{}},
+		{"available at store", MovieSearch{AvailableAtStore: store.ID}, "id", []string{"Heat"}},
+		{"collection by position", MovieSearch{CollectionID: collection.ID}, "position", []string{"Aliens", "Alien"}},
+		{"person and collection", MovieSearch{PersonID: 1, CollectionID: collection.ID}, "-position", []string{"Alien", "Aliens"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := Filters{Page: 1, PageSize: 20, Sort: tt.sort, SortList: []string{tt.sort}}
+
+			movies, _, err := models.Movies.GetAll(ctxt, tt.search, filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, movie := range movies {
+				got = append(got, movie.Title)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,7 +2,13 @@ package data
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/narinderv/blockbuster/internal/pricing"
 )
 
 var (
@@ -12,14 +18,127 @@ var (
 
 // A "Base" Model to encapsulate all Models
 type Models struct {
-	Movies MovieModel
-	Users  UserModel
+	Movies          MovieRepository
+	Users           UserRepository
+	People          PersonModel
+	Credits         CreditRepository
+	Genres          GenreRepository
+	Tokens          TokenRepository
+	Permissions     PermissionRepository
+	Ratings         RatingModel
+	Reviews         ReviewModel
+	Stores          StoreRepository
+	Inventory       InventoryRepository
+	Rentals         RentalModel
+	Ledger          LedgerModel
+	Holds           HoldModel
+	Lists           ListModel
+	Similarities    SimilarityModel
+	Recommendations RecommendationModel
+	Images          MovieImageRepository
+	Tenants         TenantRepository
+	Households      HouseholdModel
+	Profiles        ProfileRepository
+	Collections     CollectionRepository
+	Popularity      PopularityRepository
+
+	// Connection pool the units of work are begun on. Nil for the models bound to a unit of work or kept in memory
+	db *sql.DB
 }
 
-// Initializer for the Model
-func NewModel(db *sql.DB) Models {
+// Initializer for the Model. The pricing engine is used for charging the rentals
+func NewModel(conn *sql.DB, pricingEngine *pricing.Engine) Models {
+
+	db := dbPool{conn}
+
 	return Models{
-		Movies: MovieModel{DB: db},
-		Users:  UserModel{DB: db},
+		Movies:          MovieModel{DB: db, TenantID: DefaultTenantID},
+		Users:           &UserModel{DB: db, TenantID: DefaultTenantID},
+		People:          PersonModel{DB: db},
+		Credits:         CreditModel{DB: db, TenantID: DefaultTenantID},
+		Genres:          GenreModel{DB: db},
+		Tokens:          TokenModel{DB: db},
+		Permissions:     PermissionModel{DB: db},
+		Ratings:         RatingModel{DB: db},
+		Reviews:         ReviewModel{DB: db},
+		Stores:          StoreModel{DB: db, TenantID: DefaultTenantID},
+		Inventory:       InventoryModel{DB: db},
+		Rentals:         RentalModel{DB: db, Pricing: pricingEngine},
+		Ledger:          LedgerModel{DB: db},
+		Holds:           HoldModel{DB: db},
+		Lists:           ListModel{DB: db, TenantID: DefaultTenantID},
+		Similarities:    SimilarityModel{DB: db},
+		Recommendations: RecommendationModel{DB: db},
+		Images:          MovieImageModel{DB: db},
+		Tenants:         TenantModel{DB: db},
+		Households:      HouseholdModel{DB: db, TenantID: DefaultTenantID},
+		Profiles:        ProfileModel{DB: db},
+		Collections:     CollectionModel{DB: db, TenantID: DefaultTenantID},
+		Popularity:      PopularityModel{DB: db},
+		db:              conn,
+	}
+}
+
+// Copy of the models scoped to a tenant. The movies, collections, users, households, lists and stores are only read
+// and written within the tenant, as are the movies listed in the filmographies, the models created by NewModel use
+// the default tenant. The credits, ratings, reviews, images, inventory, rentals and holds are scoped through their
+// movie or store, which the handlers resolve within the tenant first.
+// The genres and people are deliberately shared by all the tenants and only managed with the catalogue permission
+func (m Models) ForTenant(tenantID int64) Models {
+
+	m.Movies = m.Movies.ForTenant(tenantID)
+	m.Users = m.Users.ForTenant(tenantID)
+	m.Credits = m.Credits.ForTenant(tenantID)
+	m.Stores = m.Stores.ForTenant(tenantID)
+	m.Households.TenantID = tenantID
+	m.Lists.TenantID = tenantID
+	m.Collections = m.Collections.ForTenant(tenantID)
+
+	return m
+}
+
+// Check if the error is a foreign key violation reported by Postgres
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+// Map of strings stored in a JSON object column. An empty object is stored for a nil map
+type jsonObject struct {
+	value *map[string]string
+}
+
+func (obj jsonObject) Value() (driver.Value, error) {
+
+	if *obj.value == nil {
+		return "{}", nil
 	}
+
+	content, err := json.Marshal(*obj.value)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(content), nil
+}
+
+func (obj jsonObject) Scan(src interface{}) error {
+
+	var content []byte
+
+	switch src := src.(type) {
+	case []byte:
+		content = src
+	case string:
+		content = []byte(src)
+	case nil:
+		*obj.value = nil
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into a JSON object", src)
+	}
+
+	*obj.value = nil
+
+	return json.Unmarshal(content, obj.value)
 }
@@ -5,12 +5,18 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
+	"github.com/narinderv/blockbuster/internal/iso"
 	"github.com/narinderv/blockbuster/internal/validator"
 )
 
+var (
+	ErrMovieInUse = errors.New("movie in use")
+)
+
 // Structure with annotations which will be used for json naming
 type Movies struct {
 	ID        int64     `json:"id"`
@@ -19,23 +25,159 @@ type Movies struct {
 	Year      int32     `json:"year,omitempty"` // Omitempty will hide the field if it is empty or blank
 	Runtime   Runtime   `json:"runtime,omitempty"`
 	Genres    []string  `json:"genre,omitempty"`
+	Plot      string    `json:"plot,omitempty"`
 	Version   int32     `json:"info_version"`
+
+	// Release metadata. The countries are ISO 3166-1 alpha-2 codes and the languages ISO 639-1 codes
+	Certifications      map[string]string `json:"certifications,omitempty"` // Age rating in each country
+	OriginalLanguage    string            `json:"original_language,omitempty"`
+	SpokenLanguages     []string          `json:"spoken_languages,omitempty"`
+	ProductionCountries []string          `json:"production_countries,omitempty"`
+	ReleaseDates        map[string]string `json:"release_dates,omitempty"` // Release date in each country (YYYY-MM-DD)
+
+	// Aggregate of the user ratings. These are read only
+	AverageRating float64 `json:"average_rating,omitempty"`
+	RatingCount   int32   `json:"rating_count,omitempty"`
+
+	// Decayed score of the recent views, rentals and ratings. This is read only
+	Popularity float64 `json:"popularity,omitempty"`
 }
 
+// Fields of a movie which can be requested in a sparse fieldset (fields=id,title,year)
+var MovieFields = []string{"id", "title", "year", "runtime", "genre", "plot", "info_version", "average_rating", "rating_count",
+	"certifications", "original_language", "spoken_languages", "production_countries", "release_dates", "popularity"}
+
+// Maximum length of the plot of a movie
+const MaxPlotLength = 5000
+
+// Format of the release dates
+const ReleaseDateLayout = "2006-01-02"
+
+// Source of the movie queries. The ratings are aggregated using a lateral join so that
+// they can be selected as well as sorted upon like any other column
+const movieSource = `movies
+	LEFT JOIN LATERAL (
+		SELECT COALESCE(avg(ratings.rating), 0)::float8 AS rating, count(*) AS rating_count
+		FROM ratings WHERE ratings.movie_id = movies.id
+	) AS r ON true`
+
+// Criteria for searching the movies. Zero values are ignored
+type MovieSearch struct {
+	Title            string
+	Genres           []string
+	PersonID         int64 // Movies in which the person is credited
+	AvailableAtStore int64 // Movies having an available copy in the store
+	CollectionID     int64 // Movies of the collection, which can be sorted by their position in it
+
+	Language             string // Original or spoken language
+	Country              string // Production country
+	CertificationCountry string // Country of the Certification
+	Certification        string // Age rating in the CertificationCountry
+	ReleaseCountry       string // Country of the release dates below
+	ReleasedAfter        string // Released in the ReleaseCountry on or after the date
+	ReleasedBefore       string // Released in the ReleaseCountry on or before the date
+}
+
+// Movies of a tenant. Only the movies of the tenant are ever read or written, and only
+// the movies permitted by the parental limit are read if there is one
 type MovieModel struct {
-	DB *sql.DB
+	DB       DBTX
+	TenantID int64
+	Limit    *ParentalLimit
+}
+
+func (m MovieModel) ForTenant(tenantID int64) MovieRepository {
+	m.TenantID = tenantID
+	return m
+}
+
+func (m MovieModel) WithLimit(limit *ParentalLimit) MovieRepository {
+	m.Limit = limit
+	return m
 }
 
-func (m MovieModel) Insert(movie *Movies) error {
+// Get the arguments of the parental limit clause
+// (certifications->>$country = ANY($permitted) OR $permitted IS NULL)
+func (m MovieModel) limitArgs() (string, interface{}) {
+	return parentalLimitArgs(m.Limit)
+}
+
+// Get the columns to be selected for the requested fields along with the scan destinations for them.
+// The id and created_at are always selected. An empty field list selects all the columns.
+func (movie *Movies) projection(fields []string) ([]string, []interface{}) {
+
+	if len(fields) == 0 {
+		fields = MovieFields
+	}
+
+	columns := []string{"id", "created_at"}
+	dest := []interface{}{&movie.ID, &movie.CreatedAt}
+
+	for _, field := range fields {
+		switch field {
+		case "title":
+			columns = append(columns, "title")
+			dest = append(dest, &movie.Title)
+		case "year":
+			columns = append(columns, "year")
+			dest = append(dest, &movie.Year)
+		case "runtime":
+			columns = append(columns, "runtime")
+			dest = append(dest, &movie.Runtime)
+		case "genre":
+			columns = append(columns, "genres")
+			dest = append(dest, pq.Array(&movie.Genres))
+		case "plot":
+			columns = append(columns, "plot")
+			dest = append(dest, &movie.Plot)
+		case "info_version":
+			columns = append(columns, "version")
+			dest = append(dest, &movie.Version)
+		case "average_rating":
+			columns = append(columns, "r.rating")
+			dest = append(dest, &movie.AverageRating)
+		case "rating_count":
+			columns = append(columns, "r.rating_count")
+			dest = append(dest, &movie.RatingCount)
+		case "certifications":
+			columns = append(columns, "certifications")
+			dest = append(dest, jsonObject{&movie.Certifications})
+		case "original_language":
+			columns = append(columns, "original_language")
+			dest = append(dest, &movie.OriginalLanguage)
+		case "spoken_languages":
+			columns = append(columns, "spoken_languages")
+			dest = append(dest, pq.Array(&movie.SpokenLanguages))
+		case "production_countries":
+			columns = append(columns, "production_countries")
+			dest = append(dest, pq.Array(&movie.ProductionCountries))
+		case "release_dates":
+			columns = append(columns, "release_dates")
+			dest = append(dest, jsonObject{&movie.ReleaseDates})
+		case "popularity":
+			columns = append(columns, "popularity")
+			dest = append(dest, &movie.Popularity)
+		}
+	}
+
+	return columns, dest
+}
+
+func (m MovieModel) Insert(ctxt context.Context, movie *Movies) error {
 
 	// Insert query
-	query := "INSERT INTO movies (title, year, runtime, genres) VALUES ($1, $2, $3, $4) RETURNING id, created_at, version"
+	query := `INSERT INTO movies (title, year, runtime, genres, plot, certifications, original_language, spoken_languages,
+		production_countries, release_dates, tenant_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	RETURNING id, created_at, version`
 
 	// Argumets to the query
-	args := []interface{}{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres)}
+	args := []interface{}{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres), movie.Plot,
+		jsonObject{&movie.Certifications}, movie.OriginalLanguage, pq.Array(movie.SpokenLanguages),
+		pq.Array(movie.ProductionCountries), jsonObject{&movie.ReleaseDates}, m.TenantID}
 
 	// Create a DB context to timeout the query if it exceeds a certian duration
-	ctxt, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctxt, cancel := withTimeout(ctxt, "movies.insert")
 
 	defer cancel()
 
@@ -43,31 +185,43 @@ func (m MovieModel) Insert(movie *Movies) error {
 	return m.DB.QueryRowContext(ctxt, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
 }
 
-func (m MovieModel) Get(id int64) (*Movies, error) {
+func (m MovieModel) Get(ctxt context.Context, id int64) (*Movies, error) {
+	return m.GetFields(ctxt, id, nil)
+}
+
+// Get a movie with only the requested fields populated
+func (m MovieModel) GetFields(ctxt context.Context, id int64, fields []string) (*Movies, error) {
 
 	// Validate if ID is valid
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
-	// Get query
-	query := `SELECT id, created_at, title, year, runtime, genres, version
-	FROM movies
-	WHERE id = $1`
-
 	// Response structure
 	var movie Movies
 
+	// Select only the requested columns
+	columns, dest := movie.projection(fields)
+
+	// Get query
+	query := fmt.Sprintf(`SELECT %s
+	FROM %s
+	WHERE id = $1 AND tenant_id = $2
+	AND (certifications->>$3 = ANY($4::text[]) OR $4 IS NULL)`, strings.Join(columns, ", "), movieSource)
+
+	limitCountry, permitted := m.limitArgs()
+
 	// Create a DB context to timeout the query if it exceeds a certian duration
-	ctxt, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctxt, cancel := withTimeout(ctxt, "movies.get_fields")
 
 	defer cancel()
 
 	// Use the context in the query
-	// err := m.DB.QueryRow(query, id).Scan(&movie.ID, &movie.CreatedAt, &movie.Title, &movie.Year, &movie.Runtime, pq.Array(&movie.Genres), &movie.Version)
-	err := m.DB.QueryRowContext(ctxt, query, id).Scan(&movie.ID, &movie.CreatedAt, &movie.Title, &movie.Year, &movie.Runtime, pq.Array(&movie.Genres), &movie.Version)
+	err := m.DB.QueryRowContext(ctxt, query, id, m.TenantID, limitCountry, permitted).Scan(dest...)
 	if err != nil {
 		switch {
+		case errors.Is(err, sql.ErrNoRows) && m.Limit != nil:
+			return nil, m.restrictedOrNotFound(ctxt, id)
 		case errors.Is(err, sql.ErrNoRows):
 			return nil, ErrRecordNotFound
 		default:
@@ -78,19 +232,41 @@ func (m MovieModel) Get(id int64) (*Movies, error) {
 	return &movie, nil
 }
 
-func (m MovieModel) Update(movie *Movies) error {
+// Tell apart a movie hidden by the parental limit from a movie which does not exist, so that
+// the clients can ask for the parental PIN
+func (m MovieModel) restrictedOrNotFound(ctxt context.Context, id int64) error {
+
+	var exists bool
+
+	err := m.DB.QueryRowContext(ctxt, "SELECT EXISTS(SELECT 1 FROM movies WHERE id = $1 AND tenant_id = $2)",
+		id, m.TenantID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrParentalRestricted
+	}
+
+	return ErrRecordNotFound
+}
+
+func (m MovieModel) Update(ctxt context.Context, movie *Movies) error {
 
 	// Update query
 	query := `UPDATE movies
-	SET title = $1, year = $2, runtime = $3, genres = $4, version = version + 1
-	WHERE id = $5 and version = $6
+	SET title = $1, year = $2, runtime = $3, genres = $4, plot = $5, certifications = $6, original_language = $7,
+		spoken_languages = $8, production_countries = $9, release_dates = $10, version = version + 1
+	WHERE id = $11 and version = $12 AND tenant_id = $13
 	RETURNING version`
 
 	// Argumets to the query
-	args := []interface{}{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres), movie.ID, movie.Version}
+	args := []interface{}{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres), movie.Plot,
+		jsonObject{&movie.Certifications}, movie.OriginalLanguage, pq.Array(movie.SpokenLanguages),
+		pq.Array(movie.ProductionCountries), jsonObject{&movie.ReleaseDates}, movie.ID, movie.Version, m.TenantID}
 
 	// Create a DB context to timeout the query if it exceeds a certian duration
-	ctxt, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctxt, cancel := withTimeout(ctxt, "movies.update")
 
 	defer cancel()
 
@@ -107,7 +283,7 @@ func (m MovieModel) Update(movie *Movies) error {
 	return nil
 }
 
-func (m MovieModel) Delete(id int64) error {
+func (m MovieModel) Delete(ctxt context.Context, id int64) error {
 
 	// Validate if ID is valid
 	if id < 1 {
@@ -115,16 +291,22 @@ func (m MovieModel) Delete(id int64) error {
 	}
 
 	//Query
-	query := "DELETE from MOVIES where id = $1"
+	query := "DELETE from MOVIES where id = $1 AND tenant_id = $2"
 
 	// Create a DB context to timeout the query if it exceeds a certian duration
-	ctxt, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctxt, cancel := withTimeout(ctxt, "movies.delete")
 
 	defer cancel()
 
-	res, err := m.DB.ExecContext(ctxt, query, id)
+	// Movies which have been rented out can not be deleted
+	res, err := m.DB.ExecContext(ctxt, query, id, m.TenantID)
 	if err != nil {
-		return err
+		switch {
+		case isForeignKeyViolation(err):
+			return ErrMovieInUse
+		default:
+			return err
+		}
 	}
 
 	rowsAffected, err := res.RowsAffected()
@@ -139,7 +321,138 @@ func (m MovieModel) Delete(id int64) error {
 	return nil
 }
 
-func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*Movies, Metadata, error) {
+// Set the plot of the movie only if it has none, so that a plot entered by hand is never overwritten.
+// Reports whether the plot was set
+func (m MovieModel) FillPlot(ctxt context.Context, movie *Movies, plot string) (bool, error) {
+
+	query := `UPDATE movies
+	SET plot = $1, version = version + 1
+	WHERE id = $2 AND plot = '' AND tenant_id = $3
+	RETURNING version`
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "movies.fill_plot")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, plot, movie.ID, m.TenantID).Scan(&movie.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+
+	movie.Plot = plot
+
+	return true, nil
+}
+
+// Time until a movie whose enrichment failed is tried again. The time doubles with each failure
+// in a row, up to the maximum
+const (
+	EnrichRetryBackoff    = time.Hour
+	MaxEnrichRetryBackoff = 7 * 24 * time.Hour
+)
+
+// Time until a movie is tried again after the given number of failures in a row
+func enrichRetryAfter(failures int32) time.Duration {
+
+	backoff := EnrichRetryBackoff
+
+	for i := int32(1); i < failures && backoff < MaxEnrichRetryBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > MaxEnrichRetryBackoff {
+		backoff = MaxEnrichRetryBackoff
+	}
+
+	return backoff
+}
+
+// Record that the movie has been enriched from the catalogue provider
+func (m MovieModel) MarkEnriched(ctxt context.Context, id int64, externalID string) error {
+
+	query := `UPDATE movies
+	SET external_id = $1, enriched_at = NOW()
+	WHERE id = $2 AND tenant_id = $3`
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "movies.mark_enriched")
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctxt, query, externalID, id, m.TenantID)
+
+	return err
+}
+
+// Record that the enrichment of the movie failed, so that it is not tried again until its backoff has passed
+func (m MovieModel) MarkEnrichFailed(ctxt context.Context, id int64) error {
+
+	query := `UPDATE movies
+	SET enrich_failures = enrich_failures + 1,
+	enrich_retry_at = NOW() + make_interval(secs => LEAST($1 * power(2, LEAST(enrich_failures, 30)), $2))
+	WHERE id = $3 AND tenant_id = $4`
+
+	args := []interface{}{EnrichRetryBackoff.Seconds(), MaxEnrichRetryBackoff.Seconds(), id, m.TenantID}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "movies.mark_enrich_failed")
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctxt, query, args...)
+
+	return err
+}
+
+// Get the movies which have never been enriched and are not backing off after a failure, the ones
+// which failed the fewest times first, then the oldest first
+func (m MovieModel) GetUnenriched(ctxt context.Context, limit int) ([]*Movies, error) {
+
+	var movie Movies
+	columns, _ := movie.projection(nil)
+
+	query := fmt.Sprintf(`SELECT %s
+	FROM %s
+	WHERE enriched_at IS NULL AND (enrich_retry_at IS NULL OR enrich_retry_at <= NOW()) AND tenant_id = $1
+	ORDER BY enrich_failures, id
+	LIMIT $2`, strings.Join(columns, ", "), movieSource)
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "movies.get_unenriched")
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctxt, query, m.TenantID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	movies := []*Movies{}
+
+	for rows.Next() {
+		var movie Movies
+
+		_, dest := movie.projection(nil)
+
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return movies, nil
+}
+
+func (m MovieModel) GetAll(ctxt context.Context, search MovieSearch, filters Filters) ([]*Movies, Metadata, error) {
 
 	// Basic Query
 	/*query := `
@@ -153,20 +466,46 @@ func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*M
 	// count(*) OVER(), return total count of matching records returned by the query
 	// 1st from clause below performs full text search
 	// 2nd clause searches presenceof input in the genre list
+	// 3rd clause restricts the movies to the ones crediting the given person
+	// 4th clause restricts the movies to the ones having an available copy in the given store
+	// 5th clause restricts the movies to the ones in the given collection, joined for their position in it
+	// 6th to 10th clauses filter on the release metadata. Empty filters are ignored
+	// 11th clause restricts the movies to the ones permitted by the parental limit, if any
+	// Last clause restricts the movies to the tenant
 	// Limit and Offset are used for pagination functionality
+	// Only the columns requested in the sparse fieldset are selected
+	columns, _ := (&Movies{}).projection(filters.Fields)
+
 	query := fmt.Sprintf(`
-			SELECT count(*) OVER(), id, created_at, title, year, runtime, genres, version
-			FROM movies WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
+			SELECT count(*) OVER(), %s
+			FROM %s
+			LEFT JOIN collection_movies cm ON cm.movie_id = movies.id AND cm.collection_id = $5
+			WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
 			AND (genres @> $2 OR $2 = '{}')
+			AND (id IN (SELECT movie_id FROM movie_credits WHERE person_id = $3) OR $3 = 0)
+			AND (id IN (SELECT movie_id FROM inventory_items WHERE store_id = $4 AND status = 'available') OR $4 = 0)
+			AND (cm.collection_id IS NOT NULL OR $5 = 0)
+			AND (original_language = $6 OR spoken_languages @> ARRAY[$6::text] OR $6 = '')
+			AND (production_countries @> ARRAY[$7::text] OR $7 = '')
+			AND (certifications @> jsonb_build_object($8::text, $9::text) OR $8 = '')
+			AND ((release_dates->>$10)::date >= NULLIF($11, '')::date OR $11 = '')
+			AND ((release_dates->>$10)::date <= NULLIF($12, '')::date OR $12 = '')
+			AND (certifications->>$13 = ANY($14::text[]) OR $14 IS NULL)
+			AND tenant_id = $15
 			ORDER BY %s %s, id
-			LIMIT $3 OFFSET $4`, filters.getSortColumn(), filters.getSortDirection())
+			LIMIT $16 OFFSET $17`, strings.Join(columns, ", "), movieSource, filters.getSortColumn(), filters.getSortDirection())
 
 	// Create a context
-	ctxt, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctxt, cancel := withTimeout(ctxt, "movies.get_all")
 	defer cancel()
 
+	limitCountry, permitted := m.limitArgs()
+
 	// Execute the query
-	rows, err := m.DB.QueryContext(ctxt, query, title, pq.Array(genres), filters.getLimit(), filters.getOffset())
+	rows, err := m.DB.QueryContext(ctxt, query, search.Title, pq.Array(search.Genres), search.PersonID,
+		search.AvailableAtStore, search.CollectionID, search.Language, search.Country, search.CertificationCountry,
+		search.Certification, search.ReleaseCountry, search.ReleasedAfter, search.ReleasedBefore, limitCountry, permitted,
+		m.TenantID, filters.getLimit(), filters.getOffset())
 	if err != nil {
 		return nil, Metadata{}, err
 	}
@@ -182,16 +521,9 @@ func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*M
 		var movie Movies
 
 		// Get the row data
-		err = rows.Scan(
-			&totalRecords,
-			&movie.ID,
-			&movie.CreatedAt,
-			&movie.Title,
-			&movie.Year,
-			&movie.Runtime,
-			pq.Array(&movie.Genres),
-			&movie.Version,
-		)
+		_, dest := movie.projection(filters.Fields)
+
+		err = rows.Scan(append([]interface{}{&totalRecords}, dest...)...)
 
 		if err != nil {
 			return nil, Metadata{}, err
@@ -206,13 +538,16 @@ func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*M
 		return nil, Metadata{}, err
 	}
 
-	// Generate the metadata
+	// Generate the metadata. The parental limit tells the clients that the movies have been filtered
 	metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	metadata.ParentalLimit = m.Limit
 
 	return movies, metadata, nil
 }
 
-func ValidateMovie(val *validator.Validator, movie *Movies) {
+// Validate the movie. The genres are validated against the taxonomy and replaced by their
+// canonical slugs, so that "Sci-Fi", "sci-fi" and "Science Fiction" are all stored as the same genre
+func ValidateMovie(val *validator.Validator, movie *Movies, taxonomy *GenreTaxonomy) {
 
 	// Title
 	val.Check(movie.Title != "", "title", "must be provided")
@@ -227,11 +562,129 @@ func ValidateMovie(val *validator.Validator, movie *Movies) {
 	val.Check(movie.Runtime != 0, "runtime", "must be provided")
 	val.Check(movie.Runtime > 0, "runtime", "must be a positive integer")
 
+	// Plot is optional
+	val.Check(len(movie.Plot) <= MaxPlotLength, "plot", fmt.Sprintf("must not be more than %d bytes", MaxPlotLength))
+
 	// Genres
 	val.Check(len(movie.Genres) != 0, "genres", "must be provided")
 	val.Check(len(movie.Genres) >= 1, "genres", "must contain atleast 1 genre")
 	val.Check(len(movie.Genres) <= 5, "genres", "must not contain more than 5 genres")
 
+	for _, genre := range movie.Genres {
+		if _, found := taxonomy.Lookup(genre); !found {
+			val.AddError("genres", "unknown genre: "+genre)
+			break
+		}
+	}
+
+	movie.Genres = taxonomy.Canonicalize(movie.Genres)
+
 	val.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
 
+	validateReleaseMetadata(val, movie)
+}
+
+// Validate the release metadata against the ISO code lists. The codes are normalised to the case
+// of the lists and the certifications to the spelling of the rating systems
+func validateReleaseMetadata(val *validator.Validator, movie *Movies) {
+
+	// Languages
+	movie.OriginalLanguage = strings.ToLower(movie.OriginalLanguage)
+	val.Check(movie.OriginalLanguage == "" || iso.IsLanguage(movie.OriginalLanguage), "original_language", "must be an ISO 639-1 language code")
+
+	for i, language := range movie.SpokenLanguages {
+		movie.SpokenLanguages[i] = strings.ToLower(language)
+
+		if !iso.IsLanguage(movie.SpokenLanguages[i]) {
+			val.AddError("spoken_languages", "unknown language: "+language)
+			break
+		}
+	}
+
+	val.Check(validator.Unique(movie.SpokenLanguages), "spoken_languages", "must not contain duplicate values")
+
+	// Countries
+	for i, country := range movie.ProductionCountries {
+		movie.ProductionCountries[i] = strings.ToUpper(country)
+
+		if !iso.IsCountry(movie.ProductionCountries[i]) {
+			val.AddError("production_countries", "unknown country: "+country)
+			break
+		}
+	}
+
+	val.Check(validator.Unique(movie.ProductionCountries), "production_countries", "must not contain duplicate values")
+
+	// Certifications, checked against the rating system of the country where known
+	certifications := make(map[string]string, len(movie.Certifications))
+
+	for country, certification := range movie.Certifications {
+		country = strings.ToUpper(country)
+
+		if !iso.IsCountry(country) {
+			val.AddError("certifications", "unknown country: "+country)
+			break
+		}
+
+		certification, ok := CanonicalCertification(country, certification)
+		if !ok {
+			val.AddError("certifications", fmt.Sprintf("unknown certification for %s: %s", country, certification))
+			break
+		}
+
+		certifications[country] = certification
+	}
+
+	if movie.Certifications != nil {
+		movie.Certifications = certifications
+	}
+
+	// Release dates
+	releaseDates := make(map[string]string, len(movie.ReleaseDates))
+
+	for country, date := range movie.ReleaseDates {
+		country = strings.ToUpper(country)
+
+		if !iso.IsCountry(country) {
+			val.AddError("release_dates", "unknown country: "+country)
+			break
+		}
+
+		released, err := time.Parse(ReleaseDateLayout, date)
+		if err != nil {
+			val.AddError("release_dates", fmt.Sprintf("must be dates in the format %s", ReleaseDateLayout))
+			break
+		}
+
+		if released.Year() < 1888 {
+			val.AddError("release_dates", "must not be before 1888")
+			break
+		}
+
+		releaseDates[country] = date
+	}
+
+	if movie.ReleaseDates != nil {
+		movie.ReleaseDates = releaseDates
+	}
+}
+
+// Get the certification of a country as spelt by its rating system. For the countries without
+// a known rating system any short certification is accepted as is
+func CanonicalCertification(country, certification string) (string, bool) {
+
+	certification = strings.TrimSpace(certification)
+
+	ratings, found := iso.Certifications(country)
+	if !found {
+		return certification, certification != "" && len(certification) <= 20
+	}
+
+	for _, rating := range ratings {
+		if strings.EqualFold(rating, certification) {
+			return rating, true
+		}
+	}
+
+	return certification, false
 }
@@ -0,0 +1,105 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+func TestCanonicalCertification(t *testing.T) {
+
+	tests := []struct {
+		country       string
+		certification string
+		want          string
+		ok            bool
+	}{
+		{"US", "pg-13", "PG-13", true},
+		{"GB", " 12a ", "12A", true},
+		{"US", "15", "15", false},
+		{"PL", "16", "16", true},
+		{"PL", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.country+" "+tt.certification, func(t *testing.T) {
+
+			got, ok := CanonicalCertification(tt.country, tt.certification)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("got %q, %t; want %q, %t", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestValidateReleaseMetadata(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		movie  Movies
+		errors map[string]string
+	}{
+		{
+			name: "valid metadata",
+			movie: Movies{
+				OriginalLanguage:    "en",
+				SpokenLanguages:     []string{"en", "fr"},
+				ProductionCountries: []string{"US", "GB"},
+				Certifications:      map[string]string{"US": "R", "GB": "15"},
+				ReleaseDates:        map[string]string{"US": "1979-05-25"},
+			},
+			errors: map[string]string{},
+		},
+		{"unknown original language", Movies{OriginalLanguage: "xx"}, map[string]string{"original_language": "must be an ISO 639-1 language code"}},
+		{"unknown spoken language", Movies{SpokenLanguages: []string{"en", "xx"}}, map[string]string{"spoken_languages": "unknown language: xx"}},
+		{"duplicate spoken languages", Movies{SpokenLanguages: []string{"en", "EN"}}, map[string]string{"spoken_languages": "must not contain duplicate values"}},
+		{"unknown production country", Movies{ProductionCountries: []string{"XX"}}, map[string]string{"production_countries": "unknown country: XX"}},
+		{"unknown certification", Movies{Certifications: map[string]string{"US": "15"}}, map[string]string{"certifications": "unknown certification for US: 15"}},
+		{"certification of an unknown country", Movies{Certifications: map[string]string{"XX": "PG"}}, map[string]string{"certifications": "unknown country: XX"}},
+		{"invalid release date", Movies{ReleaseDates: map[string]string{"US": "25/05/1979"}}, map[string]string{"release_dates": "must be dates in the format " + ReleaseDateLayout}},
+		{"release date before cinema", Movies{ReleaseDates: map[string]string{"US": "1850-01-01"}}, map[string]string{"release_dates": "must not be before 1888"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			val := validator.NewValidator()
+			validateReleaseMetadata(val, &tt.movie)
+
+			if !reflect.DeepEqual(val.Errors, tt.errors) {
+				t.Errorf("got errors %v; want %v", val.Errors, tt.errors)
+			}
+		})
+	}
+}
+
+func TestValidateReleaseMetadataNormalizes(t *testing.T) {
+
+	movie := Movies{
+		OriginalLanguage:    "EN",
+		SpokenLanguages:     []string{"En"},
+		ProductionCountries: []string{"us"},
+		Certifications:      map[string]string{"us": "pg-13"},
+		ReleaseDates:        map[string]string{"gb": "1986-08-29"},
+	}
+
+	val := validator.NewValidator()
+	validateReleaseMetadata(val, &movie)
+
+	if !val.IsValid() {
+		t.Fatalf("got errors %v; want none", val.Errors)
+	}
+
+	want := Movies{
+		OriginalLanguage:    "en",
+		SpokenLanguages:     []string{"en"},
+		ProductionCountries: []string{"US"},
+		Certifications:      map[string]string{"US": "PG-13"},
+		ReleaseDates:        map[string]string{"GB": "1986-08-29"},
+	}
+
+	if !reflect.DeepEqual(movie, want) {
+		t.Errorf("got %+v; want %+v", movie, want)
+	}
+}
@@ -0,0 +1,90 @@
+package data
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/narinderv/blockbuster/internal/iso"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+var (
+	ErrParentalRestricted = errors.New("restricted by parental controls")
+)
+
+var parentalPinRX = regexp.MustCompile(`^[0-9]+$`)
+
+// Maximum certification a user may see, in the rating system of a country. Movies without
+// a certification in the country are restricted as well, as nothing is known about them
+type ParentalLimit struct {
+	Country          string `json:"country"`
+	MaxCertification string `json:"max_certification"`
+}
+
+// Get the certifications permitted by the limit, from the least restrictive up to the maximum
+func (limit *ParentalLimit) Permitted() []string {
+
+	ratings, _ := iso.Certifications(limit.Country)
+
+	for i, rating := range ratings {
+		if rating == limit.MaxCertification {
+			return ratings[:i+1]
+		}
+	}
+
+	return []string{}
+}
+
+// Check if a movie with the given certifications is permitted by the limit
+func (limit *ParentalLimit) Permits(certifications map[string]string) bool {
+
+	certification, found := certifications[limit.Country]
+
+	return found && validator.Permittedvalues(certification, limit.Permitted()...)
+}
+
+// Get the arguments of the parental limit clause of the movie queries
+// (movies.certifications->>$country = ANY($permitted) OR $permitted IS NULL). No limit permits all the movies
+func parentalLimitArgs(limit *ParentalLimit) (string, interface{}) {
+
+	if limit == nil {
+		return "", pq.Array([]string(nil))
+	}
+
+	return limit.Country, pq.Array(limit.Permitted())
+}
+
+// Validate the parental limit. Limits can only be set in the countries with a known rating system,
+// whose certifications can be ordered
+func ValidateParentalLimit(val *validator.Validator, limit *ParentalLimit) {
+
+	limit.Country = strings.ToUpper(limit.Country)
+
+	val.Check(limit.Country != "", "country", "must be provided")
+	val.Check(limit.MaxCertification != "", "max_certification", "must be provided")
+
+	if limit.Country == "" || limit.MaxCertification == "" {
+		return
+	}
+
+	if _, found := iso.Certifications(limit.Country); !found {
+		val.AddError("country", "must be a country with a known rating system")
+		return
+	}
+
+	certification, ok := CanonicalCertification(limit.Country, limit.MaxCertification)
+
+	val.Check(ok, "max_certification", "unknown certification for "+limit.Country)
+
+	limit.MaxCertification = certification
+}
+
+// Validate the plaintext parental PIN
+func ValidateParentalPin(val *validator.Validator, pin string) {
+
+	val.Check(pin != "", "pin", "must be provided")
+	val.Check(len(pin) >= 6 && len(pin) <= 12, "pin", "must be 6 to 12 digits long")
+	val.Check(validator.MatchPattern(pin, parentalPinRX), "pin", "must only contain digits")
+}
@@ -0,0 +1,89 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+func TestParentalLimitPermits(t *testing.T) {
+
+	limit := &ParentalLimit{Country: "US", MaxCertification: "PG-13"}
+
+	tests := []struct {
+		name           string
+		certifications map[string]string
+		want           bool
+	}{
+		{"less restrictive", map[string]string{"US": "PG"}, true},
+		{"the maximum", map[string]string{"US": "PG-13"}, true},
+		{"more restrictive", map[string]string{"US": "R"}, false},
+		{"unrated", map[string]string{"US": "NR"}, false},
+		{"certified elsewhere only", map[string]string{"GB": "U"}, false},
+		{"no certification", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := limit.Permits(tt.certifications); got != tt.want {
+				t.Errorf("got %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateParentalLimit(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		limit  ParentalLimit
+		errors map[string]string
+	}{
+		{"valid limit", ParentalLimit{Country: "gb", MaxCertification: "12a"}, map[string]string{}},
+		{"missing country", ParentalLimit{MaxCertification: "PG"}, map[string]string{"country": "must be provided"}},
+		{"missing certification", ParentalLimit{Country: "US"}, map[string]string{"max_certification": "must be provided"}},
+		{"country without a rating system", ParentalLimit{Country: "PL", MaxCertification: "16"}, map[string]string{"country": "must be a country with a known rating system"}},
+		{"unknown certification", ParentalLimit{Country: "US", MaxCertification: "15"}, map[string]string{"max_certification": "unknown certification for US"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			val := validator.NewValidator()
+			ValidateParentalLimit(val, &tt.limit)
+
+			if !reflect.DeepEqual(val.Errors, tt.errors) {
+				t.Errorf("got errors %v; want %v", val.Errors, tt.errors)
+			}
+		})
+	}
+}
+
+func TestValidateParentalPin(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		pin    string
+		errors map[string]string
+	}{
+		{"six digits", "274916", map[string]string{}},
+		{"twelve digits", "274916274916", map[string]string{}},
+		{"missing", "", map[string]string{"pin": "must be provided"}},
+		{"four digits", "2749", map[string]string{"pin": "must be 6 to 12 digits long"}},
+		{"too long", "2749162749162", map[string]string{"pin": "must be 6 to 12 digits long"}},
+		{"letters", "27491a", map[string]string{"pin": "must only contain digits"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			val := validator.NewValidator()
+			ValidateParentalPin(val, tt.pin)
+
+			if !reflect.DeepEqual(val.Errors, tt.errors) {
+				t.Errorf("got errors %v; want %v", val.Errors, tt.errors)
+			}
+		})
+	}
+}
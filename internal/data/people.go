@@ -0,0 +1,230 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// A person who has worked on one or more movies e.g. an actor or a director. People are shared by all the tenants
+type Person struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	Name      string    `json:"name"`
+	BirthYear int32     `json:"birth_year,omitempty"`
+	Biography string    `json:"biography,omitempty"`
+	Version   int32     `json:"info_version"`
+}
+
+type PersonModel struct {
+	DB DBTX
+}
+
+func (m PersonModel) Insert(ctxt context.Context, person *Person) error {
+
+	// Insert query
+	query := `INSERT INTO people (name, birth_year, biography)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at, version`
+
+	// Argumets to the query
+	args := []interface{}{person.Name, person.BirthYear, person.Biography}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "people.insert")
+	defer cancel()
+
+	// Execute the query and store the result
+	return m.DB.QueryRowContext(ctxt, query, args...).Scan(&person.ID, &person.CreatedAt, &person.Version)
+}
+
+func (m PersonModel) Get(ctxt context.Context, id int64) (*Person, error) {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	// Get query
+	query := `SELECT id, created_at, name, birth_year, biography, version
+	FROM people
+	WHERE id = $1`
+
+	// Response structure
+	var person Person
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "people.get")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, id).Scan(&person.ID, &person.CreatedAt, &person.Name,
+		&person.BirthYear, &person.Biography, &person.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &person, nil
+}
+
+func (m PersonModel) Update(ctxt context.Context, person *Person) error {
+
+	// Update query
+	query := `UPDATE people
+	SET name = $1, birth_year = $2, biography = $3, version = version + 1
+	WHERE id = $4 AND version = $5
+	RETURNING version`
+
+	// Argumets to the query
+	args := []interface{}{person.Name, person.BirthYear, person.Biography, person.ID, person.Version}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "people.update")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, args...).Scan(&person.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m PersonModel) Delete(ctxt context.Context, id int64) error {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	// Query
+	query := "DELETE FROM people WHERE id = $1"
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "people.delete")
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctxt, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Get the person with exactly the given name, ignoring the case. If more than one person has the name,
+// the one added first is returned
+func (m PersonModel) GetByName(ctxt context.Context, name string) (*Person, error) {
+
+	query := `SELECT id, created_at, name, birth_year, biography, version
+	FROM people
+	WHERE lower(name) = lower($1)
+	ORDER BY id
+	LIMIT 1`
+
+	var person Person
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "people.get_by_name")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, name).Scan(&person.ID, &person.CreatedAt, &person.Name,
+		&person.BirthYear, &person.Biography, &person.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &person, nil
+}
+
+// Search the people by name using full text search
+func (m PersonModel) GetAll(ctxt context.Context, name string, filters Filters) ([]*Person, Metadata, error) {
+
+	query := fmt.Sprintf(`
+			SELECT count(*) OVER(), id, created_at, name, birth_year, biography, version
+			FROM people WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
+			ORDER BY %s %s, id
+			LIMIT $2 OFFSET $3`, filters.getSortColumn(), filters.getSortDirection())
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "people.get_all")
+	defer cancel()
+
+	// Execute the query
+	rows, err := m.DB.QueryContext(ctxt, query, name, filters.getLimit(), filters.getOffset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	// Variable to hold the data returned
+	people := []*Person{}
+	totalRecords := 0
+
+	// Traverse the rows to get the data
+	for rows.Next() {
+		var person Person
+
+		err = rows.Scan(&totalRecords, &person.ID, &person.CreatedAt, &person.Name,
+			&person.BirthYear, &person.Biography, &person.Version)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		people = append(people, &person)
+	}
+
+	// Check if any error occured while iterating
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	// Generate the metadata
+	metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return people, metadata, nil
+}
+
+func ValidatePerson(val *validator.Validator, person *Person) {
+
+	// Name
+	val.Check(person.Name != "", "name", "must be provided")
+	val.Check(len(person.Name) <= validator.MAX_LEN, "name", "must not be more than 500 bytes")
+
+	// Birth year is optional
+	if person.BirthYear != 0 {
+		val.Check(person.BirthYear >= 1800, "birth_year", "must be greater than 1800")
+		val.Check(person.BirthYear <= int32(time.Now().Year()), "birth_year", "must not be in the future")
+	}
+
+	// Biography
+	val.Check(len(person.Biography) <= 10_000, "biography", "must not be more than 10000 bytes")
+}
@@ -0,0 +1,70 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPeopleSearch(t *testing.T) {
+
+	models := newTestModels(t)
+	ctxt := context.Background()
+
+	people := []*Person{
+		{Name: "Ridley Scott", BirthYear: 1937},
+		{Name: "Tony Scott", BirthYear: 1944},
+		{Name: "Sigourney Weaver", BirthYear: 1949},
+		{Name: "ridley scott"},
+	}
+
+	for _, person := range people {
+		if err := models.People.Insert(ctxt, person); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		search string
+		want   []string
+	}{
+		{"surname", "scott", []string{"Ridley Scott", "Tony Scott", "ridley scott"}},
+		{"full name", "sigourney weaver", []string{"Sigourney Weaver"}},
+		{"no match", "hitchcock", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			found, _, err := models.People.GetAll(ctxt, tt.search, Filters{Page: 1, PageSize: 20, Sort: "id", SortList: []string{"id"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, person := range found {
+				got = append(got, person.Name)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+
+	// The name is matched ignoring the case, and the person added first wins
+	person, err := models.People.GetByName(ctxt, "RIDLEY SCOTT")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if person.ID != people[0].ID {
+		t.Errorf("get by name: got person %d; want %d", person.ID, people[0].ID)
+	}
+
+	if _, err = models.People.GetByName(ctxt, "Ridley"); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("get by partial name: got error %v; want %v", err, ErrRecordNotFound)
+	}
+}
@@ -0,0 +1,90 @@
+package data
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+// Permission codes
+const (
+	PermissionModerateReviews  = "reviews:moderate"
+	PermissionManageInventory  = "inventory:manage"
+	PermissionManageHouseholds = "households:manage"
+	PermissionWriteMovies      = "movies:write"
+)
+
+// Permission codes held by a user
+type Permissions []string
+
+// Check if the permission code is present
+func (p Permissions) Include(code string) bool {
+
+	for _, permission := range p {
+		if permission == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Permission Model
+type PermissionModel struct {
+	DB DBTX
+}
+
+// Get all the permission codes of the user
+func (m PermissionModel) GetAllForUser(ctxt context.Context, userID int64) (Permissions, error) {
+
+	query := `
+		SELECT permissions.code
+		FROM permissions
+		INNER JOIN users_permissions ON users_permissions.permission_id = permissions.id
+		WHERE users_permissions.user_id = $1`
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "permissions.get_all_for_user")
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctxt, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var permissions Permissions
+
+	for rows.Next() {
+		var permission string
+
+		if err = rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// Grant the permission codes to the user
+func (m PermissionModel) AddForUser(ctxt context.Context, userID int64, codes ...string) error {
+
+	query := `
+		INSERT INTO users_permissions
+		SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)
+		ON CONFLICT DO NOTHING`
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "permissions.add_for_user")
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctxt, query, userID, pq.Array(codes))
+	return err
+}
@@ -0,0 +1,183 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Kinds of the movie events counted for the popularity
+const (
+	EventView   = "view"
+	EventRental = "rental"
+	EventRating = "rating"
+)
+
+// Weight of each kind of event in the popularity and trending scores. Renting or rating a movie
+// shows more interest than just looking at it
+var EventWeights = map[string]float64{
+	EventView:   1,
+	EventRating: 3,
+	EventRental: 5,
+}
+
+// Time in which the weight of an event in the popularity score halves
+const PopularityHalfLife = 7 * 24 * time.Hour
+
+// Time for which the event counts are kept. The trending window can not be longer than this
+const EventRetention = 60 * 24 * time.Hour
+
+// Get the kinds and weights of the events as arrays, to be joined as unnest($kinds, $weights) AS w(kind, weight)
+func eventWeightArgs() (interface{}, interface{}) {
+
+	kinds := make([]string, 0, len(EventWeights))
+	weights := make([]float64, 0, len(EventWeights))
+
+	for kind, weight := range EventWeights {
+		kinds = append(kinds, kind)
+		weights = append(weights, weight)
+	}
+
+	return pq.Array(kinds), pq.Array(weights)
+}
+
+// Counts of the movie events by the hour, and the popularity scores computed from them
+type PopularityModel struct {
+	DB DBTX
+}
+
+// Count an event of the movie in the bucket of the current hour
+func (m PopularityModel) Record(ctxt context.Context, movieID int64, kind string) error {
+
+	query := `INSERT INTO movie_event_counts (movie_id, bucket, kind, count)
+	VALUES ($1, date_trunc('hour', NOW()), $2, 1)
+	ON CONFLICT (movie_id, bucket, kind) DO UPDATE SET count = movie_event_counts.count + 1`
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "popularity.record")
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctxt, query, movieID, kind)
+	if err != nil && isForeignKeyViolation(err) {
+		// The movie has been deleted in the meantime
+		return nil
+	}
+
+	return err
+}
+
+// Recompute the popularity of all the movies as the weighted sum of their events, each decayed
+// exponentially by its age, and drop the counts past the retention. The number of movies whose
+// popularity changed is returned
+func (m PopularityModel) Refresh(ctxt context.Context) (int64, error) {
+
+	// Create a DB context to timeout the queries if they exceed a certian duration
+	ctxt, cancel := withTimeout(ctxt, "popularity.refresh")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctxt, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	query := `DELETE FROM movie_event_counts WHERE bucket < NOW() - make_interval(secs => $1)`
+
+	_, err = tx.ExecContext(ctxt, query, EventRetention.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	query = `WITH scores AS (
+		SELECT e.movie_id, sum(e.count * w.weight * exp(-ln(2) * extract(epoch FROM NOW() - e.bucket) / $3)) AS score
+		FROM movie_event_counts e
+		INNER JOIN unnest($1::text[], $2::float8[]) AS w(kind, weight) ON w.kind = e.kind
+		GROUP BY e.movie_id
+	)
+	UPDATE movies
+	SET popularity = COALESCE(s.score, 0)
+	FROM movies m
+	LEFT JOIN scores s ON s.movie_id = m.id
+	WHERE movies.id = m.id AND movies.popularity <> COALESCE(s.score, 0)`
+
+	kinds, weights := eventWeightArgs()
+
+	res, err := tx.ExecContext(ctxt, query, kinds, weights, PopularityHalfLife.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return count, tx.Commit()
+}
+
+// Get the movies with the most events in the window up to now, most trending first. Unlike the
+// popularity, the events are not decayed so that the trending movies always reflect the window.
+// Only the fields requested in the sparse fieldset of the filters are populated
+func (m MovieModel) GetTrending(ctxt context.Context, window time.Duration, filters Filters) ([]*Movies, Metadata, error) {
+
+	columns, _ := (&Movies{}).projection(filters.Fields)
+
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), %s
+		FROM %s
+		INNER JOIN (
+			SELECT e.movie_id, sum(e.count * w.weight) AS score
+			FROM movie_event_counts e
+			INNER JOIN unnest($1::text[], $2::float8[]) AS w(kind, weight) ON w.kind = e.kind
+			WHERE e.bucket >= date_trunc('hour', NOW() - make_interval(secs => $3))
+			GROUP BY e.movie_id
+		) AS t ON t.movie_id = movies.id
+		WHERE (certifications->>$4 = ANY($5::text[]) OR $5 IS NULL)
+		AND tenant_id = $6
+		ORDER BY t.score DESC, id
+		LIMIT $7 OFFSET $8`, strings.Join(columns, ", "), movieSource)
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "movies.get_trending")
+	defer cancel()
+
+	kinds, weights := eventWeightArgs()
+	limitCountry, permitted := m.limitArgs()
+
+	rows, err := m.DB.QueryContext(ctxt, query, kinds, weights, window.Seconds(), limitCountry, permitted, m.TenantID,
+		filters.getLimit(), filters.getOffset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	movies := []*Movies{}
+	totalRecords := 0
+
+	for rows.Next() {
+		var movie Movies
+
+		_, dest := movie.projection(filters.Fields)
+
+		if err = rows.Scan(append([]interface{}{&totalRecords}, dest...)...); err != nil {
+			return nil, Metadata{}, err
+		}
+
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	metadata.ParentalLimit = m.Limit
+
+	return movies, metadata, nil
+}
@@ -0,0 +1,260 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+var (
+	ErrTooManyProfiles      = errors.New("too many profiles")
+	ErrDuplicateProfileName = errors.New("duplicate profile name")
+)
+
+// Maximum number of profiles of a household
+const MaxProfiles = 6
+
+// Viewer profile of a household. Each profile keeps its own ratings and lists, and may have its
+// own parental limit, which takes the place of the limit of the account while the profile is selected
+type Profile struct {
+	ID            int64          `json:"id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	HouseholdID   int64          `json:"household_id"`
+	Name          string         `json:"name"`
+	ParentalLimit *ParentalLimit `json:"parental_limit,omitempty"`
+	Version       int32          `json:"info_version"`
+}
+
+// Fill in the parental limit from the stored columns. An empty country means no limit
+func (profile *Profile) setParentalLimit(country, maxCertification string) {
+
+	profile.ParentalLimit = nil
+
+	if country != "" {
+		profile.ParentalLimit = &ParentalLimit{Country: country, MaxCertification: maxCertification}
+	}
+}
+
+// Get the parental limit as stored in the columns
+func (profile *Profile) parentalLimitColumns() (string, string) {
+
+	if profile.ParentalLimit == nil {
+		return "", ""
+	}
+
+	return profile.ParentalLimit.Country, profile.ParentalLimit.MaxCertification
+}
+
+type ProfileModel struct {
+	DB DBTX
+}
+
+// Add a profile to the household, provided it does not have the maximum number of profiles yet.
+// The household is locked so that concurrent inserts cannot go past the maximum
+func (m ProfileModel) Insert(ctxt context.Context, profile *Profile) error {
+
+	// Create a DB context to timeout the queries if they exceed a certian duration
+	ctxt, cancel := withTimeout(ctxt, "profiles.insert")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctxt, nil)
+	if err != nil {
+		return err
+	}
+
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	var count int
+
+	query := `SELECT (SELECT count(*) FROM profiles WHERE household_id = h.id)
+	FROM households h
+	WHERE h.id = $1
+	FOR UPDATE`
+
+	err = tx.QueryRowContext(ctxt, query, profile.HouseholdID).Scan(&count)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if count >= MaxProfiles {
+		return ErrTooManyProfiles
+	}
+
+	query = `INSERT INTO profiles (household_id, name, parental_country, parental_max_certification)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at, version`
+
+	parentalCountry, parentalMaxCertification := profile.parentalLimitColumns()
+
+	args := []interface{}{profile.HouseholdID, profile.Name, parentalCountry, parentalMaxCertification}
+
+	err = tx.QueryRowContext(ctxt, query, args...).Scan(&profile.ID, &profile.CreatedAt, &profile.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "profiles_household_name_key"`:
+			return ErrDuplicateProfileName
+		default:
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get a profile of the household
+func (m ProfileModel) Get(ctxt context.Context, householdID, id int64) (*Profile, error) {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT id, created_at, household_id, name, parental_country, parental_max_certification, version
+	FROM profiles
+	WHERE id = $1 AND household_id = $2`
+
+	var profile Profile
+	var parentalCountry, parentalMaxCertification string
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "profiles.get")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, id, householdID).Scan(&profile.ID, &profile.CreatedAt, &profile.HouseholdID,
+		&profile.Name, &parentalCountry, &parentalMaxCertification, &profile.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	profile.setParentalLimit(parentalCountry, parentalMaxCertification)
+
+	return &profile, nil
+}
+
+// Get the profiles of the household, in the order they were added
+func (m ProfileModel) GetAllForHousehold(ctxt context.Context, householdID int64) ([]*Profile, error) {
+
+	query := `SELECT id, created_at, household_id, name, parental_country, parental_max_certification, version
+	FROM profiles
+	WHERE household_id = $1
+	ORDER BY id`
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "profiles.get_all_for_household")
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctxt, query, householdID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	profiles := []*Profile{}
+
+	for rows.Next() {
+		var profile Profile
+		var parentalCountry, parentalMaxCertification string
+
+		err = rows.Scan(&profile.ID, &profile.CreatedAt, &profile.HouseholdID, &profile.Name,
+			&parentalCountry, &parentalMaxCertification, &profile.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		profile.setParentalLimit(parentalCountry, parentalMaxCertification)
+
+		profiles = append(profiles, &profile)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+func (m ProfileModel) Update(ctxt context.Context, profile *Profile) error {
+
+	query := `UPDATE profiles
+	SET name = $1, parental_country = $2, parental_max_certification = $3, version = version + 1
+	WHERE id = $4 AND household_id = $5 AND version = $6
+	RETURNING version`
+
+	parentalCountry, parentalMaxCertification := profile.parentalLimitColumns()
+
+	args := []interface{}{profile.Name, parentalCountry, parentalMaxCertification, profile.ID, profile.HouseholdID, profile.Version}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "profiles.update")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, args...).Scan(&profile.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "profiles_household_name_key"`:
+			return ErrDuplicateProfileName
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete a profile of the household along with its ratings and lists
+func (m ProfileModel) Delete(ctxt context.Context, householdID, id int64) error {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM profiles WHERE id = $1 AND household_id = $2`
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "profiles.delete")
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctxt, query, id, householdID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func ValidateProfile(val *validator.Validator, profile *Profile) {
+
+	val.Check(profile.Name != "", "name", "must be provided")
+	val.Check(len(profile.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	if profile.ParentalLimit != nil {
+		ValidateParentalLimit(val, profile.ParentalLimit)
+	}
+}
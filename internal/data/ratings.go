@@ -0,0 +1,128 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// Rating of a movie by a user on a scale of 1 to 10. A user has at most one rating per movie for
+// each of the profiles of the household, and one of their own when no profile is selected
+type Rating struct {
+	UserID    int64     `json:"user_id"`
+	ProfileID int64     `json:"profile_id,omitempty"`
+	MovieID   int64     `json:"movie_id"`
+	Rating    int32     `json:"rating"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Aggregate of the ratings of a movie
+type RatingSummary struct {
+	MovieID int64   `json:"movie_id"`
+	Average float64 `json:"average"`
+	Count   int32   `json:"count"`
+}
+
+type RatingModel struct {
+	DB DBTX
+}
+
+// Insert the rating, or replace the existing rating of the user and profile for the movie
+func (m RatingModel) Upsert(ctxt context.Context, rating *Rating) error {
+
+	query := `INSERT INTO ratings (user_id, profile_id, movie_id, rating)
+	VALUES ($1, NULLIF($2::bigint, 0), $3, $4)
+	ON CONFLICT (user_id, (COALESCE(profile_id, 0)), movie_id) DO UPDATE SET rating = EXCLUDED.rating, updated_at = NOW()
+	RETURNING created_at, updated_at`
+
+	args := []interface{}{rating.UserID, rating.ProfileID, rating.MovieID, rating.Rating}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "ratings.upsert")
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctxt, query, args...).Scan(&rating.CreatedAt, &rating.UpdatedAt)
+}
+
+// Get the rating of a movie by the user and profile. A profile ID of zero is the user's own rating
+func (m RatingModel) Get(ctxt context.Context, userID, profileID, movieID int64) (*Rating, error) {
+
+	query := `SELECT user_id, COALESCE(profile_id, 0), movie_id, rating, created_at, updated_at
+	FROM ratings
+	WHERE user_id = $1 AND COALESCE(profile_id, 0) = $2 AND movie_id = $3`
+
+	var rating Rating
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "ratings.get")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, userID, profileID, movieID).Scan(&rating.UserID, &rating.ProfileID, &rating.MovieID,
+		&rating.Rating, &rating.CreatedAt, &rating.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &rating, nil
+}
+
+// Delete the rating of a movie by the user and profile
+func (m RatingModel) Delete(ctxt context.Context, userID, profileID, movieID int64) error {
+
+	query := "DELETE FROM ratings WHERE user_id = $1 AND COALESCE(profile_id, 0) = $2 AND movie_id = $3"
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "ratings.delete")
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctxt, query, userID, profileID, movieID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Get the average and the number of ratings of a movie
+func (m RatingModel) GetSummary(ctxt context.Context, movieID int64) (*RatingSummary, error) {
+
+	query := `SELECT COALESCE(avg(rating), 0)::float8, count(*)
+	FROM ratings
+	WHERE movie_id = $1`
+
+	summary := RatingSummary{MovieID: movieID}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "ratings.get_summary")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, movieID).Scan(&summary.Average, &summary.Count)
+	if err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
+}
+
+func ValidateRating(val *validator.Validator, rating *Rating) {
+	val.Check(rating.Rating != 0, "rating", "must be provided")
+	val.Check(rating.Rating >= 1 && rating.Rating <= 10, "rating", "must be between 1 and 10")
+}
@@ -0,0 +1,60 @@
+package data
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRatingUpsertAndSummary(t *testing.T) {
+
+	models := newTestModels(t)
+	ctxt := context.Background()
+
+	alice := addTestUser(t, models, DefaultTenantID, "alice@example.com")
+	bob := addTestUser(t, models, DefaultTenantID, "bob@example.com")
+
+	alien := addTestMovie(t, models, DefaultTenantID, &Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"horror"}})
+
+	ratings := []*Rating{
+		{UserID: alice.ID, MovieID: alien.ID, Rating: 4},
+		{UserID: bob.ID, MovieID: alien.ID, Rating: 8},
+		// Rating the movie again replaces the earlier rating of the user
+		{UserID: alice.ID, MovieID: alien.ID, Rating: 10},
+	}
+
+	for _, rating := range ratings {
+		if err := models.Ratings.Upsert(ctxt, rating); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rating, err := models.Ratings.Get(ctxt, alice.ID, 0, alien.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rating.Rating != 10 {
+		t.Errorf("got rating %d; want 10", rating.Rating)
+	}
+
+	summary, err := models.Ratings.GetSummary(ctxt, alien.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Count != 2 || summary.Average != 9 {
+		t.Errorf("got %d ratings averaging %v; want 2 averaging 9", summary.Count, summary.Average)
+	}
+
+	if err = models.Ratings.Delete(ctxt, bob.ID, 0, alien.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if summary, err = models.Ratings.GetSummary(ctxt, alien.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Count != 1 || summary.Average != 10 {
+		t.Errorf("after delete: got %d ratings averaging %v; want 1 averaging 10", summary.Count, summary.Average)
+	}
+}
@@ -0,0 +1,191 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Parameters of the item-item collaborative filtering
+const (
+	RentalStrength       = 0.6 // Strength of the interest shown by renting a movie which the user has not rated
+	MinNeighbourSupport  = 2   // Minimum number of users who interacted with both the movies
+	MaxNeighbours        = 50  // Maximum number of neighbours kept for each movie
+	PopularityWindowDays = 90  // Days of rentals counted for the popularity
+	PopularityWeight     = 0.2 // Weight of the popularity in the blended score
+)
+
+// Reasons of a recommendation
+const (
+	ReasonHistory = "history" // Neighbour of the movies rated or rented by the user
+	ReasonPopular = "popular" // Popular with the other users
+)
+
+// A movie recommended to a user along with the score and the main reason for it
+type Recommendation struct {
+	Score           float64 `json:"score"`
+	Reason          string  `json:"reason"`
+	HistoryScore    float64 `json:"history_score"`
+	PopularityScore float64 `json:"popularity_score"`
+	Movie           *Movies `json:"movie"`
+}
+
+// Recommendations of the movies. The movies restricted by the parental limit, if any, are never recommended
+type RecommendationModel struct {
+	DB    DBTX
+	Limit *ParentalLimit
+}
+
+// Source of the interactions of the users with the movies. The strength of an interaction is the rating scaled
+// to between 0 and 1, or RentalStrength for movies which have been rented but not rated
+var interactionSource = fmt.Sprintf(`(
+	SELECT user_id, movie_id, max(strength) AS strength
+	FROM (
+		SELECT user_id, movie_id, rating / 10.0 AS strength FROM ratings
+		UNION ALL
+		SELECT r.user_id, r.movie_id, %v FROM rentals r
+		WHERE NOT EXISTS (SELECT 1 FROM ratings WHERE user_id = r.user_id AND movie_id = r.movie_id)
+	) s
+	GROUP BY user_id, movie_id
+)`, RentalStrength)
+
+// Recompute the collaborative filtering neighbours of all the movies and return the number of pairs stored.
+// Movies are scored by the cosine similarity of the interaction strengths of the users who interacted with both.
+// The old neighbours stay visible to the readers until the new ones are committed.
+func (m RecommendationModel) Refresh(ctxt context.Context) (int64, error) {
+
+	query := fmt.Sprintf(`WITH interactions AS %s,
+	norms AS (
+		SELECT movie_id, sqrt(sum(strength * strength)) AS norm
+		FROM interactions
+		GROUP BY movie_id
+	),
+	pairs AS (
+		SELECT a.movie_id, b.movie_id AS neighbour_id, sum(a.strength * b.strength) AS dot, count(*) AS support
+		FROM interactions a
+		INNER JOIN interactions b ON a.user_id = b.user_id AND a.movie_id <> b.movie_id
+		GROUP BY a.movie_id, b.movie_id
+		HAVING count(*) >= $1
+	),
+	ranked AS (
+		SELECT p.movie_id, p.neighbour_id, (p.dot / (na.norm * nb.norm))::float8 AS score, p.support,
+		row_number() OVER (PARTITION BY p.movie_id ORDER BY p.dot / (na.norm * nb.norm) DESC, p.neighbour_id) AS rank
+		FROM pairs p
+		INNER JOIN norms na ON na.movie_id = p.movie_id
+		INNER JOIN norms nb ON nb.movie_id = p.neighbour_id
+	)
+	INSERT INTO movie_neighbours (movie_id, neighbour_id, score, support)
+	SELECT movie_id, neighbour_id, score, support
+	FROM ranked
+	WHERE rank <= $2`, interactionSource)
+
+	// Create a DB context to timeout the queries. Scoring all the movies takes longer than a request
+	ctxt, cancel := withTimeout(ctxt, "recommendations.refresh")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctxt, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctxt, `DELETE FROM movie_neighbours`)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctxt, query, MinNeighbourSupport, MaxNeighbours)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return count, tx.Commit()
+}
+
+// Get the recommendations of a user, best first. The neighbours of the movies the user has interacted with
+// are blended with the popular movies, so that users without any history still get recommendations.
+// Movies the user has already rated or rented are excluded, as are the movies of the other tenants.
+func (m RecommendationModel) GetForUser(ctxt context.Context, userID int64, limit int) ([]*Recommendation, error) {
+
+	var movie Movies
+	columns, _ := movie.projection(nil)
+
+	query := fmt.Sprintf(`WITH seen AS (
+		SELECT movie_id, strength FROM %s i WHERE user_id = $1
+	),
+	history AS (
+		SELECT n.neighbour_id AS movie_id, sum(s.strength * n.score) AS score
+		FROM seen s
+		INNER JOIN movie_neighbours n ON n.movie_id = s.movie_id
+		GROUP BY n.neighbour_id
+	),
+	popular AS (
+		SELECT movie_id, count(*) AS n
+		FROM rentals
+		WHERE rented_at > NOW() - make_interval(days => $2)
+		GROUP BY movie_id
+	),
+	blended AS (
+		SELECT mv.id AS movie_id,
+		COALESCE(h.score / NULLIF(max(h.score) OVER (), 0), 0)::float8 AS history_score,
+		COALESCE(p.n / NULLIF(max(p.n) OVER (), 0)::float8, 0)::float8 AS popularity_score
+		FROM movies mv
+		LEFT JOIN history h ON h.movie_id = mv.id
+		LEFT JOIN popular p ON p.movie_id = mv.id
+		WHERE (h.movie_id IS NOT NULL OR p.movie_id IS NOT NULL)
+		AND mv.id NOT IN (SELECT movie_id FROM seen)
+		AND mv.tenant_id = (SELECT tenant_id FROM users WHERE id = $1)
+	)
+	SELECT b.history_score * (1 - $3::float8) + b.popularity_score * $3::float8 AS score, b.history_score, b.popularity_score, %s
+	FROM blended b
+	INNER JOIN %s ON movies.id = b.movie_id
+	WHERE (movies.certifications->>$5 = ANY($6::text[]) OR $6 IS NULL)
+	ORDER BY score DESC, movies.id
+	LIMIT $4`, interactionSource, strings.Join(columns, ", "), movieSource)
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "recommendations.get_for_user")
+	defer cancel()
+
+	limitCountry, permitted := parentalLimitArgs(m.Limit)
+
+	rows, err := m.DB.QueryContext(ctxt, query, userID, PopularityWindowDays, PopularityWeight, limit, limitCountry, permitted)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	recommendations := []*Recommendation{}
+
+	for rows.Next() {
+		entry := Recommendation{Movie: &Movies{}}
+
+		_, dest := entry.Movie.projection(nil)
+		dest = append([]interface{}{&entry.Score, &entry.HistoryScore, &entry.PopularityScore}, dest...)
+
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		entry.Reason = ReasonPopular
+		if entry.HistoryScore > 0 {
+			entry.Reason = ReasonHistory
+		}
+
+		recommendations = append(recommendations, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return recommendations, nil
+}
@@ -0,0 +1,99 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestRecommendations(t *testing.T) {
+
+	models := newTestModels(t)
+	ctxt := context.Background()
+
+	alien := &Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"science-fiction"}}
+	aliens := &Movies{Title: "Aliens", Year: 1986, Runtime: 137, Genres: []string{"action"}, Certifications: map[string]string{"US": "R"}}
+
+	for _, movie := range []*Movies{alien, aliens} {
+		if err := models.Movies.Insert(ctxt, movie); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var users []*User
+
+	for i := 0; i < 3; i++ {
+		user := &User{Name: "Viewer", Email: fmt.Sprintf("viewer%d@example.com", i), Activated: true}
+
+		if err := user.Password.SetPasswordHash("pa55word1234"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := models.Users.Insert(ctxt, user); err != nil {
+			t.Fatal(err)
+		}
+
+		users = append(users, user)
+	}
+
+	// The first two viewers liked both the movies, the third has only seen Alien
+	ratings := []*Rating{
+		{UserID: users[0].ID, MovieID: alien.ID, Rating: 10},
+		{UserID: users[0].ID, MovieID: aliens.ID, Rating: 8},
+		{UserID: users[1].ID, MovieID: alien.ID, Rating: 9},
+		{UserID: users[1].ID, MovieID: aliens.ID, Rating: 9},
+		{UserID: users[2].ID, MovieID: alien.ID, Rating: 9},
+	}
+
+	for _, rating := range ratings {
+		if err := models.Ratings.Upsert(ctxt, rating); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, err := models.Recommendations.Refresh(ctxt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The movies are each other's neighbour, supported by two viewers
+	if count != 2 {
+		t.Errorf("got %d neighbours; want 2", count)
+	}
+
+	recommendations, err := models.Recommendations.GetForUser(ctxt, users[2].ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recommendations) != 1 {
+		t.Fatalf("got %d recommendations; want Aliens only", len(recommendations))
+	}
+
+	if got := recommendations[0]; got.Movie.ID != aliens.ID || got.Reason != ReasonHistory || got.HistoryScore != 1 {
+		t.Errorf("got movie %d for %s with history score %f; want Aliens for its history with score 1", got.Movie.ID, got.Reason, got.HistoryScore)
+	}
+
+	// Aliens is left out for a viewer restricted to PG-13
+	restricted := models.Recommendations
+	restricted.Limit = &ParentalLimit{Country: "US", MaxCertification: "PG-13"}
+
+	recommendations, err = restricted.GetForUser(ctxt, users[2].ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recommendations) != 0 {
+		t.Errorf("got %d recommendations past the parental limit; want none", len(recommendations))
+	}
+
+	// The viewers who have seen everything get no recommendations
+	recommendations, err = models.Recommendations.GetForUser(ctxt, users[0].ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recommendations) != 0 {
+		t.Errorf("got %d recommendations for a viewer who has seen both; want none", len(recommendations))
+	}
+}
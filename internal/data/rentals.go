@@ -0,0 +1,391 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/pricing"
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+var (
+	ErrNoCopyAvailable = errors.New("no copy available")
+	ErrAlreadyReturned = errors.New("rental already returned")
+	ErrRenewalLimit    = errors.New("renewal limit reached")
+	ErrRentalIsOverdue = errors.New("rental is overdue")
+	ErrUnknownFormat   = errors.New("unknown format")
+)
+
+// Maximum number of times a rental can be renewed
+const MaxRenewals = 2
+
+// Rental period of each format. Newer formats are in higher demand and are rented out for shorter
+var RentalPeriods = map[string]time.Duration{
+	"dvd":     7 * 24 * time.Hour,
+	"blu-ray": 5 * 24 * time.Hour,
+	"4k":      3 * 24 * time.Hour,
+}
+
+// Rental of a copy of a movie by a user
+type Rental struct {
+	ID              int64      `json:"id"`
+	UserID          int64      `json:"user_id"`
+	InventoryItemID int64      `json:"inventory_item_id"`
+	MovieID         int64      `json:"movie_id"`
+	MovieTitle      string     `json:"movie_title,omitempty"`
+	StoreID         int64      `json:"store_id"`
+	Format          string     `json:"format"`
+	RentedAt        time.Time  `json:"rented_at"`
+	DueAt           time.Time  `json:"due_at"`
+	ReturnedAt      *time.Time `json:"returned_at,omitempty"`
+	Renewals        int32      `json:"renewals"`
+	Version         int32      `json:"info_version"`
+
+	// Charges made to the balance of the user by the last operation on the rental
+	Charges []*LedgerEntry `json:"charges,omitempty"`
+}
+
+// Check if the rental is still out and past its due date
+func (rental *Rental) IsOverdue(now time.Time) bool {
+	return rental.ReturnedAt == nil && now.After(rental.DueAt)
+}
+
+// Rental Model. The rentals, renewals and late returns are charged to the
+// balance ledger of the user as per the pricing rules
+type RentalModel struct {
+	DB      DBTX
+	Pricing *pricing.Engine
+}
+
+// Record a charge for the rental in the ledger of the user as part of the transaction
+func (m RentalModel) charge(ctxt context.Context, tx Tx, rental *Rental, kind string, amount pricing.Money) error {
+
+	// Nothing to charge
+	if amount == 0 {
+		return nil
+	}
+
+	entry := &LedgerEntry{
+		UserID:      rental.UserID,
+		RentalID:    &rental.ID,
+		Kind:        kind,
+		Amount:      amount,
+		Currency:    m.Pricing.Currency(),
+		Description: fmt.Sprintf("%s of %s copy", kind, rental.Format),
+	}
+
+	if err := insertLedgerEntry(ctxt, tx, entry); err != nil {
+		return err
+	}
+
+	rental.Charges = append(rental.Charges, entry)
+
+	return nil
+}
+
+// Rent out an available copy of the movie from the store. If the format is empty, a copy of any format
+// is rented out. The copy is locked with SKIP LOCKED, so that concurrent checkouts pick different copies
+// instead of waiting on each other. The household of the user must not have reached its rental limit.
+func (m RentalModel) Checkout(ctxt context.Context, userID, movieID, storeID int64, format string) (*Rental, error) {
+
+	// Create a DB context to timeout the queries if they exceed a certian duration
+	ctxt, cancel := withTimeout(ctxt, "rentals.checkout")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctxt, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	rental := &Rental{
+		UserID:  userID,
+		MovieID: movieID,
+		StoreID: storeID,
+	}
+
+	if err = checkHouseholdRentalLimit(ctxt, tx, userID); err != nil {
+		return nil, err
+	}
+
+	// A copy set aside for a ready hold of the user is rented out first
+	heldItemID, err := claimHeldCopy(ctxt, tx, userID, movieID, storeID, format)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lock an available copy, or the held copy
+	query := `SELECT id, format
+	FROM inventory_items
+	WHERE movie_id = $1 AND store_id = $2
+	AND ((status = 'available' AND (format = $3 OR $3 = '')) OR (status = 'on_hold' AND id = $4))
+	ORDER BY id = $4 DESC, id
+	LIMIT 1
+	FOR UPDATE SKIP LOCKED`
+
+	err = tx.QueryRowContext(ctxt, query, movieID, storeID, format, heldItemID).Scan(&rental.InventoryItemID, &rental.Format)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNoCopyAvailable
+		default:
+			return nil, err
+		}
+	}
+
+	period, found := RentalPeriods[rental.Format]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, rental.Format)
+	}
+
+	// Mark the copy as rented
+	query = `UPDATE inventory_items
+	SET status = 'rented', version = version + 1
+	WHERE id = $1`
+
+	_, err = tx.ExecContext(ctxt, query, rental.InventoryItemID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the rental
+	query = `INSERT INTO rentals (user_id, inventory_item_id, movie_id, store_id, format, rented_at, due_at)
+	VALUES ($1, $2, $3, $4, $5, NOW(), NOW() + make_interval(secs => $6))
+	RETURNING id, rented_at, due_at, version`
+
+	args := []interface{}{rental.UserID, rental.InventoryItemID, rental.MovieID, rental.StoreID, rental.Format, int64(period.Seconds())}
+
+	err = tx.QueryRowContext(ctxt, query, args...).Scan(&rental.ID, &rental.RentedAt, &rental.DueAt, &rental.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Charge the rental
+	price, err := m.Pricing.RentalPrice(rental.Format, rental.RentedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.charge(ctxt, tx, rental, LedgerRental, price); err != nil {
+		return nil, err
+	}
+
+	return rental, tx.Commit()
+}
+
+// Return the rented copy. The copy is set aside for the next waiting hold, which is returned,
+// or becomes available for renting again
+func (m RentalModel) Return(ctxt context.Context, rental *Rental) (*Hold, error) {
+
+	// Create a DB context to timeout the queries if they exceed a certian duration
+	ctxt, cancel := withTimeout(ctxt, "rentals.return")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctxt, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	query := `UPDATE rentals
+	SET returned_at = NOW(), version = version + 1
+	WHERE id = $1 AND returned_at IS NULL
+	RETURNING returned_at, version`
+
+	var returnedAt time.Time
+
+	err = tx.QueryRowContext(ctxt, query, rental.ID).Scan(&returnedAt, &rental.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrAlreadyReturned
+		default:
+			return nil, err
+		}
+	}
+
+	rental.ReturnedAt = &returnedAt
+
+	// Pass the copy on to the next waiting hold
+	hold, err := assignCopy(ctxt, tx, rental.InventoryItemID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Charge the late fee, if any
+	fee, err := m.Pricing.LateFee(rental.Format, rental.DueAt, returnedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.charge(ctxt, tx, rental, LedgerLateFee, fee); err != nil {
+		return nil, err
+	}
+
+	return hold, tx.Commit()
+}
+
+// Extend the due date of the rental by another rental period of its format.
+// Returned and overdue rentals can not be renewed.
+func (m RentalModel) Renew(ctxt context.Context, rental *Rental) error {
+
+	switch {
+	case rental.ReturnedAt != nil:
+		return ErrAlreadyReturned
+	case rental.Renewals >= MaxRenewals:
+		return ErrRenewalLimit
+	case rental.IsOverdue(time.Now()):
+		return ErrRentalIsOverdue
+	}
+
+	period, found := RentalPeriods[rental.Format]
+	if !found {
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, rental.Format)
+	}
+
+	query := `UPDATE rentals
+	SET due_at = due_at + make_interval(secs => $1), renewals = renewals + 1, version = version + 1
+	WHERE id = $2 AND version = $3 AND returned_at IS NULL AND renewals < $4
+	RETURNING due_at, renewals, version`
+
+	args := []interface{}{int64(period.Seconds()), rental.ID, rental.Version, MaxRenewals}
+
+	// Create a DB context to timeout the queries if they exceed a certian duration
+	ctxt, cancel := withTimeout(ctxt, "rentals.renew")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctxt, nil)
+	if err != nil {
+		return err
+	}
+
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctxt, query, args...).Scan(&rental.DueAt, &rental.Renewals, &rental.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	// Charge the renewal
+	price, err := m.Pricing.RenewalPrice(rental.Format)
+	if err != nil {
+		return err
+	}
+
+	if err = m.charge(ctxt, tx, rental, LedgerRenewal, price); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m RentalModel) Get(ctxt context.Context, id int64) (*Rental, error) {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT r.id, r.user_id, r.inventory_item_id, r.movie_id, m.title, r.store_id, r.format,
+	r.rented_at, r.due_at, r.returned_at, r.renewals, r.version
+	FROM rentals r
+	INNER JOIN movies m ON m.id = r.movie_id
+	WHERE r.id = $1`
+
+	var rental Rental
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "rentals.get")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, id).Scan(&rental.ID, &rental.UserID, &rental.InventoryItemID,
+		&rental.MovieID, &rental.MovieTitle, &rental.StoreID, &rental.Format, &rental.RentedAt, &rental.DueAt,
+		&rental.ReturnedAt, &rental.Renewals, &rental.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &rental, nil
+}
+
+// Get the rentals of a user. The status can be "active", "returned" or "overdue", or empty for all the rentals
+func (m RentalModel) GetAllForUser(ctxt context.Context, userID int64, status string, filters Filters) ([]*Rental, Metadata, error) {
+
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), r.id, r.user_id, r.inventory_item_id, r.movie_id, m.title, r.store_id, r.format,
+		r.rented_at, r.due_at, r.returned_at, r.renewals, r.version
+		FROM rentals r
+		INNER JOIN movies m ON m.id = r.movie_id
+		WHERE r.user_id = $1
+		AND ($2 = ''
+			OR ($2 = 'active' AND r.returned_at IS NULL)
+			OR ($2 = 'returned' AND r.returned_at IS NOT NULL)
+			OR ($2 = 'overdue' AND r.returned_at IS NULL AND r.due_at < NOW()))
+		ORDER BY r.%s %s, r.id
+		LIMIT $3 OFFSET $4`, filters.getSortColumn(), filters.getSortDirection())
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "rentals.get_all_for_user")
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctxt, query, userID, status, filters.getLimit(), filters.getOffset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	rentals := []*Rental{}
+	totalRecords := 0
+
+	for rows.Next() {
+		var rental Rental
+
+		err = rows.Scan(&totalRecords, &rental.ID, &rental.UserID, &rental.InventoryItemID, &rental.MovieID,
+			&rental.MovieTitle, &rental.StoreID, &rental.Format, &rental.RentedAt, &rental.DueAt,
+			&rental.ReturnedAt, &rental.Renewals, &rental.Version)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		rentals = append(rentals, &rental)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return rentals, metadata, nil
+}
+
+// Validate a checkout request
+func ValidateCheckout(val *validator.Validator, movieID, storeID int64, format string) {
+
+	val.Check(movieID > 0, "movie_id", "must be provided")
+	val.Check(storeID > 0, "store_id", "must be provided")
+
+	// Format is optional
+	if format != "" {
+		val.Check(validator.Permittedvalues(format, InventoryFormats...), "format", "invalid format value")
+	}
+}
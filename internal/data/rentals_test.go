@@ -0,0 +1,71 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+func TestRentalIsOverdue(t *testing.T) {
+
+	due := time.Date(2024, 3, 8, 12, 0, 0, 0, time.UTC)
+	returned := due.Add(48 * time.Hour)
+
+	tests := []struct {
+		name   string
+		rental Rental
+		now    time.Time
+		want   bool
+	}{
+		{"out before the due date", Rental{DueAt: due}, due.Add(-time.Hour), false},
+		{"out at the due date", Rental{DueAt: due}, due, false},
+		{"out past the due date", Rental{DueAt: due}, due.Add(time.Minute), true},
+		{"returned late", Rental{DueAt: due, ReturnedAt: &returned}, returned.Add(time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rental.IsOverdue(tt.now); got != tt.want {
+			t.Errorf("%s: got %t; want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRentalPeriodsCoverFormats(t *testing.T) {
+
+	for _, format := range InventoryFormats {
+		if RentalPeriods[format] <= 0 {
+			t.Errorf("format %s has no rental period", format)
+		}
+	}
+}
+
+func TestValidateCheckout(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		movieID int64
+		storeID int64
+		format  string
+		errors  map[string]string
+	}{
+		{"any format", 1, 1, "", map[string]string{}},
+		{"given format", 1, 1, "4k", map[string]string{}},
+		{"missing movie", 0, 1, "", map[string]string{"movie_id": "must be provided"}},
+		{"missing store", 1, 0, "", map[string]string{"store_id": "must be provided"}},
+		{"unknown format", 1, 1, "vhs", map[string]string{"format": "invalid format value"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			val := validator.NewValidator()
+			ValidateCheckout(val, tt.movieID, tt.storeID, tt.format)
+
+			if !reflect.DeepEqual(val.Errors, tt.errors) {
+				t.Errorf("got errors %v; want %v", val.Errors, tt.errors)
+			}
+		})
+	}
+}
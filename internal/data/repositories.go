@@ -0,0 +1,143 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// Store of the movies. MovieModel keeps the movies in Postgres and MemoryMovieModel in memory,
+// which allows the handlers to be exercised without a database
+type MovieRepository interface {
+	Insert(ctxt context.Context, movie *Movies) error
+	Get(ctxt context.Context, id int64) (*Movies, error)
+	GetFields(ctxt context.Context, id int64, fields []string) (*Movies, error)
+	Update(ctxt context.Context, movie *Movies) error
+	Delete(ctxt context.Context, id int64) error
+	FillPlot(ctxt context.Context, movie *Movies, plot string) (bool, error)
+	MarkEnriched(ctxt context.Context, id int64, externalID string) error
+	MarkEnrichFailed(ctxt context.Context, id int64) error
+	GetUnenriched(ctxt context.Context, limit int) ([]*Movies, error)
+	GetAll(ctxt context.Context, search MovieSearch, filters Filters) ([]*Movies, Metadata, error)
+	GetTrending(ctxt context.Context, window time.Duration, filters Filters) ([]*Movies, Metadata, error)
+
+	// Copy of the repository reading and writing the movies of the tenant
+	ForTenant(tenantID int64) MovieRepository
+	// Copy of the repository reading only the movies permitted by the parental limit. A nil limit permits all the movies
+	WithLimit(limit *ParentalLimit) MovieRepository
+}
+
+// Store of the users. UserModel keeps the users in Postgres and MemoryUserModel in memory
+type UserRepository interface {
+	Insert(ctxt context.Context, user *User) error
+	GetByEmail(ctxt context.Context, email string) (*User, error)
+	Update(ctxt context.Context, user *User) error
+	GetForToken(ctxt context.Context, tokenScope, tokenPlaintext string) (*User, error)
+
+	// Copy of the repository reading and writing the users of the tenant
+	ForTenant(tenantID int64) UserRepository
+}
+
+// Store of the tokens of the users. TokenModel keeps the tokens in Postgres and MemoryTokenModel in memory
+type TokenRepository interface {
+	New(ctxt context.Context, userID int64, ttl time.Duration, scope string) (*Token, error)
+	NewForProfile(ctxt context.Context, userID, profileID int64, ttl time.Duration, scope string) (*Token, error)
+	Insert(ctxt context.Context, token *Token) error
+	DeleteAllForUser(ctxt context.Context, scope string, userID int64) error
+}
+
+// Store of the viewer profiles of the households
+type ProfileRepository interface {
+	Insert(ctxt context.Context, profile *Profile) error
+	Get(ctxt context.Context, householdID, id int64) (*Profile, error)
+	GetAllForHousehold(ctxt context.Context, householdID int64) ([]*Profile, error)
+	Update(ctxt context.Context, profile *Profile) error
+	Delete(ctxt context.Context, householdID, id int64) error
+}
+
+// Store of the tenants. TenantModel keeps the tenants in Postgres and MemoryTenantModel in memory
+type TenantRepository interface {
+	GetBySlug(ctxt context.Context, slug string) (*Tenant, error)
+	GetAll(ctxt context.Context) ([]*Tenant, error)
+}
+
+// Store of the permissions granted to the users
+type PermissionRepository interface {
+	GetAllForUser(ctxt context.Context, userID int64) (Permissions, error)
+	AddForUser(ctxt context.Context, userID int64, codes ...string) error
+}
+
+// Store of the genre taxonomy
+type GenreRepository interface {
+	Insert(ctxt context.Context, genre *Genre) error
+	Get(ctxt context.Context, id int64) (*Genre, error)
+	GetAll(ctxt context.Context) ([]*Genre, error)
+	GetTaxonomy(ctxt context.Context) (*GenreTaxonomy, error)
+	Update(ctxt context.Context, genre *Genre, oldSlug string) error
+	Delete(ctxt context.Context, id int64) error
+	Merge(ctxt context.Context, source, target *Genre) (int64, error)
+}
+
+// Store of the stores of a tenant
+type StoreRepository interface {
+	Insert(ctxt context.Context, store *Store) error
+	Get(ctxt context.Context, id int64) (*Store, error)
+	GetAll(ctxt context.Context) ([]*Store, error)
+	Update(ctxt context.Context, store *Store) error
+	Delete(ctxt context.Context, id int64) error
+
+	// Copy of the repository reading and writing the stores of the tenant
+	ForTenant(tenantID int64) StoreRepository
+}
+
+// Store of the rentable copies of the movies
+type InventoryRepository interface {
+	Insert(ctxt context.Context, item *InventoryItem) error
+	Get(ctxt context.Context, id int64) (*InventoryItem, error)
+	Update(ctxt context.Context, item *InventoryItem) error
+	Delete(ctxt context.Context, id int64) error
+	GetAllForMovie(ctxt context.Context, movieID, storeID int64) ([]*InventoryItem, error)
+	GetAvailability(ctxt context.Context, movieID int64) ([]*Availability, error)
+}
+
+// Store of the cast and crew of the movies
+type CreditRepository interface {
+	Insert(ctxt context.Context, credit *Credit) error
+	Delete(ctxt context.Context, movieID, id int64) error
+	GetForMovies(ctxt context.Context, movieIDs []int64) (map[int64][]*Credit, error)
+	GetFilmography(ctxt context.Context, personID int64) ([]*Credit, error)
+
+	// Copy of the repository listing the movies of the tenant in the filmographies
+	ForTenant(tenantID int64) CreditRepository
+
+	// Copy of the repository leaving the movies restricted by the parental limit out of the filmographies
+	WithLimit(limit *ParentalLimit) CreditRepository
+}
+
+// Store of the collections of a tenant
+type CollectionRepository interface {
+	Insert(ctxt context.Context, collection *Collection) error
+	Get(ctxt context.Context, id int64) (*Collection, error)
+	GetAll(ctxt context.Context) ([]*Collection, error)
+	Update(ctxt context.Context, collection *Collection) error
+	Delete(ctxt context.Context, id int64) error
+	SetMovie(ctxt context.Context, collectionID, movieID int64, position int32) error
+	RemoveMovie(ctxt context.Context, collectionID, movieID int64) error
+	GetForMovies(ctxt context.Context, movieIDs []int64) (map[int64]*CollectionMembership, error)
+
+	// Copy of the repository reading and writing the collections of the tenant
+	ForTenant(tenantID int64) CollectionRepository
+}
+
+// Store of the artwork of the movies
+type MovieImageRepository interface {
+	Insert(ctxt context.Context, image *MovieImage) error
+	Get(ctxt context.Context, id int64) (*MovieImage, error)
+	GetAllForMovie(ctxt context.Context, movieID int64) ([]*MovieImage, error)
+	Delete(ctxt context.Context, id int64) error
+}
+
+// Store of the movie events counted for the popularity
+type PopularityRepository interface {
+	Record(ctxt context.Context, movieID int64, kind string) error
+	Refresh(ctxt context.Context) (int64, error)
+}
@@ -0,0 +1,210 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+var (
+	ErrDuplicateReview = errors.New("duplicate review")
+)
+
+// Moderation statuses of a review. Only approved reviews are visible to everyone
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+var ReviewStatuses = []string{ReviewPending, ReviewApproved, ReviewRejected}
+
+// Review of a movie written by a user. A user can write one review per movie
+type Review struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    int64     `json:"user_id"`
+	UserName  string    `json:"user_name,omitempty"`
+	MovieID   int64     `json:"movie_id"`
+	Body      string    `json:"body"`
+	Status    string    `json:"status"`
+	Version   int32     `json:"info_version"`
+}
+
+type ReviewModel struct {
+	DB DBTX
+}
+
+func (m ReviewModel) Insert(ctxt context.Context, review *Review) error {
+
+	query := `INSERT INTO reviews (user_id, movie_id, body, status)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at, version`
+
+	args := []interface{}{review.UserID, review.MovieID, review.Body, review.Status}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "reviews.insert")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, args...).Scan(&review.ID, &review.CreatedAt, &review.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "reviews_user_movie_key"`:
+			return ErrDuplicateReview
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get a review of the given movie
+func (m ReviewModel) Get(ctxt context.Context, movieID, id int64) (*Review, error) {
+
+	// Validate if ID is valid
+	if movieID < 1 || id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT r.id, r.created_at, r.user_id, u.name, r.movie_id, r.body, r.status, r.version
+	FROM reviews r
+	INNER JOIN users u ON u.id = r.user_id
+	WHERE r.id = $1 AND r.movie_id = $2`
+
+	var review Review
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "reviews.get")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, id, movieID).Scan(&review.ID, &review.CreatedAt, &review.UserID,
+		&review.UserName, &review.MovieID, &review.Body, &review.Status, &review.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &review, nil
+}
+
+func (m ReviewModel) Update(ctxt context.Context, review *Review) error {
+
+	query := `UPDATE reviews
+	SET body = $1, status = $2, version = version + 1
+	WHERE id = $3 AND version = $4
+	RETURNING version`
+
+	args := []interface{}{review.Body, review.Status, review.ID, review.Version}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "reviews.update")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, args...).Scan(&review.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m ReviewModel) Delete(ctxt context.Context, id int64) error {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := "DELETE FROM reviews WHERE id = $1"
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "reviews.delete")
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctxt, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Get the reviews of a movie having the given moderation status
+func (m ReviewModel) GetAllForMovie(ctxt context.Context, movieID int64, status string, filters Filters) ([]*Review, Metadata, error) {
+
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), r.id, r.created_at, r.user_id, u.name, r.movie_id, r.body, r.status, r.version
+		FROM reviews r
+		INNER JOIN users u ON u.id = r.user_id
+		WHERE r.movie_id = $1 AND r.status = $2
+		ORDER BY r.%s %s, r.id
+		LIMIT $3 OFFSET $4`, filters.getSortColumn(), filters.getSortDirection())
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "reviews.get_all_for_movie")
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctxt, query, movieID, status, filters.getLimit(), filters.getOffset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	reviews := []*Review{}
+	totalRecords := 0
+
+	for rows.Next() {
+		var review Review
+
+		err = rows.Scan(&totalRecords, &review.ID, &review.CreatedAt, &review.UserID, &review.UserName,
+			&review.MovieID, &review.Body, &review.Status, &review.Version)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		reviews = append(reviews, &review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return reviews, metadata, nil
+}
+
+func ValidateReview(val *validator.Validator, review *Review) {
+
+	// Body
+	val.Check(review.Body != "", "body", "must be provided")
+	val.Check(len(review.Body) <= 10_000, "body", "must not be more than 10000 bytes")
+
+	// Status
+	val.Check(validator.Permittedvalues(review.Status, ReviewStatuses...), "status", "invalid status value")
+}
@@ -0,0 +1,90 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReviewModerationVisibility(t *testing.T) {
+
+	models := newTestModels(t)
+	ctxt := context.Background()
+
+	alice := addTestUser(t, models, DefaultTenantID, "alice@example.com")
+	bob := addTestUser(t, models, DefaultTenantID, "bob@example.com")
+
+	alien := addTestMovie(t, models, DefaultTenantID, &Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"horror"}})
+
+	first := &Review{UserID: alice.ID, MovieID: alien.ID, Body: "In space no one can hear you scream", Status: ReviewPending}
+	second := &Review{UserID: bob.ID, MovieID: alien.ID, Body: "Too dark", Status: ReviewPending}
+
+	for _, review := range []*Review{first, second} {
+		if err := models.Reviews.Insert(ctxt, review); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	duplicate := &Review{UserID: alice.ID, MovieID: alien.ID, Body: "Still great", Status: ReviewPending}
+	if err := models.Reviews.Insert(ctxt, duplicate); !errors.Is(err, ErrDuplicateReview) {
+		t.Errorf("insert second review of the user: got error %v; want %v", err, ErrDuplicateReview)
+	}
+
+	// A moderator approves the first review and rejects the second one
+	first.Status = ReviewApproved
+	second.Status = ReviewRejected
+
+	for _, review := range []*Review{first, second} {
+		if err := models.Reviews.Update(ctxt, review); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An update with the old version conflicts
+	stale := *first
+	stale.Version--
+
+	if err := models.Reviews.Update(ctxt, &stale); !errors.Is(err, ErrEditConflict) {
+		t.Errorf("update stale review: got error %v; want %v", err, ErrEditConflict)
+	}
+
+	tests := []struct {
+		status string
+		want   []int64
+	}{
+		{ReviewApproved, []int64{first.ID}},
+		{ReviewRejected, []int64{second.ID}},
+		{ReviewPending, []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+
+			reviews, metadata, err := models.Reviews.GetAllForMovie(ctxt, alien.ID, tt.status, Filters{Page: 1, PageSize: 20, Sort: "id", SortList: []string{"id"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []int64{}
+			for _, review := range reviews {
+				got = append(got, review.ID)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got reviews %v; want %v", got, tt.want)
+			}
+
+			if metadata.TotalRecords != len(tt.want) {
+				t.Errorf("got %d records in total; want %d", metadata.TotalRecords, len(tt.want))
+			}
+		})
+	}
+
+	// A review is only read through its own movie
+	other := addTestMovie(t, models, DefaultTenantID, &Movies{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"crime"}})
+
+	if _, err := models.Reviews.Get(ctxt, other.ID, first.ID); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("get review through another movie: got error %v; want %v", err, ErrRecordNotFound)
+	}
+}
@@ -0,0 +1,175 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Weights of the signals in the similarity score of two movies. The signals are each between 0 and 1
+const (
+	SimilarityGenreWeight    = 0.4  // Jaccard index of the genres
+	SimilarityYearWeight     = 0.1  // Proximity of the release years
+	SimilarityCreditWeight   = 0.25 // Jaccard index of the credited people
+	SimilarityCoRatingWeight = 0.25 // Cosine similarity of the users who liked the movies
+)
+
+// Minimum rating for a user to be counted as having liked a movie
+const LikedRating = 7
+
+// Maximum number of similar movies kept for each movie
+const MaxSimilarMovies = 50
+
+// A movie similar to another along with the score and the signals it is made up of
+type SimilarMovie struct {
+	Score         float64 `json:"score"`
+	GenreScore    float64 `json:"genre_score"`
+	YearScore     float64 `json:"year_score"`
+	CreditScore   float64 `json:"credit_score"`
+	CoRatingScore float64 `json:"corating_score"`
+	Movie         *Movies `json:"movie"`
+}
+
+// Similarities of the movies. The similar movies restricted by the parental limit, if any, are left out
+type SimilarityModel struct {
+	DB    DBTX
+	Limit *ParentalLimit
+}
+
+// Get the movies most similar to the movie, best first, from the precomputed similarities
+func (m SimilarityModel) GetForMovie(ctxt context.Context, movieID int64, limit int) ([]*SimilarMovie, error) {
+
+	var movie Movies
+	columns, _ := movie.projection(nil)
+
+	query := fmt.Sprintf(`
+		SELECT s.score, s.genre_score, s.year_score, s.credit_score, s.corating_score, %s
+		FROM movie_similarities s
+		INNER JOIN %s ON movies.id = s.similar_movie_id
+		WHERE s.movie_id = $1
+		AND (movies.certifications->>$2 = ANY($3::text[]) OR $3 IS NULL)
+		ORDER BY s.score DESC, s.similar_movie_id
+		LIMIT $4`, strings.Join(columns, ", "), movieSource)
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "similarities.get_for_movie")
+	defer cancel()
+
+	limitCountry, permitted := parentalLimitArgs(m.Limit)
+
+	rows, err := m.DB.QueryContext(ctxt, query, movieID, limitCountry, permitted, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	similar := []*SimilarMovie{}
+
+	for rows.Next() {
+		entry := SimilarMovie{Movie: &Movies{}}
+
+		_, dest := entry.Movie.projection(nil)
+		dest = append([]interface{}{&entry.Score, &entry.GenreScore, &entry.YearScore, &entry.CreditScore,
+			&entry.CoRatingScore}, dest...)
+
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		similar = append(similar, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return similar, nil
+}
+
+// Recompute the similarities of all the movies and return the number of pairs stored.
+// Only the pairs sharing a genre, a credited person or a user who liked both are scored,
+// and the best MaxSimilarMovies are kept for each movie. Movies of different tenants are never
+// paired. The old similarities stay visible to the readers until the new ones are committed.
+func (m SimilarityModel) Refresh(ctxt context.Context) (int64, error) {
+
+	query := `WITH likes AS (
+		SELECT DISTINCT user_id, movie_id FROM ratings WHERE rating >= $5
+	),
+	like_counts AS (
+		SELECT movie_id, count(*) AS n FROM likes GROUP BY movie_id
+	),
+	candidates AS (
+		SELECT a.id AS movie_id, b.id AS similar_movie_id
+		FROM movies a INNER JOIN movies b ON a.id <> b.id AND a.tenant_id = b.tenant_id AND a.genres && b.genres
+		UNION
+		SELECT c1.movie_id, c2.movie_id
+		FROM movie_credits c1 INNER JOIN movie_credits c2 ON c1.person_id = c2.person_id AND c1.movie_id <> c2.movie_id
+		UNION
+		SELECT l1.movie_id, l2.movie_id
+		FROM likes l1 INNER JOIN likes l2 ON l1.user_id = l2.user_id AND l1.movie_id <> l2.movie_id
+	),
+	signals AS (
+		SELECT c.movie_id, c.similar_movie_id,
+		COALESCE(
+			(SELECT count(*) FROM (SELECT unnest(a.genres) INTERSECT SELECT unnest(b.genres)) i)::float8 /
+			NULLIF((SELECT count(*) FROM (SELECT unnest(a.genres) UNION SELECT unnest(b.genres)) u), 0), 0) AS genre_score,
+		(1.0 / (1.0 + abs(a.year - b.year) / 5.0))::float8 AS year_score,
+		COALESCE(
+			(SELECT count(DISTINCT x.person_id) FROM movie_credits x INNER JOIN movie_credits y ON x.person_id = y.person_id
+				WHERE x.movie_id = a.id AND y.movie_id = b.id)::float8 /
+			NULLIF((SELECT count(DISTINCT person_id) FROM movie_credits WHERE movie_id IN (a.id, b.id)), 0), 0) AS credit_score,
+		COALESCE(
+			(SELECT count(*) FROM likes x INNER JOIN likes y ON x.user_id = y.user_id
+				WHERE x.movie_id = a.id AND y.movie_id = b.id)::float8 /
+			NULLIF(sqrt(la.n * lb.n), 0), 0) AS corating_score
+		FROM candidates c
+		INNER JOIN movies a ON a.id = c.movie_id
+		INNER JOIN movies b ON b.id = c.similar_movie_id AND b.tenant_id = a.tenant_id
+		LEFT JOIN like_counts la ON la.movie_id = a.id
+		LEFT JOIN like_counts lb ON lb.movie_id = b.id
+	),
+	ranked AS (
+		SELECT *, row_number() OVER (PARTITION BY movie_id ORDER BY score DESC, similar_movie_id) AS rank
+		FROM (
+			SELECT *, $1 * genre_score + $2 * year_score + $3 * credit_score + $4 * corating_score AS score
+			FROM signals
+		) scored
+	)
+	INSERT INTO movie_similarities (movie_id, similar_movie_id, score, genre_score, year_score, credit_score, corating_score)
+	SELECT movie_id, similar_movie_id, score, genre_score, year_score, credit_score, corating_score
+	FROM ranked
+	WHERE rank <= $6`
+
+	args := []interface{}{SimilarityGenreWeight, SimilarityYearWeight, SimilarityCreditWeight, SimilarityCoRatingWeight,
+		LikedRating, MaxSimilarMovies}
+
+	// Create a DB context to timeout the queries. Scoring all the movies takes longer than a request
+	ctxt, cancel := withTimeout(ctxt, "similarities.refresh")
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctxt, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctxt, `DELETE FROM movie_similarities`)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctxt, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return count, tx.Commit()
+}
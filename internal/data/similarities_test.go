@@ -0,0 +1,70 @@
+package data
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestSimilarityRefresh(t *testing.T) {
+
+	models := newTestModels(t)
+	ctxt := context.Background()
+
+	alien := &Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"science-fiction", "horror"}}
+	aliens := &Movies{Title: "Aliens", Year: 1986, Runtime: 137, Genres: []string{"science-fiction", "action"}, Certifications: map[string]string{"US": "R"}}
+	heat := &Movies{Title: "Heat", Year: 1995, Runtime: 170, Genres: []string{"crime"}}
+
+	for _, movie := range []*Movies{alien, aliens, heat} {
+		if err := models.Movies.Insert(ctxt, movie); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, err := models.Similarities.Refresh(ctxt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only Alien and Aliens share a genre, and are paired both ways
+	if count != 2 {
+		t.Errorf("got %d pairs; want 2", count)
+	}
+
+	similar, err := models.Similarities.GetForMovie(ctxt, alien.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(similar) != 1 || similar[0].Movie.ID != aliens.ID {
+		t.Fatalf("got %d similar movies; want Aliens only", len(similar))
+	}
+
+	// One of the three genres is shared and the years are 7 apart
+	want := SimilarityGenreWeight/3 + SimilarityYearWeight/(1+7.0/5)
+	if math.Abs(similar[0].Score-want) > 1e-9 {
+		t.Errorf("got score %f; want %f", similar[0].Score, want)
+	}
+
+	// Aliens is left out for a viewer restricted to PG-13
+	restricted := models.Similarities
+	restricted.Limit = &ParentalLimit{Country: "US", MaxCertification: "PG-13"}
+
+	similar, err = restricted.GetForMovie(ctxt, alien.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(similar) != 0 {
+		t.Errorf("got %d similar movies past the parental limit; want none", len(similar))
+	}
+
+	similar, err = models.Similarities.GetForMovie(ctxt, heat.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(similar) != 0 {
+		t.Errorf("got %d movies similar to Heat; want none", len(similar))
+	}
+}
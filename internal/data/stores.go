@@ -0,0 +1,193 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+var (
+	ErrStoreInUse = errors.New("store in use")
+)
+
+// A store holding rentable copies of the movies
+type Store struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address,omitempty"`
+	City      string    `json:"city,omitempty"`
+	Version   int32     `json:"info_version"`
+}
+
+// Stores of a tenant. Only the stores of the tenant are ever read or written
+type StoreModel struct {
+	DB       DBTX
+	TenantID int64
+}
+
+func (m StoreModel) ForTenant(tenantID int64) StoreRepository {
+	m.TenantID = tenantID
+	return m
+}
+
+func (m StoreModel) Insert(ctxt context.Context, store *Store) error {
+
+	query := `INSERT INTO stores (name, address, city, tenant_id)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at, version`
+
+	args := []interface{}{store.Name, store.Address, store.City, m.TenantID}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "stores.insert")
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctxt, query, args...).Scan(&store.ID, &store.CreatedAt, &store.Version)
+}
+
+func (m StoreModel) Get(ctxt context.Context, id int64) (*Store, error) {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT id, created_at, name, address, city, version
+	FROM stores
+	WHERE id = $1 AND tenant_id = $2`
+
+	var store Store
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "stores.get")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, id, m.TenantID).Scan(&store.ID, &store.CreatedAt, &store.Name,
+		&store.Address, &store.City, &store.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &store, nil
+}
+
+// Get all the stores ordered by their name
+func (m StoreModel) GetAll(ctxt context.Context) ([]*Store, error) {
+
+	query := `SELECT id, created_at, name, address, city, version
+	FROM stores
+	WHERE tenant_id = $1
+	ORDER BY name, id`
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "stores.get_all")
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctxt, query, m.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	stores := []*Store{}
+
+	for rows.Next() {
+		var store Store
+
+		err = rows.Scan(&store.ID, &store.CreatedAt, &store.Name, &store.Address, &store.City, &store.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		stores = append(stores, &store)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stores, nil
+}
+
+func (m StoreModel) Update(ctxt context.Context, store *Store) error {
+
+	query := `UPDATE stores
+	SET name = $1, address = $2, city = $3, version = version + 1
+	WHERE id = $4 AND version = $5 AND tenant_id = $6
+	RETURNING version`
+
+	args := []interface{}{store.Name, store.Address, store.City, store.ID, store.Version, m.TenantID}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "stores.update")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, args...).Scan(&store.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete a store. Stores still holding inventory can not be deleted
+func (m StoreModel) Delete(ctxt context.Context, id int64) error {
+
+	// Validate if ID is valid
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := "DELETE FROM stores WHERE id = $1 AND tenant_id = $2"
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "stores.delete")
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctxt, query, id, m.TenantID)
+	if err != nil {
+		switch {
+		case isForeignKeyViolation(err):
+			return ErrStoreInUse
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func ValidateStore(val *validator.Validator, store *Store) {
+
+	// Name
+	val.Check(store.Name != "", "name", "must be provided")
+	val.Check(len(store.Name) <= validator.MAX_LEN, "name", "must not be more than 500 bytes")
+
+	// Address
+	val.Check(len(store.Address) <= validator.MAX_LEN, "address", "must not be more than 500 bytes")
+	val.Check(len(store.City) <= validator.MAX_LEN, "city", "must not be more than 500 bytes")
+}
@@ -0,0 +1,65 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestInventoryAvailability(t *testing.T) {
+
+	models := newTestModels(t)
+	ctxt := context.Background()
+
+	user := addTestUser(t, models, DefaultTenantID, "alice@example.com")
+	alien := addTestMovie(t, models, DefaultTenantID, &Movies{Title: "Alien", Year: 1979, Runtime: 117, Genres: []string{"horror"}})
+
+	downtown := addTestStore(t, models, DefaultTenantID, "Downtown")
+	uptown := addTestStore(t, models, DefaultTenantID, "Uptown")
+
+	addTestCopy(t, models, alien.ID, downtown.ID, "D-1", InventoryAvailable)
+	addTestCopy(t, models, alien.ID, downtown.ID, "D-2", InventoryRented)
+	addTestCopy(t, models, alien.ID, downtown.ID, "D-3", InventoryRetired)
+	addTestCopy(t, models, alien.ID, uptown.ID, "U-1", InventoryAvailable)
+
+	// Counts of the available and total copies per store, leaving out the retired copies
+	counts := func() [][2]int32 {
+
+		t.Helper()
+
+		availability, err := models.Inventory.GetAvailability(ctxt, alien.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := [][2]int32{}
+		for _, avail := range availability {
+			got = append(got, [2]int32{avail.Available, avail.Total})
+		}
+
+		return got
+	}
+
+	if got, want := counts(), [][2]int32{{1, 2}, {1, 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got availability %v; want %v", got, want)
+	}
+
+	// Checking out the last available copy of the store leaves none to rent
+	if _, err := models.Rentals.Checkout(ctxt, user.ID, alien.ID, downtown.ID, "dvd"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := counts(), [][2]int32{{0, 2}, {1, 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after checkout: got availability %v; want %v", got, want)
+	}
+
+	if _, err := models.Rentals.Checkout(ctxt, user.ID, alien.ID, downtown.ID, ""); !errors.Is(err, ErrNoCopyAvailable) {
+		t.Errorf("checkout without an available copy: got error %v; want %v", err, ErrNoCopyAvailable)
+	}
+
+	// Stores holding copies can not be deleted
+	if err := models.Stores.Delete(ctxt, uptown.ID); !errors.Is(err, ErrStoreInUse) {
+		t.Errorf("delete store holding copies: got error %v; want %v", err, ErrStoreInUse)
+	}
+}
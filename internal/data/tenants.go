@@ -0,0 +1,110 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Tenant owning the data created before the deployment became multi-tenant
+const DefaultTenantID = 1
+
+// Franchise chain whose catalogue, stores and users are kept apart from the other chains
+type Tenant struct {
+	ID        int64        `json:"id"`
+	CreatedAt time.Time    `json:"-"`
+	Slug      string       `json:"slug"` // Used in the subdomain or the tenant header
+	Name      string       `json:"name"`
+	Config    TenantConfig `json:"config"`
+	Version   int32        `json:"info_version"`
+}
+
+// Configuration of a tenant overriding the configuration of the deployment. Zero values
+// leave the deployment configuration in effect
+type TenantConfig struct {
+	RateLimitTPS      float64 `json:"rate_limit_tps,omitempty"`
+	RateLimitBurst    int     `json:"rate_limit_burst,omitempty"`
+	RateLimitDisabled bool    `json:"rate_limit_disabled,omitempty"`
+	EnrichDisabled    bool    `json:"enrich_disabled,omitempty"`
+}
+
+type TenantModel struct {
+	DB DBTX
+}
+
+// Get the tenant identified by the slug, ignoring the case
+func (m TenantModel) GetBySlug(ctxt context.Context, slug string) (*Tenant, error) {
+
+	query := `SELECT id, created_at, slug, name, config, version
+	FROM tenants
+	WHERE slug = $1`
+
+	var tenant Tenant
+	var config []byte
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "tenants.get_by_slug")
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctxt, query, slug).Scan(&tenant.ID, &tenant.CreatedAt, &tenant.Slug, &tenant.Name,
+		&config, &tenant.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if err = json.Unmarshal(config, &tenant.Config); err != nil {
+		return nil, err
+	}
+
+	return &tenant, nil
+}
+
+// Get all the tenants, used by the background jobs which work through every tenant
+func (m TenantModel) GetAll(ctxt context.Context) ([]*Tenant, error) {
+
+	query := `SELECT id, created_at, slug, name, config, version
+	FROM tenants
+	ORDER BY id`
+
+	// Create a context
+	ctxt, cancel := withTimeout(ctxt, "tenants.get_all")
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctxt, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tenants := []*Tenant{}
+
+	for rows.Next() {
+		var tenant Tenant
+		var config []byte
+
+		err = rows.Scan(&tenant.ID, &tenant.CreatedAt, &tenant.Slug, &tenant.Name, &config, &tenant.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = json.Unmarshal(config, &tenant.Config); err != nil {
+			return nil, err
+		}
+
+		tenants = append(tenants, &tenant)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tenants, nil
+}
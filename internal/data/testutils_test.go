@@ -0,0 +1,118 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/narinderv/blockbuster/internal/migrate"
+	"github.com/narinderv/blockbuster/internal/pricing"
+	"github.com/narinderv/blockbuster/internal/testdb"
+	"github.com/narinderv/blockbuster/migrations"
+)
+
+// Open a database with all the migrations applied, in a schema of its own which is dropped at the
+// end of the test. The tests using it are skipped unless BLOCKBUSTER_TEST_DSN is set
+func newTestDB(t *testing.T) *sql.DB {
+
+	t.Helper()
+
+	db := testdb.Open(t, "test")
+
+	migrator, err := migrate.New(db, migrations.Files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// Create the models of a test database, with the default pricing rules
+func newTestModels(t *testing.T) Models {
+
+	t.Helper()
+
+	engine, err := pricing.New(pricing.DefaultRules(), InventoryFormats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewModel(newTestDB(t), engine)
+}
+
+// Add a tenant to the test database, returning its ID
+func addTestTenant(t *testing.T, models Models, slug string) int64 {
+
+	t.Helper()
+
+	var id int64
+
+	err := models.db.QueryRow("INSERT INTO tenants (slug, name) VALUES ($1, $1) RETURNING id", slug).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+// Add a user of the tenant to the test database
+func addTestUser(t *testing.T, models Models, tenantID int64, email string) *User {
+
+	t.Helper()
+
+	user := &User{Name: "Test User", Email: email, Activated: true}
+
+	if err := user.Password.SetPasswordHash("pa55word1234"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := models.Users.ForTenant(tenantID).Insert(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	return user
+}
+
+// Add a movie of the tenant to the test database
+func addTestMovie(t *testing.T, models Models, tenantID int64, movie *Movies) *Movies {
+
+	t.Helper()
+
+	if err := models.Movies.ForTenant(tenantID).Insert(context.Background(), movie); err != nil {
+		t.Fatal(err)
+	}
+
+	return movie
+}
+
+// Add a store of the tenant to the test database
+func addTestStore(t *testing.T, models Models, tenantID int64, name string) *Store {
+
+	t.Helper()
+
+	store := &Store{Name: name}
+
+	if err := models.Stores.ForTenant(tenantID).Insert(context.Background(), store); err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+// Add a DVD copy of the movie to the store, with the given status
+func addTestCopy(t *testing.T, models Models, movieID, storeID int64, barcode, status string) *InventoryItem {
+
+	t.Helper()
+
+	item := &InventoryItem{MovieID: movieID, StoreID: storeID, Format: "dvd", Condition: "good", Barcode: barcode, Status: status}
+
+	if err := models.Inventory.Insert(context.Background(), item); err != nil {
+		t.Fatal(err)
+	}
+
+	return item
+}
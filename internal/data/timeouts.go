@@ -0,0 +1,154 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrCanceled = errors.New("operation canceled")
+	ErrTimeout  = errors.New("operation timed out")
+)
+
+// Timeouts of the database operations. The operations are named after their model and method
+// e.g. "movies.get_all" or "similarities.refresh"
+type Timeouts struct {
+	Default    time.Duration            // Timeout of the operations without one of their own
+	Operations map[string]time.Duration // Timeouts of the individual operations
+}
+
+// Timeouts used unless configured otherwise. The batch operations run by the background jobs
+// go through far more rows than the queries of the requests
+var DefaultTimeouts = Timeouts{
+	Default: 3 * time.Second,
+	Operations: map[string]time.Duration{
+		"genres.merge":            5 * time.Second,
+		"holds.expire_ready":      30 * time.Second,
+		"popularity.refresh":      time.Minute,
+		"recommendations.refresh": 5 * time.Minute,
+		"similarities.refresh":    5 * time.Minute,
+	},
+}
+
+// Timeouts in effect. These can be changed while the server is running
+var timeouts = struct {
+	sync.RWMutex
+	Timeouts
+}{Timeouts: DefaultTimeouts}
+
+// Change the timeouts of the database operations. The operations missing from the given timeouts
+// keep their default timeout
+func SetTimeouts(t Timeouts) {
+
+	operations := make(map[string]time.Duration, len(DefaultTimeouts.Operations)+len(t.Operations))
+
+	for name, timeout := range DefaultTimeouts.Operations {
+		operations[name] = timeout
+	}
+
+	for name, timeout := range t.Operations {
+		operations[name] = timeout
+	}
+
+	if t.Default <= 0 {
+		t.Default = DefaultTimeouts.Default
+	}
+
+	timeouts.Lock()
+	timeouts.Default = t.Default
+	timeouts.Operations = operations
+	timeouts.Unlock()
+}
+
+// Parse the timeouts of individual operations given as a comma separated list
+// of name=duration pairs e.g. "movies.get_all=5s,similarities.refresh=10m"
+func ParseTimeouts(value string) (map[string]time.Duration, error) {
+
+	operations := make(map[string]time.Duration)
+
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid operation timeout %q, expected name=duration", pair)
+		}
+
+		timeout, err := time.ParseDuration(parts[1])
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout of operation %s: %q", parts[0], parts[1])
+		}
+
+		operations[parts[0]] = timeout
+	}
+
+	return operations, nil
+}
+
+// Format the timeouts of individual operations in the format read by ParseTimeouts
+func FormatTimeouts(operations map[string]time.Duration) string {
+
+	pairs := make([]string, 0, len(operations))
+
+	for name, timeout := range operations {
+		pairs = append(pairs, name+"="+timeout.String())
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+// Derive the context of a database operation from the context of the caller, so that the operation
+// is cancelled along with the caller, and bound it by the timeout of the operation
+func withTimeout(ctxt context.Context, operation string) (context.Context, context.CancelFunc) {
+
+	timeouts.RLock()
+	timeout, found := timeouts.Operations[operation]
+	if !found {
+		timeout = timeouts.Default
+	}
+	timeouts.RUnlock()
+
+	return context.WithTimeout(ctxt, timeout)
+}
+
+// Tell apart the failures of the database operations run with the context which were caused by
+// the context: ErrCanceled if the caller gave up on the operation e.g. the client went away or
+// the server is shutting down, and ErrTimeout if the operation ran past its timeout. Other
+// errors are returned as they are
+func ContextError(ctxt context.Context, err error) error {
+
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case errors.Is(err, ErrCanceled), errors.Is(err, ErrTimeout):
+		return err
+	case errors.Is(ctxt.Err(), context.Canceled):
+		return fmt.Errorf("%w: %v", ErrCanceled, err)
+	case errors.Is(ctxt.Err(), context.DeadlineExceeded), errors.Is(err, context.DeadlineExceeded), isQueryCanceled(err):
+		// The caller is still waiting, so the operation was stopped by its own timeout
+		return fmt.Errorf("%w: %v", ErrTimeout, err)
+	default:
+		return err
+	}
+}
+
+// Check if the error is Postgres reporting that the query was cancelled, which is how the
+// database reports the queries stopped when their context is done
+func isQueryCanceled(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "57014"
+}
@@ -0,0 +1,109 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestParseTimeouts(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]time.Duration
+		wantErr bool
+	}{
+		{"empty", "", map[string]time.Duration{}, false},
+		{"single", "movies.get_all=5s", map[string]time.Duration{"movies.get_all": 5 * time.Second}, false},
+		{"several", "movies.get_all=5s, similarities.refresh=10m", map[string]time.Duration{
+			"movies.get_all":       5 * time.Second,
+			"similarities.refresh": 10 * time.Minute,
+		}, false},
+		{"empty entries", ",movies.get_all=5s,,", map[string]time.Duration{"movies.get_all": 5 * time.Second}, false},
+		{"missing duration", "movies.get_all", nil, true},
+		{"missing name", "=5s", nil, true},
+		{"bad duration", "movies.get_all=soon", nil, true},
+		{"zero duration", "movies.get_all=0s", nil, true},
+		{"negative duration", "movies.get_all=-1s", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTimeouts(tt.value)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v; want error %t", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatTimeouts(t *testing.T) {
+
+	operations := map[string]time.Duration{
+		"similarities.refresh": 10 * time.Minute,
+		"movies.get_all":       5 * time.Second,
+	}
+
+	formatted := FormatTimeouts(operations)
+
+	if want := "movies.get_all=5s,similarities.refresh=10m0s"; formatted != want {
+		t.Errorf("got %q; want %q", formatted, want)
+	}
+
+	parsed, err := ParseTimeouts(formatted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(parsed, operations) {
+		t.Errorf("got %v; want %v", parsed, operations)
+	}
+}
+
+func TestContextError(t *testing.T) {
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	failure := errors.New("connection refused")
+	wrapped := ContextError(cancelled, failure)
+
+	tests := []struct {
+		name string
+		ctxt context.Context
+		err  error
+		want error
+	}{
+		{"cancelled", cancelled, failure, ErrCanceled},
+		{"past the deadline", expired, failure, ErrTimeout},
+		{"deadline error", context.Background(), context.DeadlineExceeded, ErrTimeout},
+		{"query cancelled", context.Background(), &pq.Error{Code: "57014"}, ErrTimeout},
+		{"already told apart", expired, wrapped, ErrCanceled},
+		{"other error", context.Background(), failure, failure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContextError(tt.ctxt, tt.err); !errors.Is(got, tt.want) {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := ContextError(cancelled, nil); got != nil {
+		t.Errorf("got %v for no error; want nil", got)
+	}
+}
@@ -0,0 +1,112 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/validator"
+)
+
+// Scopes of the tokens
+const (
+	ScopeAuthentication = "authentication"
+)
+
+type Token struct {
+	Plaintext string    `json:"token"`
+	Hash      []byte    `json:"-"`
+	UserID    int64     `json:"-"`
+	Expiry    time.Time `json:"expiry"`
+	Scope     string    `json:"-"`
+
+	// Profile of the household the token is bound to, zero for the account itself
+	ProfileID int64 `json:"profile_id,omitempty"`
+}
+
+// Token Model
+type TokenModel struct {
+	DB DBTX
+}
+
+// Generate a new random token for the user. Only the hash of the token is stored in the database
+func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error) {
+
+	token := &Token{
+		UserID: userID,
+		Expiry: time.Now().Add(ttl),
+		Scope:  scope,
+	}
+
+	// Fill 16 bytes with random data from the OS
+	randomBytes := make([]byte, 16)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Encode the bytes into a 26 character base32 string
+	token.Plaintext = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+
+	hash := sha256.Sum256([]byte(token.Plaintext))
+	token.Hash = hash[:]
+
+	return token, nil
+}
+
+// Validate the plaintext token provided by the client
+func ValidateTokenPlaintext(val *validator.Validator, tokenPlaintext string) {
+	val.Check(tokenPlaintext != "", "token", "must be provided")
+	val.Check(len(tokenPlaintext) == 26, "token", "must be 26 bytes long")
+}
+
+// Create and store a new token
+func (m TokenModel) New(ctxt context.Context, userID int64, ttl time.Duration, scope string) (*Token, error) {
+	return m.NewForProfile(ctxt, userID, 0, ttl, scope)
+}
+
+// Create and store a new token bound to a profile of the household of the user
+func (m TokenModel) NewForProfile(ctxt context.Context, userID, profileID int64, ttl time.Duration, scope string) (*Token, error) {
+
+	token, err := generateToken(userID, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	token.ProfileID = profileID
+
+	err = m.Insert(ctxt, token)
+	return token, err
+}
+
+func (m TokenModel) Insert(ctxt context.Context, token *Token) error {
+
+	query := `INSERT INTO tokens (hash, user_id, expiry, scope, profile_id)
+	VALUES ($1, $2, $3, $4, NULLIF($5::bigint, 0))`
+
+	args := []interface{}{token.Hash, token.UserID, token.Expiry, token.Scope, token.ProfileID}
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "tokens.insert")
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctxt, query, args...)
+	return err
+}
+
+// Delete all the tokens of the given scope for the user
+func (m TokenModel) DeleteAllForUser(ctxt context.Context, scope string, userID int64) error {
+
+	query := `DELETE FROM tokens
+	WHERE scope = $1 AND user_id = $2`
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "tokens.delete_all_for_user")
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctxt, query, scope, userID)
+	return err
+}
@@ -0,0 +1,296 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Maximum number of attempts of a unit of work which keeps failing on serialization failures or deadlocks
+const maxTxAttempts = 3
+
+// Database the models run their queries on. This is the connection pool, or the transaction of a unit of
+// work for the models bound to one by WithTx
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error)
+}
+
+// Transaction of a model operation. Within a unit of work the transaction is a savepoint, which is
+// released on commit, leaving the changes to be committed along with the unit of work
+type Tx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	Commit() error
+	Rollback() error
+}
+
+// Connection pool of the models which are not bound to a unit of work
+type dbPool struct {
+	*sql.DB
+}
+
+func (pool dbPool) BeginTx(ctxt context.Context, opts *sql.TxOptions) (Tx, error) {
+	return pool.DB.BeginTx(ctxt, opts)
+}
+
+// Transaction of a unit of work. The transactions begun by the models are savepoints of it. A unit of
+// work is only ever used by one goroutine
+type unitOfWork struct {
+	*sql.Tx
+	savepoints *int // Number of the savepoints created, to name the next one
+}
+
+func (work unitOfWork) BeginTx(ctxt context.Context, opts *sql.TxOptions) (Tx, error) {
+
+	*work.savepoints++
+	name := fmt.Sprintf("model_tx_%d", *work.savepoints)
+
+	if _, err := work.Tx.ExecContext(ctxt, "SAVEPOINT "+name); err != nil {
+		return nil, err
+	}
+
+	return &savepoint{Tx: work.Tx, name: name}, nil
+}
+
+// Savepoint standing in for the transaction of a model operation within a unit of work
+type savepoint struct {
+	*sql.Tx
+	name string
+	done bool
+}
+
+func (sp *savepoint) Commit() error {
+
+	if sp.done {
+		return sql.ErrTxDone
+	}
+
+	sp.done = true
+
+	_, err := sp.Tx.Exec("RELEASE SAVEPOINT " + sp.name)
+	return err
+}
+
+func (sp *savepoint) Rollback() error {
+
+	if sp.done {
+		return sql.ErrTxDone
+	}
+
+	sp.done = true
+
+	_, err := sp.Tx.Exec("ROLLBACK TO SAVEPOINT " + sp.name)
+	return err
+}
+
+// Options of the units of work run by WithTx, which must not see the changes of concurrent units of work.
+// Conflicting units of work fail with serialization failures, on which they are retried
+var SerializableTx = &sql.TxOptions{Isolation: sql.LevelSerializable}
+
+// Run the function as a single unit of work, with all the models bound to the same serializable transaction.
+// The transaction is committed if the function succeeds, and rolled back if it fails or panics. The unit of
+// work is retried from the start on serialization failures and deadlocks, so the function must not have
+// effects outside of the database. The model operations running several queries do so within savepoints,
+// so their failures only roll back the operation and the function may handle their errors and carry on.
+// The failure of any other query aborts the whole transaction, and the function must then return the error.
+// Models already bound to a unit of work run the function within it, and the models kept in memory, which
+// are not transactional, run it as they are
+func (m Models) WithTx(ctxt context.Context, fn func(tx Models) error) error {
+	return m.WithTxOptions(ctxt, SerializableTx, fn)
+}
+
+// Run the function as a single unit of work like WithTx, with the transaction begun with the given options.
+// Nil options begin it at the READ COMMITTED isolation level of the database, which never fails on
+// serialization failures. Models already bound to a unit of work run the function within it, whatever the options
+func (m Models) WithTxOptions(ctxt context.Context, opts *sql.TxOptions, fn func(tx Models) error) error {
+
+	if m.db == nil {
+		return fn(m)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := m.runTx(ctxt, opts, fn)
+		if err == nil || !isRetryableTxError(err) || attempt == maxTxAttempts {
+			return err
+		}
+
+		// Back off a little so that the conflicting transaction can finish
+		select {
+		case <-ctxt.Done():
+			return err
+		case <-time.After(time.Duration(attempt*10) * time.Millisecond):
+		}
+	}
+}
+
+// Run a single attempt of the unit of work
+func (m Models) runTx(ctxt context.Context, opts *sql.TxOptions, fn func(tx Models) error) error {
+
+	tx, err := m.db.BeginTx(ctxt, opts)
+	if err != nil {
+		return err
+	}
+
+	// Rollback is a no-op once the transaction has been committed. The panic is passed on after the rollback
+	defer tx.Rollback()
+
+	if err = fn(m.bind(unitOfWork{Tx: tx, savepoints: new(int)})); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Copy of the models running their queries on the given database. The models kept in memory, which are
+// not transactional, stay as they are. Any other repository would silently run outside of the unit of work,
+// so binding it panics
+func (m Models) bind(db DBTX) Models {
+
+	// Units of work started by the bound models join this one
+	m.db = nil
+
+	switch repo := m.Movies.(type) {
+	case MovieModel:
+		repo.DB = db
+		m.Movies = repo
+	case MemoryMovieModel:
+	default:
+		panic(fmt.Sprintf("data: movies repository %T can not be bound to a unit of work", repo))
+	}
+
+	switch repo := m.Users.(type) {
+	case *UserModel:
+		bound := *repo
+		bound.DB = db
+		m.Users = &bound
+	case *MemoryUserModel:
+	default:
+		panic(fmt.Sprintf("data: users repository %T can not be bound to a unit of work", repo))
+	}
+
+	switch repo := m.Credits.(type) {
+	case CreditModel:
+		repo.DB = db
+		m.Credits = repo
+	case MemoryCreditModel:
+	default:
+		panic(fmt.Sprintf("data: credits repository %T can not be bound to a unit of work", repo))
+	}
+
+	switch repo := m.Genres.(type) {
+	case GenreModel:
+		repo.DB = db
+		m.Genres = repo
+	case MemoryGenreModel:
+	default:
+		panic(fmt.Sprintf("data: genres repository %T can not be bound to a unit of work", repo))
+	}
+
+	switch repo := m.Tokens.(type) {
+	case TokenModel:
+		repo.DB = db
+		m.Tokens = repo
+	case MemoryTokenModel:
+	default:
+		panic(fmt.Sprintf("data: tokens repository %T can not be bound to a unit of work", repo))
+	}
+
+	switch repo := m.Permissions.(type) {
+	case PermissionModel:
+		repo.DB = db
+		m.Permissions = repo
+	case MemoryPermissionModel:
+	default:
+		panic(fmt.Sprintf("data: permissions repository %T can not be bound to a unit of work", repo))
+	}
+
+	switch repo := m.Stores.(type) {
+	case StoreModel:
+		repo.DB = db
+		m.Stores = repo
+	case MemoryStoreModel:
+	default:
+		panic(fmt.Sprintf("data: stores repository %T can not be bound to a unit of work", repo))
+	}
+
+	switch repo := m.Inventory.(type) {
+	case InventoryModel:
+		repo.DB = db
+		m.Inventory = repo
+	case MemoryInventoryModel:
+	default:
+		panic(fmt.Sprintf("data: inventory repository %T can not be bound to a unit of work", repo))
+	}
+
+	switch repo := m.Images.(type) {
+	case MovieImageModel:
+		repo.DB = db
+		m.Images = repo
+	case MemoryMovieImageModel:
+	default:
+		panic(fmt.Sprintf("data: images repository %T can not be bound to a unit of work", repo))
+	}
+
+	switch repo := m.Tenants.(type) {
+	case TenantModel:
+		repo.DB = db
+		m.Tenants = repo
+	case MemoryTenantModel:
+	default:
+		panic(fmt.Sprintf("data: tenants repository %T can not be bound to a unit of work", repo))
+	}
+
+	switch repo := m.Profiles.(type) {
+	case ProfileModel:
+		repo.DB = db
+		m.Profiles = repo
+	case MemoryProfileModel:
+	default:
+		panic(fmt.Sprintf("data: profiles repository %T can not be bound to a unit of work", repo))
+	}
+
+	switch repo := m.Collections.(type) {
+	case CollectionModel:
+		repo.DB = db
+		m.Collections = repo
+	case MemoryCollectionModel:
+	default:
+		panic(fmt.Sprintf("data: collections repository %T can not be bound to a unit of work", repo))
+	}
+
+	switch repo := m.Popularity.(type) {
+	case PopularityModel:
+		repo.DB = db
+		m.Popularity = repo
+	case MemoryPopularityModel:
+	default:
+		panic(fmt.Sprintf("data: popularity repository %T can not be bound to a unit of work", repo))
+	}
+
+	m.People.DB = db
+	m.Ratings.DB = db
+	m.Reviews.DB = db
+	m.Rentals.DB = db
+	m.Ledger.DB = db
+	m.Holds.DB = db
+	m.Lists.DB = db
+	m.Similarities.DB = db
+	m.Recommendations.DB = db
+	m.Households.DB = db
+
+	return m
+}
+
+// Check if the unit of work failed on a serialization failure or a deadlock, which go away on a retry
+func isRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && (pqErr.Code == "40001" || pqErr.Code == "40P01")
+}
@@ -0,0 +1,350 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Database logging the statements run on it in place of running them. The first statements
+// fail with the given SQLSTATE code
+type fakeTxDB struct {
+	mutx       sync.Mutex
+	statements []string
+	isolation  []sql.IsolationLevel
+	failures   int
+	code       pq.ErrorCode
+}
+
+func (db *fakeTxDB) log(statement string) {
+	db.mutx.Lock()
+	defer db.mutx.Unlock()
+
+	db.statements = append(db.statements, strings.Join(strings.Fields(statement), " "))
+}
+
+func (db *fakeTxDB) Connect(ctxt context.Context) (driver.Conn, error) {
+	return fakeTxConn{db}, nil
+}
+
+func (db *fakeTxDB) Driver() driver.Driver {
+	return nil
+}
+
+type fakeTxConn struct {
+	db *fakeTxDB
+}
+
+func (conn fakeTxConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("statements cannot be prepared")
+}
+
+func (conn fakeTxConn) Close() error {
+	return nil
+}
+
+func (conn fakeTxConn) Begin() (driver.Tx, error) {
+	return conn.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (conn fakeTxConn) BeginTx(ctxt context.Context, opts driver.TxOptions) (driver.Tx, error) {
+
+	conn.db.log("BEGIN")
+
+	conn.db.mutx.Lock()
+	conn.db.isolation = append(conn.db.isolation, sql.IsolationLevel(opts.Isolation))
+	conn.db.mutx.Unlock()
+
+	return fakeTx{conn.db}, nil
+}
+
+func (conn fakeTxConn) ExecContext(ctxt context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+
+	conn.db.log(query)
+
+	conn.db.mutx.Lock()
+	defer conn.db.mutx.Unlock()
+
+	if conn.db.failures > 0 {
+		conn.db.failures--
+		return nil, &pq.Error{Code: conn.db.code}
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct {
+	db *fakeTxDB
+}
+
+func (tx fakeTx) Commit() error {
+	tx.db.log("COMMIT")
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.db.log("ROLLBACK")
+	return nil
+}
+
+// Create the models of a fake database
+func newFakeTxModels(t *testing.T, db *fakeTxDB) Models {
+
+	t.Helper()
+
+	conn := sql.OpenDB(db)
+	t.Cleanup(func() { conn.Close() })
+
+	return NewModel(conn, nil)
+}
+
+// Check the statements run, by their start
+func checkStatements(t *testing.T, db *fakeTxDB, want ...string) {
+
+	t.Helper()
+
+	if len(db.statements) != len(want) {
+		t.Fatalf("got statements %q; want %q", db.statements, want)
+	}
+
+	for i := range want {
+		if !strings.HasPrefix(db.statements[i], want[i]) {
+			t.Fatalf("got statements %q; want %q", db.statements, want)
+		}
+	}
+}
+
+// Add a token within the unit of work
+func insertTestToken(ctxt context.Context, tx Models) error {
+	return tx.Tokens.Insert(ctxt, &Token{UserID: 1, Expiry: time.Now().Add(time.Hour), Scope: ScopeAuthentication})
+}
+
+func TestWithTxCommit(t *testing.T) {
+
+	db := &fakeTxDB{}
+	models := newFakeTxModels(t, db)
+
+	err := models.WithTx(context.Background(), func(tx Models) error {
+		return insertTestToken(context.Background(), tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkStatements(t, db, "BEGIN", "INSERT INTO tokens", "COMMIT")
+
+	if db.isolation[0] != sql.LevelSerializable {
+		t.Errorf("got isolation level %s; want %s", db.isolation[0], sql.LevelSerializable)
+	}
+}
+
+func TestWithTxRollback(t *testing.T) {
+
+	failure := errors.New("failed")
+
+	t.Run("error", func(t *testing.T) {
+
+		db := &fakeTxDB{}
+		models := newFakeTxModels(t, db)
+
+		err := models.WithTxOptions(context.Background(), nil, func(tx Models) error {
+			if err := insertTestToken(context.Background(), tx); err != nil {
+				return err
+			}
+
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("got error %v; want %v", err, failure)
+		}
+
+		checkStatements(t, db, "BEGIN", "INSERT INTO tokens", "ROLLBACK")
+	})
+
+	t.Run("panic", func(t *testing.T) {
+
+		db := &fakeTxDB{}
+		models := newFakeTxModels(t, db)
+
+		defer func() {
+			if recover() == nil {
+				t.Fatal("got the panic recovered; want it passed on")
+			}
+
+			checkStatements(t, db, "BEGIN", "INSERT INTO tokens", "ROLLBACK")
+		}()
+
+		models.WithTxOptions(context.Background(), nil, func(tx Models) error {
+			insertTestToken(context.Background(), tx)
+			panic("failed")
+		})
+	})
+}
+
+func TestWithTxRetry(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		code     pq.ErrorCode
+		failures int
+		attempts int
+		wantErr  bool
+	}{
+		{"serialization failure", "40001", 1, 2, false},
+		{"deadlock", "40P01", 2, 3, false},
+		{"keeps failing", "40001", maxTxAttempts, maxTxAttempts, true},
+		{"other error", "23505", 1, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			db := &fakeTxDB{failures: tt.failures, code: tt.code}
+			models := newFakeTxModels(t, db)
+
+			err := models.WithTx(context.Background(), func(tx Models) error {
+				return insertTestToken(context.Background(), tx)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v; want error %t", err, tt.wantErr)
+			}
+
+			if got := len(db.isolation); got != tt.attempts {
+				t.Errorf("got %d attempts; want %d", got, tt.attempts)
+			}
+
+			want := "COMMIT"
+			if tt.wantErr {
+				want = "ROLLBACK"
+			}
+
+			if last := db.statements[len(db.statements)-1]; last != want {
+				t.Errorf("got the last attempt ending with %s; want %s", last, want)
+			}
+		})
+	}
+}
+
+func TestWithTxNested(t *testing.T) {
+
+	db := &fakeTxDB{}
+	models := newFakeTxModels(t, db)
+	ctxt := context.Background()
+
+	err := models.WithTxOptions(ctxt, nil, func(tx Models) error {
+
+		// The unit of work is joined rather than another one begun
+		err := tx.WithTx(ctxt, func(nested Models) error {
+			return insertTestToken(ctxt, nested)
+		})
+		if err != nil {
+			return err
+		}
+
+		// The transactions of the model operations are savepoints of the unit of work
+		bound := tx.Tokens.(TokenModel).DB
+
+		sp, err := bound.BeginTx(ctxt, nil)
+		if err != nil {
+			return err
+		}
+
+		if err = sp.Rollback(); err != nil {
+			return err
+		}
+
+		sp, err = bound.BeginTx(ctxt, nil)
+		if err != nil {
+			return err
+		}
+
+		return sp.Commit()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkStatements(t, db, "BEGIN", "INSERT INTO tokens", "SAVEPOINT model_tx_1", "ROLLBACK TO SAVEPOINT model_tx_1",
+		"SAVEPOINT model_tx_2", "RELEASE SAVEPOINT model_tx_2", "COMMIT")
+
+	if db.isolation[0] != sql.LevelDefault {
+		t.Errorf("got isolation level %s; want %s", db.isolation[0], sql.LevelDefault)
+	}
+}
+
+func TestWithTxMemory(t *testing.T) {
+
+	calls := 0
+
+	err := NewMemoryModels().WithTx(context.Background(), func(tx Models) error {
+		calls++
+		return nil
+	})
+	if err != nil || calls != 1 {
+		t.Errorf("got %d calls and error %v; want 1 call and no error", calls, err)
+	}
+}
+
+// Database the models are bound to in the binding tests
+type boundTestDB struct {
+	DBTX
+	name string
+}
+
+func TestBindAllModels(t *testing.T) {
+
+	db := boundTestDB{name: "unit of work"}
+	bound := NewModel(&sql.DB{}, nil).bind(db)
+
+	// Every model of the bound models, whatever its type, runs its queries on the unit of work
+	models := reflect.ValueOf(bound)
+
+	for i := 0; i < models.NumField(); i++ {
+		field := models.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		model := models.Field(i)
+		for model.Kind() == reflect.Interface || model.Kind() == reflect.Ptr {
+			model = model.Elem()
+		}
+
+		modelDB := model.FieldByName("DB")
+		if !modelDB.IsValid() {
+			t.Errorf("%s: got %s without a DB; want a model of the database", field.Name, model.Type())
+			continue
+		}
+
+		if got, ok := modelDB.Interface().(boundTestDB); !ok || got != db {
+			t.Errorf("%s: got DB %v; want the unit of work", field.Name, modelDB.Interface())
+		}
+	}
+}
+
+// Repository of the movies unknown to the units of work
+type unknownMovieRepository struct {
+	MovieRepository
+}
+
+func TestBindUnknownRepository(t *testing.T) {
+
+	models := NewModel(&sql.DB{}, nil)
+	models.Movies = unknownMovieRepository{}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("got the unknown repository bound; want a panic")
+		}
+	}()
+
+	models.bind(boundTestDB{})
+}
@@ -2,6 +2,7 @@ package data
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
 	"errors"
 	"time"
@@ -28,11 +29,38 @@ type User struct {
 	Password  password  `json:"-"`
 	Activated bool      `json:"activated"`
 	Version   int       `json:"-"`
+
+	// Household sharing the membership of the user
+	HouseholdID int64 `json:"household_id"`
+
+	// Parental controls. The PIN overrides the limit
+	ParentalLimit *ParentalLimit `json:"parental_limit,omitempty"`
+	ParentalPin   password       `json:"-"`
+
+	// Profile the authentication token of the request is bound to, zero for the account itself.
+	// Only filled in by GetForToken
+	ProfileID int64 `json:"-"`
+}
+
+// User for the requests which are not authenticated
+var AnonymousUser = &User{}
+
+// Check if the user is the anonymous user
+func (user *User) IsAnonymous() bool {
+	return user == AnonymousUser
 }
 
 // User Model
+// Users of a tenant. Only the users of the tenant are ever read or written
 type UserModel struct {
-	DB *sql.DB
+	DB       DBTX
+	TenantID int64
+}
+
+func (userModel *UserModel) ForTenant(tenantID int64) UserRepository {
+	scoped := *userModel
+	scoped.TenantID = tenantID
+	return &scoped
 }
 
 // Generate and save the password hash from the plaintext password
@@ -51,20 +79,52 @@ func (pass *password) SetPasswordHash(passwrd string) error {
 	return nil
 }
 
+// Check if a password has been set
+func (pass *password) IsSet() bool {
+	return pass.hash != nil
+}
+
+// Fill in the parental limit from the stored columns. An empty country means no limit
+func (user *User) setParentalLimit(country, maxCertification string) {
+
+	user.ParentalLimit = nil
+
+	if country != "" {
+		user.ParentalLimit = &ParentalLimit{Country: country, MaxCertification: maxCertification}
+	}
+}
+
+// Remove the parental limit and its PIN
+func (user *User) ClearParentalControls() {
+	user.ParentalLimit = nil
+	user.ParentalPin = password{}
+}
+
+// Get the parental limit as stored in the columns
+func (user *User) parentalLimitColumns() (string, string) {
+
+	if user.ParentalLimit == nil {
+		return "", ""
+	}
+
+	return user.ParentalLimit.Country, user.ParentalLimit.MaxCertification
+}
+
 // Match the input password with the stored password by  comparing the hash
 func (pass *password) MatchPassword(passwrd string) (bool, error) {
 
+	// A mismatch is not an error, the password is just not a match
 	err := bcrypt.CompareHashAndPassword(pass.hash, []byte(passwrd))
 	if err != nil {
 		switch {
 		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
-			return false, err
+			return false, nil
 		default:
 			return false, err
 		}
 	}
 
-	return true, err
+	return true, nil
 }
 
 // Validation functions
@@ -113,26 +173,29 @@ func ValidateUser(val *validator.Validator, user *User) {
 
 // User Model functions
 // Insert
-func (userModel *UserModel) Insert(user *User) error {
+func (userModel *UserModel) Insert(ctxt context.Context, user *User) error {
 
-	// Insert query
-	query := `INSERT INTO users (name, email, password_hash, activated)
-	VALUES ($1, $2, $3, $4)
-	RETURNING id, created_at, version`
+	// Insert query. A household of its own, named after the user, is created along with the user
+	query := `WITH household AS (
+		INSERT INTO households (tenant_id, name) VALUES ($5, $1) RETURNING id
+	)
+	INSERT INTO users (name, email, password_hash, activated, tenant_id, household_id)
+	SELECT $1, $2, $3, $4, $5, id FROM household
+	RETURNING id, created_at, version, household_id`
 
 	// Argumets to the query
-	args := []interface{}{user.Name, user.Email, user.Password.hash, user.Activated}
+	args := []interface{}{user.Name, user.Email, user.Password.hash, user.Activated, userModel.TenantID}
 
 	// Create a DB context to timeout the query if it exceeds a certian duration
-	ctxt, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctxt, cancel := withTimeout(ctxt, "users.insert")
 
 	defer cancel()
 
 	// Execute the query and store the result
-	err := userModel.DB.QueryRowContext(ctxt, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version)
+	err := userModel.DB.QueryRowContext(ctxt, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version, &user.HouseholdID)
 	if err != nil {
 		switch {
-		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
+		case err.Error() == `pq: duplicate key value violates unique constraint "users_tenant_email_key"`:
 			return ErrDuplicateEmail
 		default:
 			return err
@@ -143,23 +206,26 @@ func (userModel *UserModel) Insert(user *User) error {
 }
 
 // Get By Email
-func (userModel *UserModel) GetByEmail(email string) (*User, error) {
+func (userModel *UserModel) GetByEmail(ctxt context.Context, email string) (*User, error) {
 
 	query := `
-		SELECT id, created_at, name, email, password_hash, activated, version
+		SELECT id, created_at, name, email, password_hash, activated, version, household_id,
+			parental_country, parental_max_certification, parental_pin_hash
 		FROM users
-		WHERE email = $1`
+		WHERE email = $1 AND tenant_id = $2`
 
 	// Response structure
 	var user User
+	var parentalCountry, parentalMaxCertification string
 
 	// Create a DB context to timeout the query if it exceeds a certian duration
-	ctxt, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctxt, cancel := withTimeout(ctxt, "users.get_by_email")
 	defer cancel()
 
 	// Use the context in the query
-	err := userModel.DB.QueryRowContext(ctxt, query, email).Scan(&user.ID, &user.CreatedAt, &user.Name,
-		&user.Email, &user.Password.hash, &user.Activated, &user.Version)
+	err := userModel.DB.QueryRowContext(ctxt, query, email, userModel.TenantID).Scan(&user.ID, &user.CreatedAt, &user.Name,
+		&user.Email, &user.Password.hash, &user.Activated, &user.Version, &user.HouseholdID,
+		&parentalCountry, &parentalMaxCertification, &user.ParentalPin.hash)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -169,29 +235,35 @@ func (userModel *UserModel) GetByEmail(email string) (*User, error) {
 		}
 	}
 
+	user.setParentalLimit(parentalCountry, parentalMaxCertification)
+
 	return &user, nil
 }
 
 // Update user details
-func (userModel *UserModel) Update(user *User) error {
+func (userModel *UserModel) Update(ctxt context.Context, user *User) error {
 
 	// Update query
 	query := `UPDATE users
-	SET name = $1, email = $2, password_hash = $3, activated = $4, version = version + 1
-	WHERE id = $5 AND version = $6
+	SET name = $1, email = $2, password_hash = $3, activated = $4, parental_country = $5,
+		parental_max_certification = $6, parental_pin_hash = $7, version = version + 1
+	WHERE id = $8 AND version = $9 AND tenant_id = $10
 	RETURNING version`
 
+	parentalCountry, parentalMaxCertification := user.parentalLimitColumns()
+
 	// Argumets to the query
-	args := []interface{}{user.Name, user.Email, user.Password.hash, user.Activated, user.ID, user.Version}
+	args := []interface{}{user.Name, user.Email, user.Password.hash, user.Activated, parentalCountry,
+		parentalMaxCertification, user.ParentalPin.hash, user.ID, user.Version, userModel.TenantID}
 
 	// Create a DB context to timeout the query if it exceeds a certian duration
-	ctxt, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctxt, cancel := withTimeout(ctxt, "users.update")
 	defer cancel()
 
 	err := userModel.DB.QueryRowContext(ctxt, query, args...).Scan(&user.Version)
 	if err != nil {
 		switch {
-		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
+		case err.Error() == `pq: duplicate key value violates unique constraint "users_tenant_email_key"`:
 			return ErrDuplicateEmail
 		case errors.Is(err, sql.ErrNoRows):
 			return ErrEditConflict
@@ -202,3 +274,46 @@ func (userModel *UserModel) Update(user *User) error {
 
 	return nil
 }
+
+// Get the user owning the given token, provided the token has not expired
+func (userModel *UserModel) GetForToken(ctxt context.Context, tokenScope, tokenPlaintext string) (*User, error) {
+
+	// Tokens are stored as hashes
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+		SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version,
+			users.household_id, users.parental_country, users.parental_max_certification, users.parental_pin_hash,
+			COALESCE(tokens.profile_id, 0)
+		FROM users
+		INNER JOIN tokens ON users.id = tokens.user_id
+		WHERE tokens.hash = $1
+		AND tokens.scope = $2
+		AND tokens.expiry > $3
+		AND users.tenant_id = $4`
+
+	args := []interface{}{tokenHash[:], tokenScope, time.Now(), userModel.TenantID}
+
+	var user User
+	var parentalCountry, parentalMaxCertification string
+
+	// Create a DB context to timeout the query if it exceeds a certian duration
+	ctxt, cancel := withTimeout(ctxt, "users.get_for_token")
+	defer cancel()
+
+	err := userModel.DB.QueryRowContext(ctxt, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Name,
+		&user.Email, &user.Password.hash, &user.Activated, &user.Version, &user.HouseholdID,
+		&parentalCountry, &parentalMaxCertification, &user.ParentalPin.hash, &user.ProfileID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	user.setParentalLimit(parentalCountry, parentalMaxCertification)
+
+	return &user, nil
+}
@@ -0,0 +1,277 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+var ErrImageTooLarge = errors.New("image too large")
+
+// Configuration of the provider client
+type Config struct {
+	BaseURL           string  // e.g. "https://www.omdbapi.com/", or a local fake server
+	APIKey            string  // Sent as the apikey parameter, as the provider does not accept it in a header
+	RequestsPerSecond float64 // Rate limit of the provider
+	Burst             int
+}
+
+// Client of an OMDb style catalogue API, which looks up a movie with
+// GET <base>?t=<title>&y=<year>&plot=full&apikey=<key>
+type Client struct {
+	config  Config
+	baseURL *url.URL
+	client  *http.Client
+	limiter *rate.Limiter
+}
+
+func NewClient(config Config) (*Client, error) {
+
+	baseURL, err := url.Parse(config.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, fmt.Errorf("invalid provider base url %q", config.BaseURL)
+	}
+
+	if config.RequestsPerSecond <= 0 {
+		config.RequestsPerSecond = 1
+	}
+
+	if config.Burst < 1 {
+		config.Burst = 1
+	}
+
+	client := &Client{
+		config:  config,
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		limiter: rate.NewLimiter(rate.Limit(config.RequestsPerSecond), config.Burst),
+	}
+
+	return client, nil
+}
+
+// Response of the provider. Missing values are reported as "N/A"
+type lookupResponse struct {
+	Response string `json:"Response"`
+	Error    string `json:"Error"`
+	ImdbID   string `json:"imdbID"`
+	Title    string `json:"Title"`
+	Year     string `json:"Year"`
+	Runtime  string `json:"Runtime"`
+	Genre    string `json:"Genre"`
+	Plot     string `json:"Plot"`
+	Director string `json:"Director"`
+	Writer   string `json:"Writer"`
+	Actors   string `json:"Actors"`
+	Poster   string `json:"Poster"`
+}
+
+func (client *Client) Lookup(ctxt context.Context, title string, year int32) (*Details, error) {
+
+	query := url.Values{}
+	query.Set("t", title)
+	query.Set("plot", "full")
+	query.Set("apikey", client.config.APIKey)
+
+	if year > 0 {
+		query.Set("y", strconv.Itoa(int(year)))
+	}
+
+	target := *client.baseURL
+	target.RawQuery = query.Encode()
+
+	resp, err := client.get(ctxt, target.String())
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	var body lookupResponse
+
+	if err = json.NewDecoder(io.LimitReader(resp.Body, 1_048_576)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("reading provider response: %w", err)
+	}
+
+	if body.Response != "True" {
+		if strings.Contains(strings.ToLower(body.Error), "not found") {
+			return nil, ErrNotFound
+		}
+
+		return nil, fmt.Errorf("provider error: %s", body.Error)
+	}
+
+	details := &Details{
+		ExternalID: value(body.ImdbID),
+		Title:      value(body.Title),
+		Year:       leadingNumber(value(body.Year)),
+		Runtime:    leadingNumber(value(body.Runtime)),
+		Genres:     list(body.Genre),
+		Plot:       value(body.Plot),
+		PosterURL:  value(body.Poster),
+	}
+
+	for _, name := range list(body.Director) {
+		details.Credits = append(details.Credits, Credit{Name: name, Role: "director"})
+	}
+
+	for _, name := range list(body.Writer) {
+		details.Credits = append(details.Credits, Credit{Name: name, Role: "writer"})
+	}
+
+	for _, name := range list(body.Actors) {
+		details.Credits = append(details.Credits, Credit{Name: name, Role: "actor"})
+	}
+
+	return details, nil
+}
+
+func (client *Client) FetchImage(ctxt context.Context, imageURL string, maxBytes int64) ([]byte, error) {
+
+	resp, err := client.get(ctxt, imageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.ContentLength > maxBytes {
+		return nil, ErrImageTooLarge
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(content)) > maxBytes {
+		return nil, ErrImageTooLarge
+	}
+
+	return content, nil
+}
+
+// Send a GET request, waiting for the rate limiter first. ErrRateLimited is returned without waiting
+// if the wait would outlast the deadline of the context. Error statuses are turned into errors
+func (client *Client) get(ctxt context.Context, target string) (*http.Response, error) {
+
+	if err := client.limiter.Wait(ctxt); err != nil {
+		if ctxt.Err() == nil {
+			return nil, ErrRateLimited
+		}
+
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctxt, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.client.Do(req)
+	if err != nil {
+		// The errors of the client include the URL, which must not reveal the API key in the logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = redactAPIKey(urlErr.URL)
+		}
+
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		resp.Body.Close()
+		return nil, ErrRateLimited
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		resp.Body.Close()
+		return nil, fmt.Errorf("provider returned status %d", resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+// Replace the API key in the query of the URL
+func redactAPIKey(target string) string {
+
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return "<invalid url>"
+	}
+
+	query := parsed.Query()
+	if query.Get("apikey") == "" {
+		return target
+	}
+
+	query.Set("apikey", "REDACTED")
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+// Value of a field, with "N/A" treated as missing
+func value(field string) string {
+
+	field = strings.TrimSpace(field)
+	if field == "N/A" {
+		return ""
+	}
+
+	return field
+}
+
+// Split a comma separated field e.g. "Action, Sci-Fi". Notes in parentheses such as
+// "(screenplay)" are dropped and duplicates are skipped
+func list(field string) []string {
+
+	var items []string
+	seen := make(map[string]bool)
+
+	for _, item := range strings.Split(value(field), ",") {
+		if i := strings.Index(item, "("); i >= 0 {
+			item = item[:i]
+		}
+
+		item = strings.TrimSpace(item)
+
+		if item != "" && !seen[item] {
+			seen[item] = true
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// Read the number at the start of a field e.g. 136 from "136 min" or 1999 from "1999–2003"
+func leadingNumber(field string) int32 {
+
+	end := 0
+	for end < len(field) && field[end] >= '0' && field[end] <= '9' {
+		end++
+	}
+
+	number, err := strconv.Atoi(field[:end])
+	if err != nil {
+		return 0
+	}
+
+	return int32(number)
+}
@@ -0,0 +1,178 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Response of the fake provider for "The Matrix"
+const matrixResponse = `{
+	"Response": "True",
+	"imdbID": "tt0133093",
+	"Title": "The Matrix",
+	"Year": "1999",
+	"Runtime": "136 min",
+	"Genre": "Action, Sci-Fi",
+	"Plot": "A computer hacker learns about the true nature of reality.",
+	"Director": "Lana Wachowski, Lilly Wachowski",
+	"Writer": "Lilly Wachowski (screenplay), Lana Wachowski (screenplay), Lilly Wachowski",
+	"Actors": "Keanu Reeves, Laurence Fishburne",
+	"Poster": "N/A"
+}`
+
+// Start a fake provider answering the lookups of "The Matrix" with the API key "secret"
+func newFakeProvider(t *testing.T, requests *int32) *httptest.Server {
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		atomic.AddInt32(requests, 1)
+
+		query := r.URL.Query()
+
+		switch {
+		case query.Get("apikey") != "secret":
+			w.WriteHeader(http.StatusUnauthorized)
+		case query.Get("t") == "Rate Limited":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case query.Get("t") == "The Matrix" && query.Get("y") == "1999" && query.Get("plot") == "full":
+			w.Write([]byte(matrixResponse))
+		default:
+			w.Write([]byte(`{"Response": "False", "Error": "Movie not found!"}`))
+		}
+	}))
+}
+
+func newTestClient(t *testing.T, baseURL string, rps float64, burst int) *Client {
+
+	t.Helper()
+
+	client, err := NewClient(Config{BaseURL: baseURL, APIKey: "secret", RequestsPerSecond: rps, Burst: burst})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+func TestLookup(t *testing.T) {
+
+	var requests int32
+
+	server := newFakeProvider(t, &requests)
+	defer server.Close()
+
+	client := newTestClient(t, server.URL, 100, 10)
+	ctxt := context.Background()
+
+	details, err := client.Lookup(ctxt, "The Matrix", 1999)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Details{
+		ExternalID: "tt0133093",
+		Title:      "The Matrix",
+		Year:       1999,
+		Runtime:    136,
+		Genres:     []string{"Action", "Sci-Fi"},
+		Plot:       "A computer hacker learns about the true nature of reality.",
+		Credits: []Credit{
+			{Name: "Lana Wachowski", Role: "director"},
+			{Name: "Lilly Wachowski", Role: "director"},
+			{Name: "Lilly Wachowski", Role: "writer"},
+			{Name: "Lana Wachowski", Role: "writer"},
+			{Name: "Keanu Reeves", Role: "actor"},
+			{Name: "Laurence Fishburne", Role: "actor"},
+		},
+	}
+
+	if !reflect.DeepEqual(details, want) {
+		t.Errorf("got details\n%+v\nwant\n%+v", details, want)
+	}
+
+	if _, err = client.Lookup(ctxt, "Unknown Movie", 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown movie: got error %v; want %v", err, ErrNotFound)
+	}
+
+	if _, err = client.Lookup(ctxt, "Rate Limited", 0); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("rate limited by the provider: got error %v; want %v", err, ErrRateLimited)
+	}
+}
+
+func TestLookupRateLimit(t *testing.T) {
+
+	var requests int32
+
+	server := newFakeProvider(t, &requests)
+	defer server.Close()
+
+	// One request every ten seconds
+	client := newTestClient(t, server.URL, 0.1, 1)
+
+	if _, err := client.Lookup(context.Background(), "The Matrix", 1999); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next request would have to wait past the deadline, so it is refused without waiting
+	ctxt, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	if _, err := client.Lookup(ctxt, "The Matrix", 1999); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("got error %v; want %v", err, ErrRateLimited)
+	}
+
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("waited %s for the rate limiter; want no wait", elapsed)
+	}
+
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("provider got %d requests; want 1", got)
+	}
+}
+
+func TestLookupErrorHidesAPIKey(t *testing.T) {
+
+	var requests int32
+
+	server := newFakeProvider(t, &requests)
+	server.Close()
+
+	client := newTestClient(t, server.URL, 100, 10)
+
+	_, err := client.Lookup(context.Background(), "The Matrix", 1999)
+	if err == nil {
+		t.Fatal("got no error from a closed provider; want one")
+	}
+
+	if strings.Contains(err.Error(), "secret") || !strings.Contains(err.Error(), "apikey=REDACTED") {
+		t.Errorf("got error %q; want the API key redacted", err)
+	}
+}
+
+func TestFetchImage(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server.URL, 100, 10)
+
+	content, err := client.FetchImage(context.Background(), server.URL+"/poster.jpg", 100)
+	if err != nil || len(content) != 100 {
+		t.Errorf("got %d bytes and error %v; want 100 bytes", len(content), err)
+	}
+
+	if _, err = client.FetchImage(context.Background(), server.URL+"/poster.jpg", 99); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("got error %v; want %v", err, ErrImageTooLarge)
+	}
+}
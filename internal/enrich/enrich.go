@@ -0,0 +1,39 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFound    = errors.New("movie not found at the provider")
+	ErrRateLimited = errors.New("provider rate limit exceeded")
+)
+
+// Person credited in a movie by the provider
+type Credit struct {
+	Name      string
+	Role      string // One of the credit roles e.g. "actor", "director"
+	Character string
+}
+
+// Details of a movie from the catalogue provider. Empty values were not provided
+type Details struct {
+	ExternalID string
+	Title      string
+	Year       int32
+	Runtime    int32 // Minutes
+	Genres     []string
+	Plot       string
+	Credits    []Credit
+	PosterURL  string
+}
+
+// External catalogue of movie metadata
+type Provider interface {
+	// Look up a movie by its title and release year. ErrNotFound is returned if there is no such movie
+	Lookup(ctxt context.Context, title string, year int32) (*Details, error)
+
+	// Download an image e.g. a poster, reading at most maxBytes
+	FetchImage(ctxt context.Context, url string, maxBytes int64) ([]byte, error)
+}
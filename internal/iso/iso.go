@@ -0,0 +1,103 @@
+package iso
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"strings"
+)
+
+// Code lists embedded in the binary:
+//   - countries.txt, the ISO 3166-1 alpha-2 country codes and names
+//   - languages.txt, the ISO 639-1 language codes and names
+//   - certifications.txt, the age ratings of the countries with a well known rating system,
+//     from the least to the most restrictive. Unrated titles are the most restrictive
+//
+//go:embed countries.txt languages.txt certifications.txt
+var files embed.FS
+
+var (
+	countries      = readCodes("countries.txt")
+	languages      = readCodes("languages.txt")
+	certifications = readCertifications("certifications.txt")
+)
+
+// Check if the code is an ISO 3166-1 alpha-2 country code e.g. "GB"
+func IsCountry(code string) bool {
+	_, found := countries[code]
+	return found
+}
+
+// Check if the code is an ISO 639-1 language code e.g. "en"
+func IsLanguage(code string) bool {
+	_, found := languages[code]
+	return found
+}
+
+// Name of the country with the code, empty if unknown
+func CountryName(code string) string {
+	return countries[code]
+}
+
+// Name of the language with the code, empty if unknown
+func LanguageName(code string) string {
+	return languages[code]
+}
+
+// Get the age ratings of the country, from the least to the most restrictive. The ratings are not known
+// for every country
+func Certifications(country string) ([]string, bool) {
+	ratings, found := certifications[country]
+	return ratings, found
+}
+
+// Read a list of tab separated codes and names
+func readCodes(name string) map[string]string {
+
+	codes := make(map[string]string)
+
+	for _, line := range readLines(name) {
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) == 2 {
+			codes[parts[0]] = parts[1]
+		}
+	}
+
+	return codes
+}
+
+// Read the age ratings, a country and its space separated ratings on each line
+func readCertifications(name string) map[string][]string {
+
+	ratings := make(map[string][]string)
+
+	for _, line := range readLines(name) {
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) == 2 {
+			ratings[parts[0]] = strings.Fields(parts[1])
+		}
+	}
+
+	return ratings
+}
+
+// Read the non-empty lines of an embedded file. The files are part of the binary, so a missing
+// file is a build error rather than a runtime condition
+func readLines(name string) []string {
+
+	content, err := files.ReadFile(name)
+	if err != nil {
+		panic(err)
+	}
+
+	var lines []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,57 @@
+package iso
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodes(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		check func(string) bool
+		code  string
+		want  bool
+	}{
+		{"country", IsCountry, "GB", true},
+		{"lower case country", IsCountry, "gb", false},
+		{"unknown country", IsCountry, "XX", false},
+		{"language", IsLanguage, "en", true},
+		{"upper case language", IsLanguage, "EN", false},
+		{"unknown language", IsLanguage, "xx", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check(tt.code); got != tt.want {
+				t.Errorf("got %t for %q; want %t", got, tt.code, tt.want)
+			}
+		})
+	}
+
+	if name := CountryName("DE"); name != "Germany" {
+		t.Errorf("got country name %q; want %q", name, "Germany")
+	}
+
+	if name := LanguageName("fr"); name != "French" {
+		t.Errorf("got language name %q; want %q", name, "French")
+	}
+}
+
+func TestCertifications(t *testing.T) {
+
+	ratings, found := Certifications("US")
+	if !found {
+		t.Fatal("got no certifications for US")
+	}
+
+	// The ratings are ordered from the least to the most restrictive
+	want := []string{"G", "PG", "PG-13", "R", "NC-17", "NR"}
+	if !reflect.DeepEqual(ratings, want) {
+		t.Errorf("got US certifications %v; want %v", ratings, want)
+	}
+
+	if _, found := Certifications("XX"); found {
+		t.Error("got certifications for an unknown country")
+	}
+}
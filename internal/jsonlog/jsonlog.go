@@ -2,10 +2,13 @@ package jsonlog
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"runtime/debug"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -33,10 +36,26 @@ func (l Level) ErrorLevel() string {
 	}
 }
 
+// Get the level with the given name e.g. "info", as used in the configuration
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "info":
+		return LevelInfo, nil
+	case "error":
+		return LevelError, nil
+	case "fatal":
+		return LevelFatal, nil
+	case "off":
+		return LevelOff, nil
+	default:
+		return LevelInfo, fmt.Errorf("unknown log level %q", name)
+	}
+}
+
 // The logger structure
 type Logger struct {
 	out      io.Writer
-	minLevel Level
+	minLevel int32 // Level, which can be changed while logging
 	mutx     sync.Mutex
 }
 
@@ -45,15 +64,20 @@ type Logger struct {
 func New(out io.Writer, minLevel Level) *Logger {
 	return &Logger{
 		out:      out,
-		minLevel: minLevel,
+		minLevel: int32(minLevel),
 	}
 }
 
+// Change the minimum level of the messages logged
+func (logger *Logger) SetLevel(minLevel Level) {
+	atomic.StoreInt32(&logger.minLevel, int32(minLevel))
+}
+
 // Helper funtion to write out the actual log message
 func (logger *Logger) print(level Level, msg string, properties map[string]string) (int, error) {
 
 	// Wirte the log message only if it is above the minimum level configured for logging
-	if level < logger.minLevel {
+	if level < Level(atomic.LoadInt32(&logger.minLevel)) {
 		return 0, nil
 	}
 
@@ -0,0 +1,538 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrChecksumMismatch = errors.New("migration changed after it was applied")
+	ErrUnknownMigration = errors.New("unknown migration")
+	ErrNoDownMigration  = errors.New("migration cannot be reverted")
+	ErrDirtyDatabase    = errors.New("database left dirty by a failed migration")
+)
+
+// Key of the advisory lock held while migrating, so that instances started together do not apply
+// the same migrations at once
+const lockKey int64 = 7236441302
+
+// Names of the migration files e.g. 000001_create_movies_tables.up.sql
+var fileRX = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration of the database schema
+type Migration struct {
+	Version  int64
+	Name     string
+	Checksum string // SHA-256 of the up migration, to detect the migrations changed after they were applied
+	up       string
+	down     string
+}
+
+// Status of a migration. Applied migrations unknown to the binary, e.g. applied by a newer version
+// of the server, are listed as missing
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time // Zero if the migration is pending
+	Modified  bool      // The migration changed after it was applied
+	Missing   bool
+	Legacy    bool // Applied as recorded by golang-migrate, in a table which has not been converted yet
+}
+
+// Applied migration as recorded in the schema_migrations table
+type record struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Applies the migrations to the database, recording the applied versions along with their checksums
+// in the schema_migrations table
+type Migrator struct {
+	DB         *sql.DB
+	migrations []*Migration // In the order of their versions
+}
+
+// Create a migrator for the migrations read from the files
+func New(db *sql.DB, files fs.FS) (*Migrator, error) {
+
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+
+	for _, entry := range entries {
+		match := fileRX.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(files, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, found := byVersion[version]
+		if !found {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			checksum := sha256.Sum256(content)
+			migration.up = string(content)
+			migration.Checksum = hex.EncodeToString(checksum[:])
+		} else {
+			migration.down = string(content)
+		}
+	}
+
+	migrator := &Migrator{DB: db}
+
+	for _, migration := range byVersion {
+		if migration.Checksum == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up migration", migration.Version, migration.Name)
+		}
+
+		migrator.migrations = append(migrator.migrations, migration)
+	}
+
+	sort.Slice(migrator.migrations, func(i, j int) bool {
+		return migrator.migrations[i].Version < migrator.migrations[j].Version
+	})
+
+	return migrator, nil
+}
+
+// Apply all the pending migrations. The applied migrations are returned
+func (m *Migrator) Up(ctxt context.Context) ([]*Migration, error) {
+	_, applied, err := m.migrate(ctxt, -1)
+	return applied, err
+}
+
+// Migrate the database to the version, applying the pending migrations up to it and reverting the
+// migrations past it. Version 0 reverts all the migrations. The reverted and the applied migrations are returned
+func (m *Migrator) Goto(ctxt context.Context, version int64) ([]*Migration, []*Migration, error) {
+
+	if version != 0 && m.find(version) == nil {
+		return nil, nil, fmt.Errorf("%w: version %d", ErrUnknownMigration, version)
+	}
+
+	return m.migrate(ctxt, version)
+}
+
+// Revert the last applied migrations. The reverted migrations are returned
+func (m *Migrator) Down(ctxt context.Context, steps int) ([]*Migration, error) {
+
+	var done []*Migration
+
+	err := m.locked(ctxt, func(conn *sql.Conn, applied map[int64]*record) error {
+
+		versions := appliedVersions(applied)
+
+		for i := len(versions) - 1; i >= 0 && len(done) < steps; i-- {
+			migration, err := m.revertable(versions[i])
+			if err != nil {
+				return err
+			}
+
+			if err = m.revert(ctxt, conn, migration); err != nil {
+				return err
+			}
+
+			done = append(done, migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Get the status of all the migrations, known or applied, in the order of their versions. The status is
+// read as it is, without taking the migration lock or converting a table left behind by golang-migrate,
+// whose migrations are reported as legacy
+func (m *Migrator) Status(ctxt context.Context) ([]*Status, error) {
+
+	conn, err := m.DB.Conn(ctxt)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	exists, legacy, err := tableState(ctxt, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]*record)
+
+	switch {
+	case legacy:
+		legacyVersion, err := readLegacyVersion(ctxt, conn)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, migration := range m.migrations {
+			if migration.Version <= legacyVersion {
+				applied[migration.Version] = &record{name: migration.Name, checksum: migration.Checksum}
+			}
+		}
+	case exists:
+		if applied, err = readApplied(ctxt, conn); err != nil {
+			return nil, err
+		}
+	}
+
+	var statuses []*Status
+
+	for _, migration := range m.migrations {
+		status := &Status{Version: migration.Version, Name: migration.Name}
+
+		if rec, found := applied[migration.Version]; found {
+			status.AppliedAt = rec.appliedAt
+			status.Modified = rec.checksum != migration.Checksum
+			status.Legacy = legacy
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	for version, rec := range applied {
+		if m.find(version) == nil {
+			statuses = append(statuses, &Status{Version: version, Name: rec.name, AppliedAt: rec.appliedAt, Missing: true})
+		}
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+// Apply the pending migrations up to the target version and revert the migrations past it. A negative
+// target applies all the pending migrations
+func (m *Migrator) migrate(ctxt context.Context, target int64) ([]*Migration, []*Migration, error) {
+
+	var reverted, applied []*Migration
+
+	err := m.locked(ctxt, func(conn *sql.Conn, records map[int64]*record) error {
+
+		// Migrations changed after they were applied would leave the schema different from the one expected
+		for _, migration := range m.migrations {
+			if rec, found := records[migration.Version]; found && rec.checksum != migration.Checksum {
+				return fmt.Errorf("%w: version %d", ErrChecksumMismatch, migration.Version)
+			}
+		}
+
+		// Revert the migrations past the target, latest first
+		versions := appliedVersions(records)
+
+		for i := len(versions) - 1; i >= 0 && target >= 0 && versions[i] > target; i-- {
+			migration, err := m.revertable(versions[i])
+			if err != nil {
+				return err
+			}
+
+			if err = m.revert(ctxt, conn, migration); err != nil {
+				return err
+			}
+
+			reverted = append(reverted, migration)
+		}
+
+		// Apply the pending migrations up to the target, oldest first
+		for _, migration := range m.migrations {
+			if target >= 0 && migration.Version > target {
+				break
+			}
+
+			if _, found := records[migration.Version]; found {
+				continue
+			}
+
+			if err := m.apply(ctxt, conn, migration); err != nil {
+				return err
+			}
+
+			applied = append(applied, migration)
+		}
+
+		return nil
+	})
+
+	return reverted, applied, err
+}
+
+// Get the known migration with the version, nil if unknown
+func (m *Migrator) find(version int64) *Migration {
+
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+
+	return nil
+}
+
+// Get the applied migration with the version, provided it can be reverted
+func (m *Migrator) revertable(version int64) (*Migration, error) {
+
+	migration := m.find(version)
+
+	switch {
+	case migration == nil:
+		return nil, fmt.Errorf("%w: version %d", ErrUnknownMigration, version)
+	case strings.TrimSpace(migration.down) == "":
+		return nil, fmt.Errorf("%w: version %d", ErrNoDownMigration, version)
+	}
+
+	return migration, nil
+}
+
+// Run the function holding the migration lock on a connection of its own, as advisory locks are held
+// by the connections. The function is given the connection and the applied migrations
+func (m *Migrator) locked(ctxt context.Context, fn func(*sql.Conn, map[int64]*record) error) error {
+
+	conn, err := m.DB.Conn(ctxt)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctxt, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return err
+	}
+
+	// The lock is released even if the context has been cancelled
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+
+	if err = m.createTable(ctxt, conn); err != nil {
+		return err
+	}
+
+	applied, err := readApplied(ctxt, conn)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn, applied)
+}
+
+// Create the schema_migrations table if it does not exist yet. A table left behind by golang-migrate,
+// which only records the last applied version, is converted, taking all the migrations up to that
+// version as applied
+func (m *Migrator) createTable(ctxt context.Context, conn *sql.Conn) error {
+
+	_, legacy, err := tableState(ctxt, conn)
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctxt, nil)
+	if err != nil {
+		return err
+	}
+
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	var legacyVersion int64 = -1
+
+	if legacy {
+		if legacyVersion, err = readLegacyVersion(ctxt, tx); err != nil {
+			return err
+		}
+
+		if _, err = tx.ExecContext(ctxt, "DROP TABLE schema_migrations"); err != nil {
+			return err
+		}
+	}
+
+	query := `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		checksum text NOT NULL,
+		applied_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+	)`
+
+	if _, err = tx.ExecContext(ctxt, query); err != nil {
+		return err
+	}
+
+	for _, migration := range m.migrations {
+		if migration.Version > legacyVersion {
+			break
+		}
+
+		if err = recordApplied(ctxt, tx, migration); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Connection or transaction the queries reading the schema_migrations table are run on
+type queryer interface {
+	QueryRowContext(ctxt context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Check if the schema_migrations table exists, and if it is the table of golang-migrate, which has a dirty column
+func tableState(ctxt context.Context, db queryer) (bool, bool, error) {
+
+	var exists, legacy bool
+
+	query := `SELECT
+		EXISTS(
+			SELECT 1 FROM information_schema.tables
+			WHERE table_schema = current_schema() AND table_name = 'schema_migrations'
+		),
+		EXISTS(
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'schema_migrations' AND column_name = 'dirty'
+		)`
+
+	err := db.QueryRowContext(ctxt, query).Scan(&exists, &legacy)
+
+	return exists, legacy, err
+}
+
+// Get the last version applied by golang-migrate, -1 if none. A database left dirty by a failed
+// migration cannot be told apart from one which has not been migrated, and is refused
+func readLegacyVersion(ctxt context.Context, db queryer) (int64, error) {
+
+	var version int64
+	var dirty bool
+
+	err := db.QueryRowContext(ctxt, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return -1, nil
+	case err != nil:
+		return 0, err
+	case dirty:
+		return 0, fmt.Errorf("%w: version %d", ErrDirtyDatabase, version)
+	}
+
+	return version, nil
+}
+
+// Get the applied migrations by version
+func readApplied(ctxt context.Context, conn *sql.Conn) (map[int64]*record, error) {
+
+	rows, err := conn.QueryContext(ctxt, "SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	applied := make(map[int64]*record)
+
+	for rows.Next() {
+		var version int64
+		var rec record
+
+		if err = rows.Scan(&version, &rec.name, &rec.checksum, &rec.appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = &rec
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// Get the versions of the applied migrations in ascending order
+func appliedVersions(applied map[int64]*record) []int64 {
+
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	return versions
+}
+
+func recordApplied(ctxt context.Context, tx *sql.Tx, migration *Migration) error {
+
+	_, err := tx.ExecContext(ctxt, "INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+		migration.Version, migration.Name, migration.Checksum)
+
+	return err
+}
+
+// Apply the migration, recording it in the same transaction so that a failed migration leaves nothing behind
+func (m *Migrator) apply(ctxt context.Context, conn *sql.Conn, migration *Migration) error {
+
+	tx, err := conn.BeginTx(ctxt, nil)
+	if err != nil {
+		return err
+	}
+
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctxt, migration.up); err != nil {
+		return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if err = recordApplied(ctxt, tx, migration); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Revert the migration, removing its record in the same transaction
+func (m *Migrator) revert(ctxt context.Context, conn *sql.Conn, migration *Migration) error {
+
+	tx, err := conn.BeginTx(ctxt, nil)
+	if err != nil {
+		return err
+	}
+
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctxt, migration.down); err != nil {
+		return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err = tx.ExecContext(ctxt, "DELETE FROM schema_migrations WHERE version = $1", migration.Version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,317 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/narinderv/blockbuster/internal/testdb"
+)
+
+// Open an empty database in a schema of its own, for the migrations of the tests to be applied to
+func newTestDB(t *testing.T) *sql.DB {
+
+	t.Helper()
+
+	return testdb.Open(t, "test_migrate")
+}
+
+// Migrations creating the tables a and b
+var testFiles = fstest.MapFS{
+	"000001_create_a.up.sql":   {Data: []byte("CREATE TABLE a (id bigint);")},
+	"000001_create_a.down.sql": {Data: []byte("DROP TABLE a;")},
+	"000002_create_b.up.sql":   {Data: []byte("CREATE TABLE b (id bigint);")},
+	"000002_create_b.down.sql": {Data: []byte("DROP TABLE b;")},
+}
+
+func newTestMigrator(t *testing.T, db *sql.DB, files fstest.MapFS) *Migrator {
+
+	t.Helper()
+
+	migrator, err := New(db, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return migrator
+}
+
+// Check if the table exists in the schema of the test
+func tableExists(t *testing.T, db *sql.DB, table string) bool {
+
+	t.Helper()
+
+	var exists bool
+
+	err := db.QueryRow("SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return exists
+}
+
+func TestNew(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr bool
+	}{
+		{"valid", testFiles, false},
+		{"other files", fstest.MapFS{"README.md": {}, "000001_a.up.sql": {Data: []byte("SELECT 1;")}}, false},
+		{"no up migration", fstest.MapFS{"000001_a.down.sql": {Data: []byte("SELECT 1;")}}, true},
+		{"same version", fstest.MapFS{"000001_a.up.sql": {}, "000001_b.up.sql": {}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(nil, tt.files); (err != nil) != tt.wantErr {
+				t.Errorf("got error %v; want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpAndDown(t *testing.T) {
+
+	db := newTestDB(t)
+	migrator := newTestMigrator(t, db, testFiles)
+	ctxt := context.Background()
+
+	applied, err := migrator.Up(ctxt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(applied) != 2 || !tableExists(t, db, "b") {
+		t.Fatalf("got %d migrations applied; want 2", len(applied))
+	}
+
+	// Nothing is left to apply
+	if applied, err = migrator.Up(ctxt); err != nil || len(applied) != 0 {
+		t.Fatalf("got %d migrations applied again and error %v; want none", len(applied), err)
+	}
+
+	reverted, err := migrator.Down(ctxt, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reverted) != 1 || reverted[0].Version != 2 || tableExists(t, db, "b") {
+		t.Errorf("got %d migrations reverted; want version 2 reverted", len(reverted))
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+
+	db := newTestDB(t)
+	ctxt := context.Background()
+
+	if _, err := newTestMigrator(t, db, testFiles).Up(ctxt); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first migration is changed after it was applied
+	changed := fstest.MapFS{}
+	for name, file := range testFiles {
+		changed[name] = file
+	}
+
+	changed["000001_create_a.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE a (id bigint, name text);")}
+	changed["000003_create_c.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE c (id bigint);")}
+
+	migrator := newTestMigrator(t, db, changed)
+
+	if _, err := migrator.Up(ctxt); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("got error %v; want %v", err, ErrChecksumMismatch)
+	}
+
+	if tableExists(t, db, "c") {
+		t.Error("got the pending migration applied past the changed one; want nothing applied")
+	}
+
+	statuses, err := migrator.Status(ctxt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !statuses[0].Modified || statuses[1].Modified {
+		t.Errorf("got versions 1 and 2 modified %t and %t; want true and false", statuses[0].Modified, statuses[1].Modified)
+	}
+}
+
+func TestLegacyTable(t *testing.T) {
+
+	ctxt := context.Background()
+
+	// Database migrated by golang-migrate up to the first migration
+	newLegacyDB := func(t *testing.T, dirty bool) *sql.DB {
+
+		db := newTestDB(t)
+
+		_, err := db.Exec(`CREATE TABLE a (id bigint);
+			CREATE TABLE schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = db.Exec("INSERT INTO schema_migrations (version, dirty) VALUES (1, $1)", dirty); err != nil {
+			t.Fatal(err)
+		}
+
+		return db
+	}
+
+	t.Run("status", func(t *testing.T) {
+
+		db := newLegacyDB(t, false)
+
+		statuses, err := newTestMigrator(t, db, testFiles).Status(ctxt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !statuses[0].Legacy || statuses[1].Legacy || statuses[0].Modified {
+			t.Errorf("got versions 1 and 2 legacy %t and %t; want true and false", statuses[0].Legacy, statuses[1].Legacy)
+		}
+
+		// The status leaves the table as it is
+		var legacy bool
+
+		if _, legacy, err = tableState(ctxt, db); err != nil || !legacy {
+			t.Errorf("got the legacy table %t after the status (error %v); want it left as it is", legacy, err)
+		}
+	})
+
+	t.Run("conversion", func(t *testing.T) {
+
+		db := newLegacyDB(t, false)
+		migrator := newTestMigrator(t, db, testFiles)
+
+		// Applying the first migration again would fail, as the table exists
+		applied, err := migrator.Up(ctxt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(applied) != 1 || applied[0].Version != 2 {
+			t.Fatalf("got %d migrations applied; want version 2 only", len(applied))
+		}
+
+		statuses, err := migrator.Status(ctxt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, status := range statuses {
+			if status.Legacy || status.AppliedAt.IsZero() {
+				t.Errorf("got version %d legacy %t, applied at %v; want it converted and applied", status.Version, status.Legacy, status.AppliedAt)
+			}
+		}
+	})
+
+	t.Run("dirty", func(t *testing.T) {
+
+		db := newLegacyDB(t, true)
+		migrator := newTestMigrator(t, db, testFiles)
+
+		if _, err := migrator.Up(ctxt); !errors.Is(err, ErrDirtyDatabase) {
+			t.Errorf("got error %v; want %v", err, ErrDirtyDatabase)
+		}
+
+		if _, err := migrator.Status(ctxt); !errors.Is(err, ErrDirtyDatabase) {
+			t.Errorf("got status error %v; want %v", err, ErrDirtyDatabase)
+		}
+	})
+}
+
+func TestStatusReadOnly(t *testing.T) {
+
+	db := newTestDB(t)
+
+	statuses, err := newTestMigrator(t, db, testFiles).Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(statuses) != 2 || !statuses[0].AppliedAt.IsZero() || !statuses[1].AppliedAt.IsZero() {
+		t.Errorf("got statuses %v; want both migrations pending", statuses)
+	}
+
+	if tableExists(t, db, "schema_migrations") {
+		t.Error("got the schema_migrations table created by the status; want nothing written")
+	}
+}
+
+func TestLockContention(t *testing.T) {
+
+	db := newTestDB(t)
+	ctxt := context.Background()
+
+	// Another instance holds the migration lock
+	conn, err := db.Conn(ctxt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctxt, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		t.Fatal(err)
+	}
+
+	// Instances started together wait for each other, and apply each migration only once
+	const instances = 3
+
+	var wg sync.WaitGroup
+	applied := make(chan int, instances)
+	errs := make(chan error, instances)
+
+	for i := 0; i < instances; i++ {
+		migrator := newTestMigrator(t, db, testFiles)
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			done, err := migrator.Up(ctxt)
+			applied <- len(done)
+			errs <- err
+		}()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if len(applied) != 0 {
+		t.Fatal("got the migrations applied while the lock was held; want them waiting for the lock")
+	}
+
+	if _, err = conn.ExecContext(ctxt, "SELECT pg_advisory_unlock($1)", lockKey); err != nil {
+		t.Fatal(err)
+	}
+
+	wg.Wait()
+	close(applied)
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	total := 0
+	for count := range applied {
+		total += count
+	}
+
+	if total != 2 {
+		t.Errorf("got %d migrations applied by the instances; want 2", total)
+	}
+}
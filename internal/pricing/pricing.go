@@ -0,0 +1,324 @@
+package pricing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoRule = errors.New("no pricing rule for the format")
+)
+
+// Amount of money in the minor units of the currency e.g. cents.
+// Floating point numbers are never used for money to avoid rounding errors.
+type Money int64
+
+// Format the amount with two decimal places e.g. 1250 as "12.50"
+func (m Money) String() string {
+
+	sign := ""
+	if m < 0 {
+		sign = "-"
+		m = -m
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, m/100, m%100)
+}
+
+// Pricing rules of a format
+type FormatRule struct {
+	RentalPrice   Money    `json:"rental_price"`     // Price of a rental
+	RenewalPrice  Money    `json:"renewal_price"`    // Price of renewing a rental
+	LateFeePerDay Money    `json:"late_fee_per_day"` // Fee for each started day after the due date
+	GracePeriod   Duration `json:"grace_period"`     // Time after the due date before late fees apply
+	LateFeeCap    Money    `json:"late_fee_cap"`     // Maximum late fee of a rental. Zero means no cap
+}
+
+// Discount on the rental price for rentals starting on the given days of the week
+type WeekendSpecial struct {
+	Days            []string `json:"days"`             // e.g. ["saturday", "sunday"]
+	Formats         []string `json:"formats"`          // Formats the special applies to. Empty means all
+	DiscountPercent int64    `json:"discount_percent"` // Discount on the rental price
+}
+
+// Declarative pricing rules, usually loaded from a JSON file
+type Rules struct {
+	Currency        string                `json:"currency"`
+	TimeZone        string                `json:"time_zone"` // IANA time zone of the stores e.g. "America/New_York". Empty means the local time zone
+	Formats         map[string]FormatRule `json:"formats"`
+	WeekendSpecials []WeekendSpecial      `json:"weekend_specials"`
+}
+
+// Duration which is read from and written to JSON as a string e.g. "12h"
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(value []byte) error {
+
+	var str string
+
+	if err := json.Unmarshal(value, &str); err != nil {
+		return fmt.Errorf("duration must be a string e.g. \"12h\": %w", err)
+	}
+
+	duration, err := time.ParseDuration(str)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(duration)
+	return nil
+}
+
+// Rules used when no rules file is configured
+func DefaultRules() Rules {
+	return Rules{
+		Currency: "USD",
+		Formats: map[string]FormatRule{
+			"dvd":     {RentalPrice: 299, RenewalPrice: 199, LateFeePerDay: 100, GracePeriod: Duration(12 * time.Hour), LateFeeCap: 2000},
+			"blu-ray": {RentalPrice: 399, RenewalPrice: 299, LateFeePerDay: 150, GracePeriod: Duration(12 * time.Hour), LateFeeCap: 2500},
+			"4k":      {RentalPrice: 599, RenewalPrice: 399, LateFeePerDay: 200, GracePeriod: Duration(6 * time.Hour), LateFeeCap: 3000},
+		},
+		WeekendSpecials: []WeekendSpecial{
+			{Days: []string{"saturday", "sunday"}, Formats: []string{"dvd"}, DiscountPercent: 25},
+		},
+	}
+}
+
+// Validate the rules, which must price every one of the given formats. All the problems found are reported together
+func (rules Rules) Validate(formats []string) error {
+
+	var problems []string
+
+	if rules.Currency == "" {
+		problems = append(problems, "currency must be provided")
+	}
+
+	if _, err := rules.location(); err != nil {
+		problems = append(problems, fmt.Sprintf("invalid time_zone %q", rules.TimeZone))
+	}
+
+	if len(rules.Formats) == 0 {
+		problems = append(problems, "formats must be provided")
+	}
+
+	for _, format := range formats {
+		if _, ok := rules.Formats[format]; !ok {
+			problems = append(problems, fmt.Sprintf("formats.%s: rule must be provided", format))
+		}
+	}
+
+	for format, rule := range rules.Formats {
+		if rule.RentalPrice < 0 || rule.RenewalPrice < 0 || rule.LateFeePerDay < 0 || rule.LateFeeCap < 0 {
+			problems = append(problems, fmt.Sprintf("formats.%s: prices must not be negative", format))
+		}
+
+		if rule.GracePeriod < 0 {
+			problems = append(problems, fmt.Sprintf("formats.%s: grace_period must not be negative", format))
+		}
+	}
+
+	for i, special := range rules.WeekendSpecials {
+		if len(special.Days) == 0 {
+			problems = append(problems, fmt.Sprintf("weekend_specials[%d]: days must be provided", i))
+		}
+
+		for _, day := range special.Days {
+			if _, ok := parseWeekday(day); !ok {
+				problems = append(problems, fmt.Sprintf("weekend_specials[%d]: invalid day %q", i, day))
+			}
+		}
+
+		for _, format := range special.Formats {
+			if _, ok := rules.Formats[format]; !ok {
+				problems = append(problems, fmt.Sprintf("weekend_specials[%d]: unknown format %q", i, format))
+			}
+		}
+
+		if special.DiscountPercent < 0 || special.DiscountPercent > 100 {
+			problems = append(problems, fmt.Sprintf("weekend_specials[%d]: discount_percent must be between 0 and 100", i))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid pricing rules: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// Time zone in which the days of the week of the weekend specials are reckoned
+func (rules Rules) location() (*time.Location, error) {
+
+	if rules.TimeZone == "" {
+		return time.Local, nil
+	}
+
+	return time.LoadLocation(rules.TimeZone)
+}
+
+// Engine computing the prices and the late fees from the rules
+type Engine struct {
+	rules    Rules
+	location *time.Location
+}
+
+// Create a new engine after validating that the rules price every one of the formats
+func New(rules Rules, formats []string) (*Engine, error) {
+
+	if err := rules.Validate(formats); err != nil {
+		return nil, err
+	}
+
+	location, err := rules.location()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Engine{rules: rules, location: location}, nil
+}
+
+// Load the rules from a JSON file and create a new engine pricing the formats
+func Load(path string, formats []string) (*Engine, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	var rules Rules
+
+	dec := json.NewDecoder(file)
+	dec.DisallowUnknownFields()
+
+	if err = dec.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("reading pricing rules from %s: %w", path, err)
+	}
+
+	return New(rules, formats)
+}
+
+// Currency of the amounts
+func (engine *Engine) Currency() string {
+	return engine.rules.Currency
+}
+
+func (engine *Engine) rule(format string) (FormatRule, error) {
+
+	rule, found := engine.rules.Formats[format]
+	if !found {
+		return FormatRule{}, fmt.Errorf("%w: %s", ErrNoRule, format)
+	}
+
+	return rule, nil
+}
+
+// Price of a rental of the format starting at the given time, including the applicable weekend specials.
+// The day of the week is the one in the time zone of the stores. If more than one special applies,
+// the largest discount is used.
+func (engine *Engine) RentalPrice(format string, rentedAt time.Time) (Money, error) {
+
+	rule, err := engine.rule(format)
+	if err != nil {
+		return 0, err
+	}
+
+	discount := int64(0)
+	day := rentedAt.In(engine.location).Weekday()
+
+	for _, special := range engine.rules.WeekendSpecials {
+		if special.appliesTo(format, day) && special.DiscountPercent > discount {
+			discount = special.DiscountPercent
+		}
+	}
+
+	// Integer arithmetic, rounding the discount down in favour of the customer
+	price := int64(rule.RentalPrice) * (100 - discount) / 100
+
+	return Money(price), nil
+}
+
+// Price of renewing a rental of the format
+func (engine *Engine) RenewalPrice(format string) (Money, error) {
+
+	rule, err := engine.rule(format)
+	if err != nil {
+		return 0, err
+	}
+
+	return rule.RenewalPrice, nil
+}
+
+// Late fee of a rental of the format returned at the given time. A fee is charged for each started day
+// after the due date and the grace period, up to the cap of the format.
+func (engine *Engine) LateFee(format string, dueAt, returnedAt time.Time) (Money, error) {
+
+	rule, err := engine.rule(format)
+	if err != nil {
+		return 0, err
+	}
+
+	late := returnedAt.Sub(dueAt) - time.Duration(rule.GracePeriod)
+	if late <= 0 {
+		return 0, nil
+	}
+
+	// Count the started days
+	days := int64(late / (24 * time.Hour))
+	if late%(24*time.Hour) != 0 {
+		days++
+	}
+
+	fee := Money(days) * rule.LateFeePerDay
+
+	if rule.LateFeeCap > 0 && fee > rule.LateFeeCap {
+		fee = rule.LateFeeCap
+	}
+
+	return fee, nil
+}
+
+// Check if the special applies to a rental of the format starting on the day
+func (special WeekendSpecial) appliesTo(format string, day time.Weekday) bool {
+
+	formatMatches := len(special.Formats) == 0
+	for _, f := range special.Formats {
+		if f == format {
+			formatMatches = true
+			break
+		}
+	}
+
+	if !formatMatches {
+		return false
+	}
+
+	for _, d := range special.Days {
+		if weekday, ok := parseWeekday(d); ok && weekday == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Parse the name of a day of the week e.g. "saturday"
+func parseWeekday(day string) (time.Weekday, bool) {
+
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(day, weekday.String()) {
+			return weekday, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,188 @@
+package pricing
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var formats = []string{"dvd", "blu-ray", "4k"}
+
+func newTestEngine(t *testing.T, rules Rules) *Engine {
+
+	t.Helper()
+
+	engine, err := New(rules, formats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return engine
+}
+
+func TestMoneyString(t *testing.T) {
+
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{1250, "12.50"},
+		{-299, "-2.99"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.want {
+			t.Errorf("Money(%d) = %q; want %q", int64(tt.money), got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+
+	missingFormat := DefaultRules()
+	missingFormat.Formats = map[string]FormatRule{"dvd": missingFormat.Formats["dvd"], "blu-ray": missingFormat.Formats["blu-ray"]}
+
+	negativePrice := DefaultRules()
+	negativePrice.Formats = map[string]FormatRule{"dvd": {RentalPrice: -1}, "blu-ray": {}, "4k": {}}
+
+	unknownZone := DefaultRules()
+	unknownZone.TimeZone = "Mars/Olympus_Mons"
+
+	badSpecial := DefaultRules()
+	badSpecial.WeekendSpecials = []WeekendSpecial{{Days: []string{"caturday"}, Formats: []string{"vhs"}, DiscountPercent: 120}}
+
+	tests := []struct {
+		name     string
+		rules    Rules
+		problems []string
+	}{
+		{"default rules", DefaultRules(), nil},
+		{"missing format", missingFormat, []string{"formats.4k: rule must be provided"}},
+		{"negative price", negativePrice, []string{"formats.dvd: prices must not be negative"}},
+		{"unknown time zone", unknownZone, []string{`invalid time_zone "Mars/Olympus_Mons"`}},
+		{"invalid special", badSpecial, []string{`invalid day "caturday"`, `unknown format "vhs"`, "discount_percent must be between 0 and 100"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			err := tt.rules.Validate(formats)
+
+			if tt.problems == nil {
+				if err != nil {
+					t.Errorf("got error %v; want none", err)
+				}
+
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("got no error; want %v", tt.problems)
+			}
+
+			for _, problem := range tt.problems {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("got error %q; want it to contain %q", err, problem)
+				}
+			}
+		})
+	}
+}
+
+func TestLateFee(t *testing.T) {
+
+	engine := newTestEngine(t, DefaultRules())
+
+	due := time.Date(2024, 3, 8, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		format   string
+		returned time.Time
+		want     Money
+	}{
+		{"returned early", "dvd", due.Add(-24 * time.Hour), 0},
+		{"within the grace period", "dvd", due.Add(12 * time.Hour), 0},
+		{"just past the grace period", "dvd", due.Add(12*time.Hour + time.Second), 100},
+		{"one full day late", "dvd", due.Add(36 * time.Hour), 100},
+		{"second day started", "dvd", due.Add(36*time.Hour + time.Minute), 200},
+		{"shorter grace period of 4k", "4k", due.Add(7 * time.Hour), 200},
+		{"capped", "dvd", due.Add(60 * 24 * time.Hour), 2000},
+		{"capped blu-ray", "blu-ray", due.Add(60 * 24 * time.Hour), 2500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got, err := engine.LateFee(tt.format, due, tt.returned)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %s; want %s", got, tt.want)
+			}
+		})
+	}
+
+	uncapped := DefaultRules()
+	uncapped.Formats["dvd"] = FormatRule{LateFeePerDay: 100}
+
+	got, err := newTestEngine(t, uncapped).LateFee("dvd", due, due.Add(60*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != 6000 {
+		t.Errorf("uncapped: got %s; want %s", got, Money(6000))
+	}
+}
+
+func TestRentalPrice(t *testing.T) {
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database not available:", err)
+	}
+
+	rules := DefaultRules()
+	rules.TimeZone = "America/New_York"
+	rules.WeekendSpecials = append(rules.WeekendSpecials, WeekendSpecial{Days: []string{"Sunday"}, DiscountPercent: 50})
+
+	engine := newTestEngine(t, rules)
+
+	tests := []struct {
+		name     string
+		format   string
+		rentedAt time.Time
+		want     Money
+	}{
+		{"weekday", "dvd", time.Date(2024, 3, 6, 18, 0, 0, 0, newYork), 299},
+		{"saturday special", "dvd", time.Date(2024, 3, 9, 10, 0, 0, 0, newYork), 224},
+		{"special of another format", "blu-ray", time.Date(2024, 3, 9, 10, 0, 0, 0, newYork), 399},
+		{"largest of the specials", "dvd", time.Date(2024, 3, 10, 10, 0, 0, 0, newYork), 149},
+		{"friday night in the stores, saturday in UTC", "dvd", time.Date(2024, 3, 8, 22, 0, 0, 0, newYork).UTC(), 299},
+		{"sunday night in the stores, monday in UTC", "blu-ray", time.Date(2024, 3, 10, 21, 0, 0, 0, newYork).UTC(), 199},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got, err := engine.RentalPrice(tt.format, tt.rentedAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %s; want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := engine.RentalPrice("vhs", time.Now()); !errors.Is(err, ErrNoRule) {
+		t.Errorf("unknown format: got error %v; want %v", err, ErrNoRule)
+	}
+}
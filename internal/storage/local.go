@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+)
+
+// Store keeping the objects as files under a root directory. The content type is derived
+// from the extension of the key when the object is read back.
+type Local struct {
+	root string
+}
+
+// Create a local store under the root directory, creating the directory if required
+func NewLocal(root string) (*Local, error) {
+
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Local{root: root}, nil
+}
+
+func (store *Local) path(key string) (string, error) {
+
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(store.root, filepath.FromSlash(key)), nil
+}
+
+// Write the object to a temporary file first and rename it into place,
+// so that readers never see a partially written object
+func (store *Local) Put(ctxt context.Context, key string, body io.Reader, size int64, contentType string) error {
+
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+
+	// Removing the temporary file fails harmlessly once it has been renamed
+	defer os.Remove(tmp.Name())
+
+	if _, err = io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if err = ctxt.Err(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (store *Local) Get(ctxt context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+
+	path, err := store.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return nil, nil, ErrNotFound
+		default:
+			return nil, nil, err
+		}
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	info := &ObjectInfo{
+		Key:          key,
+		Size:         stat.Size(),
+		ContentType:  mime.TypeByExtension(filepath.Ext(path)),
+		LastModified: stat.ModTime(),
+	}
+
+	return file, info, nil
+}
+
+func (store *Local) Delete(ctxt context.Context, key string) error {
+
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctxt := context.Background()
+	key := "movies/1/posters/poster.png"
+
+	if err = store.Put(ctxt, key, strings.NewReader("first"), 5, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Objects are replaced
+	if err = store.Put(ctxt, key, strings.NewReader("second"), 6, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+
+	body, info, err := store.Get(ctxt, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := io.ReadAll(body)
+	body.Close()
+
+	if string(content) != "second" || info.Size != 6 || info.ContentType != "image/png" {
+		t.Errorf("got %q of %d bytes and type %q; want \"second\" of 6 bytes and type image/png", content, info.Size, info.ContentType)
+	}
+
+	if err = store.Delete(ctxt, key); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err = store.Get(ctxt, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get deleted object: got error %v; want %v", err, ErrNotFound)
+	}
+
+	if err = store.Delete(ctxt, key); err != nil {
+		t.Errorf("delete missing object: got error %v", err)
+	}
+
+	if _, _, err = store.Get(ctxt, "../outside.png"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("get escaping key: got error %v; want %v", err, ErrInvalidKey)
+	}
+}
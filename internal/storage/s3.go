@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Configuration of an S3 compatible store
+type S3Config struct {
+	Endpoint  string // e.g. "https://s3.eu-west-1.amazonaws.com" or "http://localhost:9000" for a local stand-in
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool // Address the bucket in the path instead of the host name, as required by most stand-ins
+}
+
+// Store keeping the objects in a bucket of an S3 compatible service. Requests are signed with AWS Signature
+// Version 4 so that no SDK is required.
+type S3 struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func NewS3(config S3Config) (*S3, error) {
+
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", config.Endpoint)
+	}
+
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("s3 bucket must be provided")
+	}
+
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+
+	return &S3{config: config, endpoint: endpoint, client: &http.Client{Timeout: time.Minute}}, nil
+}
+
+func (store *S3) Put(ctxt context.Context, key string, body io.Reader, size int64, contentType string) error {
+
+	// The payload is hashed for the signature, so it is read into memory. Uploads are limited in size
+	payload, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := store.newRequest(ctxt, http.MethodPut, key, payload)
+	if err != nil {
+		return err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := store.do(req)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	return nil
+}
+
+func (store *S3) Get(ctxt context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+
+	req, err := store.newRequest(ctxt, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := store.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info := &ObjectInfo{
+		Key:         key,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.LastModified = modified
+	}
+
+	return resp.Body, info, nil
+}
+
+func (store *S3) Delete(ctxt context.Context, key string) error {
+
+	req, err := store.newRequest(ctxt, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := store.do(req)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	if resp != nil {
+		resp.Body.Close()
+	}
+
+	return nil
+}
+
+// Send the request. Error statuses are turned into errors, with 404 reported as ErrNotFound
+func (store *S3) do(req *http.Request) (*http.Response, error) {
+
+	resp, err := store.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	return nil, fmt.Errorf("s3 %s %s: status %d: %s", req.Method, req.URL.Path, resp.StatusCode, bytes.TrimSpace(message))
+}
+
+// Create a signed request for the object
+func (store *S3) newRequest(ctxt context.Context, method, key string, payload []byte) (*http.Request, error) {
+
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	target := *store.endpoint
+
+	if store.config.PathStyle {
+		target.Path = strings.TrimSuffix(target.Path, "/") + "/" + store.config.Bucket + "/" + key
+	} else {
+		target.Host = store.config.Bucket + "." + target.Host
+		target.Path = strings.TrimSuffix(target.Path, "/") + "/" + key
+	}
+
+	target.RawPath = escapePath(target.Path)
+
+	req, err := http.NewRequestWithContext(ctxt, method, target.String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	req.ContentLength = int64(len(payload))
+
+	store.sign(req, payload, time.Now().UTC())
+
+	return req, nil
+}
+
+// Sign the request with AWS Signature Version 4. Only the host and the x-amz-* headers are signed
+func (store *S3) sign(req *http.Request, payload []byte, now time.Time) {
+
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + store.config.Region + "/s3/aws4_request"
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+store.config.SecretKey), date)
+	signingKey = hmacSHA256(signingKey, store.config.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		store.config.AccessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Escape the path as required by the signature. Only the unreserved characters are left as they are
+func escapePath(path string) string {
+
+	var escaped strings.Builder
+
+	for _, b := range []byte(path) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~', b == '/':
+			escaped.WriteByte(b)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+
+	return escaped.String()
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Details of a stored object
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// Object storage for the uploaded files. Keys are slash separated paths e.g. "movies/1/posters/abc.jpg"
+type Store interface {
+	// Store the object, replacing any existing object with the same key
+	Put(ctxt context.Context, key string, body io.Reader, size int64, contentType string) error
+
+	// Read the object. The caller must close the returned reader
+	Get(ctxt context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+
+	// Delete the object. Deleting a missing object is not an error
+	Delete(ctxt context.Context, key string) error
+}
+
+// Check the key and return it in its clean form. Keys must be relative and must not escape the store
+func cleanKey(key string) (string, error) {
+
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+
+	cleaned := path.Clean(key)
+	if cleaned != key || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+
+	return cleaned, nil
+}
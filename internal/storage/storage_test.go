@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCleanKey(t *testing.T) {
+
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"movies/1/posters/abc.jpg", true},
+		{"abc.jpg", true},
+		{"", false},
+		{"/etc/passwd", false},
+		{"../secrets", false},
+		{"movies/../../secrets", false},
+		{"movies//abc.jpg", false},
+		{"movies\\abc.jpg", false},
+		{".", false},
+	}
+
+	for _, tt := range tests {
+		_, err := cleanKey(tt.key)
+
+		if tt.valid && err != nil {
+			t.Errorf("cleanKey(%q): got error %v; want none", tt.key, err)
+		}
+
+		if !tt.valid && !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("cleanKey(%q): got error %v; want %v", tt.key, err, ErrInvalidKey)
+		}
+	}
+}
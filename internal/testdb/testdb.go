@@ -0,0 +1,68 @@
+// Package testdb opens the PostgreSQL databases of the tests
+package testdb
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// Open an empty database in a schema of its own, named after the prefix, which is dropped at the end
+// of the test. The tests using it are skipped unless BLOCKBUSTER_TEST_DSN names a PostgreSQL database
+// to create the schemas in
+func Open(t testing.TB, prefix string) *sql.DB {
+
+	t.Helper()
+
+	dsn := os.Getenv("BLOCKBUSTER_TEST_DSN")
+	if dsn == "" {
+		t.Skip("BLOCKBUSTER_TEST_DSN is not set")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := fmt.Sprintf("%s_%d", prefix, time.Now().UnixNano())
+
+	if _, err = admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		admin.Close()
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		admin.Close()
+	})
+
+	db, err := sql.Open("postgres", WithSearchPath(dsn, schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// Add the schema to the front of the search path of the connections. The public schema stays on the
+// path, as the extensions like citext are installed there
+func WithSearchPath(dsn, schema string) string {
+
+	// The search path is a run-time parameter of the connections, set the way the DSN is written
+	separator := " "
+	if strings.Contains(dsn, "://") {
+		separator = "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+	}
+
+	return dsn + separator + "search_path=" + schema + ",public"
+}
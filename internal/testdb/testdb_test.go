@@ -0,0 +1,21 @@
+package testdb
+
+import "testing"
+
+func TestWithSearchPath(t *testing.T) {
+
+	tests := []struct {
+		dsn  string
+		want string
+	}{
+		{"host=localhost dbname=blockbuster", "host=localhost dbname=blockbuster search_path=test_1,public"},
+		{"postgres://localhost/blockbuster", "postgres://localhost/blockbuster?search_path=test_1,public"},
+		{"postgres://localhost/blockbuster?sslmode=disable", "postgres://localhost/blockbuster?sslmode=disable&search_path=test_1,public"},
+	}
+
+	for _, tt := range tests {
+		if got := WithSearchPath(tt.dsn, "test_1"); got != tt.want {
+			t.Errorf("%s: got %q; want %q", tt.dsn, got, tt.want)
+		}
+	}
+}
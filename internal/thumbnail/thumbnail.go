@@ -0,0 +1,89 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+
+	// Decoders of the supported upload formats
+	_ "image/gif"
+	_ "image/png"
+)
+
+// Content types of the images which can be decoded
+var ContentTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+// JPEG quality of the thumbnails
+const quality = 85
+
+// Scale the image down to fit within the width and height, keeping its aspect ratio, and encode it
+// as a JPEG. Images which already fit are re-encoded without scaling.
+func Generate(src image.Image, maxWidth, maxHeight int) ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	err := jpeg.Encode(&buf, Fit(src, maxWidth, maxHeight), &jpeg.Options{Quality: quality})
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Scale the image down to fit within the width and height, keeping its aspect ratio.
+// Each pixel of the result is the average of the source pixels it covers, which avoids
+// the aliasing of nearest neighbour scaling without needing an imaging library.
+func Fit(src image.Image, maxWidth, maxHeight int) image.Image {
+
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+
+	if srcWidth <= maxWidth && srcHeight <= maxHeight {
+		return src
+	}
+
+	// Scale by the more constraining side
+	width, height := maxWidth, srcHeight*maxWidth/srcWidth
+	if height > maxHeight {
+		width, height = srcWidth*maxHeight/srcHeight, maxHeight
+	}
+
+	if width < 1 {
+		width = 1
+	}
+
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*srcHeight/height
+		y1 := bounds.Min.Y + (y+1)*srcHeight/height
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*srcWidth/width
+			x1 := bounds.Min.X + (x+1)*srcWidth/width
+
+			var r, g, b, a, n uint64
+
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+
+			// The averages are 16 bit per channel
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,69 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		width, height int
+		wantW, wantH  int
+	}{
+		{"already fits", 200, 300, 200, 300},
+		{"wide", 1280, 720, 320, 180},
+		{"tall", 1000, 3000, 160, 480},
+		{"exact aspect ratio", 640, 960, 320, 480},
+		{"thin sliver", 10000, 1, 320, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			src := image.NewRGBA(image.Rect(0, 0, tt.width, tt.height))
+
+			bounds := Fit(src, 320, 480).Bounds()
+			if bounds.Dx() != tt.wantW || bounds.Dy() != tt.wantH {
+				t.Errorf("got %dx%d; want %dx%d", bounds.Dx(), bounds.Dy(), tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestFitAveragesPixels(t *testing.T) {
+
+	// Alternating black and white columns average out to grey
+	src := image.NewGray(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x += 2 {
+		for y := 0; y < 2; y++ {
+			src.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+
+	r, _, _, _ := Fit(src, 2, 1).At(0, 0).RGBA()
+	if grey := r >> 8; grey < 120 || grey > 135 {
+		t.Errorf("got level %d; want about 127", grey)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+
+	thumb, err := Generate(image.NewRGBA(image.Rect(0, 0, 1000, 1500)), 320, 480)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := jpeg.DecodeConfig(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatalf("thumbnail is not a JPEG: %v", err)
+	}
+
+	if config.Width != 320 || config.Height != 480 {
+		t.Errorf("got %dx%d; want 320x480", config.Width, config.Height)
+	}
+}
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS genres (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    slug text UNIQUE NOT NULL,
+    name text NOT NULL,
+    aliases text[] NOT NULL DEFAULT '{}',
+    version integer NOT NULL DEFAULT 1
+);
+
+-- Create a genre for each of the distinct free text genres of the existing movies
+INSERT INTO genres (slug, name)
+SELECT DISTINCT ON (slug) slug, name
+FROM (
+    SELECT trim(both '-' FROM regexp_replace(lower(trim(g)), '[^a-z0-9]+', '-', 'g')) AS slug, initcap(trim(g)) AS name
+    FROM movies, unnest(genres) AS g
+) AS existing
+WHERE slug <> ''
+ORDER BY slug, name
+ON CONFLICT (slug) DO NOTHING;
+
+-- Store the genre slugs in the movies instead of the free text values
+UPDATE movies SET genres = ARRAY(
+    SELECT slug FROM (
+        SELECT DISTINCT ON (slug) slug, ord
+        FROM unnest(genres) WITH ORDINALITY AS g(name, ord),
+        LATERAL (SELECT trim(both '-' FROM regexp_replace(lower(trim(g.name)), '[^a-z0-9]+', '-', 'g')) AS slug) AS s
+        WHERE slug <> ''
+        ORDER BY slug, ord
+    ) AS slugs
+    ORDER BY ord
+);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS reviews;
+DROP TABLE IF EXISTS ratings;
\ No newline at end of file
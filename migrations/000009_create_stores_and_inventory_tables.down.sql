@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS inventory_items;
+DROP TABLE IF EXISTS stores;
+DELETE FROM permissions WHERE code = 'inventory:manage';
\ No newline at end of file
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS stores (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    name text NOT NULL,
+    address text NOT NULL DEFAULT '',
+    city text NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS inventory_items (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    store_id bigint NOT NULL REFERENCES stores ON DELETE RESTRICT,
+    format text NOT NULL,
+    condition text NOT NULL DEFAULT 'good',
+    barcode text UNIQUE NOT NULL,
+    status text NOT NULL DEFAULT 'available',
+    version integer NOT NULL DEFAULT 1,
+    CONSTRAINT inventory_items_format_check CHECK (format IN ('dvd', 'blu-ray', '4k')),
+    CONSTRAINT inventory_items_condition_check CHECK (condition IN ('new', 'good', 'fair', 'poor', 'damaged')),
+    CONSTRAINT inventory_items_status_check CHECK (status IN ('available', 'rented', 'retired'))
+);
+
+CREATE INDEX IF NOT EXISTS inventory_items_movie_store_idx ON inventory_items (movie_id, store_id, status);
+CREATE INDEX IF NOT EXISTS inventory_items_store_status_idx ON inventory_items (store_id, status);
+
+INSERT INTO permissions (code) VALUES ('inventory:manage') ON CONFLICT (code) DO NOTHING;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS rentals;
\ No newline at end of file
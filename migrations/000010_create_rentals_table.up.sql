@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS rentals (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    user_id bigint NOT NULL REFERENCES users ON DELETE RESTRICT,
+    inventory_item_id bigint NOT NULL REFERENCES inventory_items ON DELETE RESTRICT,
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE RESTRICT,
+    store_id bigint NOT NULL REFERENCES stores ON DELETE RESTRICT,
+    format text NOT NULL,
+    rented_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    due_at timestamp(0) with time zone NOT NULL,
+    returned_at timestamp(0) with time zone,
+    renewals integer NOT NULL DEFAULT 0,
+    version integer NOT NULL DEFAULT 1
+);
+
+-- A copy can only be out on one rental at a time
+CREATE UNIQUE INDEX IF NOT EXISTS rentals_open_item_idx ON rentals (inventory_item_id) WHERE returned_at IS NULL;
+CREATE INDEX IF NOT EXISTS rentals_user_idx ON rentals (user_id, rented_at);
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    rental_id bigint REFERENCES rentals ON DELETE SET NULL,
+    kind text NOT NULL,
+    amount bigint NOT NULL,
+    currency text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    CONSTRAINT ledger_entries_kind_check CHECK (kind IN ('rental', 'renewal', 'late_fee', 'payment', 'adjustment'))
+);
+
+CREATE INDEX IF NOT EXISTS ledger_entries_user_idx ON ledger_entries (user_id, created_at);
\ No newline at end of file
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS holds;
+UPDATE inventory_items SET status = 'available' WHERE status = 'on_hold';
+ALTER TABLE inventory_items DROP CONSTRAINT IF EXISTS inventory_items_status_check;
+ALTER TABLE inventory_items ADD CONSTRAINT inventory_items_status_check CHECK (status IN ('available', 'rented', 'retired'));
\ No newline at end of file
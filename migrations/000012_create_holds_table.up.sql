@@ -0,0 +1,22 @@
+ALTER TABLE inventory_items DROP CONSTRAINT IF EXISTS inventory_items_status_check;
+ALTER TABLE inventory_items ADD CONSTRAINT inventory_items_status_check CHECK (status IN ('available', 'rented', 'on_hold', 'retired'));
+
+CREATE TABLE IF NOT EXISTS holds (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    store_id bigint NOT NULL REFERENCES stores ON DELETE CASCADE,
+    format text NOT NULL DEFAULT '',
+    status text NOT NULL DEFAULT 'waiting',
+    inventory_item_id bigint REFERENCES inventory_items ON DELETE SET NULL,
+    ready_at timestamp(0) with time zone,
+    expires_at timestamp(0) with time zone,
+    version integer NOT NULL DEFAULT 1,
+    CONSTRAINT holds_status_check CHECK (status IN ('waiting', 'ready', 'fulfilled', 'expired', 'cancelled'))
+);
+
+-- A user can only have one open hold for a movie at a store
+CREATE UNIQUE INDEX IF NOT EXISTS holds_open_user_idx ON holds (user_id, movie_id, store_id) WHERE status IN ('waiting', 'ready');
+CREATE INDEX IF NOT EXISTS holds_queue_idx ON holds (movie_id, store_id, status, created_at);
+CREATE INDEX IF NOT EXISTS holds_expiry_idx ON holds (expires_at) WHERE status = 'ready';
\ No newline at end of file
@@ -0,0 +1,14 @@
+-- Precomputed similarity of the movies, refreshed periodically by the API server
+CREATE TABLE IF NOT EXISTS movie_similarities (
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    similar_movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    score float8 NOT NULL,
+    genre_score float8 NOT NULL DEFAULT 0,
+    year_score float8 NOT NULL DEFAULT 0,
+    credit_score float8 NOT NULL DEFAULT 0,
+    corating_score float8 NOT NULL DEFAULT 0,
+    computed_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (movie_id, similar_movie_id)
+);
+
+CREATE INDEX IF NOT EXISTS movie_similarities_score_idx ON movie_similarities (movie_id, score DESC);
@@ -0,0 +1,12 @@
+-- Item-item collaborative filtering neighbours of the movies, computed from the ratings
+-- and rentals of the users and refreshed periodically by the API server
+CREATE TABLE IF NOT EXISTS movie_neighbours (
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    neighbour_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    score float8 NOT NULL,
+    support integer NOT NULL,
+    computed_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (movie_id, neighbour_id)
+);
+
+CREATE INDEX IF NOT EXISTS movie_neighbours_score_idx ON movie_neighbours (movie_id, score DESC);
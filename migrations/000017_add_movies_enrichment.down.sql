@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS movies_unenriched_idx;
+ALTER TABLE movies DROP COLUMN IF EXISTS enriched_at;
+ALTER TABLE movies DROP COLUMN IF EXISTS external_id;
+ALTER TABLE movies DROP COLUMN IF EXISTS plot;
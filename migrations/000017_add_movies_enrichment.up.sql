@@ -0,0 +1,7 @@
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS plot text NOT NULL DEFAULT '';
+
+-- Identifier of the movie at the catalogue provider and the time it was last enriched from there
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS external_id text NOT NULL DEFAULT '';
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS enriched_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS movies_unenriched_idx ON movies (id) WHERE enriched_at IS NULL;
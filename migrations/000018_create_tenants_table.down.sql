@@ -0,0 +1,18 @@
+-- Rolling back merges the data of all the tenants into a single catalogue, where each email can only
+-- be registered once. Stop before changing anything if an email is registered with more than one tenant,
+-- as those users have to be merged or removed by hand first
+DO $$
+BEGIN
+    IF EXISTS (SELECT email FROM users GROUP BY email HAVING count(*) > 1) THEN
+        RAISE EXCEPTION 'cannot roll back the tenants: some emails are registered with more than one tenant'
+            USING HINT = 'Run SELECT email FROM users GROUP BY email HAVING count(*) > 1 to find them';
+    END IF;
+END
+$$;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_tenant_email_key;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE stores DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE movies DROP COLUMN IF EXISTS tenant_id;
+DROP TABLE IF EXISTS tenants;
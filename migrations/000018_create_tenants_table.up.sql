@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS tenants (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    slug citext UNIQUE NOT NULL,
+    name text NOT NULL,
+    config jsonb NOT NULL DEFAULT '{}',
+    version integer NOT NULL DEFAULT 1
+);
+
+-- The existing data belongs to the default tenant
+INSERT INTO tenants (id, slug, name) VALUES (1, 'default', 'Default') ON CONFLICT (id) DO NOTHING;
+SELECT setval('tenants_id_seq', (SELECT max(id) FROM tenants));
+
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS tenant_id bigint NOT NULL DEFAULT 1 REFERENCES tenants ON DELETE RESTRICT;
+ALTER TABLE movies ALTER COLUMN tenant_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS movies_tenant_idx ON movies (tenant_id);
+
+ALTER TABLE stores ADD COLUMN IF NOT EXISTS tenant_id bigint NOT NULL DEFAULT 1 REFERENCES tenants ON DELETE RESTRICT;
+ALTER TABLE stores ALTER COLUMN tenant_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS stores_tenant_idx ON stores (tenant_id);
+
+-- The same email can be registered with each of the chains
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id bigint NOT NULL DEFAULT 1 REFERENCES tenants ON DELETE RESTRICT;
+ALTER TABLE users ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+ALTER TABLE users ADD CONSTRAINT users_tenant_email_key UNIQUE (tenant_id, email);
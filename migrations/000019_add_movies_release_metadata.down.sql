@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS movies_production_countries_idx;
+DROP INDEX IF EXISTS movies_spoken_languages_idx;
+DROP INDEX IF EXISTS movies_certifications_idx;
+
+ALTER TABLE movies DROP COLUMN IF EXISTS release_dates;
+ALTER TABLE movies DROP COLUMN IF EXISTS production_countries;
+ALTER TABLE movies DROP COLUMN IF EXISTS spoken_languages;
+ALTER TABLE movies DROP COLUMN IF EXISTS original_language;
+ALTER TABLE movies DROP COLUMN IF EXISTS certifications;
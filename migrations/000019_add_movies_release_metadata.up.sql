@@ -0,0 +1,9 @@
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS certifications jsonb NOT NULL DEFAULT '{}';
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS original_language text NOT NULL DEFAULT '';
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS spoken_languages text[] NOT NULL DEFAULT '{}';
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS production_countries text[] NOT NULL DEFAULT '{}';
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS release_dates jsonb NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS movies_certifications_idx ON movies USING GIN (certifications);
+CREATE INDEX IF NOT EXISTS movies_spoken_languages_idx ON movies USING GIN (spoken_languages);
+CREATE INDEX IF NOT EXISTS movies_production_countries_idx ON movies USING GIN (production_countries);
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS parental_pin_hash;
+ALTER TABLE users DROP COLUMN IF EXISTS parental_max_certification;
+ALTER TABLE users DROP COLUMN IF EXISTS parental_country;
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS parental_country text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS parental_max_certification text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS parental_pin_hash bytea;
@@ -0,0 +1,16 @@
+DELETE FROM permissions WHERE code = 'households:manage';
+
+ALTER TABLE lists DROP COLUMN IF EXISTS profile_id;
+
+-- Only the ratings of the accounts themselves can be kept under the original key
+DELETE FROM ratings WHERE profile_id IS NOT NULL;
+DROP INDEX IF EXISTS ratings_user_profile_movie_key;
+ALTER TABLE ratings DROP COLUMN IF EXISTS profile_id;
+ALTER TABLE ratings ADD PRIMARY KEY (user_id, movie_id);
+
+DROP TABLE IF EXISTS profiles;
+
+DROP INDEX IF EXISTS users_household_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS household_id;
+
+DROP TABLE IF EXISTS households;
@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS households (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    tenant_id bigint NOT NULL REFERENCES tenants ON DELETE RESTRICT,
+    name text NOT NULL,
+    max_active_rentals integer NOT NULL DEFAULT 5,
+    version integer NOT NULL DEFAULT 1,
+    CONSTRAINT households_max_active_rentals_check CHECK (max_active_rentals >= 1)
+);
+
+-- Each existing user gets a household of their own, numbered after the user
+INSERT INTO households (id, tenant_id, name) SELECT id, tenant_id, name FROM users ON CONFLICT (id) DO NOTHING;
+SELECT setval('households_id_seq', COALESCE((SELECT max(id) FROM households), 0) + 1, false);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS household_id bigint REFERENCES households ON DELETE RESTRICT;
+UPDATE users SET household_id = id WHERE household_id IS NULL;
+ALTER TABLE users ALTER COLUMN household_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS users_household_idx ON users (household_id);
+
+-- Viewers sharing the membership of a household
+CREATE TABLE IF NOT EXISTS profiles (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    household_id bigint NOT NULL REFERENCES households ON DELETE CASCADE,
+    name text NOT NULL,
+    parental_country text NOT NULL DEFAULT '',
+    parental_max_certification text NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1,
+    CONSTRAINT profiles_household_name_key UNIQUE (household_id, name)
+);
+
+-- Ratings and lists belong to a profile, or to the account itself when no profile is selected
+ALTER TABLE ratings ADD COLUMN IF NOT EXISTS profile_id bigint REFERENCES profiles ON DELETE CASCADE;
+ALTER TABLE ratings DROP CONSTRAINT IF EXISTS ratings_pkey;
+CREATE UNIQUE INDEX IF NOT EXISTS ratings_user_profile_movie_key ON ratings (user_id, (COALESCE(profile_id, 0)), movie_id);
+
+ALTER TABLE lists ADD COLUMN IF NOT EXISTS profile_id bigint REFERENCES profiles ON DELETE CASCADE;
+
+INSERT INTO permissions (code) VALUES ('households:manage') ON CONFLICT (code) DO NOTHING;
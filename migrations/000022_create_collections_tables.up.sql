@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS collections (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    tenant_id bigint NOT NULL REFERENCES tenants ON DELETE RESTRICT,
+    name text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS collections_tenant_idx ON collections (tenant_id);
+
+-- A movie belongs to at most one collection, at a position unique within the collection
+CREATE TABLE IF NOT EXISTS collection_movies (
+    movie_id bigint PRIMARY KEY REFERENCES movies ON DELETE CASCADE,
+    collection_id bigint NOT NULL REFERENCES collections ON DELETE CASCADE,
+    position integer NOT NULL,
+    CONSTRAINT collection_movies_position_key UNIQUE (collection_id, position),
+    CONSTRAINT collection_movies_position_check CHECK (position >= 1)
+);
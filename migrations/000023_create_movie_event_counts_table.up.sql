@@ -0,0 +1,16 @@
+-- Number of the views, rentals and ratings of each movie by the hour, from which the
+-- trending movies and the popularity of the movies are computed
+CREATE TABLE IF NOT EXISTS movie_event_counts (
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    bucket timestamp(0) with time zone NOT NULL,
+    kind text NOT NULL,
+    count integer NOT NULL DEFAULT 0,
+    PRIMARY KEY (movie_id, bucket, kind),
+    CONSTRAINT movie_event_counts_kind_check CHECK (kind IN ('view', 'rental', 'rating'))
+);
+
+CREATE INDEX IF NOT EXISTS movie_event_counts_bucket_idx ON movie_event_counts (bucket);
+
+-- Decayed popularity score, refreshed periodically by the API server
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS popularity float8 NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS movies_popularity_idx ON movies (tenant_id, popularity DESC);
@@ -0,0 +1,2 @@
+-- Permission to add, edit and delete the movies of the catalogue and their images
+INSERT INTO permissions (code) VALUES ('movies:write') ON CONFLICT (code) DO NOTHING;
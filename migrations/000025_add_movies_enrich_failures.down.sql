@@ -0,0 +1,2 @@
+ALTER TABLE movies DROP COLUMN IF EXISTS enrich_retry_at;
+ALTER TABLE movies DROP COLUMN IF EXISTS enrich_failures;
@@ -0,0 +1,3 @@
+-- Number of the enrichments of the movie which failed in a row, and the time until which it is not tried again
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS enrich_failures integer NOT NULL DEFAULT 0;
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS enrich_retry_at timestamp(0) with time zone;
@@ -0,0 +1,2 @@
+-- Authentication tokens may be bound to a profile of the household, which is then in effect for every request
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS profile_id bigint REFERENCES profiles ON DELETE CASCADE;
@@ -0,0 +1,9 @@
+package migrations
+
+import "embed"
+
+// Migrations of the database schema embedded in the binary, named <version>_<name>.up.sql and
+// <version>_<name>.down.sql. They are applied in the order of their versions
+//
+//go:embed *.sql
+var Files embed.FS